@@ -11,19 +11,29 @@ package fiber
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2/internal/colorable"
+	"github.com/gofiber/fiber/v2/internal/encoding/json"
 	"github.com/gofiber/fiber/v2/internal/isatty"
 	"github.com/gofiber/fiber/v2/utils"
 
@@ -63,6 +73,14 @@ type Storage interface {
 	Close() error
 }
 
+// StreamStorage can be implemented by a Storage to receive large values, like
+// the files saved by Ctx.SaveFileToStorage, as a stream instead of a byte slice.
+type StreamStorage interface {
+	// SetStream stores the content of r for the given key, size is the
+	// number of bytes r provides. The ttl works like in Storage.Set.
+	SetStream(key string, r io.Reader, size int64, ttl time.Duration) error
+}
+
 // ErrorHandler defines a function that will process all errors
 // returned from any handlers in the stack
 //  cfg := fiber.Config{}
@@ -100,6 +118,28 @@ type App struct {
 	server *fasthttp.Server
 	// App config
 	config Config
+	// Sorted ranges of the trusted proxies
+	trustedProxies []ipRange
+	// Whether Listen was called, apps can't be mounted afterwards
+	listening bool
+	// Routes of the latest registration, named by Name
+	latestRoutes   []*Route
+	latestPath     string
+	latestHandlers []Handler
+	// Named routes
+	namedRoutes map[string]*Route
+	// Error handlers of groups, matched by their prefix
+	errorHandlers []*Route
+	// Lifecycle hooks
+	hooks *Hooks
+	// Converts the values returned to handlers, copies them if Immutable is set
+	getString func(b []byte) string
+	getBytes  func(s string) []byte
+	// Open connections and their state, closed by ShutdownWithContext
+	conns      map[net.Conn]fasthttp.ConnState
+	connsMutex sync.Mutex
+	// TLS client hellos by remote address, see Ctx.ClientHelloInfo
+	clientHellos map[string]*tls.ClientHelloInfo
 }
 
 // Config is a struct holding the server settings.
@@ -152,6 +192,33 @@ type Config struct {
 	// Default: 4 * 1024 * 1024
 	BodyLimit int `json:"body_limit"`
 
+	// StreamRequestBody calls the handler once the BodyLimit of the body is
+	// read, the rest is streamed through Ctx.BodyStream. Bodies larger than
+	// the BodyLimit are accepted, so handlers have to limit what they read.
+	// BodyParser, Body and the form methods still read the whole stream.
+	//
+	// Default: false
+	StreamRequestBody bool `json:"stream_request_body"`
+
+	// Max number of files in a multipart form, more are rejected
+	// with ErrMultipartFilesLimit.
+	//
+	// Default: 0 (no limit)
+	MaxMultipartFiles int `json:"max_multipart_files"`
+
+	// Max total size of the files in a multipart form, larger forms are
+	// rejected with ErrMultipartSizeLimit.
+	//
+	// Default: 0 (limited by BodyLimit)
+	MaxMultipartSize int `json:"max_multipart_size"`
+
+	// Max bytes of multipart form files kept in memory, the rest is stored
+	// in temporary files which are removed once the handler returns.
+	// With 0 the form is parsed by fasthttp, which keeps 16 MB in memory.
+	//
+	// Default: 0
+	MaxMultipartMemory int `json:"max_multipart_memory"`
+
 	// Maximum number of concurrent connections.
 	//
 	// Default: 256 * 1024
@@ -162,6 +229,12 @@ type Config struct {
 	// Default: nil
 	Views Views `json:"-"`
 
+	// PassLocalsToViews adds the c.Locals entries to the Map bound by c.Render,
+	// like a CSRF token or the current user. Entries of the bound Map win.
+	//
+	// Default: false
+	PassLocalsToViews bool `json:"pass_locals_to_views"`
+
 	// The amount of time allowed to read the full request including body.
 	// It is reset after the request handler has returned.
 	// The connection's read deadline is reset when the connection opens.
@@ -194,6 +267,12 @@ type Config struct {
 	// Default: 4096
 	WriteBufferSize int `json:"write_buffer_size"`
 
+	// StreamBufferSize is the size of the chunks c.SendStream copies
+	// from a stream of unknown length.
+	//
+	// Default: 32768
+	StreamBufferSize int `json:"stream_buffer_size"`
+
 	// CompressedFileSuffix adds suffix to the original file name and
 	// tries saving the resulting compressed file under the new file name.
 	//
@@ -208,6 +287,28 @@ type Config struct {
 	// Default: ""
 	ProxyHeader string `json:"proxy_header"`
 
+	// When set to true, the forwarded headers are only honored by c.IP(), c.IPs(),
+	// c.Protocol() and c.Hostname() if the request comes from one of the
+	// TrustedProxies. Other requests get the values of the connection.
+	// c.Hostname() also honors the X-Forwarded-Host header of trusted proxies.
+	//
+	// Default: false
+	EnableTrustedProxyCheck bool `json:"enable_trusted_proxy_check"`
+
+	// When set to true, c.IP() and c.IPs() validate the IP addresses of the
+	// ProxyHeader and X-Forwarded-For headers. Entries are trimmed, ports are
+	// removed and invalid entries are skipped. c.IP() returns the first
+	// valid address or the remote IP if there is none.
+	//
+	// Default: false
+	EnableIPValidation bool `json:"enable_ip_validation"`
+
+	// TrustedProxies is a list of IP addresses and CIDR ranges like 10.0.0.0/8
+	// of the proxies in front of the app, see EnableTrustedProxyCheck.
+	//
+	// Default: []string
+	TrustedProxies []string `json:"trusted_proxies"`
+
 	// GETOnly rejects all non-GET requests if set to true.
 	// This option is useful as anti-DoS protection for servers
 	// accepting only GET requests. The request size is limited
@@ -243,11 +344,31 @@ type Config struct {
 	// Default: false
 	DisableHeaderNormalizing bool `json:"disable_header_normalizing"`
 
+	// When set to true, GET routes don't answer HEAD requests,
+	// only explicitly registered HEAD routes do.
+	//
+	// Default: false
+	DisableHeadAutoRegister bool `json:"disable_head_auto_register"`
+
+	// When set to true, requests for paths which are only registered for other
+	// methods get a 404 Not Found instead of a 405 Method Not Allowed response
+	// with the Allow header.
+	//
+	// Default: false
+	DisableMethodNotAllowed bool `json:"disable_method_not_allowed"`
+
 	// When set to true, it will not print out the «Fiber» ASCII art and listening address.
 	//
 	// Default: false
 	DisableStartupMessage bool `json:"disable_startup_message"`
 
+	// ShutdownOnSignal shuts the app down with ShutdownWithContext when the
+	// process receives an interrupt or SIGTERM, so the OnShutdown hooks run
+	// before Listen returns. With Prefork every child shuts down on its own.
+	//
+	// Default: false
+	ShutdownOnSignal bool `json:"shutdown_on_signal"`
+
 	// Aggressively reduces memory usage at the cost of higher CPU usage
 	// if set to true.
 	//
@@ -258,6 +379,19 @@ type Config struct {
 	// Default: false
 	ReduceMemoryUsage bool `json:"reduce_memory_usage"`
 
+	// JSONEncoder is used by Ctx.JSON, Ctx.JSONP and the DefaultErrorHandler
+	// to encode the response body.
+	// Set it to use an alternative JSON library like goccy/go-json.
+	//
+	// Default: json.Marshal
+	JSONEncoder func(v interface{}) ([]byte, error) `json:"-"`
+
+	// JSONDecoder is used by Ctx.BodyParser to decode application/json request bodies.
+	// Set it to use an alternative JSON library like goccy/go-json.
+	//
+	// Default: json.Unmarshal
+	JSONDecoder func(data []byte, v interface{}) error `json:"-"`
+
 	// FEATURE: v2.3.x
 	// The router executes the same handler by default if StrictRouting or CaseSensitive is disabled.
 	// Enabling RedirectFixedPath will change this behaviour into a client redirect to the original route path.
@@ -297,23 +431,55 @@ type Static struct {
 	//
 	// Optional. Default value 0.
 	MaxAge int `json:"max_age"`
+
+	// File to serve, relative to the file system root, if the path is not found.
+	// Useful for SPA's with client-side routing. Only used by StaticFS.
+	//
+	// Optional. Default value "".
+	NotFoundFile string `json:"not_found_file"`
+
+	// When set to true, files are served with a Content-Disposition header
+	// that makes browsers download them under their base name.
+	//
+	// Optional. Default value false.
+	Download bool `json:"download"`
+
+	// ModifyResponse is called once the file is resolved, before the response
+	// is sent. It isn't called if no file is found. The path of the file is
+	// stored in c.Locals under StaticFileKey, for StaticFS it's the name in the
+	// file system.
+	//
+	// Optional. Default: nil
+	ModifyResponse Handler `json:"-"`
 }
 
+// StaticFileKey is the Locals key of the file served by Static.ModifyResponse
+const StaticFileKey = "static_file"
+
+// SkipLogKey is the Locals key which keeps a request out of the logs when it's
+// set to true by a handler, i.e. the probes of the healthcheck middleware
+const SkipLogKey = "skip_log"
+
 // Default Config values
 const (
 	DefaultBodyLimit            = 4 * 1024 * 1024
 	DefaultConcurrency          = 256 * 1024
 	DefaultReadBufferSize       = 4096
 	DefaultWriteBufferSize      = 4096
+	DefaultStreamBufferSize     = 32 * 1024
 	DefaultCompressedFileSuffix = ".fiber.gz"
 )
 
-// DefaultErrorHandler that process return errors from handlers
+// DefaultErrorHandler that process return errors from handlers. Clients which
+// prefer JSON get the Error encoded by the JSONEncoder, the others plain text.
 var DefaultErrorHandler = func(c *Ctx, err error) error {
 	code := StatusInternalServerError
 	if e, ok := err.(*Error); ok {
 		code = e.Code
 	}
+	if c.Accepts(MIMETextPlain, MIMEApplicationJSON) == MIMEApplicationJSON {
+		return c.Status(code).JSON(Error{Code: code, Message: err.Error()})
+	}
 	c.Set(HeaderContentType, MIMETextPlainCharsetUTF8)
 	return c.Status(code).SendString(err.Error())
 }
@@ -331,6 +497,11 @@ func New(config ...Config) *App {
 		// Create router stack
 		stack:     make([][]*Route, len(intMethod)),
 		treeStack: make([]map[string][]*Route, len(intMethod)),
+		// Create named routes map
+		namedRoutes: make(map[string]*Route),
+		// Create connections map
+		conns:        make(map[net.Conn]fasthttp.ConnState),
+		clientHellos: make(map[string]*tls.ClientHelloInfo),
 		// Create Ctx pool
 		pool: sync.Pool{
 			New: func() interface{} {
@@ -340,6 +511,8 @@ func New(config ...Config) *App {
 		// Create config
 		config: Config{},
 	}
+	// Create lifecycle hooks
+	app.hooks = &Hooks{app: app}
 	// Override config if provided
 	if len(config) > 0 {
 		app.config = config[0]
@@ -364,15 +537,29 @@ func New(config ...Config) *App {
 	if app.config.WriteBufferSize <= 0 {
 		app.config.WriteBufferSize = DefaultWriteBufferSize
 	}
+	if app.config.StreamBufferSize <= 0 {
+		app.config.StreamBufferSize = DefaultStreamBufferSize
+	}
 	if app.config.CompressedFileSuffix == "" {
 		app.config.CompressedFileSuffix = DefaultCompressedFileSuffix
 	}
+	// Zero-copy conversions, unless the values must be immutable
+	app.getString, app.getBytes = getString, getBytes
 	if app.config.Immutable {
-		getBytes, getString = getBytesImmutable, getStringImmutable
+		app.getString, app.getBytes = getStringImmutable, getBytesImmutable
 	}
 	if app.config.ErrorHandler == nil {
 		app.config.ErrorHandler = DefaultErrorHandler
 	}
+	if app.config.JSONEncoder == nil {
+		app.config.JSONEncoder = json.Marshal
+	}
+	if app.config.JSONDecoder == nil {
+		app.config.JSONDecoder = json.Unmarshal
+	}
+	if app.config.EnableTrustedProxyCheck {
+		app.trustedProxies = parseTrustedProxies(app.config.TrustedProxies)
+	}
 	// Init app
 	app.init()
 	// Return app
@@ -382,17 +569,117 @@ func New(config ...Config) *App {
 // Mount attaches another app instance as a subrouter along a routing path.
 // It's very useful to split up a large API as many independent routers and
 // compose them as a single service using Mount.
+// The routes of the mounted app keep its error handlers, apps that mount other
+// apps themselves have to do so before they are mounted. Unlike the middleware
+// of App.Use, its middleware only matches whole segments below the prefix, so
+// an app mounted at "/api" doesn't handle "/apiv2".
 func (app *App) Mount(prefix string, fiber *App) Router {
+	app.mount(prefix, fiber)
+	return app
+}
+
+// mount copies the routes of the fiber app to the stack under the prefix
+func (app *App) mount(prefix string, fiber *App) {
+	app.mutex.Lock()
+	listening := app.listening
+	app.mutex.Unlock()
+	if listening {
+		panic("mount: app is already listening\n")
+	}
+	// Routes without an error handler belong to the mounted app itself
+	var errorHandler ErrorHandler
+	if reflect.ValueOf(fiber.config.ErrorHandler).Pointer() != reflect.ValueOf(DefaultErrorHandler).Pointer() {
+		errorHandler = fiber.config.ErrorHandler
+	}
 	stack := fiber.Stack()
 	for m := range stack {
 		for r := range stack[m] {
 			route := app.copyRoute(stack[m][r])
-			app.addRoute(route.Method, app.addPrefixToRoute(prefix, route))
+			route.scoped = true
+			// Groups of the mounted app take precedence over the app itself
+			if route.errorHandler == nil {
+				route.errorHandler = fiber.nearestErrorHandler(route.path, route.Path)
+			}
+			if route.errorHandler == nil {
+				route.errorHandler = errorHandler
+			}
+			prefixed := app.addPrefixToRoute(prefix, route)
+			route = app.addRoute(route.Method, prefixed)
+			app.hooks.executeOnRouteHooks(*prefixed)
+			if route.Name != "" {
+				app.addMountedName(route, fiber.namedRoutes[route.Name])
+			}
 		}
 	}
+	app.setLatestRoutes("", nil)
+}
+
+// addMountedName registers the name of a mounted route, preferring the method
+// the name was registered for in the mounted app
+func (app *App) addMountedName(route, named *Route) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	existing, ok := app.namedRoutes[route.Name]
+	if ok && existing.Path != route.Path {
+		panic(fmt.Sprintf("mount: route name %q is used by %s and %s\n", route.Name, existing.Path, route.Path))
+	}
+	if !ok || (named != nil && route.Method == named.Method) {
+		app.namedRoutes[route.Name] = route
+	}
+}
+
+// Name assigns a name to the latest registered route, which can be used to
+// look it up with GetRoute or to build its URL with Ctx.GetRouteURL.
+//  app.Get("/users/:id", handler).Name("user.show")
+// Names must be unique, using one for another path or method panics.
+func (app *App) Name(name string) Router {
+	app.mutex.Lock()
+	if len(app.latestRoutes) == 0 {
+		app.mutex.Unlock()
+		return app
+	}
+	if existing, ok := app.namedRoutes[name]; ok && !containsRoute(app.latestRoutes, existing) {
+		route := app.latestRoutes[len(app.latestRoutes)-1]
+		app.mutex.Unlock()
+		panic(fmt.Sprintf("name: route name %q is used by %s %s and %s %s\n",
+			name, existing.Method, existing.Path, route.Method, route.Path))
+	}
+	for _, route := range app.latestRoutes {
+		if route.Name != "" && containsRoute(app.latestRoutes, app.namedRoutes[route.Name]) {
+			delete(app.namedRoutes, route.Name)
+		}
+		route.Name = name
+	}
+	route := app.latestRoutes[len(app.latestRoutes)-1]
+	app.namedRoutes[name] = route
+	app.mutex.Unlock()
+	app.hooks.executeOnNameHooks(*route)
 	return app
 }
 
+// UseErrorHandler replaces the error handler of the app, see Config.ErrorHandler.
+// Use Group.UseErrorHandler to handle the errors of some routes differently.
+func (app *App) UseErrorHandler(handler ErrorHandler) Router {
+	if handler == nil {
+		panic("error handler cannot be nil\n")
+	}
+	app.mutex.Lock()
+	app.config.ErrorHandler = handler
+	app.mutex.Unlock()
+	return app
+}
+
+// GetRoute returns the route registered with the name,
+// the returned Route is empty if there is none.
+func (app *App) GetRoute(name string) Route {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	if route, ok := app.namedRoutes[name]; ok {
+		return *route
+	}
+	return Route{}
+}
+
 // Use registers a middleware route that will match requests
 // with the provided prefix (which is optional and defaults to "/").
 //
@@ -427,8 +714,10 @@ func (app *App) Use(args ...interface{}) Router {
 
 // Get registers a route for GET methods that requests a representation
 // of the specified resource. Requests using GET should only retrieve data.
+// The route answers HEAD requests too, unless Config.DisableHeadAutoRegister is set
+// or a HEAD route is registered for the path.
 func (app *App) Get(path string, handlers ...Handler) Router {
-	return app.Add(MethodHead, path, handlers...).Add(MethodGet, path, handlers...)
+	return app.Add(MethodGet, path, handlers...)
 }
 
 // Head registers a route for HEAD methods that asks for a response identical
@@ -526,19 +815,32 @@ func NewError(code int, message ...string) *Error {
 
 // Listener can be used to pass a custom listener.
 func (app *App) Listener(ln net.Listener) error {
+	app.setListening()
 	// Prefork is supported for custom listeners
 	if app.config.Prefork {
 		addr, tls := lnMetadata(ln)
 		return app.prefork(addr, tls)
 	}
 
+	// Run listen hooks
+	if err := app.hooks.executeOnListenHooks(ln.Addr().String(), isTLSListener(ln)); err != nil {
+		return err
+	}
+
 	// Print startup message
 	if !app.config.DisableStartupMessage {
 		app.startupMessage(ln.Addr().String(), false, "")
 	}
 
 	// TODO: Detect TLS
-	return app.server.Serve(ln)
+	return app.serve(ln)
+}
+
+// setListening marks the app as listening, see App.Mount
+func (app *App) setListening() {
+	app.mutex.Lock()
+	app.listening = true
+	app.mutex.Unlock()
 }
 
 // Listen serves HTTP requests from the given addr.
@@ -546,21 +848,131 @@ func (app *App) Listener(ln net.Listener) error {
 //  app.Listen(":8080")
 //  app.Listen("127.0.0.1:8080")
 func (app *App) Listen(addr string) error {
+	return app.listen(addr, nil)
+}
+
+// ListenTLS serves HTTPS requests from the given addr.
+// certFile and keyFile are the paths to TLS certificate and key file.
+//
+//  app.ListenTLS(":8080", "./cert.pem", "./cert.key")
+func (app *App) ListenTLS(addr, certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: cannot load TLS key pair from certFile=%q and keyFile=%q: %v", certFile, keyFile, err)
+	}
+	return app.ListenTLSWithCertificate(addr, cert)
+}
+
+// ListenTLSWithCertificate serves HTTPS requests from the given addr
+// with a certificate which is already loaded.
+func (app *App) ListenTLSWithCertificate(addr string, cert tls.Certificate) error {
+	return app.listen(addr, &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	})
+}
+
+// ListenMutualTLS serves HTTPS requests from the given addr and requires
+// client certificates signed by the CA in clientCertFile.
+// certFile and keyFile are the paths to TLS certificate and key file.
+//
+//  app.ListenMutualTLS(":8080", "./cert.pem", "./cert.key", "./ca-chain-cert.pem")
+func (app *App) ListenMutualTLS(addr, certFile, keyFile, clientCertFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: cannot load TLS key pair from certFile=%q and keyFile=%q: %v", certFile, keyFile, err)
+	}
+	clientCACert, err := ioutil.ReadFile(filepath.Clean(clientCertFile))
+	if err != nil {
+		return err
+	}
+	clientCertPool := x509.NewCertPool()
+	if !clientCertPool.AppendCertsFromPEM(clientCACert) {
+		return fmt.Errorf("tls: no certificates found in clientCertFile=%q", clientCertFile)
+	}
+	return app.listen(addr, &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCertPool,
+	})
+}
+
+// listen serves requests from the given addr, using TLS if a config is provided
+func (app *App) listen(addr string, tlsConfig *tls.Config) error {
+	if tlsConfig != nil {
+		tlsConfig = app.trackClientHello(tlsConfig)
+	}
 	// Start prefork
 	if app.config.Prefork {
-		return app.prefork(addr, nil)
+		app.setListening()
+		return app.prefork(addr, tlsConfig)
 	}
 	// Setup listener
 	ln, err := net.Listen("tcp4", addr)
 	if err != nil {
 		return err
 	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	app.setListening()
+	// Run listen hooks
+	if err = app.hooks.executeOnListenHooks(ln.Addr().String(), tlsConfig != nil); err != nil {
+		_ = ln.Close()
+		return err
+	}
 	// Print startup message
 	if !app.config.DisableStartupMessage {
-		app.startupMessage(ln.Addr().String(), false, "")
+		app.startupMessage(ln.Addr().String(), tlsConfig != nil, "")
 	}
 	// Start listening
-	return app.server.Serve(ln)
+	return app.serve(ln)
+}
+
+// serve serves requests from the listener, see Config.ShutdownOnSignal
+func (app *App) serve(ln net.Listener) error {
+	if !app.config.ShutdownOnSignal {
+		return app.server.Serve(ln)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	shutdown := make(chan error, 1)
+	go func() {
+		select {
+		case <-signals:
+			shutdown <- app.ShutdownWithContext(context.Background())
+		case <-done:
+			close(shutdown)
+		}
+	}()
+
+	err := app.server.Serve(ln)
+	signal.Stop(signals)
+	close(done)
+	// Wait for the shutdown hooks if a signal stopped the server
+	if shutdownErr, ok := <-shutdown; ok && err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+// trackClientHello returns a copy of the config which remembers the
+// ClientHelloInfo of every connection for Ctx.ClientHelloInfo
+func (app *App) trackClientHello(config *tls.Config) *tls.Config {
+	config = config.Clone()
+	getConfigForClient := config.GetConfigForClient
+	config.GetConfigForClient = func(info *tls.ClientHelloInfo) (*tls.Config, error) {
+		app.connsMutex.Lock()
+		app.clientHellos[info.Conn.RemoteAddr().String()] = info
+		app.connsMutex.Unlock()
+		if getConfigForClient != nil {
+			return getConfigForClient(info)
+		}
+		return nil, nil
+	}
+	return config
 }
 
 // Config returns the app config as value ( read-only ).
@@ -578,19 +990,128 @@ func (app *App) Stack() [][]*Route {
 	return app.stack
 }
 
+// GetRoutes returns copies of all registered routes in the order of their
+// registration. Middleware is listed once with the method "USE", it can be
+// left out by passing true.
+func (app *App) GetRoutes(filterUseOption ...bool) []Route {
+	var filterUse bool
+	if len(filterUseOption) > 0 {
+		filterUse = filterUseOption[0]
+	}
+	var routes []Route
+	for m := range app.stack {
+		for _, route := range app.stack[m] {
+			method := intMethod[m]
+			if route.use && !route.static {
+				// Middleware is part of every method stack
+				if filterUse || m > 0 {
+					continue
+				}
+				method = methodUse
+			}
+			r := *route
+			r.Method = method
+			r.Params = append([]string(nil), route.Params...)
+			r.Handlers = append([]Handler(nil), route.Handlers...)
+			routes = append(routes, r)
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].pos < routes[j].pos
+	})
+	return routes
+}
+
 // Shutdown gracefully shuts down the server without interrupting any active connections.
 // Shutdown works by first closing all open listeners and then waiting indefinitely for all connections to return to idle and then shut down.
 //
 // Make sure the program doesn't exit and waits instead for Shutdown to return.
 //
-// Shutdown does not close keepalive connections so its recommended to set ReadTimeout to something else than 0.
+// Shutdown does not close keepalive connections so its recommended to set ReadTimeout to something else than 0,
+// or to use ShutdownWithTimeout or ShutdownWithContext which close them.
+//
+// The shutdown hooks run after the server stopped, even if stopping it failed.
 func (app *App) Shutdown() error {
+	return app.shutdown(context.Background(), false)
+}
+
+// ShutdownWithTimeout works like ShutdownWithContext with a context that
+// expires after the timeout.
+func (app *App) ShutdownWithTimeout(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return app.ShutdownWithContext(ctx)
+}
+
+// ShutdownWithContext stops accepting new connections, closes the idle ones and
+// waits for the active requests until the context is done. Then the remaining
+// connections are closed forcibly and the error of the context is returned,
+// handlers which are still running are not interrupted.
+//
+// The shutdown hooks run after the server stopped, even if stopping it failed.
+func (app *App) ShutdownWithContext(ctx context.Context) error {
+	return app.shutdown(ctx, true)
+}
+
+// shutdown stops the server, the idle connections are closed if closeIdle is set
+func (app *App) shutdown(ctx context.Context, closeIdle bool) error {
 	app.mutex.Lock()
-	defer app.mutex.Unlock()
-	if app.server == nil {
+	server := app.server
+	app.mutex.Unlock()
+	if server == nil {
 		return fmt.Errorf("shutdown: server is not running")
 	}
-	return app.server.Shutdown()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Shutdown()
+	}()
+	// Keepalive connections would block the shutdown until they time out
+	if closeIdle {
+		app.closeConns(fasthttp.StateIdle)
+	}
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		app.closeConns(fasthttp.StateNew, fasthttp.StateActive, fasthttp.StateIdle)
+		err = ctx.Err()
+	}
+	if hookErr := app.hooks.executeOnShutdownHooks(); err == nil {
+		err = hookErr
+	}
+	return err
+}
+
+// trackConn keeps track of the open connections, used as fasthttp.Server.ConnState
+func (app *App) trackConn(conn net.Conn, state fasthttp.ConnState) {
+	app.connsMutex.Lock()
+	switch state {
+	case fasthttp.StateHijacked, fasthttp.StateClosed:
+		delete(app.conns, conn)
+		delete(app.clientHellos, conn.RemoteAddr().String())
+	default:
+		app.conns[conn] = state
+	}
+	app.connsMutex.Unlock()
+}
+
+// closeConns closes the open connections in one of the states. The states are
+// read under the lock of trackConn, so a connection which turns active is
+// either closed before it's marked active or skipped.
+func (app *App) closeConns(states ...fasthttp.ConnState) {
+	app.connsMutex.Lock()
+	defer app.connsMutex.Unlock()
+	for conn, state := range app.conns {
+		for _, s := range states {
+			if state == s {
+				_ = conn.Close()
+				delete(app.conns, conn)
+				break
+			}
+		}
+	}
 }
 
 // Server returns the underlying fasthttp server
@@ -598,15 +1119,37 @@ func (app *App) Server() *fasthttp.Server {
 	return app.server
 }
 
+// TestTimeoutError is returned by App.Test if the response didn't arrive within the timeout.
+type TestTimeoutError struct {
+	Timeout time.Duration
+}
+
+// Error makes it compatible with the `error` interface.
+func (e *TestTimeoutError) Error() string {
+	return fmt.Sprintf("test: timeout error %vms", e.Timeout.Milliseconds())
+}
+
 // Test is used for internal debugging by passing a *http.Request.
 // Timeout is optional and defaults to 1s, -1 will disable it completely.
+// A *TestTimeoutError is returned if the timeout expires.
 func (app *App) Test(req *http.Request, msTimeout ...int) (resp *http.Response, err error) {
 	// Set timeout
 	timeout := 1000
 	if len(msTimeout) > 0 {
 		timeout = msTimeout[0]
 	}
+	return app.test(context.Background(), req, time.Duration(timeout)*time.Millisecond)
+}
 
+// TestWithContext works like Test without a timeout, the request is abandoned
+// and the error of the context is returned once the context is done.
+// Handlers receive the context as Ctx.UserContext.
+func (app *App) TestWithContext(ctx context.Context, req *http.Request) (resp *http.Response, err error) {
+	return app.test(ctx, req, -1)
+}
+
+// test serves the request, a negative timeout disables it
+func (app *App) test(ctx context.Context, req *http.Request, timeout time.Duration) (resp *http.Response, err error) {
 	// Add Content-Length if not provided with body
 	if req.Body != http.NoBody && req.Header.Get(HeaderContentLength) == "" {
 		req.Header.Add(HeaderContentLength, strconv.FormatInt(req.ContentLength, 10))
@@ -619,7 +1162,7 @@ func (app *App) Test(req *http.Request, msTimeout ...int) (resp *http.Response,
 	}
 
 	// Create test connection
-	conn := new(testConn)
+	conn := &testConn{ctx: ctx}
 
 	// Write raw http request
 	if _, err = conn.r.Write(dump); err != nil {
@@ -627,22 +1170,24 @@ func (app *App) Test(req *http.Request, msTimeout ...int) (resp *http.Response,
 	}
 
 	// Serve conn to server
-	channel := make(chan error)
+	channel := make(chan error, 1)
 	go func() {
 		channel <- app.server.ServeConn(conn)
 	}()
 
-	// Wait for callback
+	// Wait for callback, with timeout if enabled
+	var expired <-chan time.Time
 	if timeout >= 0 {
-		// With timeout
-		select {
-		case err = <-channel:
-		case <-time.After(time.Duration(timeout) * time.Millisecond):
-			return nil, fmt.Errorf("test: timeout error %vms", timeout)
-		}
-	} else {
-		// Without timeout
-		err = <-channel
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case err = <-channel:
+	case <-expired:
+		return nil, &TestTimeoutError{Timeout: timeout}
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
 
 	// Check for errors
@@ -653,8 +1198,13 @@ func (app *App) Test(req *http.Request, msTimeout ...int) (resp *http.Response,
 	// Read response
 	buffer := bufio.NewReader(&conn.w)
 
-	// Convert raw http response to *http.Response
-	return http.ReadResponse(buffer, req)
+	// Convert raw http response to *http.Response, skip interim responses like 103 Early Hints
+	for {
+		resp, err = http.ReadResponse(buffer, req)
+		if err != nil || resp.StatusCode >= StatusOK || resp.StatusCode == StatusSwitchingProtocols {
+			return resp, err
+		}
+	}
 }
 
 type disableLogger struct{}
@@ -702,6 +1252,7 @@ func (app *App) init() *App {
 
 	// fasthttp server settings
 	app.server.Handler = app.handler
+	app.server.ConnState = app.trackConn
 	app.server.Name = app.config.ServerHeader
 	app.server.Concurrency = app.config.Concurrency
 	app.server.NoDefaultDate = app.config.DisableDefaultDate
@@ -709,6 +1260,7 @@ func (app *App) init() *App {
 	app.server.DisableHeaderNamesNormalizing = app.config.DisableHeaderNormalizing
 	app.server.DisableKeepalive = app.config.DisableKeepalive
 	app.server.MaxRequestBodySize = app.config.BodyLimit
+	app.server.StreamRequestBody = app.config.StreamRequestBody
 	app.server.NoDefaultServerHeader = app.config.ServerHeader == ""
 	app.server.ReadTimeout = app.config.ReadTimeout
 	app.server.WriteTimeout = app.config.WriteTimeout
@@ -5,18 +5,25 @@
 package fiber
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"mime"
+	"mime/multipart"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -86,6 +93,100 @@ func Test_App_MethodNotAllowed(t *testing.T) {
 	utils.AssertEqual(t, "GET, HEAD, POST, OPTIONS", resp.Header.Get(HeaderAllow))
 }
 
+// go test -run Test_App_MethodNotAllowed_Head
+func Test_App_MethodNotAllowed_Head(t *testing.T) {
+	app := New()
+	app.Add(MethodGet, "/users/:id", testEmptyHandler)
+	app.Delete("/users/:id", testEmptyHandler)
+
+	resp, err := app.Test(httptest.NewRequest(MethodPost, "/users/1", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "GET, HEAD, DELETE", resp.Header.Get(HeaderAllow))
+
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/posts/1", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderAllow))
+
+	// HEAD isn't allowed without the auto registered HEAD routes
+	app = New(Config{DisableHeadAutoRegister: true})
+	app.Add(MethodGet, "/users/:id", testEmptyHandler)
+	app.Delete("/users/:id", testEmptyHandler)
+
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/users/1", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "GET, DELETE", resp.Header.Get(HeaderAllow))
+
+	resp, err = app.Test(httptest.NewRequest(MethodHead, "/users/1", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, "GET, DELETE", resp.Header.Get(HeaderAllow))
+}
+
+// go test -run Test_App_Head_Auto
+func Test_App_Head_Auto(t *testing.T) {
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+	app.Get("/explicit", func(c *Ctx) error {
+		return c.SendString("GET")
+	})
+	app.Head("/explicit", func(c *Ctx) error {
+		c.Set("X-Handler", "HEAD")
+		return c.SendString("HEAD handler")
+	})
+	app.Head("/before", func(c *Ctx) error {
+		c.Set("X-Handler", "HEAD")
+		return nil
+	})
+	app.Get("/before", func(c *Ctx) error {
+		return c.SendString("GET")
+	})
+	// The replaced HEAD route of "/explicit" isn't counted
+	utils.AssertEqual(t, 6, app.handlerCount)
+
+	resp, err := app.Test(httptest.NewRequest(MethodHead, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "13", resp.Header.Get(HeaderContentLength))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "", string(body))
+
+	// Explicit HEAD routes take precedence, regardless of the order
+	for _, path := range []string{"/explicit", "/before"} {
+		resp, err = app.Test(httptest.NewRequest(MethodHead, path, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode)
+		utils.AssertEqual(t, "HEAD", resp.Header.Get("X-Handler"), path)
+	}
+}
+
+// go test -run Test_App_DisableHeadAutoRegister
+func Test_App_DisableHeadAutoRegister(t *testing.T) {
+	app := New(Config{DisableHeadAutoRegister: true})
+	app.Get("/", testEmptyHandler)
+
+	resp, err := app.Test(httptest.NewRequest(MethodHead, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusMethodNotAllowed, resp.StatusCode)
+	utils.AssertEqual(t, MethodGet, resp.Header.Get(HeaderAllow))
+}
+
+// go test -run Test_App_DisableMethodNotAllowed
+func Test_App_DisableMethodNotAllowed(t *testing.T) {
+	app := New(Config{DisableMethodNotAllowed: true})
+	app.Post("/", testEmptyHandler)
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderAllow))
+}
+
 func Test_App_Custom_Middleware_404_Should_Not_SetMethodNotAllowed(t *testing.T) {
 	app := New()
 
@@ -180,6 +281,38 @@ func Test_App_ErrorHandler_Custom(t *testing.T) {
 	utils.AssertEqual(t, "hi, i'm an custom error", string(body))
 }
 
+// go test -run Test_App_ErrorHandler_JSON
+func Test_App_ErrorHandler_JSON(t *testing.T) {
+	app := New(Config{
+		JSONEncoder: func(v interface{}) ([]byte, error) {
+			e := v.(Error)
+			return []byte(fmt.Sprintf(`{"status":%d,"error":%q}`, e.Code, e.Message)), nil
+		},
+	})
+
+	app.Get("/", func(c *Ctx) error {
+		return ErrTeapot
+	})
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Set(HeaderAccept, MIMEApplicationJSON)
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusTeapot, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, MIMEApplicationJSON, resp.Header.Get(HeaderContentType))
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"status":418,"error":"I'm a teapot"}`, string(body))
+
+	// Plain text is preferred
+	req = httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Set(HeaderAccept, "*/*")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, MIMETextPlainCharsetUTF8, resp.Header.Get(HeaderContentType))
+}
+
 func Test_App_ErrorHandler_HandlerStack(t *testing.T) {
 	app := New(Config{
 		ErrorHandler: func(c *Ctx, err error) error {
@@ -272,6 +405,354 @@ func Test_App_Mount(t *testing.T) {
 	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
 }
 
+// go test -run Test_App_Mount_ErrorHandler
+func Test_App_Mount_ErrorHandler(t *testing.T) {
+	micro := New(Config{
+		ErrorHandler: func(c *Ctx, err error) error {
+			return c.Status(StatusTeapot).SendString("micro: " + err.Error())
+		},
+	})
+	micro.Get("/doe", func(c *Ctx) error {
+		return errors.New("doe")
+	})
+
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		return errors.New("root")
+	})
+	app.Mount("/john", micro)
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/john/doe", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusTeapot, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "micro: doe", string(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusInternalServerError, resp.StatusCode, "Status code")
+
+	// The default error handler of a mounted app doesn't override the parent one
+	app = New(Config{
+		ErrorHandler: func(c *Ctx, err error) error {
+			return c.Status(StatusTeapot).SendString("app: " + err.Error())
+		},
+	})
+	micro = New()
+	micro.Get("/doe", func(c *Ctx) error {
+		return errors.New("doe")
+	})
+	app.Mount("/john", micro)
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/john/doe", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusTeapot, resp.StatusCode, "Status code")
+}
+
+// go test -run Test_App_Group_UseErrorHandler
+func Test_App_Group_UseErrorHandler(t *testing.T) {
+	t.Parallel()
+	handler := func(name string) ErrorHandler {
+		return func(c *Ctx, err error) error {
+			return c.Status(StatusTeapot).SendString(name + ": " + err.Error())
+		}
+	}
+	fail := func(c *Ctx) error {
+		return errors.New(c.Path())
+	}
+
+	app := New(Config{ErrorHandler: handler("app")})
+	app.Get("/", fail)
+	app.Get("/apiv1", fail)
+
+	api := app.Group("/api")
+	api.Get("/users", fail)
+	api.UseErrorHandler(handler("api"))
+
+	v1 := api.Group("/v1/:version")
+	v1.Get("/users", fail)
+	v1.UseErrorHandler(handler("v1"))
+
+	// Groups without error handler use the one of the parent group
+	api.Group("/v2").Get("/users", fail)
+	api.Get("/v1/legacy", fail)
+
+	for path, expected := range map[string]string{
+		"/":               "app: /",
+		"/api/users":      "api: /api/users",
+		"/api/v1/b/users": "v1: /api/v1/b/users",
+		"/api/v2/users":   "api: /api/v2/users",
+		"/api/v1/legacy":  "v1: /api/v1/legacy",
+		"/API/Users":      "api: /API/Users",
+		"/apiv1":          "app: /apiv1",
+	} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusTeapot, resp.StatusCode, path)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, expected, string(body), path)
+	}
+}
+
+// go test -run Test_App_Mount_UseErrorHandler
+func Test_App_Mount_UseErrorHandler(t *testing.T) {
+	t.Parallel()
+	handler := func(name string) ErrorHandler {
+		return func(c *Ctx, err error) error {
+			return c.Status(StatusTeapot).SendString(name)
+		}
+	}
+	fail := func(c *Ctx) error {
+		return ErrBadRequest
+	}
+
+	micro := New(Config{ErrorHandler: handler("micro")})
+	micro.Get("/doe", fail)
+	admin := micro.Group("/admin")
+	admin.Get("/users/:id", fail)
+	admin.UseErrorHandler(handler("micro admin"))
+
+	app := New()
+	app.Group("/john").UseErrorHandler(handler("john"))
+	app.Get("/john/smith", fail)
+	app.Mount("/john", micro)
+
+	for path, expected := range map[string]string{
+		"/john/doe":            "micro",
+		"/john/admin/users/12": "micro admin",
+		"/john/smith":          "john",
+	} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, expected, string(body), path)
+	}
+}
+
+// go test -run Test_App_UseErrorHandler
+func Test_App_UseErrorHandler(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		return errors.New("root")
+	})
+	app.UseErrorHandler(func(c *Ctx, err error) error {
+		return c.Status(StatusTeapot).SendString("app: " + err.Error())
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusTeapot, resp.StatusCode, "Status code")
+
+	defer func() {
+		utils.AssertEqual(t, "error handler cannot be nil\n", recover())
+	}()
+	app.Group("/api").UseErrorHandler(nil)
+}
+
+// go test -run Test_App_Mount_Use
+func Test_App_Mount_Use(t *testing.T) {
+	micro := New()
+	micro.Use(func(c *Ctx) error {
+		c.Set("X-Micro", "1")
+		return c.Next()
+	})
+	micro.Get("/doe", testEmptyHandler)
+
+	app := New()
+	app.Mount("/john", micro)
+	app.Get("/johnny", testEmptyHandler)
+	app.Get("/", testEmptyHandler)
+
+	for path, header := range map[string]string{"/john/doe": "1", "/john": "1", "/johnny": "", "/": ""} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, header, resp.Header.Get("X-Micro"), path)
+	}
+}
+
+// go test -run Test_App_Use_Prefix
+func Test_App_Use_Prefix(t *testing.T) {
+	app := New()
+	app.Use("/john", func(c *Ctx) error {
+		c.Set("X-Use", "1")
+		return c.Next()
+	})
+	app.Get("/johnny", testEmptyHandler)
+	app.Get("/john/doe", testEmptyHandler)
+
+	// Middleware of the app itself matches any path starting with its prefix
+	for _, path := range []string{"/john/doe", "/johnny"} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, "1", resp.Header.Get("X-Use"), path)
+	}
+}
+
+// go test -run Test_App_Mount_Nested
+func Test_App_Mount_Nested(t *testing.T) {
+	users := New()
+	users.Get("/:id", func(c *Ctx) error {
+		return c.SendString(c.Params("id"))
+	})
+
+	api := New()
+	api.Mount("/users", users)
+
+	app := New()
+	app.Mount("/api/v1", api)
+	app.Group("/v2").Mount("/api", api)
+
+	for _, path := range []string{"/api/v1/users/john", "/v2/api/users/john"} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, path)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "john", string(body))
+	}
+}
+
+// go test -run Test_App_Mount_After_Listen
+func Test_App_Mount_After_Listen(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		defer func() {
+			utils.AssertEqual(t, "mount: app is already listening\n", recover())
+			utils.AssertEqual(t, nil, app.Shutdown())
+		}()
+		app.Mount("/john", New())
+	}()
+
+	ln := fasthttputil.NewInmemoryListener()
+	utils.AssertEqual(t, nil, app.Listener(ln))
+}
+
+// go test -run Test_App_Name
+func Test_App_Name(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	app.Get("/users/:id", testEmptyHandler).Name("user.show")
+	app.Post("/users", testEmptyHandler).Name("user.store")
+	app.Group("/api").All("/status", testEmptyHandler).Name("api.status")
+	app.Static("/assets", "./.github").Name("assets")
+
+	route := app.GetRoute("user.show")
+	utils.AssertEqual(t, MethodGet, route.Method)
+	utils.AssertEqual(t, "/users/:id", route.Path)
+	utils.AssertEqual(t, "user.show", route.Name)
+
+	route = app.GetRoute("user.store")
+	utils.AssertEqual(t, MethodPost, route.Method)
+	utils.AssertEqual(t, "/users", route.Path)
+
+	utils.AssertEqual(t, "/api/status", app.GetRoute("api.status").Path)
+	utils.AssertEqual(t, "/assets", app.GetRoute("assets").Path)
+	utils.AssertEqual(t, Route{}, app.GetRoute("unknown"))
+
+	// The name applies to all methods of the registration
+	for _, method := range intMethod {
+		for _, r := range app.stack[methodInt(method)] {
+			if r.Path == "/api/status" {
+				utils.AssertEqual(t, "api.status", r.Name, method)
+			}
+		}
+	}
+	for _, r := range app.stack[methodInt(MethodHead)] {
+		if r.Path == "/users/:id" {
+			utils.AssertEqual(t, "user.show", r.Name)
+		}
+	}
+
+	// Renaming the latest route releases its previous name
+	app.Put("/users/:id", testEmptyHandler).Name("user.update").Name("user.replace")
+	utils.AssertEqual(t, Route{}, app.GetRoute("user.update"))
+	utils.AssertEqual(t, MethodPut, app.GetRoute("user.replace").Method)
+}
+
+// go test -run Test_App_GetRoutes
+func Test_App_GetRoutes(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Use(testEmptyHandler)
+	app.Get("/users/:id", testEmptyHandler, testEmptyHandler).Name("user.show")
+	app.Group("/api", testEmptyHandler).Post("/users", testEmptyHandler)
+	app.Static("/assets", "./.github")
+
+	type info struct {
+		method, path, name string
+		params             []string
+		handlers           int
+	}
+	var routes []info
+	for _, r := range app.GetRoutes() {
+		routes = append(routes, info{r.Method, r.Path, r.Name, r.Params, len(r.Handlers)})
+	}
+	utils.AssertEqual(t, []info{
+		{methodUse, "/", "", nil, 1},
+		{MethodHead, "/users/:id", "user.show", []string{"id"}, 2},
+		{MethodGet, "/users/:id", "user.show", []string{"id"}, 2},
+		{methodUse, "/api", "", nil, 1},
+		{MethodPost, "/api/users", "", nil, 1},
+		{MethodGet, "/assets", "", nil, 1},
+		{MethodHead, "/assets", "", nil, 1},
+	}, routes)
+
+	routes = routes[:0]
+	for _, r := range app.GetRoutes(true) {
+		routes = append(routes, info{r.Method, r.Path, r.Name, r.Params, len(r.Handlers)})
+	}
+	utils.AssertEqual(t, 5, len(routes))
+	utils.AssertEqual(t, MethodHead, routes[0].method)
+
+	// The routes are copies
+	route := app.GetRoutes()[1]
+	route.Params[0] = "name"
+	route.Handlers[0] = nil
+	route.Path = "/changed"
+	route = app.GetRoutes()[1]
+	utils.AssertEqual(t, "/users/:id", route.Path)
+	utils.AssertEqual(t, "id", route.Params[0])
+	utils.AssertEqual(t, true, route.Handlers[0] != nil)
+}
+
+// go test -run Test_App_Name_Duplicate
+func Test_App_Name_Duplicate(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/users/:id", testEmptyHandler).Name("user")
+
+	defer func() {
+		utils.AssertEqual(t, "name: route name \"user\" is used by GET /users/:id and POST /users\n", recover())
+	}()
+	app.Post("/users", testEmptyHandler).Name("user")
+}
+
+// go test -run Test_App_Name_Mount
+func Test_App_Name_Mount(t *testing.T) {
+	t.Parallel()
+	users := New()
+	users.Get("/:id", testEmptyHandler).Name("user.show")
+
+	app := New()
+	app.Mount("/users", users)
+
+	route := app.GetRoute("user.show")
+	utils.AssertEqual(t, MethodGet, route.Method)
+	utils.AssertEqual(t, "/users/:id", route.Path)
+
+	defer func() {
+		utils.AssertEqual(t, "mount: route name \"user.show\" is used by /users/:id and /people/:id\n", recover())
+	}()
+	app.Mount("/people", users)
+}
+
 func Test_App_Use_Params(t *testing.T) {
 	app := New()
 
@@ -488,6 +969,56 @@ func Test_App_New(t *testing.T) {
 	appConfig.Get("/", testEmptyHandler)
 }
 
+// go test -race -run Test_App_Immutable
+func Test_App_Immutable(t *testing.T) {
+	app := New(Config{Immutable: true})
+
+	type captured struct {
+		param, query, header, cookie, host, path, body string
+	}
+	values := make(chan captured, 10)
+	var wg sync.WaitGroup
+	app.Post("/users/:name", func(c *Ctx) error {
+		param, query, header := c.Params("name"), c.Query("sort"), c.Get("X-Request")
+		cookie, host, path, body := c.Cookies("session"), c.Hostname(), c.Path(), c.Body()
+		wg.Add(1)
+		// Use the values after the handler returned
+		go func() {
+			defer wg.Done()
+			time.Sleep(50 * time.Millisecond)
+			values <- captured{param, query, header, cookie, host, path, string(body)}
+		}()
+		return nil
+	})
+
+	var expected []captured
+	for _, name := range []string{"john", "doe", "alexander"} {
+		req := httptest.NewRequest(MethodPost, "/users/"+name+"?sort="+name, strings.NewReader("body of "+name))
+		req.Header.Set("X-Request", "request "+name)
+		req.Header.Set(HeaderCookie, "session="+name)
+		req.Host = name + ".example.com"
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode)
+		expected = append(expected, captured{
+			name, name, "request " + name, name, name + ".example.com", "/users/" + name, "body of " + name,
+		})
+	}
+	wg.Wait()
+	close(values)
+
+	var results []captured
+	for v := range values {
+		results = append(results, v)
+	}
+	sort.Slice(results, func(i, j int) bool { return len(results[i].param) < len(results[j].param) })
+	sort.Slice(expected, func(i, j int) bool { return len(expected[i].param) < len(expected[j].param) })
+	utils.AssertEqual(t, expected, results)
+
+	// Other apps keep the zero-copy conversions
+	utils.AssertEqual(t, reflect.ValueOf(utils.UnsafeString).Pointer(), reflect.ValueOf(New().getString).Pointer())
+}
+
 func Test_App_Config(t *testing.T) {
 	app := New(Config{
 		DisableStartupMessage: true,
@@ -513,6 +1044,132 @@ func Test_App_Shutdown(t *testing.T) {
 	})
 }
 
+// go test -run Test_App_ShutdownWithTimeout
+func Test_App_ShutdownWithTimeout(t *testing.T) {
+	t.Run("graceful", func(t *testing.T) {
+		app := New(Config{DisableStartupMessage: true})
+		app.Get("/", func(c *Ctx) error {
+			time.Sleep(300 * time.Millisecond)
+			return c.SendString("done")
+		})
+		hooked := false
+		app.Hooks().OnShutdown(func() error {
+			hooked = true
+			return nil
+		})
+
+		ln := startTestListener(t, app)
+		conn := sendTestRequest(t, ln)
+		defer conn.Close()
+
+		utils.AssertEqual(t, nil, app.ShutdownWithTimeout(3*time.Second))
+		utils.AssertEqual(t, true, hooked)
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	})
+
+	t.Run("forced close", func(t *testing.T) {
+		app := New(Config{DisableStartupMessage: true})
+		release := make(chan struct{})
+		defer close(release)
+		app.Get("/", func(c *Ctx) error {
+			<-release
+			return c.SendString("done")
+		})
+		hooked := false
+		app.Hooks().OnShutdown(func() error {
+			hooked = true
+			return nil
+		})
+
+		ln := startTestListener(t, app)
+		conn := sendTestRequest(t, ln)
+		defer conn.Close()
+
+		start := time.Now()
+		utils.AssertEqual(t, context.DeadlineExceeded, app.ShutdownWithTimeout(300*time.Millisecond))
+		utils.AssertEqual(t, true, time.Since(start) < 2*time.Second)
+		utils.AssertEqual(t, true, hooked)
+
+		// The connection of the hanging request is closed
+		utils.AssertEqual(t, nil, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		_, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		utils.AssertEqual(t, true, err != nil)
+	})
+}
+
+// go test -run Test_App_ShutdownWithContext
+func Test_App_ShutdownWithContext(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+	app.Get("/", testEmptyHandler)
+	ln := startTestListener(t, app)
+
+	// An idle keepalive connection doesn't block the shutdown
+	conn := sendTestRequest(t, ln)
+	defer conn.Close()
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	utils.AssertEqual(t, nil, app.ShutdownWithContext(ctx))
+}
+
+// go test -run Test_App_Shutdown_Keepalive
+func Test_App_Shutdown_Keepalive(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+	app.Get("/", testEmptyHandler)
+	ln := startTestListener(t, app)
+
+	conn := sendTestRequest(t, ln)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+
+	// Shutdown waits for the idle keepalive connection
+	done := make(chan error, 1)
+	go func() {
+		done <- app.Shutdown()
+	}()
+	select {
+	case <-done:
+		t.Fatal("Shutdown closed the idle connection")
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	utils.AssertEqual(t, nil, conn.Close())
+	select {
+	case err = <-done:
+		utils.AssertEqual(t, nil, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("Shutdown didn't return once the connection was closed")
+	}
+}
+
+// startTestListener serves the app on a random local port
+func startTestListener(t *testing.T, app *App) net.Listener {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	go func() {
+		_ = app.Listener(ln)
+	}()
+	return ln
+}
+
+// sendTestRequest sends a GET request to the listener without waiting for the response
+func sendTestRequest(t *testing.T, ln net.Listener) net.Conn {
+	conn, err := net.Dial("tcp4", ln.Addr().String())
+	utils.AssertEqual(t, nil, err)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+	// Wait until the request reached the handler
+	time.Sleep(100 * time.Millisecond)
+	return conn
+}
+
 // go test -run Test_App_Static_Index_Default
 func Test_App_Static_Index_Default(t *testing.T) {
 	app := New()
@@ -584,6 +1241,124 @@ func Test_App_Static_MaxAge(t *testing.T) {
 	utils.AssertEqual(t, "public, max-age=100", resp.Header.Get(HeaderCacheControl), "CacheControl Control")
 }
 
+// go test -run Test_App_Static_Download
+func Test_App_Static_Download(t *testing.T) {
+	app := New()
+
+	app.Static("/", "./.github", Static{Download: true})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/testdata/fs/css/style.css", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, `attachment; filename="style.css"`, resp.Header.Get(HeaderContentDisposition))
+	utils.AssertEqual(t, "text/css; charset=utf-8", resp.Header.Get(HeaderContentType))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, `attachment; filename="index.html"`, resp.Header.Get(HeaderContentDisposition))
+}
+
+// go test -run Test_App_Static_ModifyResponse
+func Test_App_Static_ModifyResponse(t *testing.T) {
+	app := New()
+
+	var files []string
+	app.Static("/static", "./.github/testdata/fs", Static{
+		ModifyResponse: func(c *Ctx) error {
+			file := c.Locals(StaticFileKey).(string)
+			files = append(files, file)
+			if strings.HasSuffix(file, ".html") {
+				c.Set(HeaderContentSecurityPolicy, "default-src 'self'")
+			}
+			return nil
+		},
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/static/css/style.css", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderContentSecurityPolicy))
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderContentDisposition))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/static", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "default-src 'self'", resp.Header.Get(HeaderContentSecurityPolicy))
+
+	// Not called for missing files
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/static/not-found", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+
+	utils.AssertEqual(t, []string{
+		"./.github/testdata/fs/css/style.css",
+		filepath.Join("./.github/testdata/fs", "index.html"),
+	}, files)
+}
+
+// go test -run Test_App_Static_ModifyResponse_Error
+func Test_App_Static_ModifyResponse_Error(t *testing.T) {
+	app := New()
+
+	app.Static("/", "./.github", Static{
+		ModifyResponse: func(c *Ctx) error {
+			return ErrForbidden
+		},
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/index.html", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusForbidden, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, utils.StatusMessage(StatusForbidden), string(body))
+}
+
+// go test -run Test_App_Static_ByteRange
+func Test_App_Static_ByteRange(t *testing.T) {
+	app := New()
+
+	app.Static("/", "./.github/testdata", Static{ByteRange: true})
+
+	req := httptest.NewRequest(MethodGet, "/index.html", nil)
+	req.Header.Set(HeaderRange, "bytes=0-2")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusPartialContent, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "bytes", resp.Header.Get(HeaderAcceptRanges))
+	utils.AssertEqual(t, "bytes 0-2/20", resp.Header.Get(HeaderContentRange))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "<p>", string(body))
+
+	req = httptest.NewRequest(MethodGet, "/index.html", nil)
+	req.Header.Set(HeaderRange, "bytes=100-")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusRequestedRangeNotSatisfiable, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "bytes */20", resp.Header.Get(HeaderContentRange))
+
+	// Multiple ranges are streamed as multipart/byteranges
+	req = httptest.NewRequest(MethodGet, "/index.html", nil)
+	req.Header.Set(HeaderRange, "bytes=0-2,4-5")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusPartialContent, resp.StatusCode, "Status code")
+	_, params, err := mime.ParseMediaType(resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, nil, err)
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	for _, expected := range []string{"<p>", "el"} {
+		part, err := mr.NextPart()
+		utils.AssertEqual(t, nil, err)
+		body, err = ioutil.ReadAll(part)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, expected, string(body))
+	}
+	_, err = mr.NextPart()
+	utils.AssertEqual(t, io.EOF, err)
+}
+
 // go test -run Test_App_Static_Group
 func Test_App_Static_Group(t *testing.T) {
 	app := New()
@@ -885,6 +1660,122 @@ func Test_App_Listen_Prefork(t *testing.T) {
 	utils.AssertEqual(t, nil, app.Listen(":99999"))
 }
 
+// go test -run Test_App_ListenTLS
+func Test_App_ListenTLS(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+
+	utils.AssertEqual(t, false, app.ListenTLS(":0", "./.github/testdata/missing.pem", "./.github/testdata/ssl.key") == nil)
+
+	app.Get("/", func(c *Ctx) error {
+		utils.AssertEqual(t, true, c.Secure())
+		utils.AssertEqual(t, "localhost", c.ClientHelloInfo().ServerName)
+		utils.AssertEqual(t, 0, len(c.TLSConnectionState().PeerCertificates))
+		return c.SendString("secure")
+	})
+	addr := listenTestAddr(app)
+
+	go func() {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{ServerName: "localhost", InsecureSkipVerify: true}, // #nosec G402
+		}}
+		resp, err := client.Get("https://" + <-addr)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, StatusOK, resp.StatusCode)
+		utils.AssertEqual(t, nil, resp.Body.Close())
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
+
+	utils.AssertEqual(t, nil, app.ListenTLS("127.0.0.1:0", "./.github/testdata/ssl.pem", "./.github/testdata/ssl.key"))
+}
+
+// go test -run Test_App_ListenTLSWithCertificate
+func Test_App_ListenTLSWithCertificate(t *testing.T) {
+	cert, err := tls.LoadX509KeyPair("./.github/testdata/ssl.pem", "./.github/testdata/ssl.key")
+	utils.AssertEqual(t, nil, err)
+
+	app := New(Config{DisableStartupMessage: true})
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString(c.Protocol())
+	})
+	addr := listenTestAddr(app)
+
+	go func() {
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // #nosec G402
+		}}
+		resp, err := client.Get("https://" + <-addr)
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "https", string(body))
+		utils.AssertEqual(t, nil, resp.Body.Close())
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
+
+	utils.AssertEqual(t, nil, app.ListenTLSWithCertificate("127.0.0.1:0", cert))
+}
+
+// go test -run Test_App_ListenMutualTLS
+func Test_App_ListenMutualTLS(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+
+	utils.AssertEqual(t, false, app.ListenMutualTLS(":0", "./.github/testdata/ssl.pem", "./.github/testdata/ssl.key", "./.github/testdata/missing.pem") == nil)
+	utils.AssertEqual(t, false, app.ListenMutualTLS(":0", "./.github/testdata/ssl.pem", "./.github/testdata/ssl.key", "./.github/testdata/ssl.key") == nil)
+
+	app.Get("/", func(c *Ctx) error {
+		return c.SendString(c.TLSConnectionState().PeerCertificates[0].Subject.CommonName)
+	})
+	addr := listenTestAddr(app)
+
+	go func() {
+		url := "https://" + <-addr
+
+		// Clients without a certificate are rejected
+		client := &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // #nosec G402
+		}}
+		_, err := client.Get(url)
+		utils.AssertEqual(t, true, err != nil)
+
+		cert, err := tls.LoadX509KeyPair("./.github/testdata/client.pem", "./.github/testdata/client.key")
+		utils.AssertEqual(t, nil, err)
+		client = &http.Client{Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true, Certificates: []tls.Certificate{cert}}, // #nosec G402
+		}}
+		resp, err := client.Get(url)
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "fiber-client", string(body))
+		utils.AssertEqual(t, nil, resp.Body.Close())
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
+
+	utils.AssertEqual(t, nil, app.ListenMutualTLS("127.0.0.1:0", "./.github/testdata/ssl.pem", "./.github/testdata/ssl.key", "./.github/testdata/ca.pem"))
+}
+
+// go test -run Test_App_ListenTLS_Prefork
+func Test_App_ListenTLS_Prefork(t *testing.T) {
+	testPreforkMaster = true
+	defer func(cmd string) { dummyChildCmd = cmd }(dummyChildCmd)
+	dummyChildCmd = "go"
+
+	app := New(Config{DisableStartupMessage: true, Prefork: true})
+
+	utils.AssertEqual(t, nil, app.ListenTLS(":99999", "./.github/testdata/ssl.pem", "./.github/testdata/ssl.key"))
+	utils.AssertEqual(t, nil, app.ListenMutualTLS(":99999", "./.github/testdata/ssl.pem", "./.github/testdata/ssl.key", "./.github/testdata/ca.pem"))
+}
+
+// listenTestAddr returns a channel receiving the address the app listens on
+func listenTestAddr(app *App) <-chan string {
+	addr := make(chan string, 1)
+	app.Hooks().OnListen(func(data ListenData) error {
+		addr <- net.JoinHostPort(data.Host, data.Port)
+		return nil
+	})
+	return addr
+}
+
 // go test -run Test_App_Listener
 func Test_App_Listener(t *testing.T) {
 	app := New()
@@ -965,7 +1856,41 @@ func Test_Test_Timeout(t *testing.T) {
 	})
 
 	_, err = app.Test(httptest.NewRequest(MethodGet, "/timeout", nil), 50)
-	utils.AssertEqual(t, true, err != nil, "app.Test(req)")
+	utils.AssertEqual(t, &TestTimeoutError{Timeout: 50 * time.Millisecond}, err, "app.Test(req)")
+	utils.AssertEqual(t, "test: timeout error 50ms", err.Error())
+
+	// A disabled timeout waits for slow handlers
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/timeout", nil), -1)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+}
+
+// go test -run Test_Test_WithContext
+func Test_Test_WithContext(t *testing.T) {
+	app := New()
+
+	app.Get("/", func(c *Ctx) error {
+		select {
+		case <-c.UserContext().Done():
+			return c.UserContext().Err()
+		case <-time.After(time.Second):
+			return c.SendString("too late")
+		}
+	})
+	app.Get("/fast", func(c *Ctx) error {
+		return c.SendString("fast")
+	})
+
+	resp, err := app.TestWithContext(context.Background(), httptest.NewRequest(MethodGet, "/fast", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	_, err = app.TestWithContext(ctx, httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, context.Canceled, err)
+	utils.AssertEqual(t, true, time.Since(start) < 500*time.Millisecond)
 }
 
 type errorReader int
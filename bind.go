@@ -0,0 +1,227 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2/internal/schema"
+)
+
+// Validator is implemented by structs that validate themselves after binding.
+// Bind returns an 422 Unprocessable Entity error if Validate fails.
+type Validator interface {
+	Validate() error
+}
+
+// Bind binds the request data to structs, see Ctx.Bind
+type Bind struct {
+	ctx *Ctx
+}
+
+// bindDecoderPools holds the decoders used by Bind, one pool per tag as the
+// decoders cache the struct fields by the tag they were first used with
+var bindDecoderPools = map[string]*sync.Pool{
+	"query":  newBindDecoderPool("query"),
+	"params": newBindDecoderPool("params"),
+	"header": newBindDecoderPool("header"),
+	"cookie": newBindDecoderPool("cookie"),
+}
+
+func newBindDecoderPool(tag string) *sync.Pool {
+	return &sync.Pool{New: func() interface{} {
+		var decoder = schema.NewDecoder()
+		decoder.SetAliasTag(tag)
+		decoder.IgnoreUnknownKeys(true)
+		return decoder
+	}}
+}
+
+// timeType is used to find the time.Time fields with a layout tag
+var timeType = reflect.TypeOf(time.Time{})
+
+// Bind returns a binder for the request body, query string, route params,
+// headers and cookies. The struct fields are matched by the json, query, params,
+// header and cookie tags. time.Time fields are parsed as RFC3339 or with the
+// layout given in the layout tag, e.g. `query:"since" layout:"2006-01-02"`.
+func (c *Ctx) Bind() *Bind {
+	return &Bind{ctx: c}
+}
+
+// Body binds the request body to a struct, see Ctx.BodyParser
+func (b *Bind) Body(out interface{}) error {
+	if err := b.ctx.BodyParser(out); err != nil {
+		return err
+	}
+	return validate(out)
+}
+
+// Query binds the query string to a struct, repeated keys fill slices
+func (b *Bind) Query(out interface{}) error {
+	if err := b.query(out); err != nil {
+		return err
+	}
+	return validate(out)
+}
+
+// Params binds the route params to a struct
+func (b *Bind) Params(out interface{}) error {
+	if err := b.params(out); err != nil {
+		return err
+	}
+	return validate(out)
+}
+
+// Header binds the request headers to a struct, repeated headers fill slices
+func (b *Bind) Header(out interface{}) error {
+	if err := b.header(out); err != nil {
+		return err
+	}
+	return validate(out)
+}
+
+// Cookie binds the request cookies to a struct
+func (b *Bind) Cookie(out interface{}) error {
+	if err := b.cookie(out); err != nil {
+		return err
+	}
+	return validate(out)
+}
+
+// All binds the body, query string, headers, cookies and route params to a
+// struct in this order, a value of a later source overrides an earlier one.
+// The body is skipped if the request has none.
+func (b *Bind) All(out interface{}) error {
+	if len(b.ctx.fasthttp.Request.Body()) > 0 {
+		if err := b.ctx.BodyParser(out); err != nil {
+			return err
+		}
+	}
+	for _, bind := range []func(interface{}) error{b.query, b.header, b.cookie, b.params} {
+		if err := bind(out); err != nil {
+			return err
+		}
+	}
+	return validate(out)
+}
+
+func (b *Bind) query(out interface{}) error {
+	data := make(map[string][]string)
+	b.ctx.fasthttp.QueryArgs().VisitAll(func(key, val []byte) {
+		k := b.ctx.app.getString(key)
+		data[k] = append(data[k], b.ctx.app.getString(val))
+	})
+	return bindData(out, "query", data)
+}
+
+func (b *Bind) params(out interface{}) error {
+	data := make(map[string][]string, len(b.ctx.route.Params))
+	for key, val := range b.ctx.AllParams() {
+		if len(val) > 0 {
+			data[key] = []string{val}
+		}
+	}
+	return bindData(out, "params", data)
+}
+
+func (b *Bind) header(out interface{}) error {
+	return bindData(out, "header", b.ctx.GetReqHeaders())
+}
+
+func (b *Bind) cookie(out interface{}) error {
+	return b.ctx.CookieParser(out)
+}
+
+// bindData binds the data to the struct using the given tag
+func bindData(out interface{}, tag string, data map[string][]string) error {
+	if err := applyTimeLayouts(reflect.TypeOf(out), tag, "", data); err != nil {
+		return err
+	}
+
+	// Get decoder from pool
+	pool := bindDecoderPools[tag]
+	decoder := pool.Get().(*schema.Decoder)
+	defer pool.Put(decoder)
+
+	return decoder.Decode(out, data)
+}
+
+// applyTimeLayouts converts the values of time.Time fields with a layout tag
+// to RFC3339, which is understood by the decoder
+func applyTimeLayouts(t reflect.Type, tag, prefix string, data map[string][]string) error {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// Skip unexported fields
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		alias := strings.Split(field.Tag.Get(tag), ",")[0]
+		if alias == "-" {
+			continue
+		}
+		if alias == "" {
+			alias = field.Name
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+			ft = ft.Elem()
+		}
+		if ft != timeType {
+			// Embedded structs share the prefix of their parent
+			if field.Anonymous {
+				if err := applyTimeLayouts(ft, tag, prefix, data); err != nil {
+					return err
+				}
+			} else if ft.Kind() == reflect.Struct {
+				if err := applyTimeLayouts(ft, tag, prefix+alias+".", data); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		layout := field.Tag.Get("layout")
+		if layout == "" {
+			continue
+		}
+		for key, values := range data {
+			if !strings.EqualFold(key, prefix+alias) {
+				continue
+			}
+			converted := make([]string, len(values))
+			for j, value := range values {
+				if value == "" {
+					continue
+				}
+				date, err := time.Parse(layout, value)
+				if err != nil {
+					return fmt.Errorf("bind: cannot parse %s %q with layout %q", key, value, layout)
+				}
+				converted[j] = date.Format(time.RFC3339Nano)
+			}
+			data[key] = converted
+		}
+	}
+	return nil
+}
+
+// validate calls Validate on structs that implement Validator
+func validate(out interface{}) error {
+	if v, ok := out.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return NewError(StatusUnprocessableEntity, err.Error())
+		}
+	}
+	return nil
+}
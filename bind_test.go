@@ -0,0 +1,226 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 📝 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// go test -run Test_Bind_Query
+func Test_Bind_Query(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	type Address struct {
+		City string `query:"city"`
+	}
+	type Query struct {
+		Name    string    `query:"name"`
+		Tags    []string  `query:"tag"`
+		Ids     []int     `query:"id"`
+		Since   time.Time `query:"since" layout:"2006-01-02"`
+		Until   time.Time `query:"until"`
+		Address Address   `query:"address"`
+	}
+	c.Request().URI().SetQueryString("name=john&tag=a&tag=b&id=1&id=2&since=2020-12-31&until=2021-01-01T10:00:00Z&address.city=berlin")
+	q := new(Query)
+	utils.AssertEqual(t, nil, c.Bind().Query(q))
+	utils.AssertEqual(t, "john", q.Name)
+	utils.AssertEqual(t, []string{"a", "b"}, q.Tags)
+	utils.AssertEqual(t, []int{1, 2}, q.Ids)
+	utils.AssertEqual(t, time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), q.Since)
+	utils.AssertEqual(t, time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), q.Until)
+	utils.AssertEqual(t, "berlin", q.Address.City)
+
+	// invalid layout
+	c.Request().URI().SetQueryString("since=31.12.2020")
+	err := c.Bind().Query(q)
+	utils.AssertEqual(t, `bind: cannot parse since "31.12.2020" with layout "2006-01-02"`, err.Error())
+}
+
+// go test -run Test_Bind_Cookie_Layout
+func Test_Bind_Cookie_Layout(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	type Cookie struct {
+		Since time.Time `cookie:"since" layout:"2006-01-02"`
+		Until time.Time `cookie:"until"`
+	}
+	c.Request().Header.SetCookie("since", "2020-12-31")
+	c.Request().Header.SetCookie("until", "2021-01-01T10:00:00Z")
+
+	ck := new(Cookie)
+	utils.AssertEqual(t, nil, c.Bind().Cookie(ck))
+	utils.AssertEqual(t, time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), ck.Since)
+	utils.AssertEqual(t, time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC), ck.Until)
+
+	// invalid layout
+	c.Request().Header.SetCookie("since", "31.12.2020")
+	err := c.Bind().Cookie(ck)
+	utils.AssertEqual(t, `bind: cannot parse since "31.12.2020" with layout "2006-01-02"`, err.Error())
+}
+
+// go test -run Test_Bind_Params
+func Test_Bind_Params(t *testing.T) {
+	t.Parallel()
+	app := New()
+	type Params struct {
+		ID   int    `params:"id"`
+		Name string `params:"name"`
+	}
+	app.Get("/users/:id/:name?", func(c *Ctx) error {
+		p := new(Params)
+		if err := c.Bind().Params(p); err != nil {
+			return err
+		}
+		return c.SendString(p.Name + strconv.Itoa(p.ID))
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/users/1/john", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john1", getString(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/users/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "1", getString(body))
+}
+
+// go test -run Test_Bind_Header_Cookie
+func Test_Bind_Header_Cookie(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	type Header struct {
+		RequestID string   `header:"X-Request-ID"`
+		Forwarded []string `header:"X-Forwarded-For"`
+	}
+	type Cookie struct {
+		Session string `cookie:"session_id"`
+		Theme   string `cookie:"theme"`
+	}
+	c.Request().Header.Set("X-Request-Id", "123")
+	c.Request().Header.Add(HeaderXForwardedFor, "127.0.0.1")
+	c.Request().Header.Add(HeaderXForwardedFor, "127.0.0.2")
+	c.Request().Header.SetCookie("session_id", "abc")
+	c.Request().Header.SetCookie("theme", "dark")
+
+	h := new(Header)
+	utils.AssertEqual(t, nil, c.Bind().Header(h))
+	utils.AssertEqual(t, "123", h.RequestID)
+	utils.AssertEqual(t, []string{"127.0.0.1", "127.0.0.2"}, h.Forwarded)
+
+	ck := new(Cookie)
+	utils.AssertEqual(t, nil, c.Bind().Cookie(ck))
+	utils.AssertEqual(t, "abc", ck.Session)
+	utils.AssertEqual(t, "dark", ck.Theme)
+}
+
+type bindUser struct {
+	ID     int    `json:"id" query:"id" params:"id"`
+	Name   string `json:"name" query:"name"`
+	Role   string `json:"role" header:"X-Role"`
+	Locale string `query:"locale" cookie:"locale"`
+}
+
+func (u *bindUser) Validate() error {
+	if u.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// go test -run Test_Bind_All
+func Test_Bind_All(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Post("/users/:id", func(c *Ctx) error {
+		u := new(bindUser)
+		if err := c.Bind().All(u); err != nil {
+			return err
+		}
+		return c.JSON(u)
+	})
+
+	// later sources override earlier ones
+	req := httptest.NewRequest(MethodPost, "/users/3?id=2&name=doe&locale=en", strings.NewReader(`{"id":1,"name":"john","role":"user"}`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	req.Header.Set("X-Role", "admin")
+	req.Header.Set(HeaderCookie, "locale=de")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"id":3,"name":"doe","role":"admin","Locale":"de"}`, getString(body))
+
+	// requests without a body only use the other sources
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/users/3?name=john", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `{"id":3,"name":"john","role":"","Locale":""}`, getString(body))
+
+	// failed validation responds with 422
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/users/3", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusUnprocessableEntity, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "name is required", getString(body))
+}
+
+// go test -run Test_Bind_Body
+func Test_Bind_Body(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.SetContentType(MIMEApplicationJSON)
+	c.Request().SetBody([]byte(`{"name":"john"}`))
+	u := new(bindUser)
+	utils.AssertEqual(t, nil, c.Bind().Body(u))
+	utils.AssertEqual(t, "john", u.Name)
+
+	c.Request().SetBody([]byte(`{"name":""}`))
+	err := c.Bind().Body(u)
+	utils.AssertEqual(t, StatusUnprocessableEntity, err.(*Error).Code)
+}
+
+// go test -v -run=^$ -bench=Benchmark_Bind_Query -benchmem -count=4
+func Benchmark_Bind_Query(b *testing.B) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	type Query struct {
+		ID   int      `query:"id"`
+		Name string   `query:"name"`
+		Tags []string `query:"tag"`
+	}
+	c.Request().URI().SetQueryString("id=1&name=john&tag=a&tag=b")
+	q := new(Query)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_ = c.Bind().Query(q)
+	}
+	utils.AssertEqual(b, "john", q.Name)
+}
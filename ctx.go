@@ -5,15 +5,22 @@
 package fiber
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"net/url"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -21,7 +28,6 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
-	"github.com/gofiber/fiber/v2/internal/encoding/json"
 	"github.com/gofiber/fiber/v2/internal/schema"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
@@ -30,6 +36,19 @@ import (
 // maxParams defines the maximum number of parameters per route.
 const maxParams = 30
 
+// maxRestarts defines how often the routing of a request can be restarted.
+const maxRestarts = 10
+
+// Multipart form limit errors, see Config.MaxMultipartFiles and Config.MaxMultipartSize
+var (
+	ErrMultipartFilesLimit = NewError(StatusRequestEntityTooLarge, "Too many files in multipart form")
+	ErrMultipartSizeLimit  = NewError(StatusRequestEntityTooLarge, "Multipart form files are too large")
+)
+
+// ErrRestartLimit is returned by Ctx.RestartRouting when the routing
+// was restarted more than 10 times for a request.
+var ErrRestartLimit = errors.New("routing: restart limit exceeded")
+
 // Ctx represents the Context which hold the HTTP request and response.
 // It has methods for the request query string, parameters, body, HTTP headers and so on.
 type Ctx struct {
@@ -47,6 +66,11 @@ type Ctx struct {
 	values       [maxParams]string    // Route parameter values
 	fasthttp     *fasthttp.RequestCtx // Reference to *fasthttp.RequestCtx
 	matched      bool                 // Non use route matched
+	restarts     int                  // Restarts of the routing, see RestartRouting
+	userContext  context.Context      // Context set by the user, see SetUserContext
+	multipart    *multipart.Form      // Form parsed by MultipartForm
+	multipartErr error                // Error of MultipartForm
+	notFound     Route                // Route of the Ctx if no route matched
 }
 
 // Range data for c.Range
@@ -85,13 +109,14 @@ func (app *App) AcquireCtx(fctx *fasthttp.RequestCtx) *Ctx {
 	// Reset route and handler index
 	c.indexRoute = -1
 	c.indexHandler = 0
-	// Reset matched flag
+	// Reset matched flag and restarts
 	c.matched = false
+	c.restarts = 0
 	// Set paths
 	c.pathBuffer = append(c.pathBuffer[0:0], fctx.URI().PathOriginal()...)
-	c.pathOriginal = getString(fctx.URI().PathOriginal())
+	c.pathOriginal = app.getString(fctx.URI().PathOriginal())
 	// Set method
-	c.method = getString(fctx.Request.Header.Method())
+	c.method = app.getString(fctx.Request.Header.Method())
 	c.methodINT = methodInt(c.method)
 	// Attach *fasthttp.RequestCtx to ctx
 	c.fasthttp = fctx
@@ -104,81 +129,39 @@ func (app *App) AcquireCtx(fctx *fasthttp.RequestCtx) *Ctx {
 
 // ReleaseCtx releases the ctx back into the pool.
 func (app *App) ReleaseCtx(c *Ctx) {
+	// Remove temporary files of the multipart form
+	if c.multipart != nil {
+		_ = c.multipart.RemoveAll()
+		c.multipart = nil
+	}
+	c.multipartErr = nil
 	// Reset values
 	c.route = nil
 	c.fasthttp = nil
+	c.userContext = nil
 	app.pool.Put(c)
 }
 
 // Accepts checks if the specified extensions or content types are acceptable.
+// The offer with the highest quality value wins, ties are resolved by the
+// specificity of the matching media range and then by the order of the offers.
 func (c *Ctx) Accepts(offers ...string) string {
-	if len(offers) == 0 {
-		return ""
-	}
-	header := c.Get(HeaderAccept)
-	if header == "" {
-		return offers[0]
-	}
-
-	spec, commaPos := "", 0
-	for len(header) > 0 && commaPos != -1 {
-		commaPos = strings.IndexByte(header, ',')
-		if commaPos != -1 {
-			spec = utils.Trim(header[:commaPos], ' ')
-		} else {
-			spec = utils.TrimLeft(header, ' ')
-		}
-		if factorSign := strings.IndexByte(spec, ';'); factorSign != -1 {
-			spec = spec[:factorSign]
-		}
-
-		var mimetype string
-		for _, offer := range offers {
-			if len(offer) == 0 {
-				continue
-				// Accept: */*
-			} else if spec == "*/*" {
-				return offer
-			}
-
-			if strings.IndexByte(offer, '/') != -1 {
-				mimetype = offer // MIME type
-			} else {
-				mimetype = utils.GetMIME(offer) // extension
-			}
-
-			if spec == mimetype {
-				// Accept: <MIME_type>/<MIME_subtype>
-				return offer
-			}
-
-			s := strings.IndexByte(mimetype, '/')
-			// Accept: <MIME_type>/*
-			if strings.HasPrefix(spec, mimetype[:s]) && (spec[s:] == "/*" || mimetype[s:] == "/*") {
-				return offer
-			}
-		}
-		if commaPos != -1 {
-			header = header[commaPos+1:]
-		}
-	}
-
-	return ""
+	return getOffer(c.Get(HeaderAccept), acceptsOfferType, offers...)
 }
 
 // AcceptsCharsets checks if the specified charset is acceptable.
 func (c *Ctx) AcceptsCharsets(offers ...string) string {
-	return getOffer(c.Get(HeaderAcceptCharset), offers...)
+	return getOffer(c.Get(HeaderAcceptCharset), acceptsOffer, offers...)
 }
 
 // AcceptsEncodings checks if the specified encoding is acceptable.
 func (c *Ctx) AcceptsEncodings(offers ...string) string {
-	return getOffer(c.Get(HeaderAcceptEncoding), offers...)
+	return getOffer(c.Get(HeaderAcceptEncoding), acceptsOffer, offers...)
 }
 
 // AcceptsLanguages checks if the specified language is acceptable.
 func (c *Ctx) AcceptsLanguages(offers ...string) string {
-	return getOffer(c.Get(HeaderAcceptLanguage), offers...)
+	return getOffer(c.Get(HeaderAcceptLanguage), acceptsLanguageOffer, offers...)
 }
 
 // App returns the *App reference to the instance of the Fiber application
@@ -213,7 +196,7 @@ func (c *Ctx) Attachment(filename ...string) {
 		fname := filepath.Base(filename[0])
 		c.Type(filepath.Ext(fname))
 
-		c.setCanonical(HeaderContentDisposition, `attachment; filename="`+quoteString(fname)+`"`)
+		c.setCanonical(HeaderContentDisposition, contentDisposition(fname))
 		return
 	}
 	c.setCanonical(HeaderContentDisposition, "attachment")
@@ -234,9 +217,23 @@ func (c *Ctx) BaseURL() string {
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting instead.
 func (c *Ctx) Body() []byte {
+	if c.app.config.Immutable {
+		return utils.SafeBytes(c.fasthttp.Request.Body())
+	}
 	return c.fasthttp.Request.Body()
 }
 
+// BodyStream returns a reader for the raw request body, so handlers can
+// io.Copy it to files or storages. With Config.StreamRequestBody the body
+// isn't buffered beyond the BodyLimit, the stream can only be read once and
+// Body, BodyParser and the form methods read the part left in the stream.
+func (c *Ctx) BodyStream() io.Reader {
+	if stream := c.fasthttp.RequestBodyStream(); stream != nil {
+		return stream
+	}
+	return bytes.NewReader(c.fasthttp.Request.Body())
+}
+
 // decoderPool helps to improve BodyParser's and QueryParser's performance
 var decoderPool = &sync.Pool{New: func() interface{} {
 	var decoder = schema.NewDecoder()
@@ -246,39 +243,42 @@ var decoderPool = &sync.Pool{New: func() interface{} {
 
 // BodyParser binds the request body to a struct.
 // It supports decoding the following content types based on the Content-Type header:
-// application/json, application/xml, application/x-www-form-urlencoded, multipart/form-data
+// application/json, application/xml, text/xml, application/x-www-form-urlencoded,
+// multipart/form-data and the structured syntax suffixes +json and +xml (RFC 6839),
+// like application/problem+json. Nested structs of forms are set by keys like "user.name".
+// Other content types are rejected with ErrUnsupportedMediaType.
 func (c *Ctx) BodyParser(out interface{}) error {
 	// Get decoder from pool
 	schemaDecoder := decoderPool.Get().(*schema.Decoder)
 	defer decoderPool.Put(schemaDecoder)
 
-	// Get content-type
-	ctype := getString(c.fasthttp.Request.Header.ContentType())
+	// Get media type without parameters
+	ctype := parseMediaType(getString(c.fasthttp.Request.Header.ContentType()))
 
 	// Parse body accordingly
-	if strings.HasPrefix(ctype, MIMEApplicationJSON) {
+	if ctype == MIMEApplicationJSON || strings.HasSuffix(ctype, "+json") {
 		schemaDecoder.SetAliasTag("json")
-		return json.Unmarshal(c.fasthttp.Request.Body(), out)
-	} else if strings.HasPrefix(ctype, MIMEApplicationForm) {
+		return c.app.config.JSONDecoder(c.fasthttp.Request.Body(), out)
+	} else if ctype == MIMEApplicationForm {
 		schemaDecoder.SetAliasTag("form")
 		data := make(map[string][]string)
 		c.fasthttp.PostArgs().VisitAll(func(key []byte, val []byte) {
-			data[getString(key)] = append(data[getString(key)], getString(val))
+			data[c.app.getString(key)] = append(data[c.app.getString(key)], c.app.getString(val))
 		})
 		return schemaDecoder.Decode(out, data)
-	} else if strings.HasPrefix(ctype, MIMEMultipartForm) {
+	} else if ctype == MIMEMultipartForm {
 		schemaDecoder.SetAliasTag("form")
-		data, err := c.fasthttp.MultipartForm()
+		data, err := c.MultipartForm()
 		if err != nil {
 			return err
 		}
 		return schemaDecoder.Decode(out, data.Value)
-	} else if strings.HasPrefix(ctype, MIMETextXML) || strings.HasPrefix(ctype, MIMEApplicationXML) {
+	} else if ctype == MIMETextXML || ctype == MIMEApplicationXML || strings.HasSuffix(ctype, "+xml") {
 		schemaDecoder.SetAliasTag("xml")
 		return xml.Unmarshal(c.fasthttp.Request.Body(), out)
 	}
 	// No suitable content type found
-	return fmt.Errorf("bodyparser: cannot parse content-type: %v", ctype)
+	return ErrUnsupportedMediaType
 }
 
 // ClearCookie expires a specific cookie by key on the client side.
@@ -295,6 +295,17 @@ func (c *Ctx) ClearCookie(key ...string) {
 	})
 }
 
+// ClientHelloInfo returns the TLS ClientHelloInfo of the connection,
+// it is nil for plain connections and listeners passed to App.Listener.
+func (c *Ctx) ClientHelloInfo() *tls.ClientHelloInfo {
+	if !c.fasthttp.IsTLS() {
+		return nil
+	}
+	c.app.connsMutex.Lock()
+	defer c.app.connsMutex.Unlock()
+	return c.app.clientHellos[c.fasthttp.RemoteAddr().String()]
+}
+
 // Context returns *fasthttp.RequestCtx that carries a deadline
 // a cancellation signal, and other values across API boundaries.
 func (c *Ctx) Context() *fasthttp.RequestCtx {
@@ -332,13 +343,15 @@ func (c *Ctx) Cookie(cookie *Cookie) {
 // The returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting to use the value outside the Handler.
 func (c *Ctx) Cookies(key string, defaultValue ...string) string {
-	return defaultString(getString(c.fasthttp.Request.Header.Cookie(key)), defaultValue)
+	return defaultString(c.app.getString(c.fasthttp.Request.Header.Cookie(key)), defaultValue)
 }
 
 // Download transfers the file from path as an attachment.
 // Typically, browsers will prompt the user for download.
 // By default, the Content-Disposition header filename= parameter is the filepath (this typically appears in the browser dialog).
 // Override this default with the filename parameter.
+// Non-ASCII filenames are sent as a RFC 5987 encoded filename* parameter with an ASCII fallback,
+// CR and LF are removed.
 func (c *Ctx) Download(file string, filename ...string) error {
 	var fname string
 	if len(filename) > 0 {
@@ -346,7 +359,7 @@ func (c *Ctx) Download(file string, filename ...string) error {
 	} else {
 		fname = filepath.Base(file)
 	}
-	c.setCanonical(HeaderContentDisposition, `attachment; filename="`+quoteString(fname)+`"`)
+	c.setCanonical(HeaderContentDisposition, contentDisposition(fname))
 	return c.SendFile(file)
 }
 
@@ -402,27 +415,114 @@ func (c *Ctx) Format(body interface{}) error {
 	return c.SendString(b)
 }
 
+// ResFmt associates a media type or file extension with the handler that
+// sends the response in that format, see Ctx.Negotiate.
+type ResFmt struct {
+	MediaType string
+	Handler   func(*Ctx) error
+}
+
+// Negotiate performs content-negotiation on the Accept HTTP header, respecting
+// quality values and wildcards. It sets the Content-Type of the best matching
+// format and calls its handler. A missing Accept header or */* selects the first format.
+// If no format is acceptable, an 406 Not Acceptable error with the supported
+// media types is returned.
+func (c *Ctx) Negotiate(formats ...ResFmt) error {
+	c.Vary(HeaderAccept)
+
+	// Collect the offers, most calls have few enough formats to stay on the stack
+	var buf [8]string
+	offers := buf[:0]
+	for i := range formats {
+		offers = append(offers, formats[i].MediaType)
+	}
+
+	accept := getOffer(c.Get(HeaderAccept), acceptsOfferType, offers...)
+	for i := range formats {
+		if accept == "" || formats[i].MediaType != accept {
+			continue
+		}
+		if strings.IndexByte(accept, '/') != -1 {
+			c.fasthttp.Response.Header.SetContentType(accept)
+		} else {
+			c.Type(accept)
+		}
+		return formats[i].Handler(c)
+	}
+
+	// Nothing is acceptable, list the supported media types
+	supported := make([]string, 0, len(formats))
+	for i := range formats {
+		if strings.IndexByte(formats[i].MediaType, '/') != -1 {
+			supported = append(supported, formats[i].MediaType)
+		} else {
+			supported = append(supported, utils.GetMIME(formats[i].MediaType))
+		}
+	}
+	return NewError(StatusNotAcceptable, "Not Acceptable. Supported media types: "+strings.Join(supported, ", "))
+}
+
 // FormFile returns the first file by key from a MultipartForm.
 func (c *Ctx) FormFile(key string) (*multipart.FileHeader, error) {
-	return c.fasthttp.FormFile(key)
+	files, err := c.FormFiles(key)
+	if err != nil {
+		return nil, err
+	}
+	return files[0], nil
+}
+
+// FormFiles returns all files by key from a MultipartForm, like the ones
+// of an <input type="file" multiple>.
+func (c *Ctx) FormFiles(key string) ([]*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	files := form.File[key]
+	if len(files) == 0 {
+		return nil, fasthttp.ErrMissingFile
+	}
+	return files, nil
 }
 
-// FormValue returns the first value by key from a MultipartForm.
+// FormValue returns the first value by key from the query string, the
+// url-encoded body or a MultipartForm.
 // Defaults to the empty string "" if the form value doesn't exist.
 // If a default value is given, it will return that value if the form value does not exist.
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting instead.
 func (c *Ctx) FormValue(key string, defaultValue ...string) string {
-	return defaultString(getString(c.fasthttp.FormValue(key)), defaultValue)
+	if value := c.fasthttp.QueryArgs().Peek(key); len(value) > 0 {
+		return c.app.getString(value)
+	}
+	if value := c.fasthttp.PostArgs().Peek(key); len(value) > 0 {
+		return c.app.getString(value)
+	}
+	if form, err := c.MultipartForm(); err == nil {
+		if values := form.Value[key]; len(values) > 0 {
+			return defaultString(values[0], defaultValue)
+		}
+	}
+	return defaultString("", defaultValue)
 }
 
 // Fresh returns true when the response is still “fresh” in the client's cache,
 // otherwise false is returned to indicate that the client cache is now stale
 // and the full response should be sent.
+// Only GET and HEAD requests with a 2xx or 304 response status can be fresh,
+// the ETag and Last-Modified response headers must be set before calling it.
 // When a client sends the Cache-Control: no-cache request header to indicate an end-to-end
 // reload request, this module will return false to make handling these requests transparent.
 // https://github.com/jshttp/fresh/blob/10e0471669dbbfbfd8de65bc6efac2ddd0bfa057/index.js#L33
 func (c *Ctx) Fresh() bool {
+	// only safe requests with a successful response can be fresh
+	if c.methodINT != methodInt(MethodGet) && c.methodINT != methodInt(MethodHead) {
+		return false
+	}
+	if status := c.fasthttp.Response.StatusCode(); (status < StatusOK || status >= StatusMultipleChoices) && status != StatusNotModified {
+		return false
+	}
+
 	// fields
 	var modifiedSince = c.Get(HeaderIfModifiedSince)
 	var noneMatch = c.Get(HeaderIfNoneMatch)
@@ -440,30 +540,29 @@ func (c *Ctx) Fresh() bool {
 		return false
 	}
 
-	// if-none-match
+	// if-none-match, "*" matches every current representation
 	if noneMatch != "" && noneMatch != "*" {
 		var etag = getString(c.fasthttp.Response.Header.Peek(HeaderETag))
-		if etag == "" {
+		if etag == "" || isEtagStale(etag, getBytes(noneMatch)) {
 			return false
 		}
-		if isEtagStale(etag, getBytes(noneMatch)) {
+	}
+
+	// if-modified-since
+	if modifiedSince != "" {
+		var lastModified = getString(c.fasthttp.Response.Header.Peek(HeaderLastModified))
+		if lastModified == "" {
 			return false
 		}
-
-		if modifiedSince != "" {
-			var lastModified = getString(c.fasthttp.Response.Header.Peek(HeaderLastModified))
-			if lastModified != "" {
-				lastModifiedTime, err := http.ParseTime(lastModified)
-				if err != nil {
-					return false
-				}
-				modifiedSinceTime, err := http.ParseTime(modifiedSince)
-				if err != nil {
-					return false
-				}
-				return lastModifiedTime.Before(modifiedSinceTime)
-			}
+		lastModifiedTime, err := http.ParseTime(lastModified)
+		if err != nil {
+			return false
+		}
+		modifiedSinceTime, err := http.ParseTime(modifiedSince)
+		if err != nil {
+			return false
 		}
+		return !lastModifiedTime.After(modifiedSinceTime)
 	}
 	return true
 }
@@ -473,28 +572,92 @@ func (c *Ctx) Fresh() bool {
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting instead.
 func (c *Ctx) Get(key string, defaultValue ...string) string {
-	return defaultString(getString(c.fasthttp.Request.Header.Peek(key)), defaultValue)
+	return defaultString(c.app.getString(c.fasthttp.Request.Header.Peek(key)), defaultValue)
+}
+
+// GetReqHeaders returns all HTTP request headers, repeated headers are grouped
+// under their canonical MIME header key.
+// The keys and values are copies and remain valid outside the handler.
+func (c *Ctx) GetReqHeaders() map[string][]string {
+	headers := make(map[string][]string)
+	c.fasthttp.Request.Header.VisitAll(func(key, val []byte) {
+		k := textproto.CanonicalMIMEHeaderKey(string(key))
+		headers[k] = append(headers[k], string(val))
+	})
+	return headers
+}
+
+// GetRespHeaders returns all HTTP response headers, repeated headers are grouped
+// under their canonical MIME header key.
+// The keys and values are copies and remain valid outside the handler.
+func (c *Ctx) GetRespHeaders() map[string][]string {
+	headers := make(map[string][]string)
+	c.fasthttp.Response.Header.VisitAll(func(key, val []byte) {
+		k := textproto.CanonicalMIMEHeaderKey(string(key))
+		headers[k] = append(headers[k], string(val))
+	})
+	return headers
 }
 
 // Hostname contains the hostname derived from the Host HTTP header.
+// If EnableTrustedProxyCheck is set, the X-Forwarded-Host header of trusted proxies is used.
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting instead.
 func (c *Ctx) Hostname() string {
-	return getString(c.fasthttp.Request.URI().Host())
+	if c.app.config.EnableTrustedProxyCheck && c.isProxyTrusted() {
+		if host := c.Get(HeaderXForwardedHost); len(host) > 0 {
+			if commaPos := strings.IndexByte(host, ','); commaPos != -1 {
+				return utils.Trim(host[:commaPos], ' ')
+			}
+			return host
+		}
+	}
+	return c.app.getString(c.fasthttp.Request.URI().Host())
 }
 
 // IP returns the remote IP address of the request.
+// If EnableIPValidation is set, the first valid address of the ProxyHeader is returned.
 func (c *Ctx) IP() string {
-	if len(c.app.config.ProxyHeader) > 0 {
-		return c.Get(c.app.config.ProxyHeader)
+	if len(c.app.config.ProxyHeader) > 0 && c.isProxyTrusted() {
+		if !c.app.config.EnableIPValidation {
+			return c.Get(c.app.config.ProxyHeader)
+		}
+		header := c.Get(c.app.config.ProxyHeader)
+		for len(header) > 0 {
+			entry := header
+			if commaPos := strings.IndexByte(header, ','); commaPos != -1 {
+				entry, header = header[:commaPos], header[commaPos+1:]
+			} else {
+				header = ""
+			}
+			if ip, ok := forwardedIP(entry); ok {
+				return ip
+			}
+		}
 	}
 	return c.fasthttp.RemoteIP().String()
 }
 
 // IPs returns an string slice of IP addresses specified in the X-Forwarded-For request header.
+// If EnableIPValidation is set, invalid addresses are skipped.
 func (c *Ctx) IPs() (ips []string) {
 	header := c.fasthttp.Request.Header.Peek(HeaderXForwardedFor)
-	if len(header) == 0 {
+	if len(header) == 0 || !c.isProxyTrusted() {
+		return
+	}
+	if c.app.config.EnableIPValidation {
+		ips = make([]string, 0, bytes.Count(header, []byte(","))+1)
+		for len(header) > 0 {
+			entry := header
+			if commaPos := bytes.IndexByte(header, ','); commaPos != -1 {
+				entry, header = header[:commaPos], header[commaPos+1:]
+			} else {
+				header = nil
+			}
+			if ip, ok := forwardedIP(c.app.getString(entry)); ok {
+				ips = append(ips, ip)
+			}
+		}
 		return
 	}
 	ips = make([]string, bytes.Count(header, []byte(","))+1)
@@ -502,15 +665,42 @@ func (c *Ctx) IPs() (ips []string) {
 	for {
 		commaPos = bytes.IndexByte(header, ',')
 		if commaPos != -1 {
-			ips[i] = utils.Trim(getString(header[:commaPos]), ' ')
+			ips[i] = utils.Trim(c.app.getString(header[:commaPos]), ' ')
 			header, i = header[commaPos+1:], i+1
 		} else {
-			ips[i] = utils.Trim(getString(header), ' ')
+			ips[i] = utils.Trim(c.app.getString(header), ' ')
 			return
 		}
 	}
 }
 
+// ErrorHandler returns the error handler for the current request: the one of
+// the mounted app owning the current route, else the one of the nearest group
+// with an error handler, else the one of the app.
+func (c *Ctx) ErrorHandler() ErrorHandler {
+	if c.route != nil && c.route.errorHandler != nil {
+		return c.route.errorHandler
+	}
+	if handler := c.app.nearestErrorHandler(c.path, c.pathOriginal); handler != nil {
+		return handler
+	}
+	return c.app.config.ErrorHandler
+}
+
+// IsFromLocal returns true if the request came from the loopback interface.
+func (c *Ctx) IsFromLocal() bool {
+	return c.fasthttp.RemoteIP().IsLoopback()
+}
+
+// isProxyTrusted reports whether the forwarded headers of the request can be
+// trusted, which is always the case if EnableTrustedProxyCheck is disabled
+func (c *Ctx) isProxyTrusted() bool {
+	if !c.app.config.EnableTrustedProxyCheck {
+		return true
+	}
+	return ipInRanges(c.app.trustedProxies, c.fasthttp.RemoteIP())
+}
+
 // Is returns the matching content type,
 // if the incoming request's Content-Type HTTP header field matches the MIME type specified by the type parameter
 func (c *Ctx) Is(extension string) bool {
@@ -529,27 +719,28 @@ func (c *Ctx) Is(extension string) bool {
 // Array and slice values encode as JSON arrays,
 // except that []byte encodes as a base64-encoded string,
 // and a nil slice encodes as the null JSON value.
-// This method also sets the content header to application/json.
-func (c *Ctx) JSON(data interface{}) error {
-	raw, err := json.Marshal(data)
+// This method also sets the content header to application/json,
+// an optional content type like application/problem+json is used instead.
+func (c *Ctx) JSON(data interface{}, ctype ...string) error {
+	raw, err := c.app.config.JSONEncoder(data)
 	if err != nil {
 		return err
 	}
 	c.fasthttp.Response.SetBodyRaw(raw)
-	c.fasthttp.Response.Header.SetContentType(MIMEApplicationJSON)
+	if len(ctype) > 0 {
+		c.fasthttp.Response.Header.SetContentType(ctype[0])
+	} else {
+		c.fasthttp.Response.Header.SetContentType(MIMEApplicationJSON)
+	}
 	return nil
 }
 
 // JSONP sends a JSON response with JSONP support.
 // This method is identical to JSON, except that it opts-in to JSONP callback support.
 // By default, the callback name is simply callback.
+// Callback names with other characters than A-Z, a-z, 0-9, '_', '.' and '$'
+// are rejected with ErrBadRequest to prevent script injection.
 func (c *Ctx) JSONP(data interface{}, callback ...string) error {
-	raw, err := json.Marshal(data)
-
-	if err != nil {
-		return err
-	}
-
 	var result, cb string
 
 	if len(callback) > 0 {
@@ -558,6 +749,16 @@ func (c *Ctx) JSONP(data interface{}, callback ...string) error {
 		cb = "callback"
 	}
 
+	if !validCallback(cb) {
+		return NewError(StatusBadRequest, "jsonp: invalid callback name")
+	}
+
+	raw, err := c.app.config.JSONEncoder(data)
+
+	if err != nil {
+		return err
+	}
+
 	result = cb + "(" + getString(raw) + ");"
 
 	c.setCanonical(HeaderXContentTypeOptions, "nosniff")
@@ -615,10 +816,115 @@ func (c *Ctx) Method(override ...string) string {
 
 // MultipartForm parse form entries from binary.
 // This returns a map[string][]string, so given a key the value will be a string slice.
+// Forms exceeding MaxMultipartFiles or MaxMultipartSize are rejected with
+// ErrMultipartFilesLimit or ErrMultipartSizeLimit.
 func (c *Ctx) MultipartForm() (*multipart.Form, error) {
+	// The form and its error are parsed once per request
+	if c.multipart == nil && c.multipartErr == nil {
+		c.multipart, c.multipartErr = c.parseMultipartForm()
+	}
+	return c.multipart, c.multipartErr
+}
+
+// parseMultipartForm checks the limits before the form is parsed, so the files
+// of rejected forms are neither buffered nor written to temporary files.
+func (c *Ctx) parseMultipartForm() (*multipart.Form, error) {
+	body := c.fasthttp.Request.Body()
+	boundary := c.fasthttp.Request.Header.MultipartFormBoundary()
+	// Forms of 100-continue requests are parsed by fasthttp while reading the body,
+	// encoded bodies are decoded by fasthttp. Their limits are checked afterwards.
+	if len(body) == 0 || len(boundary) == 0 || len(c.fasthttp.Request.Header.Peek(HeaderContentEncoding)) > 0 {
+		form, err := c.fasthttp.MultipartForm()
+		if err != nil {
+			return nil, err
+		}
+		var files int
+		var size int64
+		for _, headers := range form.File {
+			files += len(headers)
+			for _, header := range headers {
+				size += header.Size
+			}
+		}
+		if err = c.multipartLimitsErr(files, size); err != nil {
+			return nil, err
+		}
+		return form, nil
+	}
+
+	if err := c.checkMultipartLimits(body, string(boundary)); err != nil {
+		return nil, err
+	}
+	if maxMemory := c.app.config.MaxMultipartMemory; maxMemory > 0 {
+		return multipart.NewReader(bytes.NewReader(body), string(boundary)).ReadForm(int64(maxMemory))
+	}
 	return c.fasthttp.MultipartForm()
 }
 
+// checkMultipartLimits scans the files of the body against MaxMultipartFiles
+// and MaxMultipartSize, it stops at the first file exceeding a limit
+func (c *Ctx) checkMultipartLimits(body []byte, boundary string) error {
+	maxFiles, maxSize := c.app.config.MaxMultipartFiles, int64(c.app.config.MaxMultipartSize)
+	if maxFiles <= 0 && maxSize <= 0 {
+		return nil
+	}
+	r := multipart.NewReader(bytes.NewReader(body), boundary)
+	var files int
+	var size int64
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// Parts without a file name are values, like in multipart.Reader.ReadForm
+		if part.FormName() == "" || part.FileName() == "" {
+			continue
+		}
+		files++
+		if maxSize > 0 {
+			n, err := io.Copy(ioutil.Discard, io.LimitReader(part, maxSize-size+1))
+			if err != nil {
+				return err
+			}
+			size += n
+		}
+		if err = c.multipartLimitsErr(files, size); err != nil {
+			return err
+		}
+	}
+}
+
+// multipartLimitsErr returns the error of the first exceeded limit
+func (c *Ctx) multipartLimitsErr(files int, size int64) error {
+	if maxFiles := c.app.config.MaxMultipartFiles; maxFiles > 0 && files > maxFiles {
+		return ErrMultipartFilesLimit
+	}
+	if maxSize := int64(c.app.config.MaxMultipartSize); maxSize > 0 && size > maxSize {
+		return ErrMultipartSizeLimit
+	}
+	return nil
+}
+
+// FileContentType detects the content type of a multipart file from its first
+// 512 bytes, unlike the Content-Type sent by the client it can't be spoofed
+// by the file name. It returns "application/octet-stream" for unknown types.
+func (c *Ctx) FileContentType(fileheader *multipart.FileHeader) (string, error) {
+	file, err := fileheader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	var buf [512]byte
+	n, err := io.ReadFull(file, buf[:])
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}
+
 // Next executes the next method in the stack that matches the current route.
 func (c *Ctx) Next() (err error) {
 	// Increment handler index
@@ -638,7 +944,7 @@ func (c *Ctx) Next() (err error) {
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting to use the value outside the Handler.
 func (c *Ctx) OriginalURL() string {
-	return getString(c.fasthttp.Request.Header.RequestURI())
+	return c.app.getString(c.fasthttp.Request.Header.RequestURI())
 }
 
 // Params is used to get the route parameters.
@@ -665,6 +971,40 @@ func (c *Ctx) Params(key string, defaultValue ...string) string {
 	return defaultString("", defaultValue)
 }
 
+// ParamsInt is used to get the route parameter converted to an int.
+// An error is returned if the param doesn't exist or is not a valid int.
+// If a default value is given, it will return that value instead of the error.
+func (c *Ctx) ParamsInt(key string, defaultValue ...int) (int, error) {
+	param := c.Params(key)
+	value, err := strconv.Atoi(param)
+	if err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0], nil
+		}
+		if len(param) == 0 {
+			return 0, fmt.Errorf("failed to convert param %q: param is empty", key)
+		}
+		return 0, fmt.Errorf("failed to convert param %q: %q is not a valid int", key, param)
+	}
+	return value, nil
+}
+
+// AllParams returns all route parameters with their values, wildcards are
+// named "*1", "*2" and so on. Params that are not set have an empty value.
+// Returned values are only valid within the handler. Do not store any references.
+// Make copies or use the Immutable setting to use the values outside the Handler.
+func (c *Ctx) AllParams() map[string]string {
+	params := make(map[string]string, len(c.route.Params))
+	for i, key := range c.route.Params {
+		if i < len(c.values) {
+			params[key] = c.values[i]
+		} else {
+			params[key] = ""
+		}
+	}
+	return params
+}
+
 // Path returns the path part of the request URL.
 // Optionally, you could override the path.
 func (c *Ctx) Path(override ...string) string {
@@ -689,19 +1029,22 @@ func (c *Ctx) Protocol() string {
 		return "https"
 	}
 	scheme := "http"
+	if !c.isProxyTrusted() {
+		return scheme
+	}
 	c.fasthttp.Request.Header.VisitAll(func(key, val []byte) {
 		if len(key) < 12 {
 			return // X-Forwarded-
 		} else if bytes.HasPrefix(key, []byte("X-Forwarded-")) {
 			if bytes.Equal(key, []byte(HeaderXForwardedProto)) {
-				scheme = getString(val)
+				scheme = c.app.getString(val)
 			} else if bytes.Equal(key, []byte(HeaderXForwardedProtocol)) {
-				scheme = getString(val)
+				scheme = c.app.getString(val)
 			} else if bytes.Equal(key, []byte(HeaderXForwardedSsl)) && bytes.Equal(val, []byte("on")) {
 				scheme = "https"
 			}
 		} else if bytes.Equal(key, []byte(HeaderXUrlScheme)) {
-			scheme = getString(val)
+			scheme = c.app.getString(val)
 		}
 	})
 	return scheme
@@ -713,7 +1056,74 @@ func (c *Ctx) Protocol() string {
 // Returned value is only valid within the handler. Do not store any references.
 // Make copies or use the Immutable setting to use the value outside the Handler.
 func (c *Ctx) Query(key string, defaultValue ...string) string {
-	return defaultString(getString(c.fasthttp.QueryArgs().Peek(key)), defaultValue)
+	return defaultString(c.app.getString(c.fasthttp.QueryArgs().Peek(key)), defaultValue)
+}
+
+// Queries returns all query string parameters of the url, the last value of
+// a repeated key wins. Keys like "filter[name]" are kept verbatim.
+// The returned map is a copy and can be used outside the handler.
+func (c *Ctx) Queries() map[string]string {
+	args := c.fasthttp.QueryArgs()
+	queries := make(map[string]string, args.Len())
+	args.VisitAll(func(key, value []byte) {
+		queries[string(key)] = string(value)
+	})
+	return queries
+}
+
+// QueriesAll returns all query string parameters of the url with all values
+// of repeated keys. Keys like "filter[name]" are kept verbatim.
+// The returned map is a copy and can be used outside the handler.
+func (c *Ctx) QueriesAll() map[string][]string {
+	args := c.fasthttp.QueryArgs()
+	queries := make(map[string][]string, args.Len())
+	args.VisitAll(func(key, value []byte) {
+		k := string(key)
+		queries[k] = append(queries[k], string(value))
+	})
+	return queries
+}
+
+// QueryInt returns the query string parameter in the url parsed as int.
+// Defaults to 0 if the query doesn't exist or is not a valid int.
+// If a default value is given, it will return that value instead.
+func (c *Ctx) QueryInt(key string, defaultValue ...int) int {
+	value, err := strconv.Atoi(utils.UnsafeString(c.fasthttp.QueryArgs().Peek(key)))
+	if err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0]
+		}
+		return 0
+	}
+	return value
+}
+
+// QueryBool returns the query string parameter in the url parsed as bool.
+// Defaults to false if the query doesn't exist or is not a valid bool.
+// If a default value is given, it will return that value instead.
+func (c *Ctx) QueryBool(key string, defaultValue ...bool) bool {
+	value, err := strconv.ParseBool(utils.UnsafeString(c.fasthttp.QueryArgs().Peek(key)))
+	if err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0]
+		}
+		return false
+	}
+	return value
+}
+
+// QueryFloat returns the query string parameter in the url parsed as float64.
+// Defaults to 0 if the query doesn't exist or is not a valid float.
+// If a default value is given, it will return that value instead.
+func (c *Ctx) QueryFloat(key string, defaultValue ...float64) float64 {
+	value, err := strconv.ParseFloat(utils.UnsafeString(c.fasthttp.QueryArgs().Peek(key)), 64)
+	if err != nil {
+		if len(defaultValue) > 0 {
+			return defaultValue[0]
+		}
+		return 0
+	}
+	return value
 }
 
 // QueryParser binds the query string to a struct.
@@ -727,9 +1137,9 @@ func (c *Ctx) QueryParser(out interface{}) error {
 
 	data := make(map[string][]string)
 	c.fasthttp.QueryArgs().VisitAll(func(key []byte, val []byte) {
-		k := utils.UnsafeString(key)
-		v := utils.UnsafeString(val)
-		if strings.Contains(v, ",") && equalFieldType(out, reflect.Slice, k) {
+		k := c.app.getString(key)
+		v := c.app.getString(val)
+		if strings.Contains(v, ",") && equalFieldType(out, reflect.Slice, k, "query") {
 			values := strings.Split(v, ",")
 			for i := 0; i < len(values); i++ {
 				data[k] = append(data[k], values[i])
@@ -742,7 +1152,49 @@ func (c *Ctx) QueryParser(out interface{}) error {
 	return decoder.Decode(out, data)
 }
 
-func equalFieldType(out interface{}, kind reflect.Kind, key string) bool {
+// CookieParser binds the request cookies to a struct using the cookie tag.
+// Comma-separated values fill slices. Fields with the required option, e.g.
+// `cookie:"session,required"`, return an error listing all missing cookies.
+// Time fields are parsed with their layout tag, like the other Bind sources.
+func (c *Ctx) CookieParser(out interface{}) error {
+	data := make(map[string][]string)
+	c.fasthttp.Request.Header.VisitAllCookie(func(key, val []byte) {
+		k := c.app.getString(key)
+		v := c.app.getString(val)
+		if strings.Contains(v, ",") && equalFieldType(out, reflect.Slice, k, "cookie") {
+			data[k] = append(data[k], strings.Split(v, ",")...)
+		} else {
+			data[k] = append(data[k], v)
+		}
+	})
+
+	// Time fields with a layout tag are converted like in Bind
+	if err := applyTimeLayouts(reflect.TypeOf(out), "cookie", "", data); err != nil {
+		return err
+	}
+
+	// Get decoder from pool, the decoders of Bind are cached per tag
+	pool := bindDecoderPools["cookie"]
+	decoder := pool.Get().(*schema.Decoder)
+	defer pool.Put(decoder)
+
+	err := decoder.Decode(out, data)
+	if errs, ok := err.(schema.MultiError); ok {
+		var missing []string
+		for _, e := range errs {
+			if empty, ok := e.(schema.EmptyFieldError); ok {
+				missing = append(missing, empty.Key)
+			}
+		}
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			return fmt.Errorf("cookie: missing required cookies: %s", strings.Join(missing, ", "))
+		}
+	}
+	return err
+}
+
+func equalFieldType(out interface{}, kind reflect.Kind, key, tag string) bool {
 	// Get type of interface
 	outTyp := reflect.TypeOf(out).Elem()
 	// Must be a struct to match a field
@@ -768,7 +1220,7 @@ func equalFieldType(out interface{}, kind reflect.Kind, key string) bool {
 			continue
 		}
 		// Get tag from field if exist
-		inputFieldName := typeField.Tag.Get(key)
+		inputFieldName := strings.Split(typeField.Tag.Get(tag), ",")[0]
 		if inputFieldName == "" {
 			inputFieldName = typeField.Name
 		}
@@ -800,7 +1252,7 @@ func (c *Ctx) Range(size int) (rangeData Range, err error) {
 	rangeData.Type = data[0]
 	arr := strings.Split(data[1], ",")
 	for i := 0; i < len(arr); i++ {
-		item := strings.Split(arr[i], "-")
+		item := strings.Split(utils.Trim(arr[i], ' '), "-")
 		if len(item) == 1 {
 			err = ErrRangeMalformed
 			return
@@ -808,8 +1260,14 @@ func (c *Ctx) Range(size int) (rangeData Range, err error) {
 		start, startErr := strconv.Atoi(item[0])
 		end, endErr := strconv.Atoi(item[1])
 		if startErr != nil { // -nnn
+			if end == 0 {
+				continue
+			}
 			start = size - end
 			end = size - 1
+			if start < 0 { // suffix longer than the content
+				start = 0
+			}
 		} else if endErr != nil { // nnn-
 			end = size - 1
 		}
@@ -847,33 +1305,92 @@ func (c *Ctx) Redirect(location string, status ...int) error {
 	return nil
 }
 
+// RedirectToRoute redirects to the URL of a named route, see GetRouteURL.
+// The reserved "queries" param sets the query string, as a map[string]string
+// or url.Values. If status is not specified, status defaults to 302 Found.
+func (c *Ctx) RedirectToRoute(routeName string, params Map, status ...int) error {
+	location, err := c.GetRouteURL(routeName, params)
+	if err != nil {
+		return err
+	}
+	if queries, ok := params["queries"]; ok {
+		var values url.Values
+		switch q := queries.(type) {
+		case url.Values:
+			values = q
+		case map[string]string:
+			values = make(url.Values, len(q))
+			for k, v := range q {
+				values.Set(k, v)
+			}
+		default:
+			return fmt.Errorf("route: queries must be map[string]string or url.Values, got %T", queries)
+		}
+		if query := values.Encode(); query != "" {
+			location += "?" + query
+		}
+	}
+	return c.Redirect(location, status...)
+}
+
+// RedirectBack redirects to the URL in the Referer header, or to the fallback
+// if the header is missing or refers to another origin.
+// If status is not specified, status defaults to 302 Found.
+func (c *Ctx) RedirectBack(fallback string, status ...int) error {
+	location := fallback
+	if referer := c.Get(HeaderReferer); referer != "" && c.isSameOrigin(referer) {
+		location = referer
+	}
+	return c.Redirect(location, status...)
+}
+
+// isSameOrigin reports whether the URL is relative or has the scheme and host
+// of the request
+func (c *Ctx) isSameOrigin(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if u.Scheme == "" && u.Host == "" {
+		// Relative references must be a path, browsers treat "/\host" like "//host"
+		return rawURL[0] == '/' && (len(rawURL) == 1 || (rawURL[1] != '/' && rawURL[1] != '\\'))
+	}
+	return strings.EqualFold(u.Scheme, c.Protocol()) && strings.EqualFold(u.Host, c.Hostname())
+}
+
 // Render a template with data and sends a text/html response.
 // We support the following engines: html, amber, handlebars, mustache, pug
+// The layouts are passed to the Views engine, they aren't used without one.
+// Errors contain the name of the template.
 func (c *Ctx) Render(name string, bind interface{}, layouts ...string) error {
 	var err error
 	// Get new buffer from pool
 	buf := bytebufferpool.Get()
 	defer bytebufferpool.Put(buf)
 
+	if c.app.config.PassLocalsToViews {
+		bind = c.bindLocals(bind)
+	}
+
 	if c.app.config.Views != nil {
 		// Render template from Views
 		if err := c.app.config.Views.Render(buf, name, bind, layouts...); err != nil {
-			return err
+			return fmt.Errorf("render: template %q: %w", name, err)
 		}
 	} else {
 		// Render raw template using 'name' as filepath if no engine is set
 		var tmpl *template.Template
 		if _, err = readContent(buf, name); err != nil {
-			return err
+			return fmt.Errorf("render: template %q: %w", name, err)
 		}
 		// Parse template
 		if tmpl, err = template.New("").Parse(getString(buf.Bytes())); err != nil {
-			return err
+			return fmt.Errorf("render: template %q: %w", name, err)
 		}
 		buf.Reset()
 		// Render template
 		if err = tmpl.Execute(buf, bind); err != nil {
-			return err
+			return fmt.Errorf("render: template %q: %w", name, err)
 		}
 	}
 	// Set Content-Type to text/html
@@ -884,7 +1401,47 @@ func (c *Ctx) Render(name string, bind interface{}, layouts ...string) error {
 	return err
 }
 
-// Route returns the matched Route struct.
+// bindLocals returns a Map of the locals with the entries of the bound Map,
+// other bindings than a Map are returned unchanged.
+func (c *Ctx) bindLocals(bind interface{}) interface{} {
+	var entries map[string]interface{}
+	switch b := bind.(type) {
+	case Map:
+		entries = b
+	case map[string]interface{}:
+		entries = b
+	case nil:
+	default:
+		return bind
+	}
+	data := make(Map, len(entries))
+	c.fasthttp.VisitUserValues(func(key []byte, value interface{}) {
+		data[string(key)] = value
+	})
+	for key, value := range entries {
+		data[key] = value
+	}
+	return data
+}
+
+// RestartRouting matches the routes again instead of going to the next
+// handler, using the current method and path, e.g. after changing them with
+// Ctx.Method or Ctx.Path. The route params are taken from the new match and
+// the locals are kept.
+func (c *Ctx) RestartRouting() error {
+	c.restarts++
+	if c.restarts > maxRestarts {
+		return ErrRestartLimit
+	}
+	c.indexRoute = -1
+	c.matched = false
+	_, err := c.app.next(c)
+	return err
+}
+
+// Route returns the matched Route struct. After c.Next returns it is the route
+// that handled the request last, or a route whose NotFound method returns true
+// if only middleware matched.
 func (c *Ctx) Route() *Route {
 	if c.route == nil {
 		// Fallback for fasthttp error handler
@@ -899,11 +1456,105 @@ func (c *Ctx) Route() *Route {
 	return c.route
 }
 
+// RoutePattern returns the registered path of the route, like "/users/:id",
+// which unlike the request path has a bounded number of values.
+// It is empty if no route matched the request.
+func (c *Ctx) RoutePattern() string {
+	if c.route == nil {
+		return ""
+	}
+	return c.route.Path
+}
+
+// GetRouteURL generates the URL of a named route, the params replace the
+// route parameters. Wildcard and plus parameters are set by "*" and "+",
+// or by "*1", "+1", "*2"... when the route has several of them.
+func (c *Ctx) GetRouteURL(routeName string, params Map) (string, error) {
+	route := c.app.GetRoute(routeName)
+	if route.Name == "" {
+		return "", fmt.Errorf("route: no route named %q", routeName)
+	}
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	segs := parseRoute(route.Path).segs
+	for i, seg := range segs {
+		if !seg.IsParam {
+			part := seg.Const
+			// Drop the slash in front of a missing optional parameter
+			if seg.HasOptionalSlash && i+1 < len(segs) && segs[i+1].IsParam {
+				if _, ok := routeParam(params, segs[i+1].ParamName); !ok {
+					part = utils.TrimRight(part, '/')
+				}
+			}
+			buf.WriteString(part)
+			continue
+		}
+		value, ok := routeParam(params, seg.ParamName)
+		if !ok {
+			if seg.IsOptional {
+				continue
+			}
+			return "", fmt.Errorf("route: missing param %q for route %q", seg.ParamName, routeName)
+		}
+		if seg.IsGreedy {
+			buf.WriteString(value)
+		} else {
+			buf.WriteString(url.PathEscape(value))
+		}
+	}
+	if buf.Len() == 0 {
+		return "/", nil
+	}
+	return buf.String(), nil
+}
+
+// routeParam returns the value of a route parameter, the first wildcard
+// and plus parameters can be set without their index
+func routeParam(params Map, name string) (string, bool) {
+	value, ok := params[name]
+	if !ok && len(name) == 2 && name[1] == '1' && (name[0] == wildcardParam || name[0] == plusParam) {
+		value, ok = params[name[:1]]
+	}
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
 // SaveFile saves any multipart file to disk.
 func (c *Ctx) SaveFile(fileheader *multipart.FileHeader, path string) error {
 	return fasthttp.SaveMultipartFile(fileheader, path)
 }
 
+// SaveFileToStorage saves any multipart file to an external storage system,
+// files larger than the BodyLimit are rejected with 413 Request Entity Too Large.
+// Storages implementing StreamStorage receive the file as a stream, so files
+// that were buffered on disk are never read into memory.
+func (c *Ctx) SaveFileToStorage(fileheader *multipart.FileHeader, key string, storage Storage) error {
+	limit := int64(c.app.config.BodyLimit)
+	if fileheader.Size > limit {
+		return ErrRequestEntityTooLarge
+	}
+	file, err := fileheader.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if s, ok := storage.(StreamStorage); ok {
+		return s.SetStream(key, io.LimitReader(file, fileheader.Size), fileheader.Size, 0)
+	}
+
+	content, err := ioutil.ReadAll(io.LimitReader(file, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(content)) > limit {
+		return ErrRequestEntityTooLarge
+	}
+	return storage.Set(key, content, 0)
+}
+
 // Secure returns a boolean property, that is true, if a TLS connection is established.
 func (c *Ctx) Secure() bool {
 	return c.fasthttp.IsTLS()
@@ -963,12 +1614,19 @@ func (c *Ctx) SendFile(file string, compress ...bool) error {
 			file += "/"
 		}
 	}
+	// Restore the original URI afterwards, the path and route params point to it
+	originalURL := utils.SafeString(c.OriginalURL())
+	defer func() {
+		c.fasthttp.Request.SetRequestURI(originalURL)
+		// Parse the URI again to write the original path back
+		_ = c.fasthttp.Request.URI()
+	}()
 	// Set new URI for fileHandler
 	c.fasthttp.Request.SetRequestURI(file)
 	// Save status code
 	status := c.fasthttp.Response.StatusCode()
 	// Serve file
-	sendFileHandler(c.fasthttp)
+	c.serveFile(sendFileHandler)
 	// Get the status code which is set by fasthttp
 	fsStatus := c.fasthttp.Response.StatusCode()
 	// Set the status code set by the user if it is different from the fasthttp status code and 200
@@ -982,6 +1640,129 @@ func (c *Ctx) SendFile(file string, compress ...bool) error {
 	return nil
 }
 
+// serveFile serves a file with a fasthttp handler that accepts byte ranges
+// and adds what fasthttp lacks: If-Range, multiple ranges as
+// multipart/byteranges and the size of the file for unsatisfiable ranges.
+// Overlapping ranges are merged, more than maxByteRanges get the whole file.
+func (c *Ctx) serveFile(handler fasthttp.RequestHandler) {
+	rangeHeader := utils.SafeString(c.Get(HeaderRange))
+	if rangeHeader == "" || !c.fasthttp.IsGet() {
+		handler(c.fasthttp)
+		return
+	}
+	// Serve the whole file first to get its size and validators, without
+	// compression as fasthttp doesn't compress ranges either
+	acceptEncoding := utils.SafeString(c.Get(HeaderAcceptEncoding))
+	c.fasthttp.Request.Header.Del(HeaderRange)
+	c.fasthttp.Request.Header.Del(HeaderAcceptEncoding)
+	defer func() {
+		c.fasthttp.Request.Header.Set(HeaderRange, rangeHeader)
+		if acceptEncoding != "" {
+			c.fasthttp.Request.Header.Set(HeaderAcceptEncoding, acceptEncoding)
+		}
+	}()
+	handler(c.fasthttp)
+	size := c.fasthttp.Response.Header.ContentLength()
+	if c.fasthttp.Response.StatusCode() != StatusOK || size < 0 {
+		return
+	}
+	// The whole file is sent if it changed since the client got its part
+	if ifRange := c.Get(HeaderIfRange); ifRange != "" && !ifRangeMatches(ifRange,
+		getString(c.fasthttp.Response.Header.Peek(HeaderETag)),
+		getString(c.fasthttp.Response.Header.Peek(HeaderLastModified))) {
+		return
+	}
+
+	c.fasthttp.Request.Header.Set(HeaderRange, rangeHeader)
+	ranges, err := c.Range(size)
+	if err == ErrRangeUnsatisfiable {
+		c.fasthttp.Response.Header.Set(HeaderContentRange, "bytes */"+strconv.Itoa(size))
+		c.fasthttp.Response.SetStatusCode(StatusRequestedRangeNotSatisfiable)
+		c.fasthttp.Response.Header.SetContentType(MIMETextPlainCharsetUTF8)
+		c.fasthttp.Response.SetBodyString(utils.StatusMessage(StatusRequestedRangeNotSatisfiable))
+		return
+	} else if err != nil || ranges.Type != "bytes" {
+		// Invalid ranges are ignored
+		return
+	}
+	parts := mergeRanges(ranges.Ranges)
+	if len(parts) > maxByteRanges {
+		// Too many ranges are answered with the whole file
+		return
+	}
+	if len(parts) == 1 {
+		c.serveRange(handler, parts[0].Start, parts[0].End)
+		return
+	}
+
+	// Multiple ranges are streamed as multipart/byteranges, every part is
+	// served by the handler on a copy of the request once it's written
+	contentType := utils.SafeString(getString(c.fasthttp.Response.Header.ContentType()))
+	boundary := utils.UUID()
+	req := fasthttp.AcquireRequest()
+	c.fasthttp.Request.CopyTo(req)
+	remoteAddr := c.fasthttp.RemoteAddr()
+	c.fasthttp.Response.SetStatusCode(StatusPartialContent)
+	c.fasthttp.Response.Header.Del(HeaderContentRange)
+	c.fasthttp.Response.Header.SetContentType(MIMEMultipartByteranges + "; boundary=" + boundary)
+	c.fasthttp.Response.SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer fasthttp.ReleaseRequest(req)
+		var part fasthttp.RequestCtx
+		defer part.Response.Reset()
+		for _, r := range parts {
+			req.Header.Set(HeaderRange, "bytes="+strconv.Itoa(r.Start)+"-"+strconv.Itoa(r.End))
+			part.Init(req, remoteAddr, nil)
+			// The file can't change the response anymore, abort the body
+			if handler(&part); part.Response.StatusCode() != StatusPartialContent {
+				return
+			}
+			_, _ = w.WriteString("--" + boundary + "\r\n" +
+				HeaderContentType + ": " + contentType + "\r\n" +
+				HeaderContentRange + ": bytes " + strconv.Itoa(r.Start) + "-" + strconv.Itoa(r.End) + "/" + strconv.Itoa(size) + "\r\n\r\n")
+			if err := part.Response.BodyWriteTo(w); err != nil {
+				return
+			}
+			_, _ = w.WriteString("\r\n")
+		}
+		_, _ = w.WriteString("--" + boundary + "--\r\n")
+	})
+}
+
+// maxByteRanges limits the ranges of a multipart/byteranges response
+const maxByteRanges = 16
+
+// byteRange is an element of Range.Ranges
+type byteRange = struct {
+	Start int
+	End   int
+}
+
+// mergeRanges sorts the ranges and merges the overlapping and adjacent ones,
+// so every byte of the file is sent at most once
+func mergeRanges(ranges []byteRange) []byteRange {
+	sorted := make([]byteRange, len(ranges))
+	copy(sorted, ranges)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+	merged := sorted[:1]
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start > last.End+1 {
+			merged = append(merged, r)
+		} else if r.End > last.End {
+			last.End = r.End
+		}
+	}
+	return merged
+}
+
+// serveRange serves a single range of the file
+func (c *Ctx) serveRange(handler fasthttp.RequestHandler, start, end int) {
+	c.fasthttp.Request.Header.Set(HeaderRange, "bytes="+strconv.Itoa(start)+"-"+strconv.Itoa(end))
+	handler(c.fasthttp)
+}
+
 // SendStatus sets the HTTP status code and if the response body is empty,
 // it sets the correct status message in the body.
 func (c *Ctx) SendStatus(status int) error {
@@ -1004,22 +1785,85 @@ func (c *Ctx) SendString(body string) error {
 }
 
 // SendStream sets response body stream and optional body size.
+// Without a size the stream is sent with chunked transfer encoding, it's
+// copied in chunks of Config.StreamBufferSize bytes which are flushed to
+// the client as they're read. A stream implementing io.Closer is closed
+// once the copy is done.
 func (c *Ctx) SendStream(stream io.Reader, size ...int) error {
 	if len(size) > 0 && size[0] >= 0 {
 		c.fasthttp.Response.SetBodyStream(stream, size[0])
-	} else {
-		c.fasthttp.Response.SetBodyStream(stream, -1)
-		c.setCanonical(HeaderContentLength, strconv.Itoa(len(c.fasthttp.Response.Body())))
+		return nil
 	}
-
+	return c.SendStreamDone(stream, nil)
+}
+
+// StreamDoneHandler is called with the number of bytes written to the client
+// and the error which stopped the copy, e.g. a disconnected client.
+type StreamDoneHandler = func(written int64, err error)
+
+// SendStreamDone sends the stream like SendStream without a size and calls
+// done once the copy finished or was aborted. The copy runs after the handler
+// returned, so done must not access the Ctx.
+func (c *Ctx) SendStreamDone(stream io.Reader, done StreamDoneHandler) error {
+	bufferSize := c.app.config.StreamBufferSize
+	c.fasthttp.Response.SetBodyStreamWriter(func(w *bufio.Writer) {
+		written, err := copyStream(w, stream, make([]byte, bufferSize))
+		if closer, ok := stream.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		if done != nil {
+			done(written, err)
+		}
+	})
 	return nil
 }
 
+// SendEarlyHints sends a 103 Early Hints interim response with the given Link
+// header values, e.g. "</style.css>; rel=preload; as=style", so the client can
+// preload them while the final response is prepared.
+// Clients that only speak HTTP/1.0 don't support interim responses, for them
+// this is a no-op. An error is returned if the response body already started.
+//
+// The interim response is written to the connection directly, while fasthttp
+// buffers the responses of pipelined requests. So hints are only sent for the
+// first request of a connection, on later ones an error is returned because
+// the previous response may not be flushed yet.
+func (c *Ctx) SendEarlyHints(links []string) error {
+	if c.fasthttp.Response.IsBodyStream() || c.fasthttp.Hijacked() {
+		return errors.New("earlyhints: response body already started")
+	}
+	if c.fasthttp.ConnRequestNum() > 1 {
+		return errors.New("earlyhints: previous response on the connection may be buffered")
+	}
+	conn := c.fasthttp.Conn()
+	if len(links) == 0 || conn == nil || !c.fasthttp.Request.Header.IsHTTP11() {
+		return nil
+	}
+
+	bb := bytebufferpool.Get()
+	defer bytebufferpool.Put(bb)
+	_, _ = bb.WriteString("HTTP/1.1 103 Early Hints\r\n")
+	for _, link := range links {
+		_, _ = bb.WriteString(HeaderLink + ": " + removeNewLines(link) + "\r\n")
+	}
+	_, _ = bb.WriteString("\r\n")
+
+	// The final response is written after the handler returned,
+	// so the interim response reaches the client first
+	_, err := conn.Write(bb.Bytes())
+	return err
+}
+
 // Set sets the response's HTTP header field to the specified key, value.
 func (c *Ctx) Set(key string, val string) {
 	c.fasthttp.Response.Header.Set(key, removeNewLines(val))
 }
 
+// SetUserContext sets a context implementation by user.
+func (c *Ctx) SetUserContext(ctx context.Context) {
+	c.userContext = ctx
+}
+
 func (c *Ctx) setCanonical(key string, val string) {
 	c.fasthttp.Response.Header.SetCanonical(utils.UnsafeBytes(key), utils.UnsafeBytes(val))
 }
@@ -1041,7 +1885,7 @@ func (c *Ctx) Subdomains(offset ...int) []string {
 	return subdomains
 }
 
-// Stale is not implemented yet, pull requests are welcome!
+// Stale returns true when the client's cache is stale, it is the opposite of Fresh.
 func (c *Ctx) Stale() bool {
 	return !c.Fresh()
 }
@@ -1067,6 +1911,12 @@ func (c *Ctx) String() string {
 	)
 }
 
+// TLSConnectionState returns the TLS connection state, including the verified
+// peer certificates of the client, or nil for plain connections.
+func (c *Ctx) TLSConnectionState() *tls.ConnectionState {
+	return c.fasthttp.TLSConnectionState()
+}
+
 // Type sets the Content-Type HTTP header to the MIME type specified by the file extension.
 func (c *Ctx) Type(extension string, charset ...string) *Ctx {
 	if len(charset) > 0 {
@@ -1077,6 +1927,19 @@ func (c *Ctx) Type(extension string, charset ...string) *Ctx {
 	return c
 }
 
+// UserContext returns a context implementation that was set by
+// user earlier or returns a non-nil, empty context, if it was not set earlier.
+func (c *Ctx) UserContext() context.Context {
+	if c.userContext == nil {
+		c.userContext = context.Background()
+		// Requests of App.TestWithContext carry its context
+		if conn, ok := c.fasthttp.Conn().(*testConn); ok && conn.ctx != nil {
+			c.userContext = conn.ctx
+		}
+	}
+	return c.userContext
+}
+
 // Vary adds the given header field to the Vary response header.
 // This will append the header, if not already listed, otherwise leaves it listed in the current location.
 func (c *Ctx) Vary(fields ...string) {
@@ -1115,7 +1978,7 @@ func (c *Ctx) prettifyPath() {
 	if !c.app.config.StrictRouting && len(c.pathBuffer) > 1 && c.pathBuffer[len(c.pathBuffer)-1] == '/' {
 		c.pathBuffer = utils.TrimRightBytes(c.pathBuffer, '/')
 	}
-	c.path = getString(c.pathBuffer)
+	c.path = c.app.getString(c.pathBuffer)
 
 	c.treePath = c.treePath[0:0]
 	if len(c.path) >= 3 {
@@ -10,14 +10,22 @@ package fiber
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -26,6 +34,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
+	"github.com/gofiber/fiber/v2/internal/storage/memory"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
 )
@@ -55,6 +64,21 @@ func Test_Ctx_Accepts(t *testing.T) {
 
 	c.Request().Header.Set(HeaderAccept, "*/*")
 	utils.AssertEqual(t, "html", c.Accepts("html"))
+
+	c.Request().Header.Set(HeaderAccept, "text/html;q=0.2, application/json;q=0.9")
+	utils.AssertEqual(t, "json", c.Accepts("html", "json"))
+
+	c.Request().Header.Set(HeaderAccept, "text/*, text/html;q=0")
+	utils.AssertEqual(t, "", c.Accepts("html"))
+	utils.AssertEqual(t, "text/plain", c.Accepts("html", "text/plain"))
+
+	// RFC 9110 section 12.5.1
+	c.Request().Header.Set(HeaderAccept, "text/*;q=0.3, text/plain;q=0.7, text/plain;format=flowed, text/plain;format=fixed;q=0.4, */*;q=0.5")
+	utils.AssertEqual(t, "text/plain;format=flowed", c.Accepts("text/plain", "text/plain;format=flowed"))
+	utils.AssertEqual(t, "text/plain", c.Accepts("text/html", "text/plain"))
+	utils.AssertEqual(t, "image/jpeg", c.Accepts("text/html", "image/jpeg"))
+	utils.AssertEqual(t, "image/jpeg", c.Accepts("text/plain;format=fixed", "image/jpeg"))
+	utils.AssertEqual(t, "text/plain;format=fixed", c.Accepts("text/html", "text/plain;format=fixed"))
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_Accepts -benchmem -count=4
@@ -103,6 +127,12 @@ func Test_Ctx_AcceptsCharsets(t *testing.T) {
 	defer app.ReleaseCtx(c)
 	c.Request().Header.Set(HeaderAcceptCharset, "utf-8, iso-8859-1;q=0.5")
 	utils.AssertEqual(t, "utf-8", c.AcceptsCharsets("utf-8"))
+	utils.AssertEqual(t, "utf-8", c.AcceptsCharsets("iso-8859-1", "utf-8"))
+	utils.AssertEqual(t, "", c.AcceptsCharsets("utf-16"))
+
+	c.Request().Header.Set(HeaderAcceptCharset, "*;q=0.1, UTF-8, utf-16;q=0")
+	utils.AssertEqual(t, "utf-8", c.AcceptsCharsets("utf-16", "iso-8859-1", "utf-8"))
+	utils.AssertEqual(t, "", c.AcceptsCharsets("utf-16"))
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_AcceptsCharsets -benchmem -count=4
@@ -129,6 +159,17 @@ func Test_Ctx_AcceptsEncodings(t *testing.T) {
 	c.Request().Header.Set(HeaderAcceptEncoding, "deflate, gzip;q=1.0, *;q=0.5")
 	utils.AssertEqual(t, "gzip", c.AcceptsEncodings("gzip"))
 	utils.AssertEqual(t, "abc", c.AcceptsEncodings("abc"))
+	utils.AssertEqual(t, "deflate", c.AcceptsEncodings("abc", "deflate"))
+
+	// RFC 9110 section 12.5.3
+	c.Request().Header.Set(HeaderAcceptEncoding, "compress;q=0.5, gzip;q=1.0")
+	utils.AssertEqual(t, "gzip", c.AcceptsEncodings("compress", "gzip"))
+	utils.AssertEqual(t, "", c.AcceptsEncodings("br"))
+
+	c.Request().Header.Set(HeaderAcceptEncoding, "gzip;q=1.0, identity; q=0.5, *;q=0")
+	utils.AssertEqual(t, "identity", c.AcceptsEncodings("br", "identity"))
+	utils.AssertEqual(t, "gzip", c.AcceptsEncodings("identity", "gzip"))
+	utils.AssertEqual(t, "", c.AcceptsEncodings("br"))
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_AcceptsEncodings -benchmem -count=4
@@ -154,6 +195,16 @@ func Test_Ctx_AcceptsLanguages(t *testing.T) {
 	defer app.ReleaseCtx(c)
 	c.Request().Header.Set(HeaderAcceptLanguage, "fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5")
 	utils.AssertEqual(t, "fr", c.AcceptsLanguages("fr"))
+	utils.AssertEqual(t, "fr-CH", c.AcceptsLanguages("en", "fr-CH"))
+	utils.AssertEqual(t, "en", c.AcceptsLanguages("es", "en"))
+	utils.AssertEqual(t, "es", c.AcceptsLanguages("es"))
+
+	// RFC 9110 section 12.5.4
+	c.Request().Header.Set(HeaderAcceptLanguage, "da, en-gb;q=0.8, en;q=0.7")
+	utils.AssertEqual(t, "da", c.AcceptsLanguages("en", "da"))
+	utils.AssertEqual(t, "en-GB", c.AcceptsLanguages("en-US", "en-GB"))
+	utils.AssertEqual(t, "en-US", c.AcceptsLanguages("de", "en-US"))
+	utils.AssertEqual(t, "", c.AcceptsLanguages("de"))
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_AcceptsLanguages -benchmem -count=4
@@ -247,7 +298,7 @@ func Test_Ctx_Attachment(t *testing.T) {
 	utils.AssertEqual(t, "image/png", string(c.Response().Header.Peek(HeaderContentType)))
 	// check quoting
 	c.Attachment("another document.pdf\"\r\nBla: \"fasel")
-	utils.AssertEqual(t, `attachment; filename="another+document.pdf%22%0D%0ABla%3A+%22fasel"`, string(c.Response().Header.Peek(HeaderContentDisposition)))
+	utils.AssertEqual(t, `attachment; filename="another document.pdf\"Bla: \"fasel"; filename*=UTF-8''another%20document.pdf%22Bla%3A%20%22fasel`, string(c.Response().Header.Peek(HeaderContentDisposition)))
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_Attachment -benchmem -count=4
@@ -261,7 +312,7 @@ func Benchmark_Ctx_Attachment(b *testing.B) {
 		// example with quote params
 		c.Attachment("another document.pdf\"\r\nBla: \"fasel")
 	}
-	utils.AssertEqual(b, `attachment; filename="another+document.pdf%22%0D%0ABla%3A+%22fasel"`, string(c.Response().Header.Peek(HeaderContentDisposition)))
+	utils.AssertEqual(b, `attachment; filename="another document.pdf\"Bla: \"fasel"; filename*=UTF-8''another%20document.pdf%22Bla%3A%20%22fasel`, string(c.Response().Header.Peek(HeaderContentDisposition)))
 }
 
 // go test -run Test_Ctx_BaseURL
@@ -302,6 +353,76 @@ func Test_Ctx_Body(t *testing.T) {
 	utils.AssertEqual(t, []byte("john=doe"), c.Body())
 }
 
+// go test -run Test_Ctx_BodyStream
+func Test_Ctx_BodyStream(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().SetBody([]byte("john=doe"))
+	body, err := ioutil.ReadAll(c.BodyStream())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, []byte("john=doe"), body)
+	// The body can still be read after the stream
+	utils.AssertEqual(t, []byte("john=doe"), c.Body())
+}
+
+// go test -run Test_Ctx_BodyStream_StreamRequestBody
+func Test_Ctx_BodyStream_StreamRequestBody(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		BodyLimit:         1024,
+		StreamRequestBody: true,
+	})
+
+	app.Post("/upload", func(c *Ctx) error {
+		n, err := io.Copy(ioutil.Discard, c.BodyStream())
+		if err != nil {
+			return err
+		}
+		return c.SendString(strconv.FormatInt(n, 10))
+	})
+	app.Post("/parse", func(c *Ctx) error {
+		var data struct {
+			Name string `json:"name"`
+		}
+		if err := c.BodyParser(&data); err != nil {
+			return err
+		}
+		return c.SendString(data.Name)
+	})
+
+	// The body is larger than the BodyLimit
+	req := httptest.NewRequest(MethodPost, "/upload", bytes.NewReader(bytes.Repeat([]byte("a"), 64*1024)))
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "65536", string(body))
+
+	// BodyParser reads the whole stream
+	name := strings.Repeat("j", 4*1024)
+	req = httptest.NewRequest(MethodPost, "/parse", strings.NewReader(`{"name":"`+name+`"}`))
+	req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, name, string(body))
+
+	// Without streaming the body is rejected
+	app = New(Config{BodyLimit: 1024})
+	app.Post("/upload", func(c *Ctx) error {
+		return c.SendStatus(StatusOK)
+	})
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/upload", bytes.NewReader(bytes.Repeat([]byte("a"), 64*1024))))
+	if err == nil {
+		utils.AssertEqual(t, StatusRequestEntityTooLarge, resp.StatusCode, "Status code")
+	}
+}
+
 // go test -run Test_Ctx_BodyParser
 func Test_Ctx_BodyParser(t *testing.T) {
 	t.Parallel()
@@ -327,6 +448,12 @@ func Test_Ctx_BodyParser(t *testing.T) {
 	testDecodeParser(MIMEApplicationJSON, `{"name":"john"}`)
 	testDecodeParser(MIMEApplicationForm, "name=john")
 	testDecodeParser(MIMEMultipartForm+`;boundary="b"`, "--b\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\njohn\r\n--b--")
+	testDecodeParser(MIMEApplicationJSONCharsetUTF8, `{"name":"john"}`)
+	testDecodeParser("Application/JSON ; charset=utf-8", `{"name":"john"}`)
+	testDecodeParser("application/problem+json", `{"name":"john"}`)
+	testDecodeParser(MIMETextXMLCharsetUTF8, `<Demo><name>john</name></Demo>`)
+	testDecodeParser("application/atom+xml", `<Demo><name>john</name></Demo>`)
+	testDecodeParser(MIMEApplicationForm+"; charset=utf-8", "name=john")
 
 	testDecodeParserError := func(contentType, body string) {
 		c.Request().Header.SetContentType(contentType)
@@ -337,6 +464,55 @@ func Test_Ctx_BodyParser(t *testing.T) {
 
 	testDecodeParserError("invalid-content-type", "")
 	testDecodeParserError(MIMEMultipartForm+`;boundary="b"`, "--b")
+
+	// Unsupported content types are rejected with 415
+	for _, ctype := range []string{"", MIMETextPlain, "application/jsonp", "application/json+ld", "application/xmlx"} {
+		c.Request().Header.SetContentType(ctype)
+		c.Request().SetBody([]byte(`{"name":"john"}`))
+		utils.AssertEqual(t, ErrUnsupportedMediaType, c.BodyParser(new(Demo)), ctype)
+	}
+}
+
+// go test -run Test_Ctx_BodyParser_Nested
+func Test_Ctx_BodyParser_Nested(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	type Address struct {
+		City string `form:"city" xml:"city,attr"`
+	}
+	type User struct {
+		XMLName xml.Name  `xml:"user"`
+		Name    string    `form:"name" xml:"name,attr"`
+		Address Address   `form:"address" xml:"address"`
+		Tags    []string  `form:"tags" xml:"tag"`
+		Friends []Address `form:"friends" xml:"-"`
+	}
+
+	body := "--b\r\nContent-Disposition: form-data; name=\"name\"\r\n\r\njohn\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"address.city\"\r\n\r\nBerlin\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"tags\"\r\n\r\na\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"tags\"\r\n\r\nb\r\n" +
+		"--b\r\nContent-Disposition: form-data; name=\"friends.1.city\"\r\n\r\nParis\r\n--b--"
+	c.Request().Header.SetContentType(MIMEMultipartForm + `; boundary="b"`)
+	c.Request().SetBody([]byte(body))
+	user := new(User)
+	utils.AssertEqual(t, nil, c.BodyParser(user))
+	utils.AssertEqual(t, "john", user.Name)
+	utils.AssertEqual(t, "Berlin", user.Address.City)
+	utils.AssertEqual(t, []string{"a", "b"}, user.Tags)
+	utils.AssertEqual(t, []Address{{}, {City: "Paris"}}, user.Friends)
+
+	// XML attributes
+	c.Request().Header.SetContentType(MIMEApplicationXMLCharsetUTF8)
+	c.Request().SetBody([]byte(`<user name="doe"><address city="Rome"/><tag>c</tag><tag>d</tag></user>`))
+	user = new(User)
+	utils.AssertEqual(t, nil, c.BodyParser(user))
+	utils.AssertEqual(t, "doe", user.Name)
+	utils.AssertEqual(t, "Rome", user.Address.City)
+	utils.AssertEqual(t, []string{"c", "d"}, user.Tags)
 }
 
 // go test -v -run=^$ -bench=Benchmark_Ctx_BodyParser_JSON -benchmem -count=4
@@ -495,6 +671,46 @@ func Test_Ctx_Cookies(t *testing.T) {
 	utils.AssertEqual(t, "default", c.Cookies("unknown", "default"))
 }
 
+// go test -run Test_Ctx_Negotiate
+func Test_Ctx_Negotiate(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	formats := []ResFmt{
+		{MediaType: "application/json", Handler: func(c *Ctx) error { return c.SendString("json") }},
+		{MediaType: "text/html", Handler: func(c *Ctx) error { return c.SendString("html") }},
+		{MediaType: "txt", Handler: func(c *Ctx) error { return c.SendString("txt") }},
+	}
+	negotiate := func(accept, body, ctype string) {
+		c.Response().Reset()
+		c.Request().Header.Set(HeaderAccept, accept)
+		utils.AssertEqual(t, nil, c.Negotiate(formats...), accept)
+		utils.AssertEqual(t, body, string(c.Response().Body()), accept)
+		utils.AssertEqual(t, ctype, string(c.Response().Header.ContentType()), accept)
+		utils.AssertEqual(t, HeaderAccept, string(c.Response().Header.Peek(HeaderVary)), accept)
+	}
+
+	negotiate("", "json", MIMEApplicationJSON)
+	negotiate("*/*", "json", MIMEApplicationJSON)
+	negotiate("text/html", "html", MIMETextHTML)
+	negotiate("text/*", "html", MIMETextHTML)
+	negotiate("text/html;q=0.2, application/json;q=0.9", "json", MIMEApplicationJSON)
+	negotiate("application/json;q=0.2, text/html;q=0.9", "html", MIMETextHTML)
+	negotiate("text/*;q=0.5, text/plain", "txt", MIMETextPlain)
+	negotiate("*/*;q=0.1, text/html;q=0", "json", MIMEApplicationJSON)
+
+	// nothing is acceptable
+	c.Response().Reset()
+	c.Request().Header.Set(HeaderAccept, "image/png, application/json;q=0")
+	err := c.Negotiate(formats...)
+	utils.AssertEqual(t, StatusNotAcceptable, err.(*Error).Code)
+	utils.AssertEqual(t, "Not Acceptable. Supported media types: application/json, text/html, text/plain", err.Error())
+	utils.AssertEqual(t, HeaderAccept, string(c.Response().Header.Peek(HeaderVary)))
+	utils.AssertEqual(t, StatusNotAcceptable, c.Negotiate().(*Error).Code)
+}
+
 // go test -run Test_Ctx_Format
 func Test_Ctx_Format(t *testing.T) {
 	t.Parallel()
@@ -633,6 +849,177 @@ func Test_Ctx_FormFile(t *testing.T) {
 	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
 }
 
+// testMultipartRequest creates a request uploading the files under the key
+func testMultipartRequest(t *testing.T, key string, files map[string]string) *http.Request {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	utils.AssertEqual(t, nil, writer.WriteField("name", "john"))
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ioWriter, err := writer.CreateFormFile(key, name)
+		utils.AssertEqual(t, nil, err)
+		_, err = ioWriter.Write([]byte(files[name]))
+		utils.AssertEqual(t, nil, err)
+	}
+	utils.AssertEqual(t, nil, writer.Close())
+
+	req := httptest.NewRequest(MethodPost, "/test", body)
+	req.Header.Set(HeaderContentType, writer.FormDataContentType())
+	req.Header.Set(HeaderContentLength, strconv.Itoa(body.Len()))
+	return req
+}
+
+// go test -run Test_Ctx_FormFiles
+func Test_Ctx_FormFiles(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	app.Post("/test", func(c *Ctx) error {
+		files, err := c.FormFiles("files")
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, 2, len(files))
+		utils.AssertEqual(t, "a.png", files[0].Filename)
+		utils.AssertEqual(t, "b.txt", files[1].Filename)
+
+		// The content type is sniffed from the content, not the name
+		ctype, err := c.FileContentType(files[0])
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "text/html; charset=utf-8", ctype)
+		ctype, err = c.FileContentType(files[1])
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "image/png", ctype)
+
+		_, err = c.FormFiles("missing")
+		utils.AssertEqual(t, fasthttp.ErrMissingFile, err)
+		utils.AssertEqual(t, "john", c.FormValue("name"))
+		return nil
+	})
+
+	resp, err := app.Test(testMultipartRequest(t, "files", map[string]string{
+		"a.png": "<html><body>hello</body></html>",
+		"b.txt": "\x89PNG\r\n\x1a\n",
+	}))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+}
+
+// go test -run Test_Ctx_MultipartForm_Limits
+func Test_Ctx_MultipartForm_Limits(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		MaxMultipartFiles: 2,
+		MaxMultipartSize:  10,
+	})
+
+	app.Post("/test", func(c *Ctx) error {
+		if _, err := c.MultipartForm(); err != nil {
+			return err
+		}
+		return c.SendString(c.FormValue("name"))
+	})
+
+	for _, test := range []struct {
+		files  map[string]string
+		status int
+		body   string
+	}{
+		{map[string]string{"a": "12345", "b": "12345"}, StatusOK, "john"},
+		{map[string]string{"a": "1", "b": "2", "c": "3"}, StatusRequestEntityTooLarge, ErrMultipartFilesLimit.Message},
+		{map[string]string{"a": "12345", "b": "123456"}, StatusRequestEntityTooLarge, ErrMultipartSizeLimit.Message},
+	} {
+		resp, err := app.Test(testMultipartRequest(t, "files", test.files))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, test.status, resp.StatusCode, "Status code")
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, test.body, string(body))
+	}
+}
+
+// go test -run Test_Ctx_MultipartForm_Limits_Cached
+func Test_Ctx_MultipartForm_Limits_Cached(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		MaxMultipartFiles:  1,
+		MaxMultipartMemory: 16,
+	})
+
+	app.Post("/test", func(c *Ctx) error {
+		_, err := c.MultipartForm()
+		utils.AssertEqual(t, ErrMultipartFilesLimit, err)
+		// Later calls get the error instead of the unchecked form
+		_, err = c.MultipartForm()
+		utils.AssertEqual(t, ErrMultipartFilesLimit, err)
+		_, err = c.FormFile("files")
+		utils.AssertEqual(t, ErrMultipartFilesLimit, err)
+		return c.SendString(c.FormValue("name", "none"))
+	})
+
+	resp, err := app.Test(testMultipartRequest(t, "files", map[string]string{"a": "1", "b": "2"}))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "none", string(body))
+}
+
+// go test -run Test_Ctx_MultipartForm_MaxMemory
+func Test_Ctx_MultipartForm_MaxMemory(t *testing.T) {
+	t.Parallel()
+	app := New(Config{MaxMultipartMemory: 16})
+
+	var tmpFile string
+	app.Post("/test", func(c *Ctx) error {
+		small, err := c.FormFile("small")
+		utils.AssertEqual(t, nil, err)
+		large, err := c.FormFile("large")
+		utils.AssertEqual(t, nil, err)
+
+		f, err := small.Open()
+		utils.AssertEqual(t, nil, err)
+		_, onDisk := f.(*os.File)
+		utils.AssertEqual(t, false, onDisk)
+		utils.AssertEqual(t, nil, f.Close())
+
+		// Files exceeding the memory limit are stored in temporary files
+		f, err = large.Open()
+		utils.AssertEqual(t, nil, err)
+		file, onDisk := f.(*os.File)
+		utils.AssertEqual(t, true, onDisk)
+		tmpFile = file.Name()
+		utils.AssertEqual(t, nil, f.Close())
+
+		utils.AssertEqual(t, "john", c.FormValue("name"))
+		return nil
+	})
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	utils.AssertEqual(t, nil, writer.WriteField("name", "john"))
+	for name, content := range map[string]string{"small": "hello", "large": strings.Repeat("a", 1024)} {
+		ioWriter, err := writer.CreateFormFile(name, name+".txt")
+		utils.AssertEqual(t, nil, err)
+		_, err = ioWriter.Write([]byte(content))
+		utils.AssertEqual(t, nil, err)
+	}
+	utils.AssertEqual(t, nil, writer.Close())
+	req := httptest.NewRequest(MethodPost, "/test", body)
+	req.Header.Set(HeaderContentType, writer.FormDataContentType())
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	// Temporary files are removed once the handler returned
+	utils.AssertEqual(t, true, tmpFile != "")
+	_, err = os.Stat(tmpFile)
+	utils.AssertEqual(t, true, os.IsNotExist(err))
+}
+
 // go test -run Test_Ctx_FormValue
 func Test_Ctx_FormValue(t *testing.T) {
 	t.Parallel()
@@ -717,10 +1104,73 @@ func Test_Ctx_Fresh(t *testing.T) {
 	c.Response().Header.Set(HeaderLastModified, "Wed, 21 Oct 2015 07:28:00 GMT")
 	utils.AssertEqual(t, false, c.Fresh())
 
+	// Not modified since the given date
 	c.Request().Header.Set(HeaderIfModifiedSince, "Wed, 21 Oct 2015 07:28:00 GMT")
+	utils.AssertEqual(t, true, c.Fresh())
+
+	c.Request().Header.Set(HeaderIfModifiedSince, "Wed, 21 Oct 2015 07:27:59 GMT")
 	utils.AssertEqual(t, false, c.Fresh())
 }
 
+// go test -run Test_Ctx_Fresh_Conditions
+func Test_Ctx_Fresh_Conditions(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	testCases := []struct {
+		name         string
+		method       string
+		status       int
+		noneMatch    string
+		modSince     string
+		etag         string
+		lastModified string
+		fresh        bool
+	}{
+		{name: "etag", noneMatch: `"foo"`, etag: `"foo"`, fresh: true},
+		{name: "other etag", noneMatch: `"foo"`, etag: `"bar"`},
+		{name: "list of etags", noneMatch: `"bar", "foo"`, etag: `"foo"`, fresh: true},
+		{name: "weak request etag", noneMatch: `W/"foo"`, etag: `"foo"`, fresh: true},
+		{name: "weak response etag", noneMatch: `"foo"`, etag: `W/"foo"`, fresh: true},
+		{name: "weak etags", noneMatch: `W/"foo"`, etag: `W/"foo"`, fresh: true},
+		{name: "star", noneMatch: "*", fresh: true},
+		{name: "missing etag", noneMatch: `"foo"`},
+		{name: "not modified", modSince: "Wed, 21 Oct 2015 07:28:00 GMT", lastModified: "Tue, 20 Oct 2015 07:28:00 GMT", fresh: true},
+		{name: "modified", modSince: "Wed, 21 Oct 2015 07:28:00 GMT", lastModified: "Thu, 22 Oct 2015 07:28:00 GMT"},
+		{name: "missing last modified", modSince: "Wed, 21 Oct 2015 07:28:00 GMT"},
+		{name: "etag and modified", noneMatch: `"foo"`, etag: `"foo"`, modSince: "Wed, 21 Oct 2015 07:28:00 GMT", lastModified: "Thu, 22 Oct 2015 07:28:00 GMT"},
+		{name: "head", method: MethodHead, noneMatch: `"foo"`, etag: `"foo"`, fresh: true},
+		{name: "post", method: MethodPost, noneMatch: `"foo"`, etag: `"foo"`},
+		{name: "not modified status", status: StatusNotModified, noneMatch: `"foo"`, etag: `"foo"`, fresh: true},
+		{name: "error status", status: StatusNotFound, noneMatch: `"foo"`, etag: `"foo"`},
+	}
+	for _, tc := range testCases {
+		fctx := &fasthttp.RequestCtx{}
+		if tc.method != "" {
+			fctx.Request.Header.SetMethod(tc.method)
+		}
+		if tc.status != 0 {
+			fctx.Response.SetStatusCode(tc.status)
+		}
+		if tc.noneMatch != "" {
+			fctx.Request.Header.Set(HeaderIfNoneMatch, tc.noneMatch)
+		}
+		if tc.modSince != "" {
+			fctx.Request.Header.Set(HeaderIfModifiedSince, tc.modSince)
+		}
+		if tc.etag != "" {
+			fctx.Response.Header.Set(HeaderETag, tc.etag)
+		}
+		if tc.lastModified != "" {
+			fctx.Response.Header.Set(HeaderLastModified, tc.lastModified)
+		}
+		c := app.AcquireCtx(fctx)
+		utils.AssertEqual(t, tc.fresh, c.Fresh(), tc.name)
+		utils.AssertEqual(t, !tc.fresh, c.Stale(), tc.name)
+		app.ReleaseCtx(c)
+	}
+}
+
 // go test -v -run=^$ -bench=Benchmark_Ctx_Fresh_WithNoCache -benchmem -count=4
 func Benchmark_Ctx_Fresh_WithNoCache(b *testing.B) {
 	app := New()
@@ -747,6 +1197,48 @@ func Test_Ctx_Get(t *testing.T) {
 	utils.AssertEqual(t, "default", c.Get("unknown", "default"))
 }
 
+// go test -run Test_Ctx_GetReqHeaders
+func Test_Ctx_GetReqHeaders(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	c.Request().Header.DisableNormalizing()
+	c.Request().Header.Add("x-forwarded-for", "127.0.0.1")
+	c.Request().Header.Add("X-FORWARDED-FOR", "127.0.0.2")
+	c.Request().Header.Set(HeaderAcceptEncoding, "gzip")
+	headers := c.GetReqHeaders()
+	utils.AssertEqual(t, []string{"127.0.0.1", "127.0.0.2"}, headers[HeaderXForwardedFor])
+	utils.AssertEqual(t, []string{"gzip"}, headers[HeaderAcceptEncoding])
+	utils.AssertEqual(t, 0, len(headers["x-forwarded-for"]))
+
+	// the headers are copies that outlive the request buffers
+	c.Request().Header.Reset()
+	c.Request().Header.Set(HeaderXForwardedFor, "255.255.255.255")
+	app.ReleaseCtx(c)
+	utils.AssertEqual(t, []string{"127.0.0.1", "127.0.0.2"}, headers[HeaderXForwardedFor])
+	utils.AssertEqual(t, []string{"gzip"}, headers[HeaderAcceptEncoding])
+}
+
+// go test -run Test_Ctx_GetRespHeaders
+func Test_Ctx_GetRespHeaders(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	c.Response().Header.Add("x-custom", "one")
+	c.Response().Header.Add("x-custom", "two")
+	c.Response().Header.Set(HeaderContentType, MIMEApplicationJSON)
+	headers := c.GetRespHeaders()
+	utils.AssertEqual(t, []string{"one", "two"}, headers["X-Custom"])
+	utils.AssertEqual(t, []string{MIMEApplicationJSON}, headers[HeaderContentType])
+
+	// the headers are copies that outlive the response buffers
+	c.Response().Header.Reset()
+	c.Response().Header.Set("X-Custom", "three")
+	app.ReleaseCtx(c)
+	utils.AssertEqual(t, []string{"one", "two"}, headers["X-Custom"])
+	utils.AssertEqual(t, []string{MIMEApplicationJSON}, headers[HeaderContentType])
+}
+
 // go test -run Test_Ctx_Hostname
 func Test_Ctx_Hostname(t *testing.T) {
 	t.Parallel()
@@ -775,6 +1267,115 @@ func Test_Ctx_IP_ProxyHeader(t *testing.T) {
 	utils.AssertEqual(t, "", c.IP())
 }
 
+// go test -run Test_Ctx_IP_Validation
+func Test_Ctx_IP_Validation(t *testing.T) {
+	t.Parallel()
+	app := New(Config{ProxyHeader: HeaderXForwardedFor, EnableIPValidation: true})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	c.Request().Header.Set(HeaderXForwardedFor, " , unknown, 203.0.113.195 , 70.41.3.18")
+	utils.AssertEqual(t, "203.0.113.195", c.IP())
+
+	c.Request().Header.Set(HeaderXForwardedFor, "203.0.113.195:8080")
+	utils.AssertEqual(t, "203.0.113.195", c.IP())
+
+	c.Request().Header.Set(HeaderXForwardedFor, "[2001:db8::1]:443, 1.1.1.1")
+	utils.AssertEqual(t, "2001:db8::1", c.IP())
+
+	c.Request().Header.Set(HeaderXForwardedFor, "unknown, 999.1.1.1, 1.1.1.1:port")
+	utils.AssertEqual(t, "0.0.0.0", c.IP())
+
+	c.Request().Header.Del(HeaderXForwardedFor)
+	utils.AssertEqual(t, "0.0.0.0", c.IP())
+}
+
+// go test -v -run=^$ -bench=Benchmark_Ctx_IP_Validation -benchmem -count=4
+func Benchmark_Ctx_IP_Validation(b *testing.B) {
+	app := New(Config{ProxyHeader: HeaderXForwardedFor, EnableIPValidation: true})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.Set(HeaderXForwardedFor, "203.0.113.195, 70.41.3.18, 150.172.238.178")
+	var res string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		res = c.IP()
+	}
+	utils.AssertEqual(b, "203.0.113.195", res)
+}
+
+// go test -run Test_Ctx_TrustedProxies
+func Test_Ctx_TrustedProxies(t *testing.T) {
+	t.Parallel()
+	app := New(Config{
+		ProxyHeader:             HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          []string{"10.0.0.0/8", "192.168.1.1", "2001:db8::/32"},
+	})
+	acquire := func(remoteIP string) *Ctx {
+		fctx := &fasthttp.RequestCtx{}
+		fctx.Init(&fasthttp.Request{}, &net.TCPAddr{IP: net.ParseIP(remoteIP)}, nil)
+		fctx.Request.Header.Set(HeaderXForwardedFor, "1.1.1.1, 10.0.0.2")
+		fctx.Request.Header.Set(HeaderXForwardedProto, "https")
+		fctx.Request.Header.Set(HeaderXForwardedHost, "example.com, proxy.local")
+		fctx.Request.Header.SetHost("internal.local")
+		return app.AcquireCtx(fctx)
+	}
+
+	for _, ip := range []string{"10.1.2.3", "192.168.1.1", "2001:db8::1"} {
+		c := acquire(ip)
+		utils.AssertEqual(t, "1.1.1.1, 10.0.0.2", c.IP(), ip)
+		utils.AssertEqual(t, []string{"1.1.1.1", "10.0.0.2"}, c.IPs(), ip)
+		utils.AssertEqual(t, "https", c.Protocol(), ip)
+		utils.AssertEqual(t, "example.com", c.Hostname(), ip)
+		app.ReleaseCtx(c)
+	}
+
+	for _, ip := range []string{"11.0.0.1", "192.168.1.2", "2001:db9::1"} {
+		c := acquire(ip)
+		utils.AssertEqual(t, ip, c.IP(), ip)
+		utils.AssertEqual(t, 0, len(c.IPs()), ip)
+		utils.AssertEqual(t, "http", c.Protocol(), ip)
+		utils.AssertEqual(t, "internal.local", c.Hostname(), ip)
+		app.ReleaseCtx(c)
+	}
+}
+
+// go test -v -run=^$ -bench=Benchmark_Ctx_IP_TrustedProxy -benchmem -count=4
+func Benchmark_Ctx_IP_TrustedProxy(b *testing.B) {
+	app := New(Config{
+		ProxyHeader:             HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"},
+	})
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Init(&fasthttp.Request{}, &net.TCPAddr{IP: net.ParseIP("172.16.5.4")}, nil)
+	fctx.Request.Header.Set(HeaderXForwardedFor, "1.1.1.1")
+	c := app.AcquireCtx(fctx)
+	defer app.ReleaseCtx(c)
+	var res string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		res = c.IP()
+	}
+	utils.AssertEqual(b, "1.1.1.1", res)
+}
+
+// go test -run Test_Ctx_IsFromLocal
+func Test_Ctx_IsFromLocal(t *testing.T) {
+	t.Parallel()
+	app := New()
+	for ip, local := range map[string]bool{"127.0.0.1": true, "::1": true, "10.0.0.1": false} {
+		fctx := &fasthttp.RequestCtx{}
+		fctx.Init(&fasthttp.Request{}, &net.TCPAddr{IP: net.ParseIP(ip)}, nil)
+		c := app.AcquireCtx(fctx)
+		utils.AssertEqual(t, local, c.IsFromLocal(), ip)
+		app.ReleaseCtx(c)
+	}
+}
+
 // go test -run Test_Ctx_IPs  -parallel
 func Test_Ctx_IPs(t *testing.T) {
 	t.Parallel()
@@ -791,6 +1392,35 @@ func Test_Ctx_IPs(t *testing.T) {
 	utils.AssertEqual(t, 0, len(c.IPs()))
 }
 
+// go test -run Test_Ctx_IPs_Validation
+func Test_Ctx_IPs_Validation(t *testing.T) {
+	t.Parallel()
+	app := New(Config{EnableIPValidation: true})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	c.Request().Header.Set(HeaderXForwardedFor, "127.0.0.1, , unknown,\t127.0.0.2:80 ,[::1]:8080, 2001:db8::1, 256.0.0.1")
+	utils.AssertEqual(t, []string{"127.0.0.1", "127.0.0.2", "::1", "2001:db8::1"}, c.IPs())
+
+	c.Request().Header.Set(HeaderXForwardedFor, "unknown")
+	utils.AssertEqual(t, []string{}, c.IPs())
+}
+
+// go test -v -run=^$ -bench=Benchmark_Ctx_IPs_Validation -benchmem -count=4
+func Benchmark_Ctx_IPs_Validation(b *testing.B) {
+	app := New(Config{EnableIPValidation: true})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().Header.Set(HeaderXForwardedFor, "127.0.0.1, invalid, 127.0.0.1")
+	var res []string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		res = c.IPs()
+	}
+	utils.AssertEqual(b, []string{"127.0.0.1", "127.0.0.1"}, res)
+}
+
 // go test -v -run=^$ -bench=Benchmark_Ctx_IPs -benchmem -count=4
 func Benchmark_Ctx_IPs(b *testing.B) {
 	app := New()
@@ -1010,20 +1640,93 @@ func Test_Ctx_Params(t *testing.T) {
 	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
 }
 
-// go test -v -run=^$ -bench=Benchmark_Ctx_Params -benchmem -count=4
-func Benchmark_Ctx_Params(b *testing.B) {
+// go test -run Test_Ctx_ParamsInt
+func Test_Ctx_ParamsInt(t *testing.T) {
+	t.Parallel()
 	app := New()
-	c := app.AcquireCtx(&fasthttp.RequestCtx{})
-	defer app.ReleaseCtx(c)
-	c.route = &Route{
-		Params: []string{
-			"param1", "param2", "param3", "param4",
-		},
-	}
-	c.values = [maxParams]string{
-		"john", "doe", "is", "awesome",
+	app.Get("/test/:id", func(c *Ctx) error {
+		id, err := c.ParamsInt("id")
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, 1111, id)
+		return nil
+	})
+	app.Get("/invalid/:id", func(c *Ctx) error {
+		id, err := c.ParamsInt("id")
+		utils.AssertEqual(t, `failed to convert param "id": "john" is not a valid int`, err.Error())
+		utils.AssertEqual(t, 0, id)
+		id, err = c.ParamsInt("id", 2222)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, 2222, id)
+		return nil
+	})
+	app.Get("/optional/:id?", func(c *Ctx) error {
+		id, err := c.ParamsInt("id")
+		utils.AssertEqual(t, `failed to convert param "id": param is empty`, err.Error())
+		utils.AssertEqual(t, 0, id)
+		id, err = c.ParamsInt("id", 2222)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, 2222, id)
+		return nil
+	})
+	app.Get("/wildcard/*", func(c *Ctx) error {
+		id, err := c.ParamsInt("*")
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, 3333, id)
+		return nil
+	})
+
+	for _, path := range []string{"/test/1111", "/invalid/john", "/optional", "/wildcard/3333"} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
 	}
-	var res string
+}
+
+// go test -run Test_Ctx_AllParams
+func Test_Ctx_AllParams(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/test/:user/:optional?/*", func(c *Ctx) error {
+		utils.AssertEqual(t, map[string]string{
+			"user":     "john",
+			"optional": "im",
+			"*1":       "a/cookie",
+		}, c.AllParams())
+		return nil
+	})
+	app.Get("/test2/:user/:optional?", func(c *Ctx) error {
+		utils.AssertEqual(t, map[string]string{
+			"user":     "john",
+			"optional": "",
+		}, c.AllParams())
+		return nil
+	})
+	app.Get("/static", func(c *Ctx) error {
+		utils.AssertEqual(t, map[string]string{}, c.AllParams())
+		return nil
+	})
+
+	for _, path := range []string{"/test/john/im/a/cookie", "/test2/john", "/static"} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	}
+}
+
+// go test -v -run=^$ -bench=Benchmark_Ctx_Params -benchmem -count=4
+func Benchmark_Ctx_Params(b *testing.B) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.route = &Route{
+		Params: []string{
+			"param1", "param2", "param3", "param4",
+		},
+	}
+	c.values = [maxParams]string{
+		"john", "doe", "is", "awesome",
+	}
+	var res string
 	b.ReportAllocs()
 	b.ResetTimer()
 	for n := 0; n < b.N; n++ {
@@ -1105,6 +1808,117 @@ func Test_Ctx_Query(t *testing.T) {
 	utils.AssertEqual(t, "default", c.Query("unknown", "default"))
 }
 
+// go test -run Test_Ctx_Queries
+func Test_Ctx_Queries(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("name=alex&amount=32&name=john+doe&filter%5Bname%5D=a%26b&empty")
+
+	utils.AssertEqual(t, map[string]string{
+		"name":         "john doe",
+		"amount":       "32",
+		"filter[name]": "a&b",
+		"empty":        "",
+	}, c.Queries())
+	utils.AssertEqual(t, map[string][]string{
+		"name":         {"alex", "john doe"},
+		"amount":       {"32"},
+		"filter[name]": {"a&b"},
+		"empty":        {""},
+	}, c.QueriesAll())
+
+	// The maps are copies
+	queries := c.Queries()
+	c.Request().URI().SetQueryString("name=bob")
+	utils.AssertEqual(t, "john doe", queries["name"])
+	utils.AssertEqual(t, map[string]string{"name": "bob"}, c.Queries())
+
+	c.Request().URI().SetQueryString("")
+	utils.AssertEqual(t, map[string]string{}, c.Queries())
+}
+
+// go test -v -run=^$ -bench=Benchmark_Ctx_Queries -benchmem -count=4
+func Benchmark_Ctx_Queries(b *testing.B) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("name=alex&amount=32&name=john&sort=desc")
+	var queries map[string]string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		queries = c.Queries()
+	}
+	utils.AssertEqual(b, "john", queries["name"])
+}
+
+// go test -run Test_Ctx_QueryInt
+func Test_Ctx_QueryInt(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("age=20&age=30&empty=&name=john&big=9223372036854775808&neg=-5")
+	utils.AssertEqual(t, 20, c.QueryInt("age"))
+	utils.AssertEqual(t, -5, c.QueryInt("neg"))
+	utils.AssertEqual(t, 0, c.QueryInt("empty"))
+	utils.AssertEqual(t, 0, c.QueryInt("name"))
+	utils.AssertEqual(t, 0, c.QueryInt("big"))
+	utils.AssertEqual(t, 0, c.QueryInt("unknown"))
+	utils.AssertEqual(t, 1, c.QueryInt("empty", 1))
+	utils.AssertEqual(t, 1, c.QueryInt("big", 1))
+	utils.AssertEqual(t, 1, c.QueryInt("unknown", 1))
+}
+
+// go test -run Test_Ctx_QueryBool
+func Test_Ctx_QueryBool(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("admin=true&admin=false&one=1&empty=&name=john")
+	utils.AssertEqual(t, true, c.QueryBool("admin"))
+	utils.AssertEqual(t, true, c.QueryBool("one"))
+	utils.AssertEqual(t, false, c.QueryBool("empty"))
+	utils.AssertEqual(t, false, c.QueryBool("name"))
+	utils.AssertEqual(t, false, c.QueryBool("unknown"))
+	utils.AssertEqual(t, true, c.QueryBool("empty", true))
+	utils.AssertEqual(t, true, c.QueryBool("unknown", true))
+}
+
+// go test -run Test_Ctx_QueryFloat
+func Test_Ctx_QueryFloat(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("price=1.5&price=2.5&empty=&name=john&big=1e400")
+	utils.AssertEqual(t, 1.5, c.QueryFloat("price"))
+	utils.AssertEqual(t, float64(0), c.QueryFloat("empty"))
+	utils.AssertEqual(t, float64(0), c.QueryFloat("name"))
+	utils.AssertEqual(t, float64(0), c.QueryFloat("big"))
+	utils.AssertEqual(t, float64(0), c.QueryFloat("unknown"))
+	utils.AssertEqual(t, 0.5, c.QueryFloat("big", 0.5))
+	utils.AssertEqual(t, 0.5, c.QueryFloat("unknown", 0.5))
+}
+
+// go test -v -run=^$ -bench=Benchmark_Ctx_QueryInt -benchmem -count=4
+func Benchmark_Ctx_QueryInt(b *testing.B) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Request().URI().SetQueryString("age=20")
+	var res int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		res = c.QueryInt("age")
+	}
+	utils.AssertEqual(b, 20, res)
+}
+
 // go test -run Test_Ctx_Range
 func Test_Ctx_Range(t *testing.T) {
 	t.Parallel()
@@ -1145,6 +1959,12 @@ func Test_Ctx_Range(t *testing.T) {
 	testRange("bytes=500-b", 500, 999)
 	testRange("bytes=500-1000", 500, 999)
 	testRange("bytes=500-700", 500, 700)
+	testRange("bytes=-2000", 0, 999)
+	testRange("bytes=0-1, 500-700", 0, 1)
+
+	c.Request().Header.Set(HeaderRange, "bytes=-0")
+	_, err = c.Range(1000)
+	utils.AssertEqual(t, ErrRangeUnsatisfiable, err)
 }
 
 // go test -run Test_Ctx_Route
@@ -1167,6 +1987,160 @@ func Test_Ctx_Route(t *testing.T) {
 	utils.AssertEqual(t, 0, len(c.Route().Handlers))
 }
 
+// go test -run Test_Ctx_RoutePattern
+func Test_Ctx_RoutePattern(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	var before, after []string
+	var notFound bool
+	app.Use(func(c *Ctx) error {
+		before = append(before, c.RoutePattern())
+		err := c.Next()
+		after = append(after, c.RoutePattern())
+		notFound = c.Route().NotFound()
+		return err
+	})
+	api := app.Group("/api", func(c *Ctx) error {
+		return c.Next()
+	})
+	api.Get("/users/:id", func(c *Ctx) error {
+		utils.AssertEqual(t, "/api/users/:id", c.RoutePattern())
+		return nil
+	})
+	api.Post("/users/:id", func(c *Ctx) error {
+		return ErrBadRequest
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/api/users/42", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, false, notFound)
+
+	resp, err = app.Test(httptest.NewRequest(MethodPost, "/api/users/42", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusBadRequest, resp.StatusCode, "Status code")
+
+	// Only middleware matched
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/api/posts/42", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, true, notFound)
+
+	utils.AssertEqual(t, []string{"/", "/", "/"}, before)
+	utils.AssertEqual(t, []string{"/api/users/:id", "/api/users/:id", ""}, after)
+
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, "", c.RoutePattern())
+}
+
+// go test -run Test_Ctx_Route_NotFound
+func Test_Ctx_Route_NotFound(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	var names []string
+	app.Use(func(c *Ctx) error {
+		err := c.Next()
+		utils.AssertEqual(t, true, c.Route().NotFound())
+		names = append(names, c.Route().Name)
+		// Changes are local to the Ctx
+		c.Route().Name = "changed"
+		c.Route().Params = append(c.Route().Params, "changed")
+		return err
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, "/missing", nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusNotFound, resp.StatusCode, "Status code")
+	}
+	utils.AssertEqual(t, []string{"NotFound", "NotFound"}, names)
+	utils.AssertEqual(t, 0, len(notFoundRoute.Params))
+}
+
+// go test -run Test_Ctx_RestartRouting
+func Test_Ctx_RestartRouting(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	calls := 0
+	app.Use(func(c *Ctx) error {
+		calls++
+		return c.Next()
+	})
+	app.Get("/old/:id", func(c *Ctx) error {
+		c.Locals("from", "old")
+		c.Path("/new/" + c.Params("id") + "/posts")
+		return c.RestartRouting()
+	})
+	app.Get("/new/:id/:section", func(c *Ctx) error {
+		return c.SendString(c.Params("id") + " " + c.Params("section") + " " + c.Locals("from").(string))
+	})
+	app.Get("/loop", func(c *Ctx) error {
+		return c.RestartRouting()
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/old/5", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "5 posts old", string(body))
+	utils.AssertEqual(t, 2, calls)
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/loop", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusInternalServerError, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, ErrRestartLimit.Error(), string(body))
+}
+
+// go test -run Test_Ctx_GetRouteURL
+func Test_Ctx_GetRouteURL(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/users/:id", testEmptyHandler).Name("user.show")
+	app.Get("/users/:id/posts/:post?", testEmptyHandler).Name("user.posts")
+	app.Get("/files/*", testEmptyHandler).Name("files")
+	app.Get("/:lang?", testEmptyHandler).Name("home")
+	app.Get("/flights/:from-:to/+", testEmptyHandler).Name("flights")
+
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	testCases := []struct {
+		name   string
+		params Map
+		url    string
+	}{
+		{"user.show", Map{"id": 1}, "/users/1"},
+		{"user.show", Map{"id": "john doe"}, "/users/john%20doe"},
+		{"user.posts", Map{"id": 1, "post": "hello"}, "/users/1/posts/hello"},
+		{"user.posts", Map{"id": 1}, "/users/1/posts"},
+		{"files", Map{"*": "css/style.css"}, "/files/css/style.css"},
+		{"files", Map{"*1": "app.js"}, "/files/app.js"},
+		{"files", nil, "/files"},
+		{"home", Map{"lang": "en"}, "/en"},
+		{"home", nil, "/"},
+		{"flights", Map{"from": "LAX", "to": "SFO", "+": "today"}, "/flights/LAX-SFO/today"},
+	}
+	for _, tc := range testCases {
+		url, err := c.GetRouteURL(tc.name, tc.params)
+		utils.AssertEqual(t, nil, err, tc.name)
+		utils.AssertEqual(t, tc.url, url, tc.name)
+	}
+
+	_, err := c.GetRouteURL("user.show", Map{})
+	utils.AssertEqual(t, `route: missing param "id" for route "user.show"`, err.Error())
+	_, err = c.GetRouteURL("flights", Map{"from": "LAX", "to": "SFO"})
+	utils.AssertEqual(t, `route: missing param "+1" for route "flights"`, err.Error())
+	_, err = c.GetRouteURL("unknown", nil)
+	utils.AssertEqual(t, `route: no route named "unknown"`, err.Error())
+}
+
 // go test -run Test_Ctx_RouteNormalized
 func Test_Ctx_RouteNormalized(t *testing.T) {
 	t.Parallel()
@@ -1222,6 +2196,85 @@ func Test_Ctx_SaveFile(t *testing.T) {
 	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
 }
 
+type streamStorage struct {
+	*memory.Storage
+	streamed bool
+}
+
+func (s *streamStorage) SetStream(key string, r io.Reader, size int64, ttl time.Duration) error {
+	s.streamed = true
+	val, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if int64(len(val)) != size {
+		return errors.New("size mismatch")
+	}
+	return s.Set(key, val, ttl)
+}
+
+// go test -run Test_Ctx_SaveFileToStorage
+func Test_Ctx_SaveFileToStorage(t *testing.T) {
+	app := New(Config{BodyLimit: 16 * 1024 * 1024})
+	storage := memory.New()
+	streaming := &streamStorage{Storage: memory.New()}
+
+	content := make([]byte, 10*1024*1024)
+	for i := range content {
+		content[i] = byte(i % 251)
+	}
+
+	app.Post("/test", func(c *Ctx) error {
+		fh, err := c.FormFile("file")
+		utils.AssertEqual(t, nil, err)
+
+		utils.AssertEqual(t, nil, c.SaveFileToStorage(fh, "upload", storage))
+		utils.AssertEqual(t, nil, c.SaveFileToStorage(fh, "upload", streaming))
+
+		// The BodyLimit applies to the file as well
+		app.config.BodyLimit = 1024
+		utils.AssertEqual(t, ErrRequestEntityTooLarge, c.SaveFileToStorage(fh, "too-large", storage))
+		app.config.BodyLimit = 16 * 1024 * 1024
+		return nil
+	})
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	ioWriter, err := writer.CreateFormFile("file", "test")
+	utils.AssertEqual(t, nil, err)
+	_, err = ioWriter.Write(content)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, writer.Close())
+
+	tempFiles := func() int {
+		files, err := filepath.Glob(filepath.Join(os.TempDir(), "multipart-*"))
+		utils.AssertEqual(t, nil, err)
+		return len(files)
+	}
+	before := tempFiles()
+
+	req := httptest.NewRequest(MethodPost, "/test", body)
+	req.Header.Set(HeaderContentType, writer.FormDataContentType())
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+
+	saved, err := storage.Get("upload")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, bytes.Equal(content, saved))
+
+	utils.AssertEqual(t, true, streaming.streamed)
+	saved, err = streaming.Get("upload")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, bytes.Equal(content, saved))
+
+	_, err = storage.Get("too-large")
+	utils.AssertEqual(t, true, err != nil)
+
+	// The multipart form removes its temporary files
+	utils.AssertEqual(t, before, tempFiles())
+}
+
 // go test -run Test_Ctx_Secure
 func Test_Ctx_Secure(t *testing.T) {
 	t.Parallel()
@@ -1302,12 +2355,40 @@ func Test_Ctx_Download(t *testing.T) {
 	expect, err := ioutil.ReadAll(f)
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, expect, c.Response().Body())
-	utils.AssertEqual(t, `attachment; filename="Awesome+File%21"`, string(c.Response().Header.Peek(HeaderContentDisposition)))
+	utils.AssertEqual(t, `attachment; filename="Awesome File!"`, string(c.Response().Header.Peek(HeaderContentDisposition)))
 
 	c.Download("ctx.go")
 	utils.AssertEqual(t, `attachment; filename="ctx.go"`, string(c.Response().Header.Peek(HeaderContentDisposition)))
 }
 
+// go test -run Test_Ctx_Download_Filename
+func Test_Ctx_Download_Filename(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/:name", func(c *Ctx) error {
+		return c.Download("ctx.go", c.Query("name"))
+	})
+
+	testCases := []struct {
+		name   string
+		expect string
+	}{
+		{"résumé.pdf", `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`},
+		{`my "best"; file.txt`, `attachment; filename="my \"best\"; file.txt"; filename*=UTF-8''my%20%22best%22%3B%20file.txt`},
+		{"a.txt\r\nSet-Cookie: session=1", `attachment; filename="a.txtSet-Cookie: session=1"; filename*=UTF-8''a.txtSet-Cookie%3A%20session%3D1`},
+		{`back\slash.txt`, `attachment; filename="back\\slash.txt"; filename*=UTF-8''back%5Cslash.txt`},
+		{"report-2021_v1.txt", `attachment; filename="report-2021_v1.txt"`},
+	}
+
+	for _, tc := range testCases {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, "/file?name="+url.QueryEscape(tc.name), nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+		utils.AssertEqual(t, tc.expect, resp.Header.Get(HeaderContentDisposition), tc.name)
+		utils.AssertEqual(t, "", resp.Header.Get(HeaderSetCookie), tc.name)
+	}
+}
+
 // go test -race -run Test_Ctx_SendFile
 func Test_Ctx_SendFile(t *testing.T) {
 	t.Parallel()
@@ -1352,6 +2433,109 @@ func Test_Ctx_SendFile(t *testing.T) {
 	app.ReleaseCtx(c)
 }
 
+// go test -run Test_Ctx_SendFile_Range
+func Test_Ctx_SendFile_Range(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	content, err := ioutil.ReadFile("./ctx.go")
+	utils.AssertEqual(t, nil, err)
+	fI, err := os.Stat("./ctx.go")
+	utils.AssertEqual(t, nil, err)
+	size := strconv.Itoa(len(content))
+
+	sendFile := func(header ...string) *Ctx {
+		c := app.AcquireCtx(&fasthttp.RequestCtx{})
+		for i := 0; i+1 < len(header); i += 2 {
+			c.Request().Header.Set(header[i], header[i+1])
+		}
+		utils.AssertEqual(t, nil, c.SendFile("ctx.go"))
+		return c
+	}
+
+	// single range
+	c := sendFile(HeaderRange, "bytes=0-9")
+	utils.AssertEqual(t, StatusPartialContent, c.Response().StatusCode())
+	utils.AssertEqual(t, "bytes 0-9/"+size, string(c.Response().Header.Peek(HeaderContentRange)))
+	utils.AssertEqual(t, "bytes", string(c.Response().Header.Peek(HeaderAcceptRanges)))
+	utils.AssertEqual(t, content[:10], c.Response().Body())
+	app.ReleaseCtx(c)
+
+	// suffix range
+	c = sendFile(HeaderRange, "bytes=-5")
+	utils.AssertEqual(t, StatusPartialContent, c.Response().StatusCode())
+	utils.AssertEqual(t, content[len(content)-5:], c.Response().Body())
+	app.ReleaseCtx(c)
+
+	// multiple ranges
+	c = sendFile(HeaderRange, "bytes=0-1, 4-5")
+	utils.AssertEqual(t, StatusPartialContent, c.Response().StatusCode())
+	utils.AssertEqual(t, true, strings.HasPrefix(string(c.Response().Header.ContentType()), MIMEMultipartByteranges+"; boundary="))
+	utils.AssertEqual(t, "", string(c.Response().Header.Peek(HeaderContentRange)))
+	body := string(c.Response().Body())
+	utils.AssertEqual(t, true, strings.Contains(body, "Content-Range: bytes 0-1/"+size+"\r\n\r\n"+string(content[:2])+"\r\n"))
+	utils.AssertEqual(t, true, strings.Contains(body, "Content-Range: bytes 4-5/"+size+"\r\n\r\n"+string(content[4:6])+"\r\n"))
+	utils.AssertEqual(t, true, strings.HasSuffix(body, "--\r\n"))
+	app.ReleaseCtx(c)
+
+	// overlapping and adjacent ranges are merged
+	c = sendFile(HeaderRange, "bytes=4-5, 0-1, 0-, 0-")
+	utils.AssertEqual(t, StatusPartialContent, c.Response().StatusCode())
+	utils.AssertEqual(t, "bytes 0-"+strconv.Itoa(len(content)-1)+"/"+size, string(c.Response().Header.Peek(HeaderContentRange)))
+	utils.AssertEqual(t, content, c.Response().Body())
+	app.ReleaseCtx(c)
+	c = sendFile(HeaderRange, "bytes=6-9, 0-3, 2-5, 20-29")
+	utils.AssertEqual(t, StatusPartialContent, c.Response().StatusCode())
+	body = string(c.Response().Body())
+	utils.AssertEqual(t, 2, strings.Count(body, "Content-Range: "))
+	utils.AssertEqual(t, true, strings.Contains(body, "Content-Range: bytes 0-9/"+size+"\r\n\r\n"+string(content[:10])+"\r\n"))
+	utils.AssertEqual(t, true, strings.Contains(body, "Content-Range: bytes 20-29/"+size+"\r\n\r\n"+string(content[20:30])+"\r\n"))
+	app.ReleaseCtx(c)
+
+	// too many ranges are answered with the whole file
+	many := make([]string, maxByteRanges+1)
+	for i := range many {
+		many[i] = strconv.Itoa(i*2) + "-" + strconv.Itoa(i*2)
+	}
+	c = sendFile(HeaderRange, "bytes="+strings.Join(many, ","))
+	utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+	utils.AssertEqual(t, content, c.Response().Body())
+	app.ReleaseCtx(c)
+
+	// unsatisfiable range
+	c = sendFile(HeaderRange, "bytes="+size+"-")
+	utils.AssertEqual(t, StatusRequestedRangeNotSatisfiable, c.Response().StatusCode())
+	utils.AssertEqual(t, "bytes */"+size, string(c.Response().Header.Peek(HeaderContentRange)))
+	app.ReleaseCtx(c)
+
+	// If-Range with the Last-Modified date
+	c = sendFile(HeaderRange, "bytes=0-9", HeaderIfRange, fI.ModTime().UTC().Format(http.TimeFormat))
+	utils.AssertEqual(t, StatusPartialContent, c.Response().StatusCode())
+	app.ReleaseCtx(c)
+	c = sendFile(HeaderRange, "bytes=0-9", HeaderIfRange, fI.ModTime().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+	utils.AssertEqual(t, content, c.Response().Body())
+	app.ReleaseCtx(c)
+
+	// If-Range with an ETag
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	c.Request().Header.Set(HeaderRange, "bytes=0-9")
+	c.Request().Header.Set(HeaderIfRange, `"abc"`)
+	c.Set(HeaderETag, `"abc"`)
+	utils.AssertEqual(t, nil, c.SendFile("ctx.go"))
+	utils.AssertEqual(t, StatusPartialContent, c.Response().StatusCode())
+	app.ReleaseCtx(c)
+	c = sendFile(HeaderRange, "bytes=0-9", HeaderIfRange, `W/"abc"`)
+	utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+	app.ReleaseCtx(c)
+
+	// invalid ranges are ignored
+	c = sendFile(HeaderRange, "items=0-9")
+	utils.AssertEqual(t, StatusOK, c.Response().StatusCode())
+	utils.AssertEqual(t, content, c.Response().Body())
+	app.ReleaseCtx(c)
+}
+
 // go test -race -run Test_Ctx_SendFile_404
 func Test_Ctx_SendFile_404(t *testing.T) {
 	t.Parallel()
@@ -1405,10 +2589,15 @@ func Test_Ctx_JSON(t *testing.T) {
 	utils.AssertEqual(t, `{"Age":20,"Name":"Grame"}`, string(c.Response().Body()))
 	utils.AssertEqual(t, "application/json", string(c.Response().Header.Peek("content-type")))
 
+	utils.AssertEqual(t, nil, c.JSON(Map{"title": "Not Found"}, "application/problem+json"))
+	utils.AssertEqual(t, `{"title":"Not Found"}`, string(c.Response().Body()))
+	utils.AssertEqual(t, "application/problem+json", string(c.Response().Header.Peek("content-type")))
+
 	testEmpty := func(v interface{}, r string) {
 		err := c.JSON(v)
 		utils.AssertEqual(t, nil, err)
 		utils.AssertEqual(t, r, string(c.Response().Body()))
+		utils.AssertEqual(t, "application/json", string(c.Response().Header.Peek("content-type")))
 	}
 
 	testEmpty(nil, "null")
@@ -1417,6 +2606,33 @@ func Test_Ctx_JSON(t *testing.T) {
 	testEmpty([]int{}, "[]")
 }
 
+// go test -run Test_Ctx_JSON_CustomEncoder
+func Test_Ctx_JSON_CustomEncoder(t *testing.T) {
+	t.Parallel()
+	var decoded []byte
+	app := New(Config{
+		JSONEncoder: func(v interface{}) ([]byte, error) {
+			return []byte(`{"custom":true}`), nil
+		},
+		JSONDecoder: func(data []byte, v interface{}) error {
+			decoded = data
+			return nil
+		},
+	})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	utils.AssertEqual(t, nil, c.JSON(Map{"Name": "Grame"}))
+	utils.AssertEqual(t, `{"custom":true}`, string(c.Response().Body()))
+	utils.AssertEqual(t, nil, c.JSONP(Map{"Name": "Grame"}))
+	utils.AssertEqual(t, `callback({"custom":true});`, string(c.Response().Body()))
+
+	c.Request().Header.SetContentType(MIMEApplicationJSON)
+	c.Request().SetBody([]byte(`{"name":"john"}`))
+	utils.AssertEqual(t, nil, c.BodyParser(&Map{}))
+	utils.AssertEqual(t, `{"name":"john"}`, string(decoded))
+}
+
 // go test -run=^$ -bench=Benchmark_Ctx_JSON -benchmem -count=4
 func Benchmark_Ctx_JSON(b *testing.B) {
 	app := New()
@@ -1440,6 +2656,36 @@ func Benchmark_Ctx_JSON(b *testing.B) {
 	utils.AssertEqual(b, `{"Name":"Grame","Age":20}`, string(c.Response().Body()))
 }
 
+// go test -run=^$ -bench=Benchmark_Ctx_JSON_CustomEncoder -benchmem -count=4
+func Benchmark_Ctx_JSON_CustomEncoder(b *testing.B) {
+	encoded := 0
+	app := New(Config{
+		JSONEncoder: func(v interface{}) ([]byte, error) {
+			encoded++
+			return json.Marshal(v)
+		},
+	})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	type SomeStruct struct {
+		Name string
+		Age  uint8
+	}
+	data := SomeStruct{
+		Name: "Grame",
+		Age:  20,
+	}
+	var err error
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		err = c.JSON(data)
+	}
+	utils.AssertEqual(b, nil, err)
+	utils.AssertEqual(b, b.N, encoded)
+	utils.AssertEqual(b, `{"Name":"Grame","Age":20}`, string(c.Response().Body()))
+}
+
 // go test -run Test_Ctx_JSONP
 func Test_Ctx_JSONP(t *testing.T) {
 	t.Parallel()
@@ -1462,6 +2708,17 @@ func Test_Ctx_JSONP(t *testing.T) {
 	}, "john")
 	utils.AssertEqual(t, `john({"Age":20,"Name":"Grame"});`, string(c.Response().Body()))
 	utils.AssertEqual(t, "application/javascript; charset=utf-8", string(c.Response().Header.Peek("content-type")))
+
+	utils.AssertEqual(t, nil, c.JSONP(Map{}, "$.jQuery_123"))
+	utils.AssertEqual(t, `$.jQuery_123({});`, string(c.Response().Body()))
+
+	// invalid callback names are rejected
+	for _, cb := range []string{"alert(1)//", "", "a b", "cb;alert", "<script>"} {
+		c.Response().ResetBody()
+		err := c.JSONP(Map{}, cb)
+		utils.AssertEqual(t, StatusBadRequest, err.(*Error).Code, cb)
+		utils.AssertEqual(t, "", string(c.Response().Body()), cb)
+	}
 }
 
 // go test -v  -run=^$ -bench=Benchmark_Ctx_JSONP -benchmem -count=4
@@ -1576,6 +2833,81 @@ func Test_Ctx_Redirect(t *testing.T) {
 	utils.AssertEqual(t, "http://example.com", string(c.Response().Header.Peek(HeaderLocation)))
 }
 
+// go test -run Test_Ctx_RedirectToRoute
+func Test_Ctx_RedirectToRoute(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/users/:id", testEmptyHandler).Name("user.show")
+	app.Get("/files/*", testEmptyHandler).Name("files")
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	err := c.RedirectToRoute("user.show", Map{"id": 42})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 302, c.Response().StatusCode())
+	utils.AssertEqual(t, "/users/42", string(c.Response().Header.Peek(HeaderLocation)))
+
+	err = c.RedirectToRoute("user.show", Map{
+		"id":      "john doe",
+		"queries": map[string]string{"tab": "posts", "q": "a&b"},
+	}, StatusSeeOther)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, StatusSeeOther, c.Response().StatusCode())
+	utils.AssertEqual(t, "/users/john%20doe?q=a%26b&tab=posts", string(c.Response().Header.Peek(HeaderLocation)))
+
+	err = c.RedirectToRoute("files", Map{
+		"*":       "docs/intro.md",
+		"queries": url.Values{"v": []string{"1", "2"}},
+	})
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/files/docs/intro.md?v=1&v=2", string(c.Response().Header.Peek(HeaderLocation)))
+
+	c.Response().Header.Del(HeaderLocation)
+	err = c.RedirectToRoute("missing", nil)
+	utils.AssertEqual(t, `route: no route named "missing"`, err.Error())
+	utils.AssertEqual(t, "", string(c.Response().Header.Peek(HeaderLocation)))
+
+	err = c.RedirectToRoute("user.show", Map{"id": 1, "queries": "tab=posts"})
+	utils.AssertEqual(t, "route: queries must be map[string]string or url.Values, got string", err.Error())
+}
+
+// go test -run Test_Ctx_RedirectBack
+func Test_Ctx_RedirectBack(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	for referer, expected := range map[string]string{
+		"":                             "/home",
+		"http://example.com/posts?p=2": "http://example.com/posts?p=2",
+		"HTTP://Example.com/posts":     "HTTP://Example.com/posts",
+		"https://example.com/posts":    "/home",
+		"http://example.com:8080/":     "/home",
+		"http://evil.com/posts":        "/home",
+		"//evil.com/posts":             "/home",
+		"/\\evil.com/posts":            "/home",
+		"/posts":                       "/posts",
+		"/":                            "/",
+		"posts":                        "/home",
+		"javascript:alert(1)":          "/home",
+	} {
+		fctx := &fasthttp.RequestCtx{}
+		fctx.Request.SetRequestURI("http://example.com/posts/new")
+		if referer != "" {
+			fctx.Request.Header.Set(HeaderReferer, referer)
+		}
+		c := app.AcquireCtx(fctx)
+		utils.AssertEqual(t, nil, c.RedirectBack("/home"))
+		utils.AssertEqual(t, 302, c.Response().StatusCode())
+		utils.AssertEqual(t, expected, string(c.Response().Header.Peek(HeaderLocation)), referer)
+		app.ReleaseCtx(c)
+	}
+
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, nil, c.RedirectBack("/home", StatusSeeOther))
+	utils.AssertEqual(t, StatusSeeOther, c.Response().StatusCode())
+}
+
 // go test -run Test_Ctx_Render
 func Test_Ctx_Render(t *testing.T) {
 	t.Parallel()
@@ -1595,6 +2927,7 @@ func Test_Ctx_Render(t *testing.T) {
 
 	err = c.Render("./.github/testdata/template-non-exists.html", nil)
 	utils.AssertEqual(t, false, err == nil)
+	utils.AssertEqual(t, true, strings.Contains(err.Error(), `"./.github/testdata/template-non-exists.html"`))
 
 	err = c.Render("./.github/testdata/template-invalid.html", nil)
 	utils.AssertEqual(t, false, err == nil)
@@ -1665,6 +2998,53 @@ func Test_Ctx_Render_Engine_Error(t *testing.T) {
 	defer app.ReleaseCtx(c)
 	err := c.Render("index.tmpl", nil)
 	utils.AssertEqual(t, false, err == nil)
+	utils.AssertEqual(t, `render: template "index.tmpl": errorTemplateEngine`, err.Error())
+}
+
+type layoutTemplateEngine struct {
+	layouts []string
+}
+
+func (t *layoutTemplateEngine) Render(w io.Writer, name string, bind interface{}, layout ...string) error {
+	t.layouts = layout
+	return nil
+}
+
+func (t *layoutTemplateEngine) Load() error { return nil }
+
+// go test -run Test_Ctx_Render_Engine_Layouts
+func Test_Ctx_Render_Engine_Layouts(t *testing.T) {
+	t.Parallel()
+	engine := &layoutTemplateEngine{}
+	app := New(Config{Views: engine})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, nil, c.Render("index", nil, "layouts/main", "layouts/base"))
+	utils.AssertEqual(t, []string{"layouts/main", "layouts/base"}, engine.layouts)
+}
+
+// go test -run Test_Ctx_Render_Locals
+func Test_Ctx_Render_Locals(t *testing.T) {
+	t.Parallel()
+	engine := &testTemplateEngine{}
+	utils.AssertEqual(t, nil, engine.Load())
+	app := New(Config{Views: engine, PassLocalsToViews: true})
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	c.Locals("Title", "From locals")
+	utils.AssertEqual(t, nil, c.Render("index.tmpl", nil))
+	utils.AssertEqual(t, "<h1>From locals</h1>", string(c.Response().Body()))
+
+	utils.AssertEqual(t, nil, c.Render("index.tmpl", Map{"Title": "From handler"}))
+	utils.AssertEqual(t, "<h1>From handler</h1>", string(c.Response().Body()))
+
+	utils.AssertEqual(t, nil, c.Render("index.tmpl", struct{ Title string }{"From struct"}))
+	utils.AssertEqual(t, "<h1>From struct</h1>", string(c.Response().Body()))
+
+	app.config.PassLocalsToViews = false
+	utils.AssertEqual(t, nil, c.Render("index.tmpl", Map{}))
+	utils.AssertEqual(t, "<h1><no value></h1>", string(c.Response().Body()))
 }
 
 // go test -run Test_Ctx_Render_Go_Template
@@ -1757,7 +3137,155 @@ func Test_Ctx_SendStream(t *testing.T) {
 	file, err := os.Open("./.github/index.html")
 	utils.AssertEqual(t, nil, err)
 	c.SendStream(bufio.NewReader(file))
-	utils.AssertEqual(t, true, (c.Response().Header.ContentLength() > 200))
+	utils.AssertEqual(t, true, len(c.Response().Body()) > 200)
+	utils.AssertEqual(t, -1, c.Response().Header.ContentLength())
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = 0
+	}
+	return len(b), nil
+}
+
+// discardConn discards the response and fails after limit bytes, if set
+type discardConn struct {
+	testConn
+	written  int64
+	limit    int64
+	maxWrite int
+}
+
+func (c *discardConn) Write(b []byte) (int, error) {
+	if c.limit > 0 && c.written+int64(len(b)) > c.limit {
+		return 0, errors.New("connection reset by peer")
+	}
+	c.written += int64(len(b))
+	if len(b) > c.maxWrite {
+		c.maxWrite = len(b)
+	}
+	return len(b), nil
+}
+
+// go test -run Test_Ctx_SendStream_Chunked
+func Test_Ctx_SendStream_Chunked(t *testing.T) {
+	const size = 100 * 1024 * 1024
+	app := New(Config{StreamBufferSize: 64 * 1024})
+
+	result := make(chan int64, 1)
+	app.Get("/", func(c *Ctx) error {
+		return c.SendStreamDone(io.LimitReader(zeroReader{}, size), func(written int64, err error) {
+			utils.AssertEqual(t, nil, err)
+			result <- written
+		})
+	})
+
+	conn := new(discardConn)
+	_, err := conn.r.WriteString("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	utils.AssertEqual(t, nil, err)
+
+	utils.AssertEqual(t, nil, app.server.ServeConn(conn))
+
+	utils.AssertEqual(t, int64(size), <-result)
+	utils.AssertEqual(t, true, conn.written > size)
+	// the stream isn't buffered, it reaches the conn in chunks of the
+	// StreamBufferSize plus the chunk framing at most
+	utils.AssertEqual(t, true, conn.maxWrite <= 64*1024+32, fmt.Sprintf("largest write %d", conn.maxWrite))
+}
+
+// go test -run Test_Ctx_SendStream_Disconnect
+func Test_Ctx_SendStream_Disconnect(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	type streamResult struct {
+		written int64
+		err     error
+	}
+	result := make(chan streamResult, 1)
+	app.Get("/", func(c *Ctx) error {
+		return c.SendStreamDone(zeroReader{}, func(written int64, err error) {
+			result <- streamResult{written, err}
+		})
+	})
+
+	conn := &discardConn{limit: 1024 * 1024}
+	_, err := conn.r.WriteString("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, false, app.server.ServeConn(conn) == nil)
+
+	select {
+	case res := <-result:
+		utils.AssertEqual(t, false, res.err == nil)
+		utils.AssertEqual(t, true, res.written > 0)
+		utils.AssertEqual(t, true, res.written <= 2*1024*1024)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream copy didn't stop after the client disconnected")
+	}
+}
+
+// go test -run Test_Ctx_SendEarlyHints
+func Test_Ctx_SendEarlyHints(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		utils.AssertEqual(t, nil, c.SendEarlyHints([]string{"</style.css>; rel=preload; as=style", "</app.js>; rel=preload; as=script"}))
+		return c.SendString("done")
+	})
+	app.Get("/stream", func(c *Ctx) error {
+		utils.AssertEqual(t, nil, c.SendStream(bytes.NewReader([]byte("done")), 4))
+		utils.AssertEqual(t, "earlyhints: response body already started", c.SendEarlyHints([]string{"</style.css>; rel=preload"}).Error())
+		return nil
+	})
+
+	// the interim response precedes the final one
+	conn := new(testConn)
+	_, err := conn.r.WriteString("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, app.server.ServeConn(conn))
+	raw := conn.w.String()
+	utils.AssertEqual(t, true, strings.HasPrefix(raw, "HTTP/1.1 103 Early Hints\r\n"+
+		"Link: </style.css>; rel=preload; as=style\r\n"+
+		"Link: </app.js>; rel=preload; as=script\r\n\r\n"+
+		"HTTP/1.1 200 OK\r\n"), raw)
+	utils.AssertEqual(t, true, strings.HasSuffix(raw, "\r\n\r\ndone"), raw)
+
+	// pipelined requests are refused, the first response may be buffered
+	var pipelinedErr error
+	app.Get("/pipelined", func(c *Ctx) error {
+		pipelinedErr = c.SendEarlyHints([]string{"</style.css>; rel=preload; as=style"})
+		return c.SendString("pipelined")
+	})
+	conn = new(testConn)
+	_, err = conn.r.WriteString("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n" +
+		"GET /pipelined HTTP/1.1\r\nHost: example.com\r\nConnection: close\r\n\r\n")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, app.server.ServeConn(conn))
+	raw = conn.w.String()
+	utils.AssertEqual(t, "earlyhints: previous response on the connection may be buffered", pipelinedErr.Error())
+	utils.AssertEqual(t, 1, strings.Count(raw, "103 Early Hints"), raw)
+	utils.AssertEqual(t, true, strings.HasSuffix(raw, "\r\n\r\npipelined"), raw)
+
+	// HTTP/1.0 clients only get the final response
+	conn = new(testConn)
+	_, err = conn.r.WriteString("GET / HTTP/1.0\r\nHost: example.com\r\n\r\n")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, app.server.ServeConn(conn))
+	utils.AssertEqual(t, true, strings.HasPrefix(conn.w.String(), "HTTP/1.1 200 OK\r\n"), conn.w.String())
+
+	// app.Test skips the interim response
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "done", string(body))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/stream", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
 }
 
 // go test -run Test_Ctx_Set
@@ -1863,6 +3391,31 @@ func Benchmark_Ctx_Type_Charset(b *testing.B) {
 	}
 }
 
+// go test -run Test_Ctx_UserContext
+func Test_Ctx_UserContext(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+
+	t.Run("Nil_Context", func(t *testing.T) {
+		ctx := c.UserContext()
+		utils.AssertEqual(t, ctx, context.Background())
+	})
+	t.Run("ValueContext", func(t *testing.T) {
+		testKey := struct{}{}
+		testValue := "Test Value"
+		ctx := context.WithValue(context.Background(), testKey, testValue)
+		c.SetUserContext(ctx)
+		utils.AssertEqual(t, testValue, c.UserContext().Value(testKey))
+	})
+
+	// the user context is reset when the ctx is released
+	app.ReleaseCtx(c)
+	c = app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	utils.AssertEqual(t, context.Background(), c.UserContext())
+}
+
 // go test -run Test_Ctx_Vary
 func Test_Ctx_Vary(t *testing.T) {
 	t.Parallel()
@@ -2016,10 +3569,79 @@ func Test_Ctx_QueryParser(t *testing.T) {
 
 func Test_Ctx_EqualFieldType(t *testing.T) {
 	var out int
-	utils.AssertEqual(t, false, equalFieldType(&out, reflect.Int, "key"))
+	utils.AssertEqual(t, false, equalFieldType(&out, reflect.Int, "key", "query"))
 
 	var dummy struct{ f string }
-	utils.AssertEqual(t, false, equalFieldType(&dummy, reflect.String, "key"))
+	utils.AssertEqual(t, false, equalFieldType(&dummy, reflect.String, "key", "query"))
+
+	var tagged struct {
+		Hobbies []string `cookie:"hobby,required"`
+	}
+	utils.AssertEqual(t, true, equalFieldType(&tagged, reflect.Slice, "hobby", "cookie"))
+	utils.AssertEqual(t, false, equalFieldType(&tagged, reflect.Slice, "hobby", "query"))
+}
+
+// go test -run Test_Ctx_CookieParser -v
+func Test_Ctx_CookieParser(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	type Prefs struct {
+		Theme    string   `cookie:"theme"`
+		FontSize int      `cookie:"font_size"`
+		Compact  bool     `cookie:"compact"`
+		Zoom     float64  `cookie:"zoom"`
+		Langs    []string `cookie:"langs"`
+		Ignored  string   `cookie:"-"`
+	}
+	c.Request().Header.Set(HeaderCookie, "theme=dark; font_size=14; compact=true; zoom=1.5; langs=en,de; Ignored=x")
+	prefs := new(Prefs)
+	utils.AssertEqual(t, nil, c.CookieParser(prefs))
+	utils.AssertEqual(t, "dark", prefs.Theme)
+	utils.AssertEqual(t, 14, prefs.FontSize)
+	utils.AssertEqual(t, true, prefs.Compact)
+	utils.AssertEqual(t, 1.5, prefs.Zoom)
+	utils.AssertEqual(t, []string{"en", "de"}, prefs.Langs)
+	utils.AssertEqual(t, "", prefs.Ignored)
+
+	c.Request().Header.Set(HeaderCookie, "font_size=big")
+	utils.AssertEqual(t, true, c.CookieParser(new(Prefs)) != nil)
+
+	type Session struct {
+		ID    string `cookie:"session_id,required"`
+		User  string `cookie:"user,required"`
+		Theme string `cookie:"theme"`
+	}
+	c.Request().Header.Set(HeaderCookie, "theme=dark")
+	err := c.CookieParser(new(Session))
+	utils.AssertEqual(t, "cookie: missing required cookies: session_id, user", err.Error())
+
+	c.Request().Header.Set(HeaderCookie, "session_id=abc; user=john")
+	sess := new(Session)
+	utils.AssertEqual(t, nil, c.CookieParser(sess))
+	utils.AssertEqual(t, "abc", sess.ID)
+	utils.AssertEqual(t, "john", sess.User)
+}
+
+// go test -v  -run=^$ -bench=Benchmark_Ctx_CookieParser -benchmem -count=4
+func Benchmark_Ctx_CookieParser(b *testing.B) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	type Prefs struct {
+		Theme    string   `cookie:"theme"`
+		FontSize int      `cookie:"font_size"`
+		Langs    []string `cookie:"langs"`
+	}
+	c.Request().Header.Set(HeaderCookie, "theme=dark; font_size=14; langs=en,de")
+	prefs := new(Prefs)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		_ = c.CookieParser(prefs)
+	}
+	utils.AssertEqual(b, nil, c.CookieParser(prefs))
 }
 
 // go test -v  -run=^$ -bench=Benchmark_Ctx_QueryParser -benchmem -count=4
@@ -3,7 +3,6 @@ module github.com/gofiber/fiber/v2
 go 1.14
 
 require (
-	github.com/klauspost/compress v1.11.0 // indirect
-	github.com/valyala/fasthttp v1.17.0
-	golang.org/x/sys v0.0.0-20201101102859-da207088b7d1
+	github.com/valyala/fasthttp v1.26.0
+	golang.org/x/sys v0.0.0-20210514084401-e8d321eab015
 )
@@ -19,13 +19,25 @@ type Group struct {
 // It's very useful to split up a large API as many independent routers and
 // compose them as a single service using Mount.
 func (grp *Group) Mount(prefix string, fiber *App) Router {
-	stack := fiber.Stack()
-	for m := range stack {
-		for r := range stack[m] {
-			route := grp.app.copyRoute(stack[m][r])
-			grp.app.addRoute(route.Method, grp.app.addPrefixToRoute(getGroupPath(grp.prefix, prefix), route))
-		}
-	}
+	grp.app.mount(getGroupPath(grp.prefix, prefix), fiber)
+	return grp
+}
+
+// Name assigns a name to the latest registered route
+func (grp *Group) Name(name string) Router {
+	grp.app.Name(name)
+	return grp
+}
+
+// UseErrorHandler sets the error handler for the routes below the group prefix,
+// including the ones registered before. The error handler of the nearest group
+// is used, falling back to the one of the app.
+//  api := app.Group("/api")
+//  api.UseErrorHandler(func(c *fiber.Ctx, err error) error {
+//  	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+//  })
+func (grp *Group) UseErrorHandler(handler ErrorHandler) Router {
+	grp.app.addErrorHandler(grp.prefix, handler)
 	return grp
 }
 
@@ -62,9 +74,10 @@ func (grp *Group) Use(args ...interface{}) Router {
 
 // Get registers a route for GET methods that requests a representation
 // of the specified resource. Requests using GET should only retrieve data.
+// The route answers HEAD requests too, unless Config.DisableHeadAutoRegister is set
+// or a HEAD route is registered for the path.
 func (grp *Group) Get(path string, handlers ...Handler) Router {
-	path = getGroupPath(grp.prefix, path)
-	return grp.app.Add(MethodHead, path, handlers...).Add(MethodGet, path, handlers...)
+	return grp.Add(MethodGet, path, handlers...)
 }
 
 // Head registers a route for HEAD methods that asks for a response identical
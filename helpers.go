@@ -5,25 +5,34 @@
 package fiber
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"hash/crc32"
 	"io"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 	"unsafe"
 
 	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
 	"github.com/gofiber/fiber/v2/utils"
-	"github.com/valyala/fasthttp"
 )
 
 /* #nosec */
+// isTLSListener reports whether the listener was created by tls.NewListener
+func isTLSListener(ln net.Listener) bool {
+	return reflect.ValueOf(ln).String() == "<*tls.listener Value>"
+}
+
 // lnMetadata will close the listener and return the addr and tls config
 func lnMetadata(ln net.Listener) (addr string, cfg *tls.Config) {
 	// Get addr
@@ -53,7 +62,7 @@ func lnMetadata(ln net.Listener) (addr string, cfg *tls.Config) {
 	pointer := reflect.ValueOf(ln)
 
 	// Is it a tls.listener?
-	if pointer.String() == "<*tls.listener Value>" {
+	if isTLSListener(ln) {
 		// Copy value from pointer
 		if val := reflect.Indirect(pointer); val.Type() != nil {
 			// Get private field from value
@@ -85,13 +94,80 @@ func readContent(rf io.ReaderFrom, name string) (n int64, err error) {
 	return rf.ReadFrom(f)
 }
 
-// quoteString escape special characters in a given string
-func quoteString(raw string) string {
+// copyStream copies src to w using buf and flushes w after every chunk,
+// a failed flush means the client is gone and stops the copy
+func copyStream(w *bufio.Writer, src io.Reader, buf []byte) (written int64, err error) {
+	for {
+		n, rerr := src.Read(buf)
+		if n > 0 {
+			if _, err = w.Write(buf[:n]); err == nil {
+				err = w.Flush()
+			}
+			if err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// contentDisposition returns the Content-Disposition attachment value for
+// the filename per RFC 6266. CR and LF are stripped, names which aren't
+// plain ASCII also get a RFC 5987 encoded filename* parameter.
+func contentDisposition(filename string) string {
 	bb := bytebufferpool.Get()
-	// quoted := string(fasthttp.AppendQuotedArg(bb.B, getBytes(raw)))
-	quoted := getString(fasthttp.AppendQuotedArg(bb.B, getBytes(raw)))
-	bytebufferpool.Put(bb)
-	return quoted
+	defer bytebufferpool.Put(bb)
+
+	_, _ = bb.WriteString(`attachment; filename="`)
+	plain := true
+	for _, r := range filename {
+		switch {
+		case r == '\r' || r == '\n':
+			plain = false
+		case r == '"' || r == '\\':
+			plain = false
+			_ = bb.WriteByte('\\')
+			_ = bb.WriteByte(byte(r))
+		case r < 0x20 || r == 0x7f:
+			plain = false
+		case r > 0x7e:
+			plain = false
+			_ = bb.WriteByte('_')
+		default:
+			_ = bb.WriteByte(byte(r))
+		}
+	}
+	_ = bb.WriteByte('"')
+	if !plain {
+		_, _ = bb.WriteString("; filename*=UTF-8''")
+		for i := 0; i < len(filename); i++ {
+			if ch := filename[i]; isAttrChar(ch) {
+				_ = bb.WriteByte(ch)
+			} else if ch != '\r' && ch != '\n' {
+				_ = bb.WriteByte('%')
+				_ = bb.WriteByte(upperHex[ch>>4])
+				_ = bb.WriteByte(upperHex[ch&0xf])
+			}
+		}
+	}
+	return bb.String()
+}
+
+const upperHex = "0123456789ABCDEF"
+
+// isAttrChar reports whether ch may appear unencoded in a RFC 5987 value
+func isAttrChar(ch byte) bool {
+	switch {
+	case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", ch) >= 0
 }
 
 // removeNewLines will replace `\r` and `\n` with an empty space
@@ -117,47 +193,48 @@ func removeNewLines(raw string) string {
 	return raw
 }
 
-// Scan stack if other methods match the request
+// Scan stack if other methods match the request and list them in the Allow
+// header, HEAD is allowed with GET unless Config.DisableHeadAutoRegister is set
 func methodExist(ctx *Ctx) (exist bool) {
-	for i := 0; i < len(intMethod); i++ {
+	allow := bytebufferpool.Get()
+	defer bytebufferpool.Put(allow)
+	for i := range intMethod {
 		// Skip original method
-		if ctx.methodINT == i {
+		if ctx.methodINT == i || !methodMatches(ctx, i) {
 			continue
 		}
-		// Reset stack index
-		ctx.indexRoute = -1
-		tree, ok := ctx.app.treeStack[i][ctx.treePath]
-		if !ok {
-			tree = ctx.app.treeStack[i][""]
-		}
-		// Get stack length
-		lenr := len(tree) - 1
-		//Loop over the route stack starting from previous index
-		for ctx.indexRoute < lenr {
-			// Increment route index
-			ctx.indexRoute++
-			// Get *Route
-			route := tree[ctx.indexRoute]
-			// Skip use routes
-			if route.use {
-				continue
-			}
-			// Check if it matches the request path
-			match := route.match(ctx.path, ctx.pathOriginal, &ctx.values)
-			// No match, next route
-			if match {
-				// We matched
-				exist = true
-				// Add method to Allow header
-				ctx.Append(HeaderAllow, intMethod[i])
-				// Break stack loop
-				break
-			}
+		exist = true
+		if allow.Len() > 0 {
+			_, _ = allow.WriteString(", ")
 		}
+		_, _ = allow.WriteString(intMethod[i])
+		// GET requests can be answered for HEAD too
+		if intMethod[i] == MethodGet && !ctx.app.config.DisableHeadAutoRegister &&
+			ctx.method != MethodHead && !methodMatches(ctx, methodInt(MethodHead)) {
+			_, _ = allow.WriteString(", " + MethodHead)
+		}
+	}
+	if exist {
+		ctx.Set(HeaderAllow, getString(allow.Bytes()))
 	}
 	return
 }
 
+// methodMatches reports whether a non use route of the method matches the request path
+func methodMatches(ctx *Ctx, method int) bool {
+	tree, ok := ctx.app.treeStack[method][ctx.treePath]
+	if !ok {
+		tree = ctx.app.treeStack[method][""]
+	}
+	for _, route := range tree {
+		// Skip use routes
+		if !route.use && route.match(ctx.path, ctx.pathOriginal, &ctx.values) {
+			return true
+		}
+	}
+	return false
+}
+
 // uniqueRouteStack drop all not unique routes from the slice
 func uniqueRouteStack(stack []*Route) []*Route {
 	var unique []*Route
@@ -174,6 +251,22 @@ func uniqueRouteStack(stack []*Route) []*Route {
 	return unique
 }
 
+// validCallback checks if the JSONP callback name only contains
+// the characters A-Z, a-z, 0-9, '_', '.' and '$'
+func validCallback(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		switch c := name[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '_', c == '.', c == '$':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // defaultString returns the value or a default value if it is set
 func defaultString(value string, defaultValue []string) string {
 	if len(value) == 0 && len(defaultValue) > 0 {
@@ -237,40 +330,188 @@ func getGroupPath(prefix, path string) string {
 	return utils.TrimRight(prefix, '/') + path
 }
 
-// return valid offer for header negotiation
-func getOffer(header string, offers ...string) string {
+// parseMediaType returns the lowercase media type of a Content-Type header
+// value without its parameters, e.g. "application/json; charset=utf-8"
+// becomes "application/json"
+func parseMediaType(ctype string) string {
+	if i := strings.IndexByte(ctype, ';'); i >= 0 {
+		ctype = ctype[:i]
+	}
+	ctype = utils.Trim(ctype, ' ')
+	for i := 0; i < len(ctype); i++ {
+		if ctype[i] >= 'A' && ctype[i] <= 'Z' {
+			return utils.ToLower(ctype)
+		}
+	}
+	return ctype
+}
+
+// getOffer returns the offer with the highest quality value in the header,
+// ties are resolved by the specificity of the matching range and then by the
+// order of the offers. isAccepted returns the specificity of a range for an
+// offer, or -1 if it doesn't match. Ranges with q=0 are not acceptable.
+func getOffer(header string, isAccepted func(spec, params, offer string) int, offers ...string) string {
 	if len(offers) == 0 {
 		return ""
 	} else if header == "" {
 		return offers[0]
 	}
 
-	spec, commaPos := "", 0
-	for len(header) > 0 && commaPos != -1 {
-		commaPos = strings.IndexByte(header, ',')
-		if commaPos != -1 {
-			spec = utils.Trim(header[:commaPos], ' ')
-		} else {
-			spec = header
+	best, bestQ, bestSpecificity := "", 0.0, -1
+	for _, offer := range offers {
+		if len(offer) == 0 {
+			continue
 		}
-		if factorSign := strings.IndexByte(spec, ';'); factorSign != -1 {
-			spec = spec[:factorSign]
+		// The most specific range that matches the offer decides its quality
+		q, specificity := 0.0, -1
+		forEachAccept(header, func(spec, params string, specQ float64) {
+			if s := isAccepted(spec, params, offer); s > specificity {
+				q, specificity = specQ, s
+			}
+		})
+		if q > bestQ || (q == bestQ && q > 0 && specificity > bestSpecificity) {
+			best, bestQ, bestSpecificity = offer, q, specificity
 		}
+	}
 
-		for _, offer := range offers {
-			// has star prefix
-			if len(spec) >= 1 && spec[len(spec)-1] == '*' {
-				return offer
-			} else if strings.HasPrefix(spec, offer) {
-				return offer
+	return best
+}
+
+// acceptsOffer is the matcher of Accept-Charset and Accept-Encoding,
+// * matches every offer and any other range only the same offer
+func acceptsOffer(spec, _, offer string) int {
+	if spec == "*" {
+		return 0
+	} else if strings.EqualFold(spec, offer) {
+		return 2
+	}
+	return -1
+}
+
+// acceptsLanguageOffer is the matcher of Accept-Language, a range also
+// matches the offers it is a prefix of, e.g. en matches en-US, see RFC 4647
+func acceptsLanguageOffer(spec, _, offer string) int {
+	if spec == "*" {
+		return 0
+	} else if strings.EqualFold(spec, offer) {
+		return 2
+	} else if len(offer) > len(spec) && offer[len(spec)] == '-' && strings.EqualFold(spec, offer[:len(spec)]) {
+		return 1
+	}
+	return -1
+}
+
+// acceptsOfferType is the matcher of Accept, offers can be media types or
+// file extensions
+func acceptsOfferType(spec, params, offer string) int {
+	if strings.IndexByte(offer, '/') == -1 {
+		offer = utils.GetMIME(offer) // extension
+	}
+	return mediaTypeSpecificity(spec, params, offer)
+}
+
+// forEachAccept calls fn for every range of an Accept header with its
+// parameters, excluding the quality value q which defaults to 1.
+// Ranges with an invalid quality value are skipped.
+func forEachAccept(header string, fn func(spec, params string, q float64)) {
+	for len(header) > 0 {
+		part := header
+		if commaPos := strings.IndexByte(header, ','); commaPos != -1 {
+			part, header = header[:commaPos], header[commaPos+1:]
+		} else {
+			header = ""
+		}
+		spec, params := part, ""
+		if factorSign := strings.IndexByte(part, ';'); factorSign != -1 {
+			spec, params = part[:factorSign], part[factorSign+1:]
+		}
+		spec = utils.Trim(spec, ' ')
+		if spec == "" {
+			continue
+		}
+		q, valid := 1.0, true
+		for start := 0; start < len(params); {
+			end := strings.IndexByte(params[start:], ';')
+			if end == -1 {
+				end = len(params)
+			} else {
+				end += start
 			}
+			param := utils.Trim(params[start:end], ' ')
+			if len(param) > 1 && (param[0] == 'q' || param[0] == 'Q') && param[1] == '=' {
+				var err error
+				if q, err = strconv.ParseFloat(param[2:], 64); err != nil || q < 0 || q > 1 {
+					valid = false
+				}
+				// Parameters after the quality value are extensions
+				params = params[:start]
+				break
+			}
+			start = end + 1
+		}
+		if valid {
+			fn(spec, params, q)
+		}
+	}
+}
+
+// mediaTypeSpecificity returns how specific the media range matches the offer,
+// -1 if it doesn't match, 0 for */*, 1 for type/* and 2 for an exact match.
+// Every parameter of the range has to be part of the offer and adds to the specificity.
+func mediaTypeSpecificity(spec, params, offer string) int {
+	offerParams := ""
+	if factorSign := strings.IndexByte(offer, ';'); factorSign != -1 {
+		offer, offerParams = utils.Trim(offer[:factorSign], ' '), offer[factorSign+1:]
+	}
+	specificity := -1
+	specSlash, offerSlash := strings.IndexByte(spec, '/'), strings.IndexByte(offer, '/')
+	switch {
+	case spec == "*/*" || offer == "*/*":
+		specificity = 0
+	case specSlash == -1 || offerSlash == -1:
+		return -1
+	case strings.EqualFold(spec, offer):
+		specificity = 2
+	case !strings.EqualFold(spec[:specSlash], offer[:offerSlash]):
+		return -1
+	case spec[specSlash:] == "/*" || offer[offerSlash:] == "/*":
+		specificity = 1
+	default:
+		return -1
+	}
+	// Every parameter of the range has to be part of the offer
+	for len(params) > 0 {
+		param := params
+		if pos := strings.IndexByte(params, ';'); pos != -1 {
+			param, params = params[:pos], params[pos+1:]
+		} else {
+			params = ""
 		}
-		if commaPos != -1 {
-			header = header[commaPos+1:]
+		if param = utils.Trim(param, ' '); param == "" {
+			continue
 		}
+		if !hasParam(offerParams, param) {
+			return -1
+		}
+		specificity++
 	}
+	return specificity
+}
 
-	return ""
+// hasParam checks if the parameters contain the given key=value pair
+func hasParam(params, param string) bool {
+	for len(params) > 0 {
+		p := params
+		if pos := strings.IndexByte(params, ';'); pos != -1 {
+			p, params = params[:pos], params[pos+1:]
+		} else {
+			params = ""
+		}
+		if strings.EqualFold(utils.Trim(p, ' '), param) {
+			return true
+		}
+	}
+	return false
 }
 
 func matchEtag(s string, etag string) bool {
@@ -281,6 +522,132 @@ func matchEtag(s string, etag string) bool {
 	return false
 }
 
+// forwardedIP returns the IP address of an entry of a forwarded header
+// without surrounding whitespace and port, ok is false for invalid addresses
+func forwardedIP(entry string) (ip string, ok bool) {
+	ip = strings.TrimSpace(entry)
+	if len(ip) > 0 && ip[0] == '[' {
+		// [IPv6]:port
+		end := strings.IndexByte(ip, ']')
+		if end == -1 || !validPort(ip[end+1:]) {
+			return "", false
+		}
+		ip = ip[1:end]
+		return ip, utils.IsIPv6(ip)
+	}
+	if colonPos := strings.IndexByte(ip, ':'); colonPos != -1 && strings.IndexByte(ip[colonPos+1:], ':') == -1 {
+		// IPv4:port
+		if !validPort(ip[colonPos:]) {
+			return "", false
+		}
+		ip = ip[:colonPos]
+		return ip, utils.IsIPv4(ip)
+	}
+	return ip, utils.IsIPv4(ip) || utils.IsIPv6(ip)
+}
+
+// validPort accepts an empty string or a colon followed by digits
+func validPort(port string) bool {
+	if len(port) == 0 {
+		return true
+	} else if len(port) == 1 || port[0] != ':' {
+		return false
+	}
+	for i := 1; i < len(port); i++ {
+		if port[i] < '0' || port[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ipRange is an inclusive range of IP addresses in their 16 byte form
+type ipRange struct {
+	start, end [net.IPv6len]byte
+}
+
+// parseTrustedProxies parses the IP addresses and CIDR ranges of the trusted
+// proxies into sorted ranges without overlaps, so they can be binary searched
+func parseTrustedProxies(proxies []string) []ipRange {
+	ranges := make([]ipRange, 0, len(proxies))
+	for _, proxy := range proxies {
+		var r ipRange
+		if strings.IndexByte(proxy, '/') != -1 {
+			_, network, err := net.ParseCIDR(proxy)
+			if err != nil {
+				panic(fmt.Sprintf("trusted proxies: invalid CIDR range %s\n", proxy))
+			}
+			mask := network.Mask
+			if len(mask) == net.IPv4len {
+				// The IPv4-in-IPv6 prefix is part of the network
+				mask = append(net.CIDRMask(96, 128)[:12], mask...)
+			}
+			copy(r.start[:], network.IP.To16())
+			for i := range r.end {
+				r.end[i] = r.start[i] | ^mask[i]
+			}
+		} else {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				panic(fmt.Sprintf("trusted proxies: invalid IP address %s\n", proxy))
+			}
+			copy(r.start[:], ip.To16())
+			r.end = r.start
+		}
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return bytes.Compare(ranges[i].start[:], ranges[j].start[:]) < 0
+	})
+	// Merge overlapping ranges
+	merged := ranges[:0]
+	for _, r := range ranges {
+		if last := len(merged) - 1; last >= 0 && bytes.Compare(r.start[:], merged[last].end[:]) <= 0 {
+			if bytes.Compare(r.end[:], merged[last].end[:]) > 0 {
+				merged[last].end = r.end
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// ipInRanges reports whether the IP address is part of one of the sorted ranges
+func ipInRanges(ranges []ipRange, ip net.IP) bool {
+	var key [net.IPv6len]byte
+	switch len(ip) {
+	case net.IPv4len:
+		key[10], key[11] = 0xff, 0xff
+		copy(key[12:], ip)
+	case net.IPv6len:
+		copy(key[:], ip)
+	default:
+		return false
+	}
+	// Find the last range that starts before or at the address
+	i := sort.Search(len(ranges), func(i int) bool {
+		return bytes.Compare(ranges[i].start[:], key[:]) > 0
+	})
+	return i > 0 && bytes.Compare(key[:], ranges[i-1].end[:]) <= 0
+}
+
+// ifRangeMatches reports whether the If-Range header matches the ETag or
+// Last-Modified date of the response, weak ETags never match
+func ifRangeMatches(ifRange, etag, lastModified string) bool {
+	if ifRange[0] == '"' {
+		return ifRange == etag
+	} else if strings.HasPrefix(ifRange, "W/") {
+		return false
+	}
+	date, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	return err == nil && date.Equal(modified)
+}
+
 func isEtagStale(etag string, noneMatchBytes []byte) bool {
 	var start, end int
 
@@ -354,8 +721,9 @@ func (a testAddr) String() string {
 }
 
 type testConn struct {
-	r bytes.Buffer
-	w bytes.Buffer
+	r   bytes.Buffer
+	w   bytes.Buffer
+	ctx context.Context // context of App.TestWithContext
 }
 
 func (c *testConn) Read(b []byte) (int, error)  { return c.r.Read(b) }
@@ -438,12 +806,14 @@ const (
 	MIMETextXML               = "text/xml"
 	MIMETextHTML              = "text/html"
 	MIMETextPlain             = "text/plain"
+	MIMETextEventStream       = "text/event-stream"
 	MIMEApplicationXML        = "application/xml"
 	MIMEApplicationJSON       = "application/json"
 	MIMEApplicationJavaScript = "application/javascript"
 	MIMEApplicationForm       = "application/x-www-form-urlencoded"
 	MIMEOctetStream           = "application/octet-stream"
 	MIMEMultipartForm         = "multipart/form-data"
+	MIMEMultipartByteranges   = "multipart/byteranges"
 
 	MIMETextXMLCharsetUTF8               = "text/xml; charset=utf-8"
 	MIMETextHTMLCharsetUTF8              = "text/html; charset=utf-8"
@@ -681,6 +1051,7 @@ const (
 	HeaderSignedHeaders                   = "Signed-Headers"
 	HeaderSourceMap                       = "SourceMap"
 	HeaderUpgrade                         = "Upgrade"
+	HeaderXAccelBuffering                 = "X-Accel-Buffering"
 	HeaderXDNSPrefetchControl             = "X-DNS-Prefetch-Control"
 	HeaderXPingback                       = "X-Pingback"
 	HeaderXRequestID                      = "X-Request-ID"
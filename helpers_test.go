@@ -265,9 +265,52 @@ func Test_Utils_Parse_Address(t *testing.T) {
 }
 
 func Test_Utils_GetOffset(t *testing.T) {
-	utils.AssertEqual(t, "", getOffer("hello"))
-	utils.AssertEqual(t, "1", getOffer("", "1"))
-	utils.AssertEqual(t, "", getOffer("2", "1"))
+	utils.AssertEqual(t, "", getOffer("hello", acceptsOffer))
+	utils.AssertEqual(t, "1", getOffer("", acceptsOffer, "1"))
+	utils.AssertEqual(t, "", getOffer("2", acceptsOffer, "1"))
+
+	utils.AssertEqual(t, "", getOffer("gzip;q=0", acceptsOffer, "gzip"))
+	utils.AssertEqual(t, "br", getOffer("gzip;q=0.5, br", acceptsOffer, "gzip", "br"))
+	utils.AssertEqual(t, "gzip", getOffer("gzip, br", acceptsOffer, "gzip", "br"))
+	utils.AssertEqual(t, "br", getOffer("*;q=0.5, br", acceptsOffer, "gzip", "br"))
+	utils.AssertEqual(t, "", getOffer("*, gzip;q=0", acceptsOffer, "gzip"))
+	utils.AssertEqual(t, "GZIP", getOffer("gzip", acceptsOffer, "GZIP"))
+
+	utils.AssertEqual(t, "en-US", getOffer("en", acceptsLanguageOffer, "en-US"))
+	utils.AssertEqual(t, "", getOffer("en-US", acceptsLanguageOffer, "en"))
+	utils.AssertEqual(t, "", getOffer("en", acceptsLanguageOffer, "eng"))
+	utils.AssertEqual(t, "en-US", getOffer("en;q=0.5, en-US", acceptsLanguageOffer, "en-GB", "en-US"))
+	utils.AssertEqual(t, "en-GB", getOffer("en-US;q=0, en", acceptsLanguageOffer, "en-US", "en-GB"))
+
+	utils.AssertEqual(t, "json", getOffer("text/*;q=0.5, application/json", acceptsOfferType, "html", "json"))
+}
+
+// go test -v -run=Test_Utils_ParseTrustedProxies -count=3
+func Test_Utils_ParseTrustedProxies(t *testing.T) {
+	ranges := parseTrustedProxies([]string{"10.0.0.0/8", "10.1.0.0/16", "192.168.0.1", "::1", "10.0.0.1"})
+	utils.AssertEqual(t, 3, len(ranges))
+
+	for ip, trusted := range map[string]bool{
+		"10.0.0.0":        true,
+		"10.255.255.255":  true,
+		"11.0.0.0":        false,
+		"9.255.255.255":   false,
+		"192.168.0.1":     true,
+		"192.168.0.2":     false,
+		"::1":             true,
+		"::2":             false,
+		"::ffff:10.0.0.1": true,
+	} {
+		utils.AssertEqual(t, trusted, ipInRanges(ranges, net.ParseIP(ip)), ip)
+	}
+	utils.AssertEqual(t, true, ipInRanges(ranges, net.ParseIP("10.0.0.1").To4()))
+	utils.AssertEqual(t, false, ipInRanges(ranges, nil))
+	utils.AssertEqual(t, false, ipInRanges(nil, net.ParseIP("10.0.0.1")))
+
+	defer func() {
+		utils.AssertEqual(t, "trusted proxies: invalid IP address localhost\n", recover())
+	}()
+	parseTrustedProxies([]string{"localhost"})
 }
 
 func Test_Utils_TestAddr_Network(t *testing.T) {
@@ -0,0 +1,141 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"net"
+)
+
+// OnRouteHandler is called with every registered route
+type OnRouteHandler = func(Route) error
+
+// OnNameHandler is called with every named route
+type OnNameHandler = OnRouteHandler
+
+// OnListenHandler is called when the app starts listening
+type OnListenHandler = func(ListenData) error
+
+// OnForkHandler is called with the pid of every started prefork child
+type OnForkHandler = func(int) error
+
+// OnShutdownHandler is called when the app is shut down
+type OnShutdownHandler = func() error
+
+// ListenData describes the address the app is listening on
+type ListenData struct {
+	Host string `json:"host"`
+	Port string `json:"port"`
+	TLS  bool   `json:"tls"`
+}
+
+// Hooks holds the lifecycle hooks of an app
+type Hooks struct {
+	app *App
+
+	onRoute    []OnRouteHandler
+	onName     []OnNameHandler
+	onListen   []OnListenHandler
+	onFork     []OnForkHandler
+	onShutdown []OnShutdownHandler
+}
+
+// Hooks returns the lifecycle hooks of the app.
+func (app *App) Hooks() *Hooks {
+	return app.hooks
+}
+
+// OnRoute adds hooks which are called with every registered route,
+// an error makes the registration panic.
+func (h *Hooks) OnRoute(handler ...OnRouteHandler) {
+	h.app.mutex.Lock()
+	h.onRoute = append(h.onRoute, handler...)
+	h.app.mutex.Unlock()
+}
+
+// OnName adds hooks which are called with every named route,
+// an error makes the naming panic.
+func (h *Hooks) OnName(handler ...OnNameHandler) {
+	h.app.mutex.Lock()
+	h.onName = append(h.onName, handler...)
+	h.app.mutex.Unlock()
+}
+
+// OnListen adds hooks which are called with the resolved address once the
+// listener is bound, an error aborts the startup. With Prefork they only run
+// in the master process.
+func (h *Hooks) OnListen(handler ...OnListenHandler) {
+	h.app.mutex.Lock()
+	h.onListen = append(h.onListen, handler...)
+	h.app.mutex.Unlock()
+}
+
+// OnFork adds hooks which are called with the pid of every prefork child,
+// an error aborts the startup.
+func (h *Hooks) OnFork(handler ...OnForkHandler) {
+	h.app.mutex.Lock()
+	h.onFork = append(h.onFork, handler...)
+	h.app.mutex.Unlock()
+}
+
+// OnShutdown adds hooks which are called by App.Shutdown, even if shutting
+// down the server failed. Set Config.ShutdownOnSignal to run them on an
+// interrupt or SIGTERM.
+func (h *Hooks) OnShutdown(handler ...OnShutdownHandler) {
+	h.app.mutex.Lock()
+	h.onShutdown = append(h.onShutdown, handler...)
+	h.app.mutex.Unlock()
+}
+
+func (h *Hooks) executeOnRouteHooks(route Route) {
+	for _, handler := range h.onRoute {
+		if err := handler(route); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (h *Hooks) executeOnNameHooks(route Route) {
+	for _, handler := range h.onName {
+		if err := handler(route); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func (h *Hooks) executeOnListenHooks(addr string, tls bool) error {
+	if len(h.onListen) == 0 {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	data := ListenData{Host: host, Port: port, TLS: tls}
+	for _, handler := range h.onListen {
+		if err := handler(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hooks) executeOnForkHooks(pid int) error {
+	for _, handler := range h.onFork {
+		if err := handler(pid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeOnShutdownHooks runs all hooks and returns the first error
+func (h *Hooks) executeOnShutdownHooks() (err error) {
+	for _, handler := range h.onShutdown {
+		if hookErr := handler(); hookErr != nil && err == nil {
+			err = hookErr
+		}
+	}
+	return err
+}
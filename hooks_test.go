@@ -0,0 +1,197 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// go test -run Test_Hooks_OnRoute
+func Test_Hooks_OnRoute(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	var routes []string
+	app.Hooks().OnRoute(func(r Route) error {
+		routes = append(routes, r.Method+" "+r.Path)
+		return nil
+	})
+
+	app.Use(testEmptyHandler)
+	app.Get("/users/:id", testEmptyHandler)
+	app.Group("/api").Post("/users", testEmptyHandler)
+	app.Static("/assets", "./.github")
+
+	sub := New()
+	sub.Delete("/:id", testEmptyHandler)
+	app.Mount("/posts", sub)
+
+	utils.AssertEqual(t, []string{
+		"USE /",
+		"HEAD /users/:id",
+		"GET /users/:id",
+		"POST /api/users",
+		"GET /assets",
+		"HEAD /assets",
+		"DELETE /posts/:id",
+	}, routes)
+}
+
+// go test -run Test_Hooks_OnRoute_Error
+func Test_Hooks_OnRoute_Error(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Hooks().OnRoute(func(r Route) error {
+		return errors.New("invalid route " + r.Path)
+	})
+
+	defer func() {
+		utils.AssertEqual(t, errors.New("invalid route /john"), recover())
+	}()
+	app.Get("/john", testEmptyHandler)
+}
+
+// go test -run Test_Hooks_OnName
+func Test_Hooks_OnName(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	var names []string
+	app.Hooks().OnName(func(r Route) error {
+		names = append(names, r.Name+" "+r.Method+" "+r.Path)
+		return nil
+	})
+
+	app.Get("/users/:id", testEmptyHandler).Name("user.show")
+	app.Post("/users", testEmptyHandler)
+
+	utils.AssertEqual(t, []string{"user.show GET /users/:id"}, names)
+}
+
+// go test -run Test_Hooks_OnListen
+func Test_Hooks_OnListen(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+
+	var data ListenData
+	app.Hooks().OnListen(func(d ListenData) error {
+		data = d
+		return nil
+	})
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		utils.AssertEqual(t, nil, app.Shutdown())
+	}()
+
+	utils.AssertEqual(t, nil, app.Listen("127.0.0.1:0"))
+	utils.AssertEqual(t, "127.0.0.1", data.Host)
+	utils.AssertEqual(t, true, data.Port != "" && data.Port != "0")
+	utils.AssertEqual(t, false, data.TLS)
+}
+
+// go test -run Test_Hooks_OnListen_Prefork
+func Test_Hooks_OnListen_Prefork(t *testing.T) {
+	testPreforkMaster = true
+	defer func(cmd string) { dummyChildCmd = cmd }(dummyChildCmd)
+	dummyChildCmd = "go"
+
+	app := New(Config{DisableStartupMessage: true})
+
+	var data ListenData
+	app.Hooks().OnListen(func(d ListenData) error {
+		data = d
+		return nil
+	})
+
+	utils.AssertEqual(t, nil, app.prefork("127.0.0.1:0", nil))
+	utils.AssertEqual(t, "127.0.0.1", data.Host)
+	utils.AssertEqual(t, true, data.Port != "" && data.Port != "0")
+}
+
+// go test -run Test_Hooks_OnListen_Error
+func Test_Hooks_OnListen_Error(t *testing.T) {
+	app := New(Config{DisableStartupMessage: true})
+	app.Hooks().OnListen(func(d ListenData) error {
+		return errors.New("registration failed")
+	})
+
+	utils.AssertEqual(t, errors.New("registration failed"), app.Listen("127.0.0.1:0"))
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	defer ln.Close()
+	utils.AssertEqual(t, errors.New("registration failed"), app.Listener(ln))
+}
+
+// go test -run Test_Hooks_OnShutdown
+func Test_Hooks_OnShutdown(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	calls := 0
+	app.Hooks().OnShutdown(func() error {
+		calls++
+		return errors.New("cleanup failed")
+	}, func() error {
+		calls++
+		return nil
+	})
+
+	utils.AssertEqual(t, errors.New("cleanup failed"), app.Shutdown())
+	utils.AssertEqual(t, 2, calls)
+}
+
+// go test -run Test_Hooks_OnShutdown_Signal
+func Test_Hooks_OnShutdown_Signal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("interrupt can't be sent to the process on windows")
+	}
+	app := New(Config{DisableStartupMessage: true, ShutdownOnSignal: true})
+
+	calls := 0
+	app.Hooks().OnShutdown(func() error {
+		calls++
+		return nil
+	})
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		process, err := os.FindProcess(os.Getpid())
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, nil, process.Signal(os.Interrupt))
+	}()
+
+	utils.AssertEqual(t, nil, app.Listen("127.0.0.1:0"))
+	utils.AssertEqual(t, 1, calls)
+}
+
+// go test -run Test_Hooks_OnFork
+func Test_Hooks_OnFork(t *testing.T) {
+	testPreforkMaster = true
+	defer func(cmd string) { dummyChildCmd = cmd }(dummyChildCmd)
+	dummyChildCmd = "go"
+
+	app := New(Config{DisableStartupMessage: true})
+
+	var pids []int
+	app.Hooks().OnFork(func(pid int) error {
+		pids = append(pids, pid)
+		return nil
+	})
+	utils.AssertEqual(t, nil, app.prefork("127.0.0.1:", nil))
+	utils.AssertEqual(t, true, len(pids) > 0)
+
+	app.Hooks().OnFork(func(pid int) error {
+		return errors.New("fork failed")
+	})
+	utils.AssertEqual(t, errors.New("fork failed"), app.prefork("127.0.0.1:", nil))
+}
@@ -0,0 +1,144 @@
+// Package extractor compiles the KeyLookup of the csrf and keyauth middleware
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Func extracts a value from the request
+type Func = func(c *fiber.Ctx) (string, error)
+
+// Source is a "<source>:<key>" pair of a KeyLookup
+type Source struct {
+	Name string
+	Key  string
+}
+
+// ErrMalformed is returned by Parse if a KeyLookup pair has no key
+var ErrMalformed = errors.New("KeyLookup must in the form of <source>:<key>")
+
+// Parse splits a comma separated KeyLookup into its sources
+func Parse(keyLookup string) ([]Source, error) {
+	var sources []Source
+	for _, lookup := range strings.Split(keyLookup, ",") {
+		selectors := strings.Split(strings.TrimSpace(lookup), ":")
+		if len(selectors) != 2 {
+			return nil, ErrMalformed
+		}
+		sources = append(sources, Source{Name: selectors[0], Key: selectors[1]})
+	}
+	return sources, nil
+}
+
+// New returns an extractor which tries the sources in order, the value of the
+// first one which has it is returned. The sources are query, form, param and
+// cookie, any other source reads the header of the key. The error of a missing
+// value wraps errMissing, like "<errMissing> in query".
+//
+// headerExtractor can replace the extractor of a header, i.e. to parse the
+// Authorization header, it is called with the canonical header and the error
+// of a missing header. It uses the default extractor if it returns nil.
+func New(sources []Source, errMissing error, headerExtractor func(header string, errMissing error) Func) Func {
+	var extractors []Func
+	for _, source := range sources {
+		switch source.Name {
+		case "query":
+			extractors = append(extractors, fromQuery(source.Key, missing(errMissing, "query")))
+		case "form":
+			extractors = append(extractors, fromForm(source.Key, missing(errMissing, "form")))
+		case "param":
+			extractors = append(extractors, fromParam(source.Key, missing(errMissing, "param")))
+		case "cookie":
+			extractors = append(extractors, fromCookie(source.Key, missing(errMissing, "cookie")))
+		default:
+			// By default we extract from a header
+			header := textproto.CanonicalMIMEHeaderKey(source.Key)
+			errHeader := missing(errMissing, "header")
+			var extractor Func
+			if headerExtractor != nil {
+				extractor = headerExtractor(header, errHeader)
+			}
+			if extractor == nil {
+				extractor = fromHeader(header, errHeader)
+			}
+			extractors = append(extractors, extractor)
+		}
+	}
+
+	if len(extractors) == 1 {
+		return extractors[0]
+	}
+	return func(c *fiber.Ctx) (string, error) {
+		for _, extractor := range extractors {
+			if value, err := extractor(c); err == nil {
+				return value, nil
+			}
+		}
+		return "", errMissing
+	}
+}
+
+// missing returns the error of a value which is missing in the source
+func missing(errMissing error, source string) error {
+	return fmt.Errorf("%w in %s", errMissing, source)
+}
+
+// fromHeader returns a function that extracts the value from the request header.
+func fromHeader(header string, errMissing error) Func {
+	return func(c *fiber.Ctx) (string, error) {
+		value := c.Get(header)
+		if value == "" {
+			return "", errMissing
+		}
+		return value, nil
+	}
+}
+
+// fromQuery returns a function that extracts the value from the query string.
+func fromQuery(param string, errMissing error) Func {
+	return func(c *fiber.Ctx) (string, error) {
+		value := c.Query(param)
+		if value == "" {
+			return "", errMissing
+		}
+		return value, nil
+	}
+}
+
+// fromForm returns a function that extracts the value from the form.
+func fromForm(param string, errMissing error) Func {
+	return func(c *fiber.Ctx) (string, error) {
+		value := c.FormValue(param)
+		if value == "" {
+			return "", errMissing
+		}
+		return value, nil
+	}
+}
+
+// fromParam returns a function that extracts the value from the url param string.
+func fromParam(param string, errMissing error) Func {
+	return func(c *fiber.Ctx) (string, error) {
+		value := c.Params(param)
+		if value == "" {
+			return "", errMissing
+		}
+		return value, nil
+	}
+}
+
+// fromCookie returns a function that extracts the value from the cookie header.
+func fromCookie(param string, errMissing error) Func {
+	return func(c *fiber.Ctx) (string, error) {
+		value := c.Cookies(param)
+		if value == "" {
+			return "", errMissing
+		}
+		return value, nil
+	}
+}
@@ -0,0 +1,29 @@
+//go:build go1.21
+// +build go1.21
+
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+// The helpers need Go 1.21, which lets the build constraint of this file raise
+// the Go 1.14 language version of the module to one with type parameters.
+
+// Locals is the typed counterpart of Ctx.Locals. If a value is passed it is
+// stored under the key, otherwise the stored value is returned as T.
+// The zero value of T is returned if the key is absent or holds another type.
+func Locals[T any](c *Ctx, key string, value ...T) T {
+	val, _ := LocalsOK(c, key, value...)
+	return val
+}
+
+// LocalsOK works like Locals, but also reports whether the key holds a value of type T.
+func LocalsOK[T any](c *Ctx, key string, value ...T) (T, bool) {
+	if len(value) > 0 {
+		c.Locals(key, value[0])
+		return value[0], true
+	}
+	val, ok := c.Locals(key).(T)
+	return val, ok
+}
@@ -0,0 +1,81 @@
+//go:build go1.21
+// +build go1.21
+
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+)
+
+type testLocalsUser struct {
+	Name string
+}
+
+// go test -run Test_Locals
+func Test_Locals(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	utils.AssertEqual(t, 42, Locals(c, "answer", 42))
+	utils.AssertEqual(t, 42, Locals[int](c, "answer"))
+	utils.AssertEqual(t, 42, c.Locals("answer"))
+
+	Locals(c, "user", &testLocalsUser{Name: "john"})
+	utils.AssertEqual(t, "john", Locals[*testLocalsUser](c, "user").Name)
+
+	// Missing key and wrong type return the zero value
+	utils.AssertEqual(t, "", Locals[string](c, "missing"))
+	utils.AssertEqual(t, "", Locals[string](c, "answer"))
+	utils.AssertEqual(t, (*testLocalsUser)(nil), Locals[*testLocalsUser](c, "answer"))
+}
+
+// go test -run Test_LocalsOK
+func Test_LocalsOK(t *testing.T) {
+	t.Parallel()
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+
+	c.Locals("name", "john")
+
+	val, ok := LocalsOK[string](c, "name")
+	utils.AssertEqual(t, "john", val)
+	utils.AssertEqual(t, true, ok)
+
+	num, ok := LocalsOK[int](c, "name")
+	utils.AssertEqual(t, 0, num)
+	utils.AssertEqual(t, false, ok)
+
+	_, ok = LocalsOK[string](c, "missing")
+	utils.AssertEqual(t, false, ok)
+
+	val, ok = LocalsOK(c, "name", "doe")
+	utils.AssertEqual(t, "doe", val)
+	utils.AssertEqual(t, true, ok)
+	utils.AssertEqual(t, "doe", c.Locals("name"))
+}
+
+// go test -v -run=^$ -bench=Benchmark_Locals -benchmem -count=4
+func Benchmark_Locals(b *testing.B) {
+	app := New()
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	c.Locals("name", "john")
+
+	var res string
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		res = Locals[string](c, "name")
+	}
+	utils.AssertEqual(b, "john", res)
+}
@@ -3,6 +3,7 @@ package basicauth
 import (
 	"encoding/base64"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/utils"
@@ -13,6 +14,13 @@ func New(config Config) fiber.Handler {
 	// Set default config
 	cfg := configDefault(config)
 
+	// The challenge of unauthorized responses, see RFC 7617
+	challenge := `Basic realm="` + strings.ReplaceAll(cfg.Realm, `"`, `\"`) + `", charset="UTF-8"`
+	unauthorized := func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderWWWAuthenticate, challenge)
+		return cfg.Unauthorized(c)
+	}
+
 	// Return new handler
 	return func(c *fiber.Ctx) error {
 		// Don't execute middleware if Next returns true
@@ -24,24 +32,27 @@ func New(config Config) fiber.Handler {
 		auth := c.Get(fiber.HeaderAuthorization)
 
 		// Check if the header contains content besides "basic".
-		if len(auth) <= 6 || strings.ToLower(auth[:5]) != "basic" {
-			return cfg.Unauthorized(c)
+		if len(auth) <= 6 || !strings.EqualFold(auth[:6], "basic ") {
+			return unauthorized(c)
 		}
 
 		// Decode the header contents
 		raw, err := base64.StdEncoding.DecodeString(auth[6:])
 		if err != nil {
-			return cfg.Unauthorized(c)
+			return unauthorized(c)
 		}
 
-		// Get the credentials
+		// Get the credentials, they must be UTF-8 as asked by the challenge
 		creds := utils.UnsafeString(raw)
+		if !utf8.ValidString(creds) {
+			return unauthorized(c)
+		}
 
 		// Check if the credentials are in the correct form
 		// which is "username:password".
 		index := strings.Index(creds, ":")
 		if index == -1 {
-			return cfg.Unauthorized(c)
+			return unauthorized(c)
 		}
 
 		// Get the username and password
@@ -55,6 +66,6 @@ func New(config Config) fiber.Handler {
 		}
 
 		// Authentication failed
-		return cfg.Unauthorized(c)
+		return unauthorized(c)
 	}
 }
@@ -93,6 +93,88 @@ func Test_Middleware_BasicAuth(t *testing.T) {
 	}
 }
 
+// go test -run Test_BasicAuth_Challenge
+func Test_BasicAuth_Challenge(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Users: map[string]string{
+			"jöhn": "dœ",
+		},
+		Realm: `The "Admin" Area`,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals("username").(string))
+	})
+
+	for _, auth := range []string{
+		"",
+		"Basic",
+		"Basicam9objpkb2U=",
+		"Basic !invalid!",
+		"Basic " + b64.StdEncoding.EncodeToString([]byte("no-colon")),
+		"Basic " + b64.StdEncoding.EncodeToString([]byte("j\xf6hn:d\x9c")),
+		"Basic " + b64.StdEncoding.EncodeToString([]byte("jöhn:wrong")),
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(fiber.HeaderAuthorization, auth)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusUnauthorized, resp.StatusCode, auth)
+		utils.AssertEqual(t, `Basic realm="The \"Admin\" Area", charset="UTF-8"`, resp.Header.Get(fiber.HeaderWWWAuthenticate))
+	}
+
+	// UTF-8 credentials are accepted
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "basic "+b64.StdEncoding.EncodeToString([]byte("jöhn:dœ")))
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "jöhn", string(body))
+}
+
+// go test -run Test_BasicAuth_Authorizer
+func Test_BasicAuth_Authorizer(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Authorizer: func(user, pass string) bool {
+			return user == "john" && pass == "hashed"
+		},
+		Unauthorized: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusUnauthorized).SendString("who are you?")
+		},
+		ContextUsername: "_user",
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals("_user").(string))
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Basic "+b64.StdEncoding.EncodeToString([]byte("john:hashed")))
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john", string(body))
+
+	// The custom response gets the challenge as well
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Basic "+b64.StdEncoding.EncodeToString([]byte("john:doe")))
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusUnauthorized, resp.StatusCode)
+	utils.AssertEqual(t, `Basic realm="Restricted", charset="UTF-8"`, resp.Header.Get(fiber.HeaderWWWAuthenticate))
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "who are you?", string(body))
+}
+
 // go test -v -run=^$ -bench=Benchmark_Middleware_BasicAuth -benchmem -count=4
 func Benchmark_Middleware_BasicAuth(b *testing.B) {
 	app := fiber.New()
@@ -1,6 +1,8 @@
 package basicauth
 
 import (
+	"crypto/subtle"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -18,7 +20,8 @@ type Config struct {
 
 	// Realm is a string to define realm attribute of BasicAuth.
 	// the realm identifies the system to authenticate against
-	// and can be used by clients to save credentials
+	// and can be used by clients to save credentials. The challenge
+	// asks for UTF-8 credentials per RFC 7617.
 	//
 	// Optional. Default: "Restricted".
 	Realm string
@@ -33,7 +36,8 @@ type Config struct {
 	Authorizer func(string, string) bool
 
 	// Unauthorized defines the response body for unauthorized responses.
-	// By default it will return with a 401 Unauthorized and the correct WWW-Auth header
+	// By default it will return with a 401 Unauthorized, the WWW-Authenticate
+	// header is set before it's called
 	//
 	// Optional. Default: nil
 	Unauthorized fiber.Handler
@@ -82,16 +86,15 @@ func configDefault(config ...Config) Config {
 	}
 	if cfg.Authorizer == nil {
 		cfg.Authorizer = func(user, pass string) bool {
-			user, exist := cfg.Users[user]
-			if !exist {
-				return false
-			}
-			return user == pass
+			// Unknown users are compared as well, so their timing doesn't
+			// reveal which users exist
+			expected, exist := cfg.Users[user]
+			match := subtle.ConstantTimeCompare([]byte(expected), []byte(pass)) == 1
+			return exist && match
 		}
 	}
 	if cfg.Unauthorized == nil {
 		cfg.Unauthorized = func(c *fiber.Ctx) error {
-			c.Set(fiber.HeaderWWWAuthenticate, "basic realm="+cfg.Realm)
 			return c.SendStatus(fiber.StatusUnauthorized)
 		}
 	}
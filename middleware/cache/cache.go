@@ -3,7 +3,11 @@
 package cache
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -12,6 +16,15 @@ import (
 	"github.com/gofiber/fiber/v2/utils"
 )
 
+// The X-Cache header tells whether the response was served from the cache
+const (
+	headerXCache = "X-Cache"
+
+	cacheHit         = "hit"
+	cacheMiss        = "miss"
+	cacheUnreachable = "unreachable"
+)
+
 // New creates a new middleware handler
 func New(config ...Config) fiber.Handler {
 	// Set default config
@@ -19,12 +32,11 @@ func New(config ...Config) fiber.Handler {
 
 	var (
 		// Cache settings
-		timestamp  = uint64(time.Now().Unix())
-		expiration = uint64(cfg.Expiration.Seconds())
-		mux        = &sync.RWMutex{}
+		timestamp = uint64(time.Now().Unix())
+		mux       = &sync.RWMutex{}
 
 		// Default store logic (if no Store is provided)
-		entries = make(map[string]entry)
+		entries = newMemory(cfg.MaxMemory)
 	)
 
 	// Update timestamp every second
@@ -49,16 +61,60 @@ func New(config ...Config) fiber.Handler {
 				// GC the entries every 10 seconds
 				time.Sleep(10 * time.Second)
 				mux.Lock()
-				for k := range entries {
-					if atomic.LoadUint64(&timestamp) >= entries[k].exp {
-						delete(entries, k)
-					}
-				}
+				entries.deleteExpired(atomic.LoadUint64(&timestamp))
 				mux.Unlock()
 			}
 		}()
 	}
 
+	// Delete an entry from the store, the caller holds the lock
+	deleteEntry := func(key string) error {
+		// Use default memory storage
+		if cfg.defaultStore {
+			entries.delete(key)
+			return nil
+		}
+		// Use custom storage
+		if err := cfg.Storage.Delete(key); err != nil {
+			return err
+		}
+		return cfg.Storage.Delete(key + "_body")
+	}
+
+	// Get the Vary header of the responses cached for a key, the caller holds the lock
+	getVary := func(key string) (string, error) {
+		key += "_vary"
+		// Use default memory storage
+		if cfg.defaultStore {
+			if e, ok := entries.get(key); ok && atomic.LoadUint64(&timestamp) < e.exp {
+				return string(e.body), nil
+			}
+			return "", nil
+		}
+		// Use custom storage
+		vary, err := cfg.Storage.Get(key)
+		return string(vary), err
+	}
+
+	// Store the Vary header of the responses cached for a key, the caller holds the lock
+	setVary := func(key, vary string, ts uint64, exp time.Duration) error {
+		key += "_vary"
+		// Use default memory storage
+		if cfg.defaultStore {
+			if vary == "" {
+				entries.delete(key)
+			} else {
+				entries.set(key, entry{body: []byte(vary), exp: ts + uint64(exp.Seconds())})
+			}
+			return nil
+		}
+		// Use custom storage
+		if vary == "" {
+			return cfg.Storage.Delete(key)
+		}
+		return cfg.Storage.Set(key, []byte(vary), exp)
+	}
+
 	// Return new handler
 	return func(c *fiber.Ctx) error {
 		// Don't execute middleware if Next returns true
@@ -66,13 +122,14 @@ func New(config ...Config) fiber.Handler {
 			return c.Next()
 		}
 
-		// Only cache GET methods
-		if c.Method() != fiber.MethodGet {
+		// Only cache the configured methods
+		if !cfg.cacheable(c.Method()) {
+			c.Set(headerXCache, cacheUnreachable)
 			return c.Next()
 		}
 
 		// Get key from request
-		key := cfg.Key(c)
+		baseKey := Key(c.Method(), cfg.KeyGenerator(c))
 
 		// Create new entry
 		var entry entry
@@ -82,9 +139,27 @@ func New(config ...Config) fiber.Handler {
 		mux.Lock()
 		defer mux.Unlock()
 
+		// Fold the request headers the cached response varies by into the key
+		manifest, err := getVary(baseKey)
+		if err != nil {
+			return err
+		}
+
+		// Delete the cached responses if the request asks for it
+		if cfg.CacheInvalidator != nil && cfg.CacheInvalidator(c) {
+			if err := deleteEntry(baseKey); err != nil {
+				return err
+			}
+			if err := setVary(baseKey, "", 0, 0); err != nil {
+				return err
+			}
+			manifest = ""
+		}
+		key := varyKey(c, baseKey, manifest)
+
 		// Check if we need to use the default in-memory storage
 		if cfg.defaultStore {
-			entry = entries[key]
+			entry, _ = entries.get(key)
 
 		} else {
 			// Load data from store
@@ -109,25 +184,15 @@ func New(config ...Config) fiber.Handler {
 		// Get timestamp
 		ts := atomic.LoadUint64(&timestamp)
 
-		// Set expiration if entry does not exist
-		if entry.exp == 0 {
-			entry.exp = ts + expiration
+		// The client may ask to revalidate the cached response
+		revalidate := cfg.RespectCacheControl && requestNoCache(c.Get(fiber.HeaderCacheControl))
 
-		} else if ts >= entry.exp {
-			// Check if entry is expired
-			// Use default memory storage
-			if cfg.defaultStore {
-				delete(entries, key)
-			} else { // Use custom storage
-				if err := cfg.Storage.Delete(key); err != nil {
-					return err
-				}
-				if err := cfg.Storage.Delete(key + "_body"); err != nil {
-					return err
-				}
+		if entry.exp != 0 && (ts >= entry.exp || revalidate) {
+			// Delete the expired or revalidated entry
+			if err := deleteEntry(key); err != nil {
+				return err
 			}
-
-		} else {
+		} else if entry.exp != 0 {
 			if cfg.defaultStore {
 				c.Response().SetBodyRaw(entry.body)
 			} else {
@@ -136,6 +201,10 @@ func New(config ...Config) fiber.Handler {
 			// Set response headers from cache
 			c.Response().SetStatusCode(entry.status)
 			c.Response().Header.SetContentTypeBytes(entry.cType)
+			for name, value := range entry.headers {
+				c.Response().Header.SetBytesV(name, value)
+			}
+			c.Set(headerXCache, cacheHit)
 
 			// Set Cache-Control header if enabled
 			if cfg.CacheControl {
@@ -152,15 +221,65 @@ func New(config ...Config) fiber.Handler {
 			return err
 		}
 
+		// Let the response decide how long it may be cached
+		exp := cfg.Expiration
+		if cfg.RespectCacheControl {
+			var ok bool
+			if exp, ok = responseExpiration(string(c.Response().Header.Peek(fiber.HeaderCacheControl)), exp); !ok {
+				c.Set(headerXCache, cacheUnreachable)
+				return nil
+			}
+		}
+
+		// Don't cache oversized bodies
+		if cfg.MaxBytes > 0 && len(c.Response().Body()) > cfg.MaxBytes {
+			c.Set(headerXCache, cacheUnreachable)
+			return nil
+		}
+
+		// Vary: * matches no other request
+		respVary := normalizeVary(string(c.Response().Header.Peek(fiber.HeaderVary)))
+		if respVary == "*" {
+			c.Set(headerXCache, cacheUnreachable)
+			return nil
+		}
+		// A changed Vary header starts a new manifest, which orphans the
+		// responses cached for the previous one
+		newManifest := manifest
+		if respVary != varyNames(manifest) {
+			newManifest = ""
+			if respVary != "" {
+				newManifest = strconv.FormatInt(time.Now().UnixNano(), 36) + ":" + respVary
+			}
+		}
+		if newManifest != "" || manifest != "" {
+			if err := setVary(baseKey, newManifest, ts, exp); err != nil {
+				return err
+			}
+			key = varyKey(c, baseKey, newManifest)
+		}
+
+		c.Set(headerXCache, cacheMiss)
+		entry.exp = ts + uint64(exp.Seconds())
+
 		// Cache response
 		entryBody = utils.SafeBytes(c.Response().Body())
 		entry.status = c.Response().StatusCode()
 		entry.cType = utils.SafeBytes(c.Response().Header.ContentType())
+		entry.headers = nil
+		for _, name := range cfg.StoreHeaders {
+			if value := c.Response().Header.Peek(name); len(value) > 0 {
+				if entry.headers == nil {
+					entry.headers = make(map[string][]byte, len(cfg.StoreHeaders))
+				}
+				entry.headers[name] = utils.SafeBytes(value)
+			}
+		}
 
 		// Use default memory storage
 		if cfg.defaultStore {
 			entry.body = entryBody
-			entries[key] = entry
+			entries.set(key, entry)
 
 		} else {
 			// Use custom storage
@@ -170,12 +289,12 @@ func New(config ...Config) fiber.Handler {
 			}
 
 			// Pass bytes to Storage
-			if err = cfg.Storage.Set(key, data, cfg.Expiration); err != nil {
+			if err = cfg.Storage.Set(key, data, exp); err != nil {
 				return err
 			}
 
 			// Pass bytes to Storage
-			if err = cfg.Storage.Set(key+"_body", entryBody, cfg.Expiration); err != nil {
+			if err = cfg.Storage.Set(key+"_body", entryBody, exp); err != nil {
 				return err
 			}
 		}
@@ -184,3 +303,132 @@ func New(config ...Config) fiber.Handler {
 		return nil
 	}
 }
+
+// Key returns the storage key of the responses cached for a request with the
+// method and the key returned by the KeyGenerator. The responses of other
+// methods than GET are cached separately. The key is hashed, so that long
+// query strings can't exceed the key limits of a Storage.
+func Key(method, key string) string {
+	if method != fiber.MethodGet {
+		key = method + " " + key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Delete removes the responses cached for the key, built with Key, from the
+// storage of the middleware. Use Config.CacheInvalidator with the default
+// in-memory storage.
+func Delete(storage fiber.Storage, key string) error {
+	for _, k := range []string{key, key + "_body", key + "_vary"} {
+		if err := storage.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// varyKey folds the manifest and the values of the request headers it
+// names into the key. The manifest is a unique prefix and the normalized
+// Vary header of the cached responses, separated by a colon.
+func varyKey(c *fiber.Ctx, key, manifest string) string {
+	if manifest == "" {
+		return key
+	}
+	h := sha256.New()
+	_, _ = h.Write([]byte(key))
+	_, _ = h.Write([]byte(manifest))
+	for _, name := range strings.Split(varyNames(manifest), ",") {
+		_, _ = h.Write([]byte{0})
+		_, _ = h.Write([]byte(name))
+		_, _ = h.Write([]byte{'='})
+		_, _ = h.Write(c.Request().Header.Peek(name))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// varyNames returns the normalized Vary header of a manifest
+func varyNames(manifest string) string {
+	return manifest[strings.IndexByte(manifest, ':')+1:]
+}
+
+// normalizeVary returns the sorted and lowercased header names of a Vary
+// header, or "*" if it contains a wildcard
+func normalizeVary(vary string) string {
+	if vary == "" {
+		return ""
+	}
+	var names []string
+	for _, name := range strings.Split(vary, ",") {
+		name = utils.ToLower(strings.TrimSpace(name))
+		if name == "*" {
+			return "*"
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	unique := names[:0]
+	for i, name := range names {
+		if i == 0 || name != names[i-1] {
+			unique = append(unique, name)
+		}
+	}
+	return strings.Join(unique, ",")
+}
+
+// visitCacheControl calls visit with the lowercased name and the value
+// of every Cache-Control directive
+func visitCacheControl(cc string, visit func(name, value string)) {
+	for _, directive := range strings.Split(cc, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		name, value := directive, ""
+		if i := strings.IndexByte(directive, '='); i >= 0 {
+			name = strings.TrimSpace(directive[:i])
+			value = strings.Trim(strings.TrimSpace(directive[i+1:]), `"`)
+		}
+		if name != "" {
+			visit(name, value)
+		}
+	}
+}
+
+// requestNoCache reports whether the request Cache-Control asks to revalidate
+func requestNoCache(cc string) (noCache bool) {
+	visitCacheControl(cc, func(name, _ string) {
+		noCache = noCache || name == "no-cache"
+	})
+	return noCache
+}
+
+// responseExpiration returns how long a response with the Cache-Control
+// header may be cached, s-maxage wins over max-age. It returns false if
+// the response must not be stored in a shared cache.
+func responseExpiration(cc string, expiration time.Duration) (time.Duration, bool) {
+	storable, maxAge, sMaxAge := true, -1, -1
+	visitCacheControl(cc, func(name, value string) {
+		switch name {
+		case "no-store", "no-cache", "private":
+			storable = false
+		case "max-age":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				maxAge = v
+			}
+		case "s-maxage":
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				sMaxAge = v
+			}
+		}
+	})
+	if sMaxAge >= 0 {
+		maxAge = sMaxAge
+	}
+	if !storable || maxAge == 0 {
+		return 0, false
+	}
+	if maxAge > 0 {
+		return time.Duration(maxAge) * time.Second, true
+	}
+	return expiration, true
+}
@@ -8,6 +8,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -199,11 +201,12 @@ func Test_Cache_Invalid_Method(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, "123", string(body))
 
+	// the query string is part of the key
 	resp, err = app.Test(httptest.NewRequest("GET", "/get?cache=12345", nil))
 	utils.AssertEqual(t, nil, err)
 	body, err = ioutil.ReadAll(resp.Body)
 	utils.AssertEqual(t, nil, err)
-	utils.AssertEqual(t, "123", string(body))
+	utils.AssertEqual(t, "12345", string(body))
 }
 
 func Test_Cache_NothingToCache(t *testing.T) {
@@ -232,10 +235,252 @@ func Test_Cache_NothingToCache(t *testing.T) {
 	}
 }
 
+// go test -run Test_Cache_XCache
+func Test_Cache_XCache(t *testing.T) {
+	app := fiber.New()
+	app.Use(New())
+
+	app.All("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "miss", resp.Header.Get("X-Cache"))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "hit", resp.Header.Get("X-Cache"))
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "unreachable", resp.Header.Get("X-Cache"))
+}
+
+// go test -run Test_Cache_RespectCacheControl
+func Test_Cache_RespectCacheControl(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(Config{RespectCacheControl: true}))
+
+	var calls int
+	app.Get("/:cc", func(c *fiber.Ctx) error {
+		calls++
+		if cc := c.Params("cc"); cc != "default" {
+			c.Set(fiber.HeaderCacheControl, cc)
+		}
+		return c.SendString(fmt.Sprintf("%d", calls))
+	})
+
+	request := func(path string, cc ...string) string {
+		req := httptest.NewRequest("GET", path, nil)
+		if len(cc) > 0 {
+			req.Header.Set(fiber.HeaderCacheControl, cc[0])
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		return resp.Header.Get("X-Cache")
+	}
+
+	// responses which must not be stored
+	for _, path := range []string{"/no-store", "/private,max-age=60", "/no-cache", "/max-age=0", "/public,max-age=60,s-maxage=0"} {
+		utils.AssertEqual(t, "unreachable", request(path), path)
+		utils.AssertEqual(t, "unreachable", request(path), path)
+	}
+	utils.AssertEqual(t, 10, calls)
+
+	// requests with no-cache revalidate
+	calls = 0
+	utils.AssertEqual(t, "miss", request("/default"))
+	utils.AssertEqual(t, "hit", request("/default"))
+	utils.AssertEqual(t, "miss", request("/default", "no-cache"))
+	utils.AssertEqual(t, "hit", request("/default"))
+	utils.AssertEqual(t, 2, calls)
+
+	// max-age and s-maxage override the expiration
+	calls = 0
+	utils.AssertEqual(t, "miss", request("/max-age=1"))
+	utils.AssertEqual(t, "miss", request("/max-age=600,s-maxage=1"))
+	utils.AssertEqual(t, "hit", request("/max-age=1"))
+	utils.AssertEqual(t, "hit", request("/max-age=600,s-maxage=1"))
+	time.Sleep(2 * time.Second)
+	utils.AssertEqual(t, "miss", request("/max-age=1"))
+	utils.AssertEqual(t, "miss", request("/max-age=600,s-maxage=1"))
+	utils.AssertEqual(t, 4, calls)
+}
+
+// go test -run Test_Cache_StoreHeaders
+func Test_Cache_StoreHeaders(t *testing.T) {
+	for _, storage := range []fiber.Storage{nil, testStore{stmap: map[string][]byte{}, mutex: &sync.RWMutex{}}} {
+		app := fiber.New()
+		app.Use(New(Config{Storage: storage, StoreHeaders: []string{"X-Version", fiber.HeaderLocation}}))
+
+		app.Get("/", func(c *fiber.Ctx) error {
+			c.Set("X-Version", "1")
+			c.Set("X-Request", "not stored")
+			return c.Status(fiber.StatusCreated).JSON(fiber.Map{"id": 1})
+		})
+
+		_, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "hit", resp.Header.Get("X-Cache"))
+		utils.AssertEqual(t, fiber.StatusCreated, resp.StatusCode)
+		utils.AssertEqual(t, fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+		utils.AssertEqual(t, "1", resp.Header.Get("X-Version"))
+		utils.AssertEqual(t, "", resp.Header.Get("X-Request"))
+		utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderLocation))
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, `{"id":1}`, string(body))
+	}
+}
+
+// go test -run Test_Cache_MaxBytes
+func Test_Cache_MaxBytes(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(Config{MaxBytes: 10}))
+
+	app.Get("/:size", func(c *fiber.Ctx) error {
+		size, _ := strconv.Atoi(c.Params("size"))
+		return c.SendString(strings.Repeat("a", size))
+	})
+
+	for _, tc := range []struct{ path, xCache string }{
+		{"/10", "miss"},
+		{"/10", "hit"},
+		{"/11", "unreachable"},
+		{"/11", "unreachable"},
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", tc.path, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.xCache, resp.Header.Get("X-Cache"), tc.path)
+	}
+}
+
+// go test -run Test_Cache_Methods
+func Test_Cache_Methods(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(Config{Methods: []string{fiber.MethodGet, fiber.MethodOptions}}))
+
+	app.Options("/", func(c *fiber.Ctx) error {
+		return c.SendString("options")
+	})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("get")
+	})
+
+	for _, tc := range []struct{ method, xCache, body string }{
+		{"OPTIONS", "miss", "options"},
+		{"GET", "miss", "get"},
+		{"OPTIONS", "hit", "options"},
+		{"GET", "hit", "get"},
+		{"HEAD", "unreachable", ""},
+	} {
+		resp, err := app.Test(httptest.NewRequest(tc.method, "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.xCache, resp.Header.Get("X-Cache"), tc.method)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.body, string(body), tc.method)
+	}
+}
+
+// go test -run Test_Cache_MaxMemory
+func Test_Cache_MaxMemory(t *testing.T) {
+	app := fiber.New()
+	// room for two responses, keys are 64 bytes
+	app.Use(New(Config{MaxMemory: 2 * (64 + 100 + len(fiber.MIMETextPlainCharsetUTF8))}))
+
+	app.Get("/:id", func(c *fiber.Ctx) error {
+		return c.SendString(strings.Repeat(c.Params("id"), 100))
+	})
+
+	for _, tc := range []struct{ path, xCache string }{
+		{"/a", "miss"},
+		{"/b", "miss"},
+		{"/a", "hit"},
+		// evicts /b, the least recently used
+		{"/c", "miss"},
+		{"/a", "hit"},
+		{"/c", "hit"},
+		{"/b", "miss"},
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", tc.path, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tc.xCache, resp.Header.Get("X-Cache"), tc.path)
+	}
+}
+
+// go test -run Test_Cache_CacheInvalidator
+func Test_Cache_CacheInvalidator(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(Config{
+		CacheInvalidator: func(c *fiber.Ctx) bool {
+			return c.Query("refresh") == "1"
+		},
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return c.Path()
+		},
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Vary(fiber.HeaderAcceptLanguage)
+		return c.SendString("Hello, World!")
+	})
+
+	request := func(path, lang string) string {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(fiber.HeaderAcceptLanguage, lang)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		return resp.Header.Get("X-Cache")
+	}
+
+	utils.AssertEqual(t, "miss", request("/", "en"))
+	utils.AssertEqual(t, "miss", request("/", "de"))
+	utils.AssertEqual(t, "hit", request("/", "en"))
+	utils.AssertEqual(t, "miss", request("/?refresh=1", "en"))
+	utils.AssertEqual(t, "hit", request("/", "en"))
+	// the other variants are purged as well
+	utils.AssertEqual(t, "miss", request("/", "de"))
+}
+
+// go test -run Test_Cache_Delete
+func Test_Cache_Delete(t *testing.T) {
+	store := testStore{stmap: map[string][]byte{}, mutex: &sync.RWMutex{}}
+	app := fiber.New()
+	app.Use(New(Config{Storage: store}))
+
+	var version int
+	app.Get("/articles/:id", func(c *fiber.Ctx) error {
+		return c.SendString(fmt.Sprintf("%s@%d", c.Params("id"), version))
+	})
+	app.Post("/articles/:id", func(c *fiber.Ctx) error {
+		version++
+		return Delete(store, Key(fiber.MethodGet, "/articles/"+c.Params("id")))
+	})
+
+	request := func(method, path string) string {
+		resp, err := app.Test(httptest.NewRequest(method, path, nil))
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return string(body)
+	}
+
+	utils.AssertEqual(t, "1@0", request("GET", "/articles/1"))
+	utils.AssertEqual(t, "2@0", request("GET", "/articles/2"))
+	request("POST", "/articles/1")
+	utils.AssertEqual(t, "1@1", request("GET", "/articles/1"))
+	utils.AssertEqual(t, "2@0", request("GET", "/articles/2"))
+}
+
 func Test_CustomKey(t *testing.T) {
 	app := fiber.New()
 	var called bool
-	app.Use(New(Config{Key: func(c *fiber.Ctx) string {
+	app.Use(New(Config{KeyGenerator: func(c *fiber.Ctx) string {
 		called = true
 		return c.Path()
 	}}))
@@ -249,6 +494,106 @@ func Test_CustomKey(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, true, called)
 
+	// the deprecated Key is still used
+	called = false
+	app = fiber.New()
+	app.Use(New(Config{Key: func(c *fiber.Ctx) string {
+		called = true
+		return c.Path()
+	}}))
+	_, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, called)
+}
+
+// go test -run Test_Cache_Query
+func Test_Cache_Query(t *testing.T) {
+	app := fiber.New()
+	app.Use(New())
+
+	app.Get("/search", func(c *fiber.Ctx) error {
+		return c.SendString(c.Query("q"))
+	})
+
+	for _, q := range []string{"a", "b", "a"} {
+		resp, err := app.Test(httptest.NewRequest("GET", "/search?q="+q, nil))
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, q, string(body))
+	}
+}
+
+// go test -run Test_Cache_Vary
+func Test_Cache_Vary(t *testing.T) {
+	app := fiber.New()
+	app.Use(New())
+
+	var calls int
+	app.Get("/", func(c *fiber.Ctx) error {
+		calls++
+		c.Vary(fiber.HeaderAcceptLanguage)
+		return c.SendString(c.Get(fiber.HeaderAcceptLanguage))
+	})
+	app.Get("/wildcard", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderVary, "*")
+		return c.SendString("wildcard")
+	})
+
+	request := func(path, lang string) (string, string) {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(fiber.HeaderAcceptLanguage, lang)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		return resp.Header.Get("X-Cache"), string(body)
+	}
+
+	for _, tc := range []struct{ lang, xCache string }{
+		{"en", "miss"},
+		{"de", "miss"},
+		{"en", "hit"},
+		{"de", "hit"},
+		{"fr", "miss"},
+	} {
+		xCache, body := request("/", tc.lang)
+		utils.AssertEqual(t, tc.xCache, xCache, tc.lang)
+		utils.AssertEqual(t, tc.lang, body)
+	}
+	utils.AssertEqual(t, 3, calls)
+
+	xCache, _ := request("/wildcard", "en")
+	utils.AssertEqual(t, "unreachable", xCache)
+	xCache, _ = request("/wildcard", "en")
+	utils.AssertEqual(t, "unreachable", xCache)
+}
+
+// go test -run Test_Cache_Storage_Keys
+func Test_Cache_Storage_Keys(t *testing.T) {
+	store := testStore{stmap: map[string][]byte{}, mutex: &sync.RWMutex{}}
+	app := fiber.New()
+	app.Use(New(Config{Storage: store}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Vary(fiber.HeaderAcceptLanguage)
+		return c.SendString("Hello, World!")
+	})
+
+	path := "/?q=" + strings.Repeat("a", 2048)
+	resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "miss", resp.Header.Get("X-Cache"))
+
+	resp, err = app.Test(httptest.NewRequest("GET", path, nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "hit", resp.Header.Get("X-Cache"))
+
+	// the entry, its body and the vary header names
+	utils.AssertEqual(t, 3, len(store.stmap))
+	for key := range store.stmap {
+		utils.AssertEqual(t, true, len(key) <= 64+len("_body"), key)
+	}
 }
 
 // go test -v -run=^$ -bench=Benchmark_Cache -benchmem -count=4
@@ -24,13 +24,58 @@ type Config struct {
 	// Optional. Default: false
 	CacheControl bool
 
-	// Key allows you to generate custom keys, by default c.Path() is used
+	// RespectCacheControl honors the Cache-Control directives. Responses with
+	// no-store, no-cache or private aren't cached, max-age and s-maxage
+	// override the Expiration and requests with no-cache skip the cached
+	// response and store the new one.
+	//
+	// Optional. Default: false
+	RespectCacheControl bool
+
+	// KeyGenerator allows you to generate custom keys, by default the path and
+	// the query string are used. The request headers named by the Vary header
+	// of the response are added to the key, the key is hashed before it's
+	// passed to the Storage.
 	//
 	// Default: func(c *fiber.Ctx) string {
+	//   if q := c.Request().URI().QueryString(); len(q) > 0 {
+	//     return c.Path() + "?" + string(q)
+	//   }
 	//   return c.Path()
 	// }
+	KeyGenerator func(*fiber.Ctx) string
+
+	// Deprecated, use KeyGenerator instead
 	Key func(*fiber.Ctx) string
 
+	// CacheInvalidator deletes the responses cached for the request, before
+	// the handler runs, when it returns true
+	//
+	// Optional. Default: nil
+	CacheInvalidator func(*fiber.Ctx) bool
+
+	// Methods are the request methods whose responses are cached
+	//
+	// Optional. Default: []string{fiber.MethodGet, fiber.MethodHead}
+	Methods []string
+
+	// StoreHeaders are the response headers which are cached along with the
+	// status code, the Content-Type and the body
+	//
+	// Optional. Default: nil
+	StoreHeaders []string
+
+	// MaxBytes is the size of the largest body which is cached, 0 means unlimited
+	//
+	// Optional. Default: 0
+	MaxBytes int
+
+	// MaxMemory is the byte budget of the default in-memory storage, the least
+	// recently used responses are evicted to stay below it. 0 means unlimited
+	//
+	// Optional. Default: 0
+	MaxMemory int
+
 	// Deprecated, use Storage instead
 	Store fiber.Storage
 
@@ -49,9 +94,13 @@ var ConfigDefault = Config{
 	Next:         nil,
 	Expiration:   1 * time.Minute,
 	CacheControl: false,
-	Key: func(c *fiber.Ctx) string {
+	KeyGenerator: func(c *fiber.Ctx) string {
+		if q := c.Request().URI().QueryString(); len(q) > 0 {
+			return c.Path() + "?" + string(q)
+		}
 		return c.Path()
 	},
+	Methods:      []string{fiber.MethodGet, fiber.MethodHead},
 	defaultStore: true,
 }
 
@@ -72,8 +121,15 @@ func configDefault(config ...Config) Config {
 	if int(cfg.Expiration.Seconds()) == 0 {
 		cfg.Expiration = ConfigDefault.Expiration
 	}
-	if cfg.Key == nil {
-		cfg.Key = ConfigDefault.Key
+	if cfg.Key != nil && cfg.KeyGenerator == nil {
+		fmt.Println("cache: `Key` is deprecated, use `KeyGenerator` instead")
+		cfg.KeyGenerator = cfg.Key
+	}
+	if cfg.KeyGenerator == nil {
+		cfg.KeyGenerator = ConfigDefault.KeyGenerator
+	}
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = ConfigDefault.Methods
 	}
 	if cfg.Storage == nil && cfg.Store == nil {
 		cfg.defaultStore = true
@@ -85,3 +141,13 @@ func configDefault(config ...Config) Config {
 	}
 	return cfg
 }
+
+// cacheable reports whether responses of the method are cached
+func (cfg *Config) cacheable(method string) bool {
+	for _, m := range cfg.Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// memory is the default in-memory storage. With a byte budget the
+// least recently used entries are evicted once it is exceeded.
+type memory struct {
+	maxBytes int
+	size     int
+	lru      *list.List
+	items    map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry entry
+	size  int
+}
+
+func newMemory(maxBytes int) *memory {
+	return &memory{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the entry of the key and marks it as recently used
+func (m *memory) get(key string) (entry, bool) {
+	el, ok := m.items[key]
+	if !ok {
+		return entry{}, false
+	}
+	m.lru.MoveToFront(el)
+	return el.Value.(*memoryItem).entry, true
+}
+
+// set stores the entry and evicts the least recently used entries
+// until the budget fits, entries larger than the budget aren't stored
+func (m *memory) set(key string, e entry) {
+	m.delete(key)
+	size := len(key) + len(e.body) + len(e.cType)
+	for name, value := range e.headers {
+		size += len(name) + len(value)
+	}
+	if m.maxBytes > 0 && size > m.maxBytes {
+		return
+	}
+	m.items[key] = m.lru.PushFront(&memoryItem{key: key, entry: e, size: size})
+	m.size += size
+	for m.maxBytes > 0 && m.size > m.maxBytes {
+		m.remove(m.lru.Back())
+	}
+}
+
+func (m *memory) delete(key string) {
+	if el, ok := m.items[key]; ok {
+		m.remove(el)
+	}
+}
+
+// deleteExpired removes the entries which expired at ts
+func (m *memory) deleteExpired(ts uint64) {
+	for _, el := range m.items {
+		if ts >= el.Value.(*memoryItem).entry.exp {
+			m.remove(el)
+		}
+	}
+}
+
+func (m *memory) remove(el *list.Element) {
+	item := m.lru.Remove(el).(*memoryItem)
+	delete(m.items, item.key)
+	m.size -= item.size
+}
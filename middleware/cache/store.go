@@ -9,4 +9,6 @@ type entry struct {
 	cType  []byte `msg:"cType"`
 	status int    `msg:"status"`
 	exp    uint64 `msg:"exp"`
+	// headers holds the values of Config.StoreHeaders
+	headers map[string][]byte `msg:"headers"`
 }
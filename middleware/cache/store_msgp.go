@@ -48,6 +48,36 @@ func (z *entry) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "exp")
 				return
 			}
+		case "headers":
+			var zb0002 uint32
+			zb0002, err = dc.ReadMapHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "headers")
+				return
+			}
+			if z.headers == nil {
+				z.headers = make(map[string][]byte, zb0002)
+			} else if len(z.headers) > 0 {
+				for key := range z.headers {
+					delete(z.headers, key)
+				}
+			}
+			for zb0002 > 0 {
+				zb0002--
+				var za0001 string
+				var za0002 []byte
+				za0001, err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "headers")
+					return
+				}
+				za0002, err = dc.ReadBytes(za0002)
+				if err != nil {
+					err = msgp.WrapError(err, "headers", za0001)
+					return
+				}
+				z.headers[za0001] = za0002
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -61,9 +91,9 @@ func (z *entry) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z *entry) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 4
+	// map header, size 5
 	// write "body"
-	err = en.Append(0x84, 0xa4, 0x62, 0x6f, 0x64, 0x79)
+	err = en.Append(0x85, 0xa4, 0x62, 0x6f, 0x64, 0x79)
 	if err != nil {
 		return
 	}
@@ -102,15 +132,37 @@ func (z *entry) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "exp")
 		return
 	}
+	// write "headers"
+	err = en.Append(0xa7, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteMapHeader(uint32(len(z.headers)))
+	if err != nil {
+		err = msgp.WrapError(err, "headers")
+		return
+	}
+	for za0001, za0002 := range z.headers {
+		err = en.WriteString(za0001)
+		if err != nil {
+			err = msgp.WrapError(err, "headers")
+			return
+		}
+		err = en.WriteBytes(za0002)
+		if err != nil {
+			err = msgp.WrapError(err, "headers", za0001)
+			return
+		}
+	}
 	return
 }
 
 // MarshalMsg implements msgp.Marshaler
 func (z *entry) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 4
+	// map header, size 5
 	// string "body"
-	o = append(o, 0x84, 0xa4, 0x62, 0x6f, 0x64, 0x79)
+	o = append(o, 0x85, 0xa4, 0x62, 0x6f, 0x64, 0x79)
 	o = msgp.AppendBytes(o, z.body)
 	// string "cType"
 	o = append(o, 0xa5, 0x63, 0x54, 0x79, 0x70, 0x65)
@@ -121,6 +173,13 @@ func (z *entry) MarshalMsg(b []byte) (o []byte, err error) {
 	// string "exp"
 	o = append(o, 0xa3, 0x65, 0x78, 0x70)
 	o = msgp.AppendUint64(o, z.exp)
+	// string "headers"
+	o = append(o, 0xa7, 0x68, 0x65, 0x61, 0x64, 0x65, 0x72, 0x73)
+	o = msgp.AppendMapHeader(o, uint32(len(z.headers)))
+	for za0001, za0002 := range z.headers {
+		o = msgp.AppendString(o, za0001)
+		o = msgp.AppendBytes(o, za0002)
+	}
 	return
 }
 
@@ -166,6 +225,36 @@ func (z *entry) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "exp")
 				return
 			}
+		case "headers":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadMapHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "headers")
+				return
+			}
+			if z.headers == nil {
+				z.headers = make(map[string][]byte, zb0002)
+			} else if len(z.headers) > 0 {
+				for key := range z.headers {
+					delete(z.headers, key)
+				}
+			}
+			for zb0002 > 0 {
+				var za0001 string
+				var za0002 []byte
+				zb0002--
+				za0001, bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "headers")
+					return
+				}
+				za0002, bts, err = msgp.ReadBytesBytes(bts, za0002)
+				if err != nil {
+					err = msgp.WrapError(err, "headers", za0001)
+					return
+				}
+				z.headers[za0001] = za0002
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -180,6 +269,12 @@ func (z *entry) UnmarshalMsg(bts []byte) (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z *entry) Msgsize() (s int) {
-	s = 1 + 5 + msgp.BytesPrefixSize + len(z.body) + 6 + msgp.BytesPrefixSize + len(z.cType) + 7 + msgp.IntSize + 4 + msgp.Uint64Size
+	s = 1 + 5 + msgp.BytesPrefixSize + len(z.body) + 6 + msgp.BytesPrefixSize + len(z.cType) + 7 + msgp.IntSize + 4 + msgp.Uint64Size + 8 + msgp.MapHeaderSize
+	if z.headers != nil {
+		for za0001, za0002 := range z.headers {
+			_ = za0002
+			s += msgp.StringPrefixSize + len(za0001) + msgp.BytesPrefixSize + len(za0002)
+		}
+	}
 	return
 }
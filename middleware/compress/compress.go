@@ -1,7 +1,11 @@
 package compress
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
 )
 
@@ -10,32 +14,18 @@ func New(config ...Config) fiber.Handler {
 	// Set default config
 	cfg := configDefault(config...)
 
-	// Setup request handlers
-	var (
-		fctx       = func(c *fasthttp.RequestCtx) {}
-		compressor fasthttp.RequestHandler
-	)
-
-	// Setup compression algorithm
+	// Setup compression levels
+	var brotliLevel, level int
 	switch cfg.Level {
 	case LevelDefault:
 		// LevelDefault
-		compressor = fasthttp.CompressHandlerBrotliLevel(fctx,
-			fasthttp.CompressBrotliDefaultCompression,
-			fasthttp.CompressDefaultCompression,
-		)
+		brotliLevel, level = fasthttp.CompressBrotliDefaultCompression, fasthttp.CompressDefaultCompression
 	case LevelBestSpeed:
 		// LevelBestSpeed
-		compressor = fasthttp.CompressHandlerBrotliLevel(fctx,
-			fasthttp.CompressBrotliBestSpeed,
-			fasthttp.CompressBestSpeed,
-		)
+		brotliLevel, level = fasthttp.CompressBrotliBestSpeed, fasthttp.CompressBestSpeed
 	case LevelBestCompression:
 		// LevelBestCompression
-		compressor = fasthttp.CompressHandlerBrotliLevel(fctx,
-			fasthttp.CompressBrotliBestCompression,
-			fasthttp.CompressBestCompression,
-		)
+		brotliLevel, level = fasthttp.CompressBrotliBestCompression, fasthttp.CompressBestCompression
 	default:
 		// LevelDisabled
 		return func(c *fiber.Ctx) error {
@@ -43,6 +33,9 @@ func New(config ...Config) fiber.Handler {
 		}
 	}
 
+	// Setup stream compressor
+	compressor := fasthttp.CompressHandlerBrotliLevel(func(c *fasthttp.RequestCtx) {}, brotliLevel, level)
+
 	// Return new handler
 	return func(c *fiber.Ctx) error {
 		// Don't execute middleware if Next returns true
@@ -55,10 +48,144 @@ func New(config ...Config) fiber.Handler {
 			return err
 		}
 
-		// Compress response
+		// Skip responses which are already encoded, too small or of a
+		// content type which doesn't compress
+		if len(c.Response().Header.Peek(fiber.HeaderContentEncoding)) > 0 ||
+			!compressibleType(utils.UnsafeString(c.Response().Header.ContentType())) ||
+			!compressibleLength(c.Response(), cfg.MinLength) {
+			return nil
+		}
+
+		// The response depends on the Accept-Encoding header from now on
+		c.Vary(fiber.HeaderAcceptEncoding)
+
+		accept := utils.CopyString(c.Get(fiber.HeaderAcceptEncoding))
+		encoding := negotiateEncoding(accept)
+		if encoding == "" {
+			return nil
+		}
+
+		if !c.Response().IsBodyStream() {
+			compressBody(c.Response(), encoding, brotliLevel, level)
+			return nil
+		}
+
+		// Compress stream, the compressor only sees the negotiated encoding
+		// and skips content types other than text and application ones
+		contentType := string(c.Response().Header.ContentType())
+		c.Response().Header.SetContentType(fiber.MIMEOctetStream)
+		c.Request().Header.Set(fiber.HeaderAcceptEncoding, encoding)
 		compressor(c.Context())
+		c.Request().Header.Set(fiber.HeaderAcceptEncoding, accept)
+		c.Response().Header.SetContentType(contentType)
 
 		// Return from handler
 		return nil
 	}
 }
+
+// encodings are ordered by preference if the client accepts several with
+// the same quality
+var encodings = [...]string{"br", "gzip", "deflate"}
+
+// negotiateEncoding returns the accepted encoding with the highest quality,
+// an empty string is returned if none of the encodings is accepted
+func negotiateEncoding(accept string) string {
+	qualities := [len(encodings)]float64{-1, -1, -1}
+	wildcard := -1.0
+	for _, part := range strings.Split(accept, ",") {
+		name, q := part, 1.0
+		if i := strings.IndexByte(part, ';'); i != -1 {
+			name = part[:i]
+			param := strings.TrimSpace(part[i+1:])
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			var err error
+			if q, err = strconv.ParseFloat(param[2:], 64); err != nil {
+				continue
+			}
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		for i := range encodings {
+			if encodings[i] == name {
+				qualities[i] = q
+			}
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for i, q := range qualities {
+		// Encodings which are not listed are accepted through the wildcard
+		if q < 0 {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encodings[i], q
+		}
+	}
+	return best
+}
+
+// incompressibleTypes are content types which are compressed already
+var incompressibleTypes = []string{
+	"video/",
+	"audio/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/x-gzip",
+	"application/x-bzip2",
+	"application/x-xz",
+	"application/zstd",
+	"application/x-7z-compressed",
+	"application/x-rar-compressed",
+	"application/vnd.rar",
+}
+
+// compressibleType reports whether responses of the content type are
+// compressed, images except SVG, videos, audio and archives are skipped
+func compressibleType(contentType string) bool {
+	// Server-sent events must reach the client without delay
+	if strings.HasPrefix(contentType, fiber.MIMETextEventStream) {
+		return false
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		return strings.HasPrefix(contentType, "image/svg+xml")
+	}
+	for _, t := range incompressibleTypes {
+		if strings.HasPrefix(contentType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// compressBody replaces the body with its compressed form
+func compressBody(resp *fasthttp.Response, encoding string, brotliLevel, level int) {
+	var body []byte
+	switch encoding {
+	case "br":
+		body = fasthttp.AppendBrotliBytesLevel(nil, resp.Body(), brotliLevel)
+	case "gzip":
+		body = fasthttp.AppendGzipBytesLevel(nil, resp.Body(), level)
+	default:
+		body = fasthttp.AppendDeflateBytesLevel(nil, resp.Body(), level)
+	}
+	resp.SetBodyRaw(body)
+	resp.Header.Set(fiber.HeaderContentEncoding, encoding)
+}
+
+// compressibleLength reports whether the body has at least minLength bytes,
+// streams of unknown length are always compressed
+func compressibleLength(resp *fasthttp.Response, minLength int) bool {
+	if resp.IsBodyStream() {
+		size := resp.Header.ContentLength()
+		return size < 0 || size >= minLength
+	}
+	return len(resp.Body()) >= minLength
+}
@@ -1,6 +1,8 @@
 package compress
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -46,6 +48,32 @@ func Test_Compress_Gzip(t *testing.T) {
 	utils.AssertEqual(t, true, len(body) < len(filedata))
 }
 
+// go test -run Test_Compress_SSE
+func Test_Compress_SSE(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SSE(func(w *fiber.SSEWriter) {
+			_ = w.WriteEvent("1", "message", filedata)
+		})
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderContentEncoding))
+
+	// Validate that the events are not compressed
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, len(body) > len(filedata))
+}
+
 // go test -run Test_Compress_Different_Level
 func Test_Compress_Different_Level(t *testing.T) {
 	levels := []Level{LevelBestSpeed, LevelBestCompression}
@@ -180,3 +208,129 @@ func Test_Compress_Next(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
 }
+
+// go test -run Test_Compress_Negotiation
+func Test_Compress_Negotiation(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.Send(filedata)
+	})
+
+	cases := map[string]string{
+		"gzip, deflate, br":           "br",
+		"deflate, gzip":               "gzip",
+		"br;q=0.5, gzip":              "gzip",
+		"br;q=0, gzip;q=0.2, *;q=0.5": "deflate",
+		"*":                           "br",
+		"identity":                    "",
+		"gzip;q=0":                    "",
+	}
+	for accept, encoding := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", accept)
+
+		resp, err := app.Test(req, 10000)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+		utils.AssertEqual(t, encoding, resp.Header.Get(fiber.HeaderContentEncoding), accept)
+		utils.AssertEqual(t, fiber.HeaderAcceptEncoding, resp.Header.Get(fiber.HeaderVary), accept)
+	}
+}
+
+// go test -run Test_Compress_Skip
+func Test_Compress_Skip(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{MinLength: 100}))
+
+	app.Get("/small", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.Send(filedata[:99])
+	})
+	app.Get("/:type/:subtype", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, c.Params("type")+"/"+c.Params("subtype"))
+		return c.Send(filedata)
+	})
+
+	for path, compressed := range map[string]bool{
+		"/small":                 false,
+		"/image/png":             false,
+		"/image/svg+xml":         true,
+		"/video/mp4":             false,
+		"/audio/mpeg":            false,
+		"/font/woff2":            false,
+		"/application/zip":       false,
+		"/application/json":      true,
+		"/text/html":             true,
+		"/font/ttf":              true,
+		"/custom/x-uncompressed": true,
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+		if compressed {
+			utils.AssertEqual(t, "gzip", resp.Header.Get(fiber.HeaderContentEncoding), path)
+			utils.AssertEqual(t, fiber.HeaderAcceptEncoding, resp.Header.Get(fiber.HeaderVary), path)
+		} else {
+			utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderContentEncoding), path)
+			utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderVary), path)
+		}
+	}
+}
+
+// go test -run Test_Compress_MinLength
+func Test_Compress_MinLength(t *testing.T) {
+	utils.AssertEqual(t, 1024, configDefault(Config{}).MinLength)
+	utils.AssertEqual(t, 0, configDefault(Config{MinLength: -1}).MinLength)
+
+	app := fiber.New()
+
+	app.Use(New(Config{MinLength: -1}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+		return c.SendString("a")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "gzip", resp.Header.Get(fiber.HeaderContentEncoding))
+}
+
+// go test -run Test_Compress_Stream
+func Test_Compress_Stream(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "font/ttf")
+		return c.SendStream(bytes.NewReader(filedata), -1)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "gzip", resp.Header.Get(fiber.HeaderContentEncoding))
+	utils.AssertEqual(t, "font/ttf", resp.Header.Get(fiber.HeaderContentType))
+
+	zr, err := gzip.NewReader(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(zr)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, filedata, body)
+}
@@ -11,14 +11,21 @@ type Config struct {
 	// Optional. Default: nil
 	Next func(c *fiber.Ctx) bool
 
-	// Level determines the compression algorithm
+	// Level determines the compression level of each encoding
 	//
 	// Optional. Default: LevelDefault
 	// LevelDisabled:         -1
-	// LevelDefault:          0
-	// LevelBestSpeed:        1
-	// LevelBestCompression:  2
+	// LevelDefault:          0  brotli 4, gzip and deflate 6
+	// LevelBestSpeed:        1  brotli 0, gzip and deflate 1
+	// LevelBestCompression:  2  brotli 11, gzip and deflate 9
 	Level Level
+
+	// MinLength is the minimum body size in bytes to compress, streamed
+	// bodies of unknown length are always compressed. A negative value
+	// compresses bodies of any size.
+	//
+	// Optional. Default: 1024
+	MinLength int
 }
 
 // Level is numeric representation of compression level
@@ -34,8 +41,9 @@ const (
 
 // ConfigDefault is the default config
 var ConfigDefault = Config{
-	Next:  nil,
-	Level: LevelDefault,
+	Next:      nil,
+	Level:     LevelDefault,
+	MinLength: 1024,
 }
 
 // Helper function to set default values
@@ -52,5 +60,10 @@ func configDefault(config ...Config) Config {
 	if cfg.Level < LevelDisabled || cfg.Level > LevelBestCompression {
 		cfg.Level = ConfigDefault.Level
 	}
+	if cfg.MinLength == 0 {
+		cfg.MinLength = ConfigDefault.MinLength
+	} else if cfg.MinLength < 0 {
+		cfg.MinLength = 0
+	}
 	return cfg
 }
@@ -16,10 +16,18 @@ type Config struct {
 	Next func(c *fiber.Ctx) bool
 
 	// AllowOrigin defines a list of origins that may access the resource.
+	// Subdomains are matched with wildcards, i.e. "https://*.example.com".
 	//
-	// Optional. Default value "*"
+	// Optional. Default value "*", or none if AllowOriginsFunc is set
 	AllowOrigins string
 
+	// AllowOriginsFunc is called for origins which don't match AllowOrigins,
+	// i.e. for tenant subdomains created at runtime. Allowed origins are
+	// echoed, the responses to rejected origins have no CORS headers.
+	//
+	// Optional. Default: nil
+	AllowOriginsFunc func(origin string) bool
+
 	// AllowMethods defines a list methods allowed when accessing the resource.
 	// This is used in response to a preflight request.
 	//
@@ -35,7 +43,9 @@ type Config struct {
 	// AllowCredentials indicates whether or not the response to the request
 	// can be exposed when the credentials flag is true. When used as part of
 	// a response to a preflight request, this indicates whether or not the
-	// actual request can be made using credentials.
+	// actual request can be made using credentials. It can't be combined with
+	// the wildcard origin "*", the origins have to be listed or allowed by
+	// AllowOriginsFunc.
 	//
 	// Optional. Default value false.
 	AllowCredentials bool
@@ -47,7 +57,7 @@ type Config struct {
 	ExposeHeaders string
 
 	// MaxAge indicates how long (in seconds) the results of a preflight request
-	// can be cached.
+	// can be cached. A negative value sends 0, so the results aren't cached.
 	//
 	// Optional. Default value 0.
 	MaxAge int
@@ -55,8 +65,9 @@ type Config struct {
 
 // ConfigDefault is the default config
 var ConfigDefault = Config{
-	Next:         nil,
-	AllowOrigins: "*",
+	Next:             nil,
+	AllowOrigins:     "*",
+	AllowOriginsFunc: nil,
 	AllowMethods: strings.Join([]string{
 		fiber.MethodGet,
 		fiber.MethodPost,
@@ -84,13 +95,19 @@ func New(config ...Config) fiber.Handler {
 		if cfg.AllowMethods == "" {
 			cfg.AllowMethods = ConfigDefault.AllowMethods
 		}
-		if cfg.AllowOrigins == "" {
+		// AllowOriginsFunc decides alone if no origins are listed
+		if cfg.AllowOrigins == "" && cfg.AllowOriginsFunc == nil {
 			cfg.AllowOrigins = ConfigDefault.AllowOrigins
 		}
 	}
 
-	// Convert string to slice
-	allowOrigins := strings.Split(strings.Replace(cfg.AllowOrigins, " ", "", -1), ",")
+	// Parse the allowed origins once
+	allowOrigins := parseOrigins(cfg.AllowOrigins)
+
+	// Reflecting any origin with credentials would expose them to every site
+	if allowOrigins.all && cfg.AllowCredentials {
+		panic("[CORS] AllowCredentials can't be used with AllowOrigins \"*\", list the origins or use AllowOriginsFunc")
+	}
 
 	// Strip white spaces
 	allowMethods := strings.Replace(cfg.AllowMethods, " ", "", -1)
@@ -99,6 +116,9 @@ func New(config ...Config) fiber.Handler {
 
 	// Convert int to string
 	maxAge := strconv.Itoa(cfg.MaxAge)
+	if cfg.MaxAge < 0 {
+		maxAge = "0"
+	}
 
 	// Return new handler
 	return func(c *fiber.Ctx) error {
@@ -111,25 +131,30 @@ func New(config ...Config) fiber.Handler {
 		origin := c.Get(fiber.HeaderOrigin)
 		allowOrigin := ""
 
-		// Check allowed origins
-		for _, o := range allowOrigins {
-			if o == "*" && cfg.AllowCredentials {
-				allowOrigin = origin
-				break
-			}
-			if o == "*" || o == origin {
-				allowOrigin = o
-				break
-			}
-			if matchSubdomain(origin, o) {
-				allowOrigin = origin
-				break
-			}
+		// Check allowed origins, specific origins are echoed
+		allowed := true
+		switch {
+		case allowOrigins.all:
+			allowOrigin = "*"
+		case origin == "":
+			// An empty origin is never echoed
+			allowed = false
+		case allowOrigins.match(origin):
+			allowOrigin = origin
+		case cfg.AllowOriginsFunc != nil && cfg.AllowOriginsFunc(origin):
+			allowOrigin = origin
+		default:
+			allowed = false
 		}
 
 		// Simple request
 		if c.Method() != http.MethodOptions {
 			c.Vary(fiber.HeaderOrigin)
+
+			// Rejected origins get no CORS headers
+			if !allowed {
+				return c.Next()
+			}
 			c.Set(fiber.HeaderAccessControlAllowOrigin, allowOrigin)
 
 			if cfg.AllowCredentials {
@@ -145,6 +170,11 @@ func New(config ...Config) fiber.Handler {
 		c.Vary(fiber.HeaderOrigin)
 		c.Vary(fiber.HeaderAccessControlRequestMethod)
 		c.Vary(fiber.HeaderAccessControlRequestHeaders)
+
+		// Rejected origins get no CORS headers
+		if !allowed {
+			return c.SendStatus(fiber.StatusNoContent)
+		}
 		c.Set(fiber.HeaderAccessControlAllowOrigin, allowOrigin)
 		c.Set(fiber.HeaderAccessControlAllowMethods, allowMethods)
 
@@ -164,7 +194,7 @@ func New(config ...Config) fiber.Handler {
 		}
 
 		// Set MaxAge is set
-		if cfg.MaxAge > 0 {
+		if cfg.MaxAge != 0 {
 			c.Set(fiber.HeaderAccessControlMaxAge, maxAge)
 		}
 
@@ -2,6 +2,7 @@ package cors
 
 import (
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
@@ -54,11 +55,10 @@ func Test_CORS_Wildcard(t *testing.T) {
 
 	// OPTIONS (preflight) response headers when AllowOrigins is *
 	app.Use(New(Config{
-		AllowOrigins:     "*",
-		AllowCredentials: true,
-		MaxAge:           3600,
-		ExposeHeaders:    "X-Request-ID",
-		AllowHeaders:     "Authentication",
+		AllowOrigins:  "*",
+		MaxAge:        3600,
+		ExposeHeaders: "X-Request-ID",
+		AllowHeaders:  "Authentication",
 	}))
 
 	// Make request
@@ -71,8 +71,8 @@ func Test_CORS_Wildcard(t *testing.T) {
 	handler(ctx)
 
 	// Check result
-	utils.AssertEqual(t, "localhost", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
-	utils.AssertEqual(t, "true", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowCredentials)))
+	utils.AssertEqual(t, "*", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
+	utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowCredentials)))
 	utils.AssertEqual(t, "3600", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlMaxAge)))
 	utils.AssertEqual(t, "Authentication", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowHeaders)))
 
@@ -81,9 +81,47 @@ func Test_CORS_Wildcard(t *testing.T) {
 	ctx.Request.Header.SetMethod(fiber.MethodGet)
 	handler(ctx)
 
-	utils.AssertEqual(t, "true", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowCredentials)))
+	utils.AssertEqual(t, "*", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
+	utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowCredentials)))
 	utils.AssertEqual(t, "X-Request-ID", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlExposeHeaders)))
+}
+
+// go test -run Test_CORS_Wildcard_AllowCredentials
+func Test_CORS_Wildcard_AllowCredentials(t *testing.T) {
+	for _, config := range []Config{
+		{AllowOrigins: "*", AllowCredentials: true},
+		{AllowOrigins: "https://example.com, *", AllowCredentials: true},
+		// AllowOrigins defaults to "*"
+		{AllowCredentials: true},
+	} {
+		func() {
+			defer func() {
+				utils.AssertEqual(t, true, recover() != nil, config.AllowOrigins)
+			}()
+			New(config)
+		}()
+	}
+}
+
+// go test -run Test_CORS_AllowCredentials_Empty_Origin
+func Test_CORS_AllowCredentials_Empty_Origin(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(Config{
+		AllowOrigins:     "https://example.com",
+		AllowOriginsFunc: func(string) bool { return true },
+		AllowCredentials: true,
+	}))
+	handler := app.Handler()
+
+	for _, method := range []string{fiber.MethodGet, fiber.MethodOptions} {
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.Header.SetMethod(method)
+		handler(ctx)
 
+		// An empty Access-Control-Allow-Origin header is never sent
+		utils.AssertEqual(t, false, strings.Contains(ctx.Response.Header.String(), fiber.HeaderAccessControlAllowOrigin), method)
+		utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowCredentials)), method)
+	}
 }
 
 // go test -run -v Test_CORS_Subdomain
@@ -235,3 +273,104 @@ func Test_CORS_Next(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
 }
+
+// go test -run Test_CORS_AllowOriginsFunc
+func Test_CORS_AllowOriginsFunc(t *testing.T) {
+	app := fiber.New()
+
+	var called []string
+	app.Use(New(Config{
+		AllowOrigins: "https://example.com, https://*.static.example.com",
+		AllowOriginsFunc: func(origin string) bool {
+			called = append(called, origin)
+			return strings.HasSuffix(origin, ".tenant.example.com")
+		},
+		AllowCredentials: true,
+		MaxAge:           -1,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	handler := app.Handler()
+
+	for _, tt := range []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://example.com", true},
+		{"https://a.static.example.com", true},
+		{"https://static.example.com", false},
+		{"https://acme.tenant.example.com", true},
+		{"https://evil.com", false},
+	} {
+		// Simple request
+		ctx := &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI("/")
+		ctx.Request.Header.SetMethod(fiber.MethodGet)
+		ctx.Request.Header.Set(fiber.HeaderOrigin, tt.origin)
+		handler(ctx)
+
+		utils.AssertEqual(t, fiber.StatusOK, ctx.Response.StatusCode())
+		utils.AssertEqual(t, "Origin", string(ctx.Response.Header.Peek(fiber.HeaderVary)))
+		if tt.allowed {
+			utils.AssertEqual(t, tt.origin, string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
+			utils.AssertEqual(t, "true", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowCredentials)))
+		} else {
+			utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
+			utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowCredentials)))
+		}
+
+		// Preflight request
+		ctx = &fasthttp.RequestCtx{}
+		ctx.Request.SetRequestURI("/")
+		ctx.Request.Header.SetMethod(fiber.MethodOptions)
+		ctx.Request.Header.Set(fiber.HeaderOrigin, tt.origin)
+		ctx.Request.Header.Set(fiber.HeaderAccessControlRequestMethod, fiber.MethodPost)
+		handler(ctx)
+
+		utils.AssertEqual(t, fiber.StatusNoContent, ctx.Response.StatusCode())
+		if tt.allowed {
+			utils.AssertEqual(t, tt.origin, string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
+			utils.AssertEqual(t, "0", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlMaxAge)))
+		} else {
+			utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
+			utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowMethods)))
+			utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlMaxAge)))
+		}
+	}
+
+	// The func only sees the origins the list doesn't allow
+	utils.AssertEqual(t, []string{
+		"https://static.example.com", "https://static.example.com",
+		"https://acme.tenant.example.com", "https://acme.tenant.example.com",
+		"https://evil.com", "https://evil.com",
+	}, called)
+}
+
+// go test -run Test_CORS_AllowOriginsFunc_Only
+func Test_CORS_AllowOriginsFunc_Only(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		AllowOriginsFunc: func(origin string) bool {
+			return origin == "https://example.com"
+		},
+	}))
+
+	handler := app.Handler()
+
+	// No origins are listed, so "*" isn't the default
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fiber.MethodGet)
+	ctx.Request.Header.Set(fiber.HeaderOrigin, "https://evil.com")
+	handler(ctx)
+	utils.AssertEqual(t, "", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
+
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod(fiber.MethodGet)
+	ctx.Request.Header.Set(fiber.HeaderOrigin, "https://example.com")
+	handler(ctx)
+	utils.AssertEqual(t, "https://example.com", string(ctx.Response.Header.Peek(fiber.HeaderAccessControlAllowOrigin)))
+}
@@ -2,51 +2,68 @@ package cors
 
 import "strings"
 
-func matchScheme(domain, pattern string) bool {
-	didx := strings.Index(domain, ":")
-	pidx := strings.Index(pattern, ":")
-	return didx != -1 && pidx != -1 && domain[:didx] == pattern[:pidx]
+// maxHostLength is the longest host name, longer origins never match
+const maxHostLength = 253
+
+// origins holds the parsed AllowOrigins, wildcard subdomains like
+// "https://*.example.com" are matched by their scheme and domain suffix
+type origins struct {
+	all        bool
+	exact      []string
+	subdomains []subdomain
 }
 
-// matchSubdomain compares authority with wildcard
-func matchSubdomain(domain, pattern string) bool {
-	if !matchScheme(domain, pattern) {
-		return false
-	}
-	didx := strings.Index(domain, "://")
-	pidx := strings.Index(pattern, "://")
-	if didx == -1 || pidx == -1 {
-		return false
-	}
-	domAuth := domain[didx+3:]
-	// to avoid long loop by invalid long domain
-	if len(domAuth) > 253 {
-		return false
-	}
-	patAuth := pattern[pidx+3:]
+type subdomain struct {
+	prefix string
+	suffix string
+}
 
-	domComp := strings.Split(domAuth, ".")
-	patComp := strings.Split(patAuth, ".")
-	for i := len(domComp)/2 - 1; i >= 0; i-- {
-		opp := len(domComp) - 1 - i
-		domComp[i], domComp[opp] = domComp[opp], domComp[i]
-	}
-	for i := len(patComp)/2 - 1; i >= 0; i-- {
-		opp := len(patComp) - 1 - i
-		patComp[i], patComp[opp] = patComp[opp], patComp[i]
+// parseOrigins parses the comma separated list of allowed origins
+func parseOrigins(allowOrigins string) origins {
+	var o origins
+	for _, origin := range strings.Split(strings.Replace(allowOrigins, " ", "", -1), ",") {
+		origin = strings.ToLower(origin)
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			o.all = true
+			continue
+		}
+		if i := strings.Index(origin, "://*."); i != -1 {
+			o.subdomains = append(o.subdomains, subdomain{prefix: origin[:i+3], suffix: origin[i+4:]})
+			continue
+		}
+		o.exact = append(o.exact, origin)
 	}
+	return o
+}
 
-	for i, v := range domComp {
-		if len(patComp) <= i {
-			return false
-		}
-		p := patComp[i]
-		if p == "*" {
+// match reports whether the origin is in the list, apart from "*"
+func (o origins) match(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, exact := range o.exact {
+		if origin == exact {
 			return true
 		}
-		if p != v {
-			return false
+	}
+	for _, s := range o.subdomains {
+		if s.match(origin) {
+			return true
 		}
 	}
 	return false
 }
+
+// match reports whether the origin is a subdomain with the same scheme,
+// the domain itself doesn't match
+func (s subdomain) match(origin string) bool {
+	if len(origin) <= len(s.prefix)+len(s.suffix) || len(origin)-len(s.prefix) > maxHostLength {
+		return false
+	}
+	if !strings.HasPrefix(origin, s.prefix) || !strings.HasSuffix(origin, s.suffix) {
+		return false
+	}
+	sub := origin[len(s.prefix) : len(origin)-len(s.suffix)]
+	return !strings.ContainsAny(sub, "/:@")
+}
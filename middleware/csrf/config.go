@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
 	"github.com/gofiber/fiber/v2/utils"
 )
 
@@ -16,7 +17,8 @@ type Config struct {
 	Next func(c *fiber.Ctx) bool
 
 	// KeyLookup is a string in the form of "<source>:<key>" that is used
-	// to extract token from the request.
+	// to extract token from the request. Multiple sources are separated by
+	// commas and tried in order, i.e. "header:X-CSRF-Token,form:_csrf".
 	// Possible values:
 	// - "header:<name>"
 	// - "query:<name>"
@@ -27,6 +29,32 @@ type Config struct {
 	// Optional. Default: "header:X-CSRF-Token"
 	KeyLookup string
 
+	// Extractor extracts the token from the request, it replaces KeyLookup
+	// for custom schemes. Errors are passed to the ErrorHandler.
+	//
+	// Optional. Default: nil
+	Extractor func(c *fiber.Ctx) (string, error)
+
+	// ErrorHandler is called if the origin or token of an unsafe request
+	// is missing or invalid. The error is ErrOriginNotFound, ErrOriginInvalid,
+	// ErrTokenInvalid or wraps ErrTokenNotFound.
+	//
+	// Optional. Default: returns fiber.ErrForbidden
+	ErrorHandler fiber.ErrorHandler
+
+	// TrustedOrigins are accepted besides the host of the request as
+	// Origin, or Referer if there is no Origin, of unsafe requests.
+	// Subdomains are matched with wildcards, i.e. "https://*.example.com".
+	//
+	// Optional. Default: nil
+	TrustedOrigins []string
+
+	// RejectMissingOrigin rejects unsafe requests which have neither an
+	// Origin nor a Referer header
+	//
+	// Optional. Default: false
+	RejectMissingOrigin bool
+
 	// Name of the session cookie. This cookie will store session key.
 	// Optional. Default value "_csrf".
 	CookieName string
@@ -61,10 +89,10 @@ type Config struct {
 	// Optional. Default: memory.New()
 	Storage fiber.Storage
 
-	// Context key to store generated CSRF token into context.
-	// If left empty, token will not be stored in context.
+	// Context key to store generated CSRF token into context,
+	// see TokenFromContext.
 	//
-	// Optional. Default: ""
+	// Optional. Default: "csrf"
 	ContextKey string
 
 	// KeyGenerator creates a new CSRF token
@@ -72,6 +100,27 @@ type Config struct {
 	// Optional. Default: utils.UUID
 	KeyGenerator func() string
 
+	// Session stores the token in the session of the request instead of
+	// the Storage. The token is bound to the session ID and rotated once
+	// the session is regenerated, destroying the session invalidates it.
+	// Handlers get the same session from Store.Get, it is saved by the
+	// middleware unless the Store.Handler middleware runs before.
+	//
+	// Optional. Default: nil
+	Session *session.Store
+
+	// SessionKey is the key of the token in the session
+	//
+	// Optional. Default: "fiber.csrf.token"
+	SessionKey string
+
+	// SingleUseToken consumes the token of every successfully verified
+	// unsafe request and issues a fresh one in the response cookie and
+	// the ContextKey
+	//
+	// Optional. Default: false
+	SingleUseToken bool
+
 	// Deprecated, please use Expiration
 	CookieExpires time.Duration
 
@@ -89,6 +138,14 @@ var ConfigDefault = Config{
 	CookieSameSite: "Strict",
 	Expiration:     1 * time.Hour,
 	KeyGenerator:   utils.UUID,
+	SessionKey:     "fiber.csrf.token",
+	ContextKey:     "csrf",
+	ErrorHandler:   defaultErrorHandler,
+}
+
+// defaultErrorHandler rejects the request with 403 Forbidden
+func defaultErrorHandler(_ *fiber.Ctx, _ error) error {
+	return fiber.ErrForbidden
 }
 
 // Helper function to set default values
@@ -142,6 +199,15 @@ func configDefault(config ...Config) Config {
 	if cfg.KeyGenerator == nil {
 		cfg.KeyGenerator = ConfigDefault.KeyGenerator
 	}
+	if cfg.ErrorHandler == nil {
+		cfg.ErrorHandler = ConfigDefault.ErrorHandler
+	}
+	if cfg.SessionKey == "" {
+		cfg.SessionKey = ConfigDefault.SessionKey
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigDefault.ContextKey
+	}
 
 	return cfg
 }
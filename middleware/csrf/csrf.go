@@ -1,14 +1,16 @@
 package csrf
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
-	"net/textproto"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/internal/extractor"
 	"github.com/gofiber/fiber/v2/internal/storage/memory"
+	"github.com/gofiber/fiber/v2/middleware/session"
 )
 
 // New creates a new middleware handler
@@ -17,33 +19,18 @@ func New(config ...Config) fiber.Handler {
 	cfg := configDefault(config...)
 
 	// Set default values
-	if cfg.Storage == nil {
+	if cfg.Storage == nil && cfg.Session == nil {
 		cfg.Storage = memory.New()
 	}
 
 	// Generate the correct extractor to get the token from the correct location
-	selectors := strings.Split(cfg.KeyLookup, ":")
-
-	if len(selectors) != 2 {
-		panic("[CSRF] KeyLookup must in the form of <source>:<key>")
+	extractor := cfg.Extractor
+	if extractor == nil {
+		extractor = newExtractor(cfg.KeyLookup, cfg.CookieName)
 	}
 
-	// By default we extract from a header
-	extractor := csrfFromHeader(textproto.CanonicalMIMEHeaderKey(selectors[1]))
-
-	switch selectors[0] {
-	case "form":
-		extractor = csrfFromForm(selectors[1])
-	case "query":
-		extractor = csrfFromQuery(selectors[1])
-	case "param":
-		extractor = csrfFromParam(selectors[1])
-	case "cookie":
-		if selectors[1] == cfg.CookieName {
-			panic(fmt.Sprintf("KeyLookup key %s can't be the same as CookieName %s", selectors[1], cfg.CookieName))
-		}
-		extractor = csrfFromCookie(selectors[1])
-	}
+	// Compile the trusted origins
+	trustedOrigins := newOrigins(cfg.TrustedOrigins)
 
 	// We only use Keys in Storage, so we need a dummy value
 	dummyVal := []byte{'+'}
@@ -55,63 +42,86 @@ func New(config ...Config) fiber.Handler {
 			return c.Next()
 		}
 
+		// Load the session which holds the token, the handlers share it
+		// through the Locals of the session middleware
+		var (
+			sess      *session.Session
+			sessionID string
+			managed   bool
+		)
+		if cfg.Session != nil {
+			managed = c.Locals(cfg.Session.ContextKey) != nil
+			if sess, err = cfg.Session.Get(c); err != nil {
+				return err
+			}
+			if !managed {
+				c.Locals(cfg.Session.ContextKey, sess)
+			}
+			sessionID = sess.ID()
+		}
+
 		var token string
 
 		// Action depends on the HTTP method
 		switch c.Method() {
 		case fiber.MethodGet:
-			// Declare empty token and try to get existing CSRF from cookie
-			token = c.Cookies(cfg.CookieName)
+			// Try to get the existing CSRF token from the session or cookie
+			if sess != nil {
+				token = sessionToken(sess, cfg.SessionKey)
+			} else {
+				token = c.Cookies(cfg.CookieName)
+			}
 
 			// Generate CSRF token if not exist
 			if token == "" {
-				// Generate new CSRF token
-				token = cfg.KeyGenerator()
-
-				// Add token to Storage
-				if err = cfg.Storage.Set(token, dummyVal, cfg.Expiration); err != nil {
-					fmt.Println("[CSRF]", err.Error())
-				}
+				token = issueToken(&cfg, sess, dummyVal)
 			}
 
-			// Create cookie to pass token to client
-			cookie := &fiber.Cookie{
-				Name:     cfg.CookieName,
-				Value:    token,
-				Domain:   cfg.CookieDomain,
-				Path:     cfg.CookiePath,
-				Expires:  time.Now().Add(cfg.Expiration),
-				Secure:   cfg.CookieSecure,
-				HTTPOnly: cfg.CookieHTTPOnly,
-				SameSite: cfg.CookieSameSite,
+			// Pass token to client
+			setCookie(c, &cfg, token)
+		case fiber.MethodHead, fiber.MethodOptions, fiber.MethodTrace:
+			// Safe methods are not verified
+		default:
+			// Verify the origin before the token
+			if err = trustedOrigins.check(c, cfg.RejectMissingOrigin); err != nil {
+				return cfg.ErrorHandler(c, err)
 			}
 
-			// Set cookie to response
-			c.Cookie(cookie)
-		case fiber.MethodPost, fiber.MethodDelete, fiber.MethodPatch, fiber.MethodPut:
 			// Verify CSRF token
 			// Extract token from client request i.e. header, query, param, form or cookie
 			token, err = extractor(c)
+			if err == nil && token == "" {
+				err = ErrTokenNotFound
+			}
 			if err != nil {
-				return fiber.ErrForbidden
+				return cfg.ErrorHandler(c, err)
 			}
-			// We have a problem extracting the csrf token from Storage
-			if _, err = cfg.Storage.Get(token); err != nil {
+			if sess != nil {
+				// The token must be issued for the current session
+				if stored := sessionToken(sess, cfg.SessionKey); stored == "" ||
+					subtle.ConstantTimeCompare([]byte(stored), []byte(token)) != 1 {
+					expireCookie(c, &cfg)
+					return cfg.ErrorHandler(c, ErrTokenInvalid)
+				}
+			} else if _, err = cfg.Storage.Get(token); err != nil {
+				// We have a problem extracting the csrf token from Storage
 				// The token is invalid, let client generate a new one
 				if err = cfg.Storage.Delete(token); err != nil {
 					fmt.Println("[CSRF]", err.Error())
 				}
-				// Expire cookie
-				c.Cookie(&fiber.Cookie{
-					Name:     cfg.CookieName,
-					Domain:   cfg.CookieDomain,
-					Path:     cfg.CookiePath,
-					Expires:  time.Now().Add(-1 * time.Minute),
-					Secure:   cfg.CookieSecure,
-					HTTPOnly: cfg.CookieHTTPOnly,
-					SameSite: cfg.CookieSameSite,
-				})
-				return fiber.ErrForbidden
+				expireCookie(c, &cfg)
+				return cfg.ErrorHandler(c, ErrTokenInvalid)
+			}
+
+			// Consume the token and issue a fresh one
+			if cfg.SingleUseToken {
+				if sess == nil {
+					if err = cfg.Storage.Delete(token); err != nil {
+						fmt.Println("[CSRF]", err.Error())
+					}
+				}
+				token = issueToken(&cfg, sess, dummyVal)
+				setCookie(c, &cfg, token)
 			}
 		}
 
@@ -119,75 +129,116 @@ func New(config ...Config) fiber.Handler {
 		// a new header value is generated
 		c.Vary(fiber.HeaderCookie)
 
-		// Store token in context if set
-		if cfg.ContextKey != "" {
-			c.Locals(cfg.ContextKey, token)
-		}
+		// Store token in context
+		c.Locals(cfg.ContextKey, token)
 
 		// Continue stack
-		return c.Next()
-	}
-}
+		err = c.Next()
 
-var (
-	errMissingHeader = errors.New("missing csrf token in header")
-	errMissingQuery  = errors.New("missing csrf token in query")
-	errMissingParam  = errors.New("missing csrf token in param")
-	errMissingForm   = errors.New("missing csrf token in form")
-	errMissingCookie = errors.New("missing csrf token in cookie")
-)
+		if sess != nil {
+			// Rotate the token if the handlers regenerated the session
+			if token != "" && sess.ID() != sessionID {
+				setCookie(c, &cfg, issueToken(&cfg, sess, dummyVal))
+			}
 
-// csrfFromHeader returns a function that extracts token from the request header.
-func csrfFromHeader(param string) func(c *fiber.Ctx) (string, error) {
-	return func(c *fiber.Ctx) (string, error) {
-		token := c.Get(param)
-		if token == "" {
-			return "", errMissingHeader
+			// Save the session unless the session middleware does it
+			if !managed && sess.Dirty() {
+				if saveErr := sess.Save(); err == nil {
+					err = saveErr
+				}
+			}
 		}
-		return token, nil
+		return err
 	}
 }
 
-// csrfFromQuery returns a function that extracts token from the query string.
-func csrfFromQuery(param string) func(c *fiber.Ctx) (string, error) {
-	return func(c *fiber.Ctx) (string, error) {
-		token := c.Query(param)
-		if token == "" {
-			return "", errMissingQuery
-		}
-		return token, nil
+// sessionToken returns the token stored in the session, tokens issued for
+// a previous session ID are ignored
+func sessionToken(sess *session.Session, key string) string {
+	value, _ := sess.GetString(key)
+	prefix := sess.ID() + ":"
+	if !strings.HasPrefix(value, prefix) {
+		return ""
 	}
+	return value[len(prefix):]
 }
 
-// csrfFromParam returns a function that extracts token from the url param string.
-func csrfFromParam(param string) func(c *fiber.Ctx) (string, error) {
-	return func(c *fiber.Ctx) (string, error) {
-		token := c.Params(param)
-		if token == "" {
-			return "", errMissingParam
-		}
-		return token, nil
+// issueToken generates a new token and stores it in the session or Storage
+func issueToken(cfg *Config, sess *session.Session, dummyVal []byte) string {
+	token := cfg.KeyGenerator()
+	if sess != nil {
+		sess.Set(cfg.SessionKey, sess.ID()+":"+token)
+		return token
+	}
+	// Add token to Storage
+	if err := cfg.Storage.Set(token, dummyVal, cfg.Expiration); err != nil {
+		fmt.Println("[CSRF]", err.Error())
 	}
+	return token
 }
 
-// csrfFromForm returns a function that extracts a token from a multipart-form.
-func csrfFromForm(param string) func(c *fiber.Ctx) (string, error) {
-	return func(c *fiber.Ctx) (string, error) {
-		token := c.FormValue(param)
-		if token == "" {
-			return "", errMissingForm
-		}
-		return token, nil
+// setCookie creates a cookie to pass the token to the client
+func setCookie(c *fiber.Ctx, cfg *Config, token string) {
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.CookieName,
+		Value:    token,
+		Domain:   cfg.CookieDomain,
+		Path:     cfg.CookiePath,
+		Expires:  time.Now().Add(cfg.Expiration),
+		Secure:   cfg.CookieSecure,
+		HTTPOnly: cfg.CookieHTTPOnly,
+		SameSite: cfg.CookieSameSite,
+	})
+}
+
+// expireCookie removes the token cookie from the client
+func expireCookie(c *fiber.Ctx, cfg *Config) {
+	c.Cookie(&fiber.Cookie{
+		Name:     cfg.CookieName,
+		Domain:   cfg.CookieDomain,
+		Path:     cfg.CookiePath,
+		Expires:  time.Now().Add(-1 * time.Minute),
+		Secure:   cfg.CookieSecure,
+		HTTPOnly: cfg.CookieHTTPOnly,
+		SameSite: cfg.CookieSameSite,
+	})
+}
+
+// TokenFromContext returns the token stored by the middleware, or an empty
+// string if there is none. Pass the ContextKey if a custom one is configured.
+func TokenFromContext(c *fiber.Ctx, contextKey ...string) string {
+	key := ConfigDefault.ContextKey
+	if len(contextKey) > 0 {
+		key = contextKey[0]
+	}
+	if key == "" {
+		return ""
+	}
+	if token, ok := c.Locals(key).(string); ok {
+		return token
 	}
+	return ""
 }
 
-// csrfFromCookie returns a function that extracts token from the cookie header.
-func csrfFromCookie(param string) func(c *fiber.Ctx) (string, error) {
-	return func(c *fiber.Ctx) (string, error) {
-		token := c.Cookies(param)
-		if token == "" {
-			return "", errMissingCookie
+var (
+	// ErrTokenNotFound is passed to the ErrorHandler if the request has no token
+	ErrTokenNotFound = errors.New("missing csrf token")
+	// ErrTokenInvalid is passed to the ErrorHandler if the token is unknown,
+	// expired or issued for another session
+	ErrTokenInvalid = errors.New("invalid csrf token")
+)
+
+// newExtractor compiles the KeyLookup into an extractor, the sources of a
+// comma separated KeyLookup are tried in order
+func newExtractor(keyLookup, cookieName string) func(c *fiber.Ctx) (string, error) {
+	sources, err := extractor.Parse(keyLookup)
+	if err != nil {
+		panic("[CSRF] " + err.Error())
+	}
+	for _, source := range sources {
+		if source.Name == "cookie" && source.Key == cookieName {
+			panic(fmt.Sprintf("KeyLookup key %s can't be the same as CookieName %s", source.Key, cookieName))
 		}
-		return token, nil
 	}
+	return extractor.New(sources, ErrTokenNotFound, nil)
 }
@@ -1,11 +1,15 @@
 package csrf
 
 import (
+	"errors"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/session"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
 )
@@ -233,3 +237,336 @@ func Test_CSRF_From_Cookie(t *testing.T) {
 	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
 	utils.AssertEqual(t, "OK", string(ctx.Response.Body()))
 }
+
+func Test_CSRF_TokenFromContext(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+	app.Use("/custom", New(Config{ContextKey: "token"}))
+
+	var token, missing string
+	app.Get("/", func(c *fiber.Ctx) error {
+		token = TokenFromContext(c)
+		missing = TokenFromContext(c, "")
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/custom", func(c *fiber.Ctx) error {
+		token = TokenFromContext(c, "token")
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	cookie := resp.Header.Get(fiber.HeaderSetCookie)
+	utils.AssertEqual(t, true, token != "")
+	utils.AssertEqual(t, true, strings.HasPrefix(cookie, ConfigDefault.CookieName+"="+token+";"))
+	utils.AssertEqual(t, "", missing)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/custom", nil))
+	utils.AssertEqual(t, nil, err)
+	cookie = resp.Header.Get(fiber.HeaderSetCookie)
+	utils.AssertEqual(t, true, token != "")
+	utils.AssertEqual(t, true, strings.Contains(cookie, ConfigDefault.CookieName+"="+token+";"))
+}
+
+// cookieValue returns the value of the named response cookie
+func cookieValue(resp *http.Response, name string) string {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// go test -run Test_CSRF_Session
+func Test_CSRF_Session(t *testing.T) {
+	app := fiber.New()
+	store := session.New()
+
+	app.Use(New(Config{Session: store, ContextKey: "csrf"}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(TokenFromContext(c, "csrf"))
+	})
+	app.Post("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Post("/login", func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		return sess.Regenerate()
+	})
+	app.Post("/logout", func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		return sess.Destroy()
+	})
+
+	post := func(path, token, sessionID string) *http.Response {
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("X-Csrf-Token", token)
+		req.Header.Set(fiber.HeaderCookie, "session_id="+sessionID)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		return resp
+	}
+
+	// The token is issued with the session
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	sessionID := cookieValue(resp, "session_id")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	token := string(body)
+	utils.AssertEqual(t, true, sessionID != "")
+	utils.AssertEqual(t, token, cookieValue(resp, ConfigDefault.CookieName))
+
+	// The same token is served for the session
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderCookie, "session_id="+sessionID)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, token, string(body))
+
+	utils.AssertEqual(t, fiber.StatusOK, post("/", token, sessionID).StatusCode)
+	utils.AssertEqual(t, fiber.StatusForbidden, post("/", token, "other").StatusCode)
+	utils.AssertEqual(t, fiber.StatusForbidden, post("/", "invalid", sessionID).StatusCode)
+
+	// Regenerating the session rotates the token
+	resp = post("/login", token, sessionID)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	newSessionID := cookieValue(resp, "session_id")
+	newToken := cookieValue(resp, ConfigDefault.CookieName)
+	utils.AssertEqual(t, true, newSessionID != sessionID)
+	utils.AssertEqual(t, true, newToken != "" && newToken != token)
+	utils.AssertEqual(t, fiber.StatusForbidden, post("/", token, newSessionID).StatusCode)
+	utils.AssertEqual(t, fiber.StatusOK, post("/", newToken, newSessionID).StatusCode)
+
+	// Destroying the session invalidates the token
+	utils.AssertEqual(t, fiber.StatusOK, post("/logout", newToken, newSessionID).StatusCode)
+	utils.AssertEqual(t, fiber.StatusForbidden, post("/", newToken, newSessionID).StatusCode)
+}
+
+// go test -run Test_CSRF_SingleUseToken
+func Test_CSRF_SingleUseToken(t *testing.T) {
+	for name, store := range map[string]*session.Store{"storage": nil, "session": session.New()} {
+		t.Run(name, func(t *testing.T) {
+			app := fiber.New()
+
+			app.Use(New(Config{Session: store, SingleUseToken: true, ContextKey: "csrf"}))
+
+			app.Get("/", func(c *fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+			app.Post("/", func(c *fiber.Ctx) error {
+				return c.SendString(TokenFromContext(c, "csrf"))
+			})
+
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			utils.AssertEqual(t, nil, err)
+			token := cookieValue(resp, ConfigDefault.CookieName)
+			sessionID := cookieValue(resp, "session_id")
+
+			post := func(token string) *http.Response {
+				req := httptest.NewRequest("POST", "/", nil)
+				req.Header.Set("X-Csrf-Token", token)
+				req.Header.Set(fiber.HeaderCookie, "session_id="+sessionID)
+				resp, err := app.Test(req)
+				utils.AssertEqual(t, nil, err)
+				return resp
+			}
+
+			// The token is consumed and a fresh one is issued
+			resp = post(token)
+			utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+			body, err := ioutil.ReadAll(resp.Body)
+			utils.AssertEqual(t, nil, err)
+			newToken := cookieValue(resp, ConfigDefault.CookieName)
+			utils.AssertEqual(t, true, newToken != "" && newToken != token)
+			utils.AssertEqual(t, newToken, string(body))
+
+			utils.AssertEqual(t, fiber.StatusForbidden, post(token).StatusCode)
+			utils.AssertEqual(t, fiber.StatusOK, post(newToken).StatusCode)
+		})
+	}
+}
+
+// go test -run Test_CSRF_Multiple_KeyLookup
+func Test_CSRF_Multiple_KeyLookup(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{KeyLookup: "header:X-CSRF-Token, form:_csrf"}))
+
+	app.Post("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	token := cookieValue(resp, ConfigDefault.CookieName)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set("X-CSRF-Token", token)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest("POST", "/", strings.NewReader("_csrf="+token))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationForm)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusForbidden, resp.StatusCode)
+}
+
+// go test -run Test_CSRF_Extractor
+func Test_CSRF_Extractor(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Extractor: func(c *fiber.Ctx) (string, error) {
+			return strings.TrimPrefix(c.Get(fiber.HeaderAuthorization), "CSRF "), nil
+		},
+	}))
+
+	app.Post("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	token := cookieValue(resp, ConfigDefault.CookieName)
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "CSRF "+token)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+}
+
+// go test -run Test_CSRF_ErrorHandler
+func Test_CSRF_ErrorHandler(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			if errors.Is(err, ErrTokenNotFound) {
+				return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+			}
+			return c.Status(fiber.StatusForbidden).SendString(err.Error())
+		},
+	}))
+
+	app.All("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	// Safe methods skip the verification
+	for _, method := range []string{"HEAD", "OPTIONS", "TRACE"} {
+		resp, err := app.Test(httptest.NewRequest(method, "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, method)
+	}
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusBadRequest, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "missing csrf token in header", string(body))
+
+	req := httptest.NewRequest("DELETE", "/", nil)
+	req.Header.Set("X-Csrf-Token", "invalid")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusForbidden, resp.StatusCode)
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, ErrTokenInvalid.Error(), string(body))
+}
+
+// go test -run Test_CSRF_Origin
+func Test_CSRF_Origin(t *testing.T) {
+	for _, rejectMissing := range []bool{false, true} {
+		app := fiber.New()
+
+		app.Use(New(Config{
+			TrustedOrigins:      []string{"https://trusted.org", "https://*.example.org/"},
+			RejectMissingOrigin: rejectMissing,
+		}))
+
+		app.Post("/", func(c *fiber.Ctx) error {
+			return c.SendStatus(fiber.StatusOK)
+		})
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		token := cookieValue(resp, ConfigDefault.CookieName)
+
+		missing := fiber.StatusOK
+		if rejectMissing {
+			missing = fiber.StatusForbidden
+		}
+
+		for _, tc := range []struct {
+			origin, referer string
+			status          int
+		}{
+			{"", "", missing},
+			{"http://example.com", "", fiber.StatusOK},
+			{"https://EXAMPLE.com", "", fiber.StatusOK},
+			{"https://trusted.org", "", fiber.StatusOK},
+			{"https://app.example.org", "", fiber.StatusOK},
+			{"https://a.b.example.org", "", fiber.StatusOK},
+			{"https://example.org", "", fiber.StatusForbidden},
+			{"http://app.example.org", "", fiber.StatusForbidden},
+			{"https://evil.com", "", fiber.StatusForbidden},
+			{"null", "", fiber.StatusForbidden},
+			{"", "https://app.example.org/form?a=b", fiber.StatusOK},
+			{"", "https://evil.com/form", fiber.StatusForbidden},
+			{"https://evil.com", "https://trusted.org/", fiber.StatusForbidden},
+		} {
+			req := httptest.NewRequest("POST", "/", nil)
+			req.Header.Set("X-Csrf-Token", token)
+			if tc.origin != "" {
+				req.Header.Set(fiber.HeaderOrigin, tc.origin)
+			}
+			if tc.referer != "" {
+				req.Header.Set(fiber.HeaderReferer, tc.referer)
+			}
+			resp, err := app.Test(req)
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, tc.status, resp.StatusCode, tc.origin+" "+tc.referer)
+		}
+	}
+}
+
+// go test -run Test_CSRF_Origin_Before_Token
+func Test_CSRF_Origin_Before_Token(t *testing.T) {
+	app := fiber.New()
+
+	var errs []error
+	app.Use(New(Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			errs = append(errs, err)
+			return fiber.ErrForbidden
+		},
+	}))
+
+	req := httptest.NewRequest("POST", "/", nil)
+	req.Header.Set(fiber.HeaderOrigin, "https://evil.com")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusForbidden, resp.StatusCode)
+	utils.AssertEqual(t, []error{ErrOriginInvalid}, errs)
+}
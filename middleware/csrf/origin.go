@@ -0,0 +1,87 @@
+package csrf
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// ErrOriginNotFound is passed to the ErrorHandler if an unsafe request
+	// has neither an Origin nor a Referer header and RejectMissingOrigin is set
+	ErrOriginNotFound = errors.New("missing origin")
+	// ErrOriginInvalid is passed to the ErrorHandler if the origin of an
+	// unsafe request is neither the host nor one of the TrustedOrigins
+	ErrOriginInvalid = errors.New("invalid origin")
+)
+
+// origins matches request origins against the TrustedOrigins
+type origins struct {
+	exact    []string
+	wildcard [][2]string
+}
+
+// newOrigins compiles the trusted origins, i.e. "https://*.example.com"
+// is split into the scheme and the domain suffix
+func newOrigins(trusted []string) origins {
+	var o origins
+	for _, origin := range trusted {
+		origin = strings.TrimRight(strings.ToLower(strings.TrimSpace(origin)), "/")
+		if i := strings.Index(origin, "://*."); i != -1 {
+			o.wildcard = append(o.wildcard, [2]string{origin[:i+3], origin[i+4:]})
+			continue
+		}
+		o.exact = append(o.exact, origin)
+	}
+	return o
+}
+
+// trusted reports whether the origin is in the list, wildcards match any
+// subdomain but not the domain itself
+func (o origins) trusted(origin string) bool {
+	for _, exact := range o.exact {
+		if origin == exact {
+			return true
+		}
+	}
+	for _, wildcard := range o.wildcard {
+		if strings.HasPrefix(origin, wildcard[0]) && strings.HasSuffix(origin, wildcard[1]) &&
+			len(origin) > len(wildcard[0])+len(wildcard[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// check validates the Origin of the request, or the Referer if the Origin is
+// absent. It must match the host of the request or a trusted origin.
+func (o origins) check(c *fiber.Ctx, rejectMissing bool) error {
+	origin := c.Get(fiber.HeaderOrigin)
+	if origin == "" {
+		if referer := c.Get(fiber.HeaderReferer); referer != "" {
+			u, err := url.Parse(referer)
+			if err != nil || u.Scheme == "" || u.Host == "" {
+				return ErrOriginInvalid
+			}
+			origin = u.Scheme + "://" + u.Host
+		}
+	}
+	if origin == "" {
+		if rejectMissing {
+			return ErrOriginNotFound
+		}
+		return nil
+	}
+
+	origin = strings.ToLower(origin)
+	// Same host, the scheme is ignored for TLS terminating proxies
+	if i := strings.Index(origin, "://"); i != -1 && origin[i+3:] == strings.ToLower(c.Hostname()) {
+		return nil
+	}
+	if o.trusted(origin) {
+		return nil
+	}
+	return ErrOriginInvalid
+}
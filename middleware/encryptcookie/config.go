@@ -0,0 +1,74 @@
+package encryptcookie
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// Config defines the config for middleware.
+type Config struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c *fiber.Ctx) bool
+
+	// Except is a list of cookie names which are not encrypted, i.e. the
+	// CSRF cookie which is read by scripts
+	//
+	// Optional. Default: []
+	Except []string
+
+	// Key is the base64 encoded 32 byte key passed to the Encryptor and
+	// Decryptor, use GenerateKey to create one
+	//
+	// Required.
+	Key string
+
+	// Encryptor encrypts the cookie values of the response
+	//
+	// Optional. Default: EncryptCookie
+	Encryptor func(decryptedString, key string) (string, error)
+
+	// Decryptor decrypts the cookie values of the request, cookies which
+	// fail are removed from the request
+	//
+	// Optional. Default: DecryptCookie
+	Decryptor func(encryptedString, key string) (string, error)
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Next:      nil,
+	Except:    []string{},
+	Key:       "",
+	Encryptor: EncryptCookie,
+	Decryptor: DecryptCookie,
+}
+
+// Helper function to set default values
+func configDefault(config ...Config) Config {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		panic("[encryptcookie] Key is required")
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Key == "" {
+		panic("[encryptcookie] Key is required")
+	}
+	// The default encryption needs a valid AES-256 key
+	if cfg.Encryptor == nil || cfg.Decryptor == nil {
+		if _, err := decodeKey(cfg.Key); err != nil {
+			panic("[encryptcookie] " + err.Error())
+		}
+	}
+	if cfg.Encryptor == nil {
+		cfg.Encryptor = ConfigDefault.Encryptor
+	}
+	if cfg.Decryptor == nil {
+		cfg.Decryptor = ConfigDefault.Decryptor
+	}
+	return cfg
+}
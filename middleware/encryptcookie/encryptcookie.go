@@ -0,0 +1,69 @@
+package encryptcookie
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// New creates a new middleware handler
+func New(config ...Config) fiber.Handler {
+	// Set default config
+	cfg := configDefault(config...)
+
+	// Return new handler
+	return func(c *fiber.Ctx) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Decrypt request cookies, the header can't be changed while visiting it
+		var names, values []string
+		c.Request().Header.VisitAllCookie(func(key, value []byte) {
+			if name := string(key); !isDisabled(name, cfg.Except) {
+				names = append(names, name)
+				values = append(values, string(value))
+			}
+		})
+		for i, name := range names {
+			decrypted, err := cfg.Decryptor(values[i], cfg.Key)
+			if err != nil {
+				// Don't pass tampered values to the handlers
+				c.Request().Header.DelCookie(name)
+				continue
+			}
+			c.Request().Header.SetCookie(name, decrypted)
+		}
+
+		// Continue stack
+		err := c.Next()
+
+		// Encrypt response cookies
+		names = names[:0]
+		c.Response().Header.VisitAllCookie(func(key, _ []byte) {
+			if name := string(key); !isDisabled(name, cfg.Except) {
+				names = append(names, name)
+			}
+		})
+		for _, name := range names {
+			cookie := fasthttp.AcquireCookie()
+			cookie.SetKey(name)
+			if c.Response().Header.Cookie(cookie) && len(cookie.Value()) > 0 {
+				encrypted, encErr := cfg.Encryptor(string(cookie.Value()), cfg.Key)
+				if encErr != nil {
+					// Never send the plain value
+					c.Response().Header.DelCookie(name)
+					if err == nil {
+						err = encErr
+					}
+				} else {
+					cookie.SetValue(encrypted)
+					c.Response().Header.SetCookie(cookie)
+				}
+			}
+			fasthttp.ReleaseCookie(cookie)
+		}
+
+		return err
+	}
+}
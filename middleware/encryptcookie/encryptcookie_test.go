@@ -0,0 +1,299 @@
+package encryptcookie
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+)
+
+var testKey = GenerateKey()
+
+// go test -run Test_Middleware_EncryptCookie
+func Test_Middleware_EncryptCookie(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Key: testKey,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("value=" + c.Cookies("test"))
+	})
+	app.Post("/", func(c *fiber.Ctx) error {
+		c.Cookie(&fiber.Cookie{
+			Name:  "test",
+			Value: "SomeThing",
+		})
+		return nil
+	})
+
+	h := app.Handler()
+
+	// Test empty cookie
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("GET")
+	h(ctx)
+	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
+	utils.AssertEqual(t, "value=", string(ctx.Response.Body()))
+
+	// Test invalid cookie
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.Header.SetCookie("test", "Invalid")
+	h(ctx)
+	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
+	utils.AssertEqual(t, "value=", string(ctx.Response.Body()))
+
+	// Test tampered cookie
+	encrypted, err := EncryptCookie("SomeThing", testKey)
+	utils.AssertEqual(t, nil, err)
+	raw, _ := base64.RawURLEncoding.DecodeString(encrypted)
+	raw[len(raw)-1] ^= 1
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.Header.SetCookie("test", base64.RawURLEncoding.EncodeToString(raw))
+	h(ctx)
+	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
+	utils.AssertEqual(t, "value=", string(ctx.Response.Body()))
+
+	// Test valid cookie
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("POST")
+	h(ctx)
+	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
+
+	encryptedCookie := fasthttp.Cookie{}
+	encryptedCookie.SetKey("test")
+	utils.AssertEqual(t, true, ctx.Response.Header.Cookie(&encryptedCookie), "Get cookie value")
+	utils.AssertEqual(t, false, string(encryptedCookie.Value()) == "SomeThing")
+	decryptedCookieValue, _ := DecryptCookie(string(encryptedCookie.Value()), testKey)
+	utils.AssertEqual(t, "SomeThing", decryptedCookieValue)
+
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.Header.SetCookie("test", string(encryptedCookie.Value()))
+	h(ctx)
+	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
+	utils.AssertEqual(t, "value=SomeThing", string(ctx.Response.Body()))
+}
+
+// go test -run Test_Encrypt_Cookie_Next
+func Test_Encrypt_Cookie_Next(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Key: testKey,
+		Next: func(_ *fiber.Ctx) bool {
+			return true
+		},
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Cookie(&fiber.Cookie{
+			Name:  "test",
+			Value: "SomeThing",
+		})
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "SomeThing", resp.Cookies()[0].Value)
+}
+
+// go test -run Test_Encrypt_Cookie_Except
+func Test_Encrypt_Cookie_Except(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Key: testKey,
+		Except: []string{
+			"test1",
+		},
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Cookie(&fiber.Cookie{
+			Name:  "test1",
+			Value: "SomeThing",
+		})
+		c.Cookie(&fiber.Cookie{
+			Name:  "test2",
+			Value: "SomeThing",
+		})
+		return c.SendString(c.Cookies("test1"))
+	})
+
+	h := app.Handler()
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.Header.SetCookie("test1", "Plain")
+	h(ctx)
+	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
+	utils.AssertEqual(t, "Plain", string(ctx.Response.Body()))
+
+	normalCookie := fasthttp.Cookie{}
+	normalCookie.SetKey("test1")
+	utils.AssertEqual(t, true, ctx.Response.Header.Cookie(&normalCookie), "Get cookie value")
+	utils.AssertEqual(t, "SomeThing", string(normalCookie.Value()))
+
+	encryptedCookie := fasthttp.Cookie{}
+	encryptedCookie.SetKey("test2")
+	utils.AssertEqual(t, true, ctx.Response.Header.Cookie(&encryptedCookie), "Get cookie value")
+	decryptedCookieValue, _ := DecryptCookie(string(encryptedCookie.Value()), testKey)
+	utils.AssertEqual(t, "SomeThing", decryptedCookieValue)
+}
+
+// go test -run Test_Encrypt_Cookie_Custom_Encryptor
+func Test_Encrypt_Cookie_Custom_Encryptor(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Key: "secret",
+		Encryptor: func(decryptedString, _ string) (string, error) {
+			return base64.StdEncoding.EncodeToString([]byte(decryptedString)), nil
+		},
+		Decryptor: func(encryptedString, _ string) (string, error) {
+			decodedBytes, err := base64.StdEncoding.DecodeString(encryptedString)
+			return string(decodedBytes), err
+		},
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("value=" + c.Cookies("test"))
+	})
+	app.Post("/", func(c *fiber.Ctx) error {
+		c.Cookie(&fiber.Cookie{
+			Name:  "test",
+			Value: "SomeThing",
+		})
+		return nil
+	})
+
+	h := app.Handler()
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("POST")
+	h(ctx)
+	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
+
+	encryptedCookie := fasthttp.Cookie{}
+	encryptedCookie.SetKey("test")
+	utils.AssertEqual(t, true, ctx.Response.Header.Cookie(&encryptedCookie), "Get cookie value")
+	utils.AssertEqual(t, base64.StdEncoding.EncodeToString([]byte("SomeThing")), string(encryptedCookie.Value()))
+
+	ctx = &fasthttp.RequestCtx{}
+	ctx.Request.Header.SetMethod("GET")
+	ctx.Request.Header.SetCookie("test", string(encryptedCookie.Value()))
+	h(ctx)
+	utils.AssertEqual(t, 200, ctx.Response.StatusCode())
+	utils.AssertEqual(t, "value=SomeThing", string(ctx.Response.Body()))
+}
+
+// go test -run Test_Encrypt_Cookie_Encryptor_Error
+func Test_Encrypt_Cookie_Encryptor_Error(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Key: "secret",
+		Encryptor: func(_, _ string) (string, error) {
+			return "", errors.New("encryption failed")
+		},
+		Decryptor: DecryptCookie,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Cookie(&fiber.Cookie{
+			Name:  "test",
+			Value: "SomeThing",
+		})
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+	utils.AssertEqual(t, 0, len(resp.Cookies()))
+}
+
+// go test -run Test_Encrypt_Cookie_Invalid_Key
+func Test_Encrypt_Cookie_Invalid_Key(t *testing.T) {
+	for _, cfg := range []Config{{}, {Key: "short"}, {Key: base64.StdEncoding.EncodeToString([]byte("0123456789"))}} {
+		func() {
+			defer func() {
+				utils.AssertEqual(t, true, recover() != nil)
+			}()
+			New(cfg)
+		}()
+	}
+
+	// A missing config panics as well
+	defer func() {
+		utils.AssertEqual(t, "[encryptcookie] Key is required", recover())
+	}()
+	New()
+}
+
+// go test -run Test_GenerateKey
+func Test_GenerateKey(t *testing.T) {
+	key := GenerateKey()
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 32, len(decoded))
+	utils.AssertEqual(t, false, key == GenerateKey())
+}
+
+// go test -run Test_Encrypt_Decrypt_Cookie
+func Test_Encrypt_Decrypt_Cookie(t *testing.T) {
+	encrypted, err := EncryptCookie("SomeThing", testKey)
+	utils.AssertEqual(t, nil, err)
+	other, _ := EncryptCookie("SomeThing", testKey)
+	utils.AssertEqual(t, false, encrypted == other, "nonce must be random")
+
+	decrypted, err := DecryptCookie(encrypted, testKey)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "SomeThing", decrypted)
+
+	_, err = DecryptCookie(encrypted, GenerateKey())
+	utils.AssertEqual(t, true, err != nil)
+	_, err = DecryptCookie("", testKey)
+	utils.AssertEqual(t, errCiphertext, err)
+	_, err = EncryptCookie("SomeThing", "invalid")
+	utils.AssertEqual(t, errInvalidKey, err)
+}
+
+// go test -v -run=^$ -bench=Benchmark_Middleware_EncryptCookie -benchmem -count=4
+func Benchmark_Middleware_EncryptCookie(b *testing.B) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Key: testKey,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Cookie(&fiber.Cookie{
+			Name:  "test",
+			Value: "SomeThing",
+		})
+		return nil
+	})
+
+	h := app.Handler()
+
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.Header.SetMethod("GET")
+	fctx.Request.SetRequestURI("/")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for n := 0; n < b.N; n++ {
+		h(fctx)
+	}
+}
@@ -0,0 +1,89 @@
+package encryptcookie
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+var (
+	errInvalidKey = errors.New("Key must be a base64 encoded 32 byte key")
+	errCiphertext = errors.New("encrypted value is too short")
+)
+
+// GenerateKey returns a random base64 encoded 32 byte key
+func GenerateKey() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// EncryptCookie encrypts the value with AES-256-GCM, the nonce is prepended
+// and the result is base64 URL encoded
+func EncryptCookie(value, key string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(value)+gcm.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(value), nil)), nil
+}
+
+// DecryptCookie decrypts a value encrypted by EncryptCookie
+func DecryptCookie(value, key string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < gcm.NonceSize() {
+		return "", errCiphertext
+	}
+	plain, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// decodeKey decodes the base64 key, it must be 32 bytes long
+func decodeKey(key string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, errInvalidKey
+	}
+	return raw, nil
+}
+
+// newGCM creates the AES-GCM cipher of the key
+func newGCM(key string) (cipher.AEAD, error) {
+	raw, err := decodeKey(key)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// isDisabled reports whether the cookie is in the Except list
+func isDisabled(name string, except []string) bool {
+	for _, e := range except {
+		if name == e {
+			return true
+		}
+	}
+	return false
+}
@@ -24,12 +24,28 @@ type Config struct {
 	//
 	// Optional. Default: nil
 	Next func(c *fiber.Ctx) bool
+
+	// MaxBodySize is the largest response body an ETag is generated for,
+	// larger bodies aren't hashed. Streamed bodies are always skipped.
+	//
+	// Optional. Default: 0 (no limit)
+	MaxBodySize int
+
+	// CurrentETag returns the ETag of the current representation of the
+	// requested resource, or "" if there is none. It enables the If-Match
+	// and If-None-Match preconditions of unsafe requests, which are
+	// answered with 412 Precondition Failed before the handler runs.
+	//
+	// Optional. Default: nil
+	CurrentETag func(c *fiber.Ctx) (string, error)
 }
 
 // ConfigDefault is the default config
 var ConfigDefault = Config{
-	Weak: false,
-	Next: nil,
+	Weak:        false,
+	Next:        nil,
+	MaxBodySize: 0,
+	CurrentETag: nil,
 }
 
 var normalizedHeaderETag = []byte("Etag")
@@ -54,6 +70,22 @@ func New(config ...Config) fiber.Handler {
 			return c.Next()
 		}
 
+		// Only GET and HEAD requests are answered with 304 Not Modified
+		method := c.Method()
+		safe := method == fiber.MethodGet || method == fiber.MethodHead
+
+		// Check the preconditions of unsafe requests before they change
+		// the resource
+		if !safe && cfg.CurrentETag != nil {
+			var passed bool
+			if passed, err = checkPreconditions(c, cfg.CurrentETag); err != nil {
+				return
+			}
+			if !passed {
+				return c.SendStatus(fiber.StatusPreconditionFailed)
+			}
+		}
+
 		// Return err if next handler returns one
 		if err = c.Next(); err != nil {
 			return
@@ -63,72 +95,123 @@ func New(config ...Config) fiber.Handler {
 		if c.Response().StatusCode() != fiber.StatusOK {
 			return
 		}
-		body := c.Response().Body()
-		// Skips ETag if no response body is present
-		if len(body) <= 0 {
-			return
-		}
-
-		// Generate ETag for response
-		bb := bytebufferpool.Get()
-		defer bytebufferpool.Put(bb)
 
-		// Enable weak tag
-		if cfg.Weak {
-			_, _ = bb.Write(weakPrefix)
-		}
+		// Keep the ETag of the handler
+		etag := c.Response().Header.Peek(fiber.HeaderETag)
+		if len(etag) == 0 {
+			// Don't buffer streamed bodies
+			if c.Response().IsBodyStream() {
+				return
+			}
+			body := c.Response().Body()
 
-		_ = bb.WriteByte('"')
-		bb.B = appendUint(bb.Bytes(), uint32(len(body)))
-		_ = bb.WriteByte('-')
-		bb.B = appendUint(bb.Bytes(), crc32.Checksum(body, crc32q))
-		_ = bb.WriteByte('"')
+			// Skips ETag if no response body is present, or it's too large
+			if len(body) <= 0 || (cfg.MaxBodySize > 0 && len(body) > cfg.MaxBodySize) {
+				return
+			}
 
-		etag := bb.Bytes()
+			// Generate ETag for response
+			bb := bytebufferpool.Get()
+			defer bytebufferpool.Put(bb)
 
-		// Get ETag header from request
-		clientEtag := c.Request().Header.Peek(fiber.HeaderIfNoneMatch)
+			// Enable weak tag
+			if cfg.Weak {
+				_, _ = bb.Write(weakPrefix)
+			}
 
-		// Check if client's ETag is weak
-		if bytes.HasPrefix(clientEtag, weakPrefix) {
-			// Check if server's ETag is weak
-			if bytes.Equal(clientEtag[2:], etag) || bytes.Equal(clientEtag[2:], etag[2:]) {
-				// W/1 == 1 || W/1 == W/1
-				c.Context().ResetBody()
+			_ = bb.WriteByte('"')
+			bb.B = appendUint(bb.Bytes(), uint32(len(body)))
+			_ = bb.WriteByte('-')
+			bb.B = appendUint(bb.Bytes(), crc32.Checksum(body, crc32q))
+			_ = bb.WriteByte('"')
 
-				return c.SendStatus(fiber.StatusNotModified)
-			}
-			// W/1 != W/2 || W/1 != 2
+			etag = bb.Bytes()
 			c.Response().Header.SetCanonical(normalizedHeaderETag, etag)
-
-			return
 		}
 
-		if bytes.Contains(clientEtag, etag) {
-			// 1 == 1
+		// Compare with the ETags of the request, weak comparison is used
+		if safe && matchETag(c.Request().Header.Peek(fiber.HeaderIfNoneMatch), etag, false) {
 			c.Context().ResetBody()
-
 			return c.SendStatus(fiber.StatusNotModified)
 		}
-		// 1 != 2
-		c.Response().Header.SetCanonical(normalizedHeaderETag, etag)
-
 		return
 	}
 }
 
+// checkPreconditions reports whether the resource matches If-Match and
+// doesn't match If-None-Match
+func checkPreconditions(c *fiber.Ctx, currentETag func(c *fiber.Ctx) (string, error)) (bool, error) {
+	ifMatch := c.Request().Header.Peek(fiber.HeaderIfMatch)
+	ifNoneMatch := c.Request().Header.Peek(fiber.HeaderIfNoneMatch)
+	if len(ifMatch) == 0 && len(ifNoneMatch) == 0 {
+		return true, nil
+	}
+
+	current, err := currentETag(c)
+	if err != nil {
+		return false, err
+	}
+	etag := []byte(current)
+
+	// If-Match uses the strong comparison, see RFC 7232 section 3.1
+	if len(ifMatch) > 0 && !matchETag(ifMatch, etag, true) {
+		return false, nil
+	}
+	if len(ifNoneMatch) > 0 && matchETag(ifNoneMatch, etag, false) {
+		return false, nil
+	}
+	return true, nil
+}
+
+// matchETag reports whether the etag is in the comma separated list of the
+// header, "*" matches any existing etag. The strong comparison doesn't
+// match weak etags, the weak one ignores the W/ prefixes.
+func matchETag(header, etag []byte, strong bool) bool {
+	if len(header) == 0 || len(etag) == 0 {
+		return false
+	}
+	if strong && bytes.HasPrefix(etag, weakPrefix) {
+		return false
+	}
+	etag = bytes.TrimPrefix(etag, weakPrefix)
+	for len(header) > 0 {
+		var tag []byte
+		if i := bytes.IndexByte(header, ','); i != -1 {
+			tag, header = header[:i], header[i+1:]
+		} else {
+			tag, header = header, nil
+		}
+		tag = bytes.TrimSpace(tag)
+		if len(tag) == 1 && tag[0] == '*' {
+			return true
+		}
+		if bytes.HasPrefix(tag, weakPrefix) {
+			if strong {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if bytes.Equal(tag, etag) {
+			return true
+		}
+	}
+	return false
+}
+
 // appendUint appends n to dst and returns the extended dst.
 func appendUint(dst []byte, n uint32) []byte {
 	var b [20]byte
 	buf := b[:]
 	i := len(buf)
 	var q uint32
+
 	for n >= 10 {
 		i--
 		q = n / 10
 		buf[i] = '0' + byte(n-q*10)
 		n = q
 	}
+
 	i--
 	buf[i] = '0' + byte(n)
 
@@ -1,6 +1,7 @@
 package etag
 
 import (
+	"bytes"
 	"io/ioutil"
 	"net/http/httptest"
 	"testing"
@@ -192,3 +193,198 @@ func Benchmark_Etag(b *testing.B) {
 	utils.AssertEqual(b, 200, fctx.Response.Header.StatusCode())
 	utils.AssertEqual(b, `"13-1831710635"`, string(fctx.Response.Header.Peek(fiber.HeaderETag)))
 }
+
+// go test -run Test_ETag_Head
+func Test_ETag_Head(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+
+	get, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `"13-1831710635"`, get.Header.Get(fiber.HeaderETag))
+
+	// HEAD requests get the ETag of the GET response
+	resp, err := app.Test(httptest.NewRequest("HEAD", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, get.Header.Get(fiber.HeaderETag), resp.Header.Get(fiber.HeaderETag))
+
+	req := httptest.NewRequest("HEAD", "/", nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, get.Header.Get(fiber.HeaderETag))
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNotModified, resp.StatusCode)
+}
+
+// go test -run Test_ETag_IfNoneMatch
+func Test_ETag_IfNoneMatch(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	app.All("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+
+	for _, tt := range []struct {
+		method      string
+		ifNoneMatch string
+		status      int
+	}{
+		{"GET", `"13-1831710635"`, fiber.StatusNotModified},
+		{"GET", `W/"13-1831710635"`, fiber.StatusNotModified},
+		{"GET", `"other", "13-1831710635"`, fiber.StatusNotModified},
+		{"GET", `"other",W/"13-1831710635"`, fiber.StatusNotModified},
+		{"GET", `*`, fiber.StatusNotModified},
+		{"GET", `"other", "13-1831710635-x"`, fiber.StatusOK},
+		{"GET", `"13-1831710635`, fiber.StatusOK},
+		{"HEAD", `"13-1831710635"`, fiber.StatusNotModified},
+		// Unsafe methods are never answered with 304
+		{"POST", `"13-1831710635"`, fiber.StatusOK},
+		{"PUT", `*`, fiber.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, "/", nil)
+		req.Header.Set(fiber.HeaderIfNoneMatch, tt.ifNoneMatch)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tt.status, resp.StatusCode, tt.method+" "+tt.ifNoneMatch)
+		utils.AssertEqual(t, `"13-1831710635"`, resp.Header.Get(fiber.HeaderETag))
+	}
+}
+
+// go test -run Test_ETag_WeakEtag_StrongClient
+func Test_ETag_WeakEtag_StrongClient(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{Weak: true}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+
+	// Weak comparison ignores the W/ prefix
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, `"13-1831710635"`)
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNotModified, resp.StatusCode)
+	utils.AssertEqual(t, `W/"13-1831710635"`, resp.Header.Get(fiber.HeaderETag))
+}
+
+// go test -run Test_ETag_Handler_Etag
+func Test_ETag_Handler_Etag(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderETag, `"v2"`)
+		return c.SendString("Hello, World!")
+	})
+
+	// The ETag of the handler isn't overridden
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, `"v2"`, resp.Header.Get(fiber.HeaderETag))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderIfNoneMatch, `W/"v2"`)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNotModified, resp.StatusCode)
+}
+
+// go test -run Test_ETag_Skip_Large_And_Stream
+func Test_ETag_Skip_Large_And_Stream(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		MaxBodySize: 13,
+	}))
+
+	app.Get("/small", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+	app.Get("/large", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World!!")
+	})
+	app.Get("/stream", func(c *fiber.Ctx) error {
+		return c.SendStream(bytes.NewReader([]byte("Hello")))
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/small", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, `"13-1831710635"`, resp.Header.Get(fiber.HeaderETag))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/large", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderETag))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/stream", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderETag))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "Hello", string(body))
+}
+
+// go test -run Test_ETag_Preconditions
+func Test_ETag_Preconditions(t *testing.T) {
+	app := fiber.New()
+
+	current := `"v1"`
+	var updates int
+	app.Use(New(Config{
+		CurrentETag: func(c *fiber.Ctx) (string, error) {
+			if c.Path() == "/missing" {
+				return "", nil
+			}
+			if c.Path() == "/error" {
+				return "", fiber.ErrServiceUnavailable
+			}
+			return current, nil
+		},
+	}))
+
+	app.All("/*", func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet {
+			updates++
+		}
+		return c.SendString("updated")
+	})
+
+	for _, tt := range []struct {
+		method, path, header, value string
+		status                      int
+	}{
+		{"PUT", "/", fiber.HeaderIfMatch, `"v1"`, fiber.StatusOK},
+		{"PUT", "/", fiber.HeaderIfMatch, `"v0", "v1"`, fiber.StatusOK},
+		{"PUT", "/", fiber.HeaderIfMatch, `*`, fiber.StatusOK},
+		{"PUT", "/", fiber.HeaderIfMatch, `"v0"`, fiber.StatusPreconditionFailed},
+		// If-Match uses the strong comparison
+		{"PUT", "/", fiber.HeaderIfMatch, `W/"v1"`, fiber.StatusPreconditionFailed},
+		{"DELETE", "/missing", fiber.HeaderIfMatch, `*`, fiber.StatusPreconditionFailed},
+		{"POST", "/", fiber.HeaderIfNoneMatch, `*`, fiber.StatusPreconditionFailed},
+		{"POST", "/", fiber.HeaderIfNoneMatch, `W/"v1"`, fiber.StatusPreconditionFailed},
+		{"POST", "/missing", fiber.HeaderIfNoneMatch, `*`, fiber.StatusOK},
+		{"POST", "/error", fiber.HeaderIfMatch, `*`, fiber.StatusServiceUnavailable},
+		// Safe methods aren't checked before the handler
+		{"GET", "/", fiber.HeaderIfMatch, `"v0"`, fiber.StatusOK},
+	} {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set(tt.header, tt.value)
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tt.status, resp.StatusCode, tt.method+" "+tt.path+" "+tt.value)
+	}
+
+	// Failed preconditions don't reach the handler
+	utils.AssertEqual(t, 4, updates)
+}
@@ -2,9 +2,14 @@ package favicon
 
 import (
 	"io/ioutil"
+	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
 )
 
 // Config defines the config for middleware.
@@ -14,11 +19,23 @@ type Config struct {
 	// Optional. Default: nil
 	Next func(c *fiber.Ctx) bool
 
-	// File holds the path to an actual favicon that will be cached
+	// File holds the path to an actual favicon that will be cached,
+	// it is opened in the FileSystem if one is provided
 	//
 	// Optional. Default: ""
 	File string
 
+	// URL of the favicon
+	//
+	// Optional. Default: "/favicon.ico"
+	URL string
+
+	// FileSystem to read the File from instead of the disk, use http.FS
+	// or NewFS for a fs.FS such as an embed.FS
+	//
+	// Optional. Default: nil
+	FileSystem http.FileSystem
+
 	// CacheControl defines how the Cache-Control header in the response should be set
 	//
 	// Optional. Default: "public, max-age=31536000"
@@ -29,6 +46,7 @@ type Config struct {
 var ConfigDefault = Config{
 	Next:         nil,
 	File:         "",
+	URL:          "/favicon.ico",
 	CacheControl: "public, max-age=31536000",
 }
 
@@ -36,8 +54,12 @@ const (
 	hType  = "image/x-icon"
 	hAllow = "GET, HEAD, OPTIONS"
 	hZero  = "0"
+	hGzip  = "gzip"
 )
 
+// The gzipped icon is only served if it is smaller than this ratio of the icon
+const minCompressRatio = 0.8
+
 // New creates a new middleware handler
 func New(config ...Config) fiber.Handler {
 	// Set default config
@@ -54,22 +76,34 @@ func New(config ...Config) fiber.Handler {
 		if cfg.File == "" {
 			cfg.File = ConfigDefault.File
 		}
+		if cfg.URL == "" {
+			cfg.URL = ConfigDefault.URL
+		} else if !strings.HasPrefix(cfg.URL, "/") {
+			cfg.URL = "/" + cfg.URL
+		}
 		if cfg.CacheControl == "" {
 			cfg.CacheControl = ConfigDefault.CacheControl
 		}
 	}
 
-	// Load icon if provided
+	// Load and compress icon if provided
 	var (
-		err     error
-		icon    []byte
-		iconLen string
+		icon     []byte
+		iconLen  string
+		iconType = hType
+		gzipped  []byte
+		gzipLen  string
 	)
 	if cfg.File != "" {
-		if icon, err = ioutil.ReadFile(cfg.File); err != nil {
-			panic(err)
-		}
+		icon = readIcon(cfg.FileSystem, cfg.File)
 		iconLen = strconv.Itoa(len(icon))
+		if ext := filepath.Ext(cfg.File); ext != "" && !strings.EqualFold(ext, ".ico") {
+			iconType = utils.GetMIME(strings.ToLower(ext))
+		}
+		if zbody := fasthttp.AppendGzipBytes(nil, icon); float64(len(zbody)) < float64(len(icon))*minCompressRatio {
+			gzipped = zbody
+			gzipLen = strconv.Itoa(len(gzipped))
+		}
 	}
 
 	// Return new handler
@@ -80,7 +114,7 @@ func New(config ...Config) fiber.Handler {
 		}
 
 		// Only respond to favicon requests
-		if len(c.Path()) != 12 || c.Path() != "/favicon.ico" {
+		if len(c.Path()) != len(cfg.URL) || c.Path() != cfg.URL {
 			return c.Next()
 		}
 
@@ -98,12 +132,45 @@ func New(config ...Config) fiber.Handler {
 
 		// Serve cached favicon
 		if len(icon) > 0 {
-			c.Set(fiber.HeaderContentLength, iconLen)
-			c.Set(fiber.HeaderContentType, hType)
+			c.Set(fiber.HeaderContentType, iconType)
 			c.Set(fiber.HeaderCacheControl, cfg.CacheControl)
+			if gzipped != nil {
+				c.Vary(fiber.HeaderAcceptEncoding)
+				if len(c.Get(fiber.HeaderAcceptEncoding)) > 0 && c.AcceptsEncodings(hGzip) == hGzip {
+					c.Set(fiber.HeaderContentEncoding, hGzip)
+					c.Set(fiber.HeaderContentLength, gzipLen)
+					return c.Status(fiber.StatusOK).Send(gzipped)
+				}
+			}
+			c.Set(fiber.HeaderContentLength, iconLen)
 			return c.Status(fiber.StatusOK).Send(icon)
 		}
 
 		return c.SendStatus(fiber.StatusNoContent)
 	}
 }
+
+// readIcon reads the file from the disk or the file system, it panics if
+// the file can't be read
+func readIcon(fs http.FileSystem, name string) []byte {
+	if fs == nil {
+		icon, err := ioutil.ReadFile(name)
+		if err != nil {
+			panic(err)
+		}
+		return icon
+	}
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	f, err := fs.Open(name)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+	icon, err := ioutil.ReadAll(f)
+	if err != nil {
+		panic(err)
+	}
+	return icon
+}
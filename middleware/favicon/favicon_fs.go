@@ -0,0 +1,23 @@
+//go:build go1.16
+// +build go1.16
+
+package favicon
+
+import (
+	"io/fs"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// NewFS creates a new middleware handler serving the file of fsys, such as
+// an embed.FS. It replaces the File and FileSystem of the config.
+func NewFS(fsys fs.FS, file string, config ...Config) fiber.Handler {
+	cfg := ConfigDefault
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	cfg.File = file
+	cfg.FileSystem = http.FS(fsys)
+	return New(cfg)
+}
@@ -0,0 +1,58 @@
+//go:build go1.16
+// +build go1.16
+
+package favicon
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// go test -run Test_Middleware_Favicon_NewFS
+func Test_Middleware_Favicon_NewFS(t *testing.T) {
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><circle r="8"/></svg>`)
+	fsys := fstest.MapFS{
+		"static/favicon.svg": {Data: svg},
+		"static/favicon.png": {Data: []byte("png")},
+	}
+
+	app := fiber.New()
+	app.Use(NewFS(fsys, "static/favicon.svg", Config{
+		URL:          "/favicon.svg",
+		CacheControl: "public, max-age=100",
+	}))
+	app.Use(NewFS(fsys, "static/favicon.png", Config{
+		URL: "/favicon.png",
+	}))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/favicon.svg", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "image/svg+xml", resp.Header.Get(fiber.HeaderContentType))
+	utils.AssertEqual(t, "public, max-age=100", resp.Header.Get(fiber.HeaderCacheControl))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, svg, body)
+
+	resp, err = app.Test(httptest.NewRequest("HEAD", "/favicon.png", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "image/png", resp.Header.Get(fiber.HeaderContentType))
+	utils.AssertEqual(t, "public, max-age=31536000", resp.Header.Get(fiber.HeaderCacheControl))
+}
+
+// go test -run Test_Middleware_Favicon_NewFS_Not_Found
+func Test_Middleware_Favicon_NewFS_Not_Found(t *testing.T) {
+	defer func() {
+		if err := recover(); err == nil {
+			t.Fatal("should cache panic")
+		}
+	}()
+
+	NewFS(fstest.MapFS{}, "favicon.ico")
+}
@@ -1,7 +1,11 @@
 package favicon
 
 import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
@@ -118,3 +122,82 @@ func Test_Favicon_Next(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
 }
+
+// go test -run Test_Middleware_Favicon_URL
+func Test_Middleware_Favicon_URL(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		File: "../../.github/testdata/favicon.ico",
+		URL:  "static/favicon.ico",
+	}))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/static/favicon.ico", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "image/x-icon", resp.Header.Get(fiber.HeaderContentType))
+
+	// The default URL isn't served anymore
+	resp, err = app.Test(httptest.NewRequest("GET", "/favicon.ico", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode, "Status code")
+
+	resp, err = app.Test(httptest.NewRequest("POST", "/static/favicon.ico", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusMethodNotAllowed, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "GET, HEAD, OPTIONS", resp.Header.Get(fiber.HeaderAllow))
+}
+
+// go test -run Test_Middleware_Favicon_FileSystem
+func Test_Middleware_Favicon_FileSystem(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		File:       "favicon.ico",
+		FileSystem: http.Dir("../../.github/testdata"),
+	}))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/favicon.ico", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "image/x-icon", resp.Header.Get(fiber.HeaderContentType))
+
+	icon, err := ioutil.ReadFile("../../.github/testdata/favicon.ico")
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, icon, body)
+}
+
+// go test -run Test_Middleware_Favicon_Gzip
+func Test_Middleware_Favicon_Gzip(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		File: "../../.github/testdata/favicon.ico",
+	}))
+
+	icon, err := ioutil.ReadFile("../../.github/testdata/favicon.ico")
+	utils.AssertEqual(t, nil, err)
+
+	req := httptest.NewRequest("GET", "/favicon.ico", nil)
+	req.Header.Set(fiber.HeaderAcceptEncoding, "gzip, deflate")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "gzip", resp.Header.Get(fiber.HeaderContentEncoding))
+	utils.AssertEqual(t, fiber.HeaderAcceptEncoding, resp.Header.Get(fiber.HeaderVary))
+	utils.AssertEqual(t, "image/x-icon", resp.Header.Get(fiber.HeaderContentType))
+
+	zr, err := gzip.NewReader(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	body, err := ioutil.ReadAll(zr)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, icon, body)
+
+	// Clients without gzip support get the plain icon
+	resp, err = app.Test(httptest.NewRequest("GET", "/favicon.ico", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "", resp.Header.Get(fiber.HeaderContentEncoding))
+	utils.AssertEqual(t, strconv.Itoa(len(icon)), resp.Header.Get(fiber.HeaderContentLength))
+}
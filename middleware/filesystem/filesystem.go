@@ -3,6 +3,7 @@ package filesystem
 import (
 	"net/http"
 	"os"
+	pathpkg "path"
 	"strconv"
 	"strings"
 	"sync"
@@ -43,6 +44,20 @@ type Config struct {
 	//
 	// Optional. Default: ""
 	NotFoundFile string `json:"not_found_file"`
+
+	// NotFoundFileWithExtensions serves the NotFoundFile for missing paths
+	// with a file extension as well, i.e. "/app.js". By default they are
+	// answered with 404, so missing assets aren't replaced by the SPA.
+	//
+	// Optional. Default: false
+	NotFoundFileWithExtensions bool `json:"not_found_file_with_extensions"`
+
+	// PathPrefix is prepended to the request path and the NotFoundFile
+	// before they are opened in the Root, i.e. "build" for an embed.FS
+	// whose files keep the "build/" prefix.
+	//
+	// Optional. Default: ""
+	PathPrefix string `json:"path_prefix"`
 }
 
 // ConfigDefault is the default config
@@ -73,6 +88,16 @@ func New(config ...Config) fiber.Handler {
 		if cfg.NotFoundFile != "" && !strings.HasPrefix(cfg.NotFoundFile, "/") {
 			cfg.NotFoundFile = "/" + cfg.NotFoundFile
 		}
+		// Prefixes naming the Root itself like "/" or "./" are dropped,
+		// paths like "//index.html" are invalid for an http.FS
+		if cfg.PathPrefix = pathpkg.Clean("/" + cfg.PathPrefix); cfg.PathPrefix == "/" {
+			cfg.PathPrefix = ""
+		}
+		if cfg.PathPrefix != "" {
+			if cfg.NotFoundFile != "" {
+				cfg.NotFoundFile = cfg.PathPrefix + cfg.NotFoundFile
+			}
+		}
 	}
 
 	if cfg.Root == nil {
@@ -108,13 +133,20 @@ func New(config ...Config) fiber.Handler {
 			path = "/" + path
 		}
 
+		// Look the path up below the PathPrefix of the Root, the cleaned path
+		// can't leave it and has no trailing slash, which is invalid for an http.FS
+		if cfg.PathPrefix != "" {
+			path = pathpkg.Join(cfg.PathPrefix, pathpkg.Clean(path))
+		}
+
 		var (
 			file http.File
 			stat os.FileInfo
 		)
 
 		file, err = cfg.Root.Open(path)
-		if err != nil && os.IsNotExist(err) && cfg.NotFoundFile != "" {
+		if err != nil && os.IsNotExist(err) && cfg.NotFoundFile != "" &&
+			(cfg.NotFoundFileWithExtensions || getFileExtension(pathpkg.Base(path)) == "") {
 			file, err = cfg.Root.Open(cfg.NotFoundFile)
 		}
 
@@ -0,0 +1,57 @@
+//go:build go1.16
+// +build go1.16
+
+package filesystem
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// go test -run Test_FileSystem_PathPrefix_FS
+func Test_FileSystem_PathPrefix_FS(t *testing.T) {
+	app := fiber.New()
+
+	app.Use("/root", New(Config{
+		Root:       http.FS(os.DirFS("../../.github/testdata/fs")),
+		PathPrefix: "/",
+	}))
+	app.Use("/dot", New(Config{
+		Root:         http.FS(os.DirFS("../../.github/testdata/fs")),
+		PathPrefix:   "./",
+		NotFoundFile: "index.html",
+	}))
+	app.Use("/prefix", New(Config{
+		Root:       http.FS(os.DirFS("../../.github")),
+		PathPrefix: "./testdata/fs",
+	}))
+
+	tests := []struct {
+		url         string
+		statusCode  int
+		contentType string
+	}{
+		{"/root", 200, "text/html"},
+		{"/root/css/style.css", 200, "text/css"},
+		{"/root/css/missing.css", 404, ""},
+		{"/dot", 200, "text/html"},
+		{"/dot/users/42", 200, "text/html"},
+		{"/prefix", 200, "text/html"},
+		{"/prefix/img/fiber.png", 200, "image/png"},
+		{"/prefix/missing", 404, ""},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.url, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tt.statusCode, resp.StatusCode, tt.url)
+		if tt.contentType != "" {
+			utils.AssertEqual(t, tt.contentType, resp.Header.Get(fiber.HeaderContentType), tt.url)
+		}
+	}
+}
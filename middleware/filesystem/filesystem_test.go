@@ -187,3 +187,64 @@ func Test_FileSystem_UsingParam_NonFile(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, 404, resp.StatusCode)
 }
+
+// go test -run Test_FileSystem_PathPrefix
+func Test_FileSystem_PathPrefix(t *testing.T) {
+	app := fiber.New()
+
+	// The files keep the "testdata/fs/" prefix, like an embed.FS
+	app.Use("/spa", New(Config{
+		Root:         http.Dir("../../.github"),
+		PathPrefix:   "testdata/fs",
+		NotFoundFile: "index.html",
+	}))
+	app.Use("/browse", New(Config{
+		Root:       http.Dir("../../.github"),
+		PathPrefix: "/testdata/fs/",
+		Browse:     true,
+	}))
+
+	tests := []struct {
+		url         string
+		statusCode  int
+		contentType string
+	}{
+		{"/spa", 200, "text/html"},
+		{"/spa/css/style.css", 200, "text/css"},
+		{"/spa/img/fiber.png", 200, "image/png"},
+		// Client side routes get the NotFoundFile
+		{"/spa/users/42", 200, "text/html"},
+		// Missing assets aren't replaced
+		{"/spa/css/missing.css", 404, ""},
+		{"/spa/testdata/fs/index.html", 404, ""},
+		// Directories still honor Index and Browse
+		{"/browse", 200, "text/html"},
+		{"/browse/img", 200, "text/html"},
+		{"/browse/missing", 404, ""},
+	}
+
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.url, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tt.statusCode, resp.StatusCode, tt.url)
+		if tt.contentType != "" {
+			utils.AssertEqual(t, tt.contentType, resp.Header.Get(fiber.HeaderContentType), tt.url)
+		}
+	}
+}
+
+// go test -run Test_FileSystem_NotFoundFileWithExtensions
+func Test_FileSystem_NotFoundFileWithExtensions(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Root:                       http.Dir("../../.github/testdata/fs"),
+		NotFoundFile:               "index.html",
+		NotFoundFileWithExtensions: true,
+	}))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/missing.js", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "text/html", resp.Header.Get(fiber.HeaderContentType))
+}
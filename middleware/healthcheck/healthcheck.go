@@ -0,0 +1,47 @@
+package healthcheck
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// New creates a new middleware handler
+func New(config ...Config) fiber.Handler {
+	// Set default config
+	cfg := configDefault(config...)
+
+	liveness := strings.TrimRight(cfg.LivenessEndpoint, "/")
+	readiness := strings.TrimRight(cfg.ReadinessEndpoint, "/")
+
+	// Return new handler
+	return func(c *fiber.Ctx) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Probes only answer GET and HEAD
+		if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+			return c.Next()
+		}
+
+		var probe HealthChecker
+		switch strings.TrimRight(c.Path(), "/") {
+		case liveness:
+			probe = cfg.LivenessProbe
+		case readiness:
+			probe = cfg.ReadinessProbe
+		default:
+			return c.Next()
+		}
+
+		// Probes are polled constantly, keep them out of the logs
+		c.Locals(fiber.SkipLogKey, true)
+
+		if probe(c) {
+			return c.SendStatus(fiber.StatusOK)
+		}
+		return c.SendStatus(fiber.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,115 @@
+package healthcheck
+
+import (
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
+	"github.com/gofiber/fiber/v2/middleware/logger"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// go test -run Test_HealthCheck
+func Test_HealthCheck(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/livez", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("HEAD", "/readyz/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	// Other methods and paths are passed on
+	resp, err = app.Test(httptest.NewRequest("POST", "/livez", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+// go test -run Test_HealthCheck_Next
+func Test_HealthCheck_Next(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Next: func(_ *fiber.Ctx) bool {
+			return true
+		},
+	}))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/livez", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
+}
+
+// go test -run Test_HealthCheck_Probes
+func Test_HealthCheck_Probes(t *testing.T) {
+	app := fiber.New()
+
+	var ready int32
+	app.Use(New(Config{
+		LivenessEndpoint:  "/live",
+		ReadinessEndpoint: "/ready",
+		ReadinessProbe: func(_ *fiber.Ctx) bool {
+			return atomic.LoadInt32(&ready) == 1
+		},
+	}))
+
+	// The probes short-circuit the stack
+	var hits int32
+	app.Use(func(c *fiber.Ctx) error {
+		atomic.AddInt32(&hits, 1)
+		return c.Next()
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/ready", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	atomic.StoreInt32(&ready, 1)
+	resp, err = app.Test(httptest.NewRequest("GET", "/ready", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/live", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, int32(0), atomic.LoadInt32(&hits))
+
+	// The default endpoints are replaced
+	resp, err = app.Test(httptest.NewRequest("GET", "/livez", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
+	utils.AssertEqual(t, int32(1), atomic.LoadInt32(&hits))
+}
+
+// go test -run Test_HealthCheck_Logger
+func Test_HealthCheck_Logger(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	app.Use(logger.New(logger.Config{
+		Format: "${path}",
+		Output: buf,
+	}))
+	app.Use(New())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/livez", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "", buf.String())
+
+	_, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/", buf.String())
+}
@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Config defines the config for middleware.
+type Config struct {
+	// Next defines a function to skip this middleware when returned true.
+	//
+	// Optional. Default: nil
+	Next func(c *fiber.Ctx) bool
+
+	// Lifetime is how long a response is replayed for its key
+	//
+	// Optional. Default: 30 * time.Minute
+	Lifetime time.Duration
+
+	// KeyHeader is the request header which holds the idempotency key,
+	// requests without it bypass the middleware
+	//
+	// Optional. Default: "Idempotency-Key"
+	KeyHeader string
+
+	// MaxKeyLength is the longest key that is accepted, requests with a
+	// longer key bypass the middleware
+	//
+	// Optional. Default: 64
+	MaxKeyLength int
+
+	// KeepResponseHeaders are the response headers which are stored and
+	// replayed, nil keeps all of them
+	//
+	// Optional. Default: nil
+	KeepResponseHeaders []string
+
+	// Lock serializes the requests of the same key, requests with other
+	// keys never wait on each other
+	//
+	// Optional. Default: an in memory lock for this process only
+	Lock Locker
+
+	// Storage is used to store the responses
+	//
+	// Optional. Default: memory.New()
+	Storage fiber.Storage
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Next:                nil,
+	Lifetime:            30 * time.Minute,
+	KeyHeader:           "Idempotency-Key",
+	MaxKeyLength:        64,
+	KeepResponseHeaders: nil,
+	Lock:                nil,
+	Storage:             nil,
+}
+
+// Helper function to set default values
+func configDefault(config ...Config) Config {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		return ConfigDefault
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Lifetime <= 0 {
+		cfg.Lifetime = ConfigDefault.Lifetime
+	}
+	if cfg.KeyHeader == "" {
+		cfg.KeyHeader = ConfigDefault.KeyHeader
+	}
+	if cfg.MaxKeyLength <= 0 {
+		cfg.MaxKeyLength = ConfigDefault.MaxKeyLength
+	}
+	return cfg
+}
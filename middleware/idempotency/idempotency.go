@@ -0,0 +1,146 @@
+package idempotency
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/internal/storage/memory"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// HeaderReplayed is set to "true" on responses which are replayed
+const HeaderReplayed = "X-Idempotent-Replayed"
+
+// Storage ErrNotExist
+const errNotExist = "key does not exist"
+
+// New creates a new middleware handler
+func New(config ...Config) fiber.Handler {
+	// Set default config
+	cfg := configDefault(config...)
+
+	// Set default values
+	if cfg.Storage == nil {
+		cfg.Storage = memory.New()
+	}
+	if cfg.Lock == nil {
+		cfg.Lock = NewMemoryLock()
+	}
+
+	// Only the selected headers are kept, nil keeps all of them
+	var keepHeaders map[string]struct{}
+	if cfg.KeepResponseHeaders != nil {
+		keepHeaders = make(map[string]struct{}, len(cfg.KeepResponseHeaders))
+		for _, h := range cfg.KeepResponseHeaders {
+			keepHeaders[strings.ToLower(h)] = struct{}{}
+		}
+	}
+
+	// Return new handler
+	return func(c *fiber.Ctx) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Safe methods can be retried anyway
+		if isSafeMethod(c.Method()) {
+			return c.Next()
+		}
+
+		// Requests without a valid key aren't tracked
+		key := c.Get(cfg.KeyHeader)
+		if key == "" || len(key) > cfg.MaxKeyLength {
+			return c.Next()
+		}
+		key = utils.CopyString(key)
+
+		// Replay the response of a finished request
+		if res, err := load(cfg.Storage, key); err != nil {
+			return err
+		} else if res != nil {
+			return replay(c, res)
+		}
+
+		// Wait for a running request of the same key
+		if err := cfg.Lock.Lock(key); err != nil {
+			return err
+		}
+		defer func() {
+			_ = cfg.Lock.Unlock(key)
+		}()
+
+		// The request may have finished while waiting
+		if res, err := load(cfg.Storage, key); err != nil {
+			return err
+		} else if res != nil {
+			return replay(c, res)
+		}
+
+		// Continue stack, failed requests can be retried
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		// Store the response, it's copied by MarshalMsg
+		res := response{
+			status:  c.Response().StatusCode(),
+			headers: make(map[string][]byte),
+			body:    c.Response().Body(),
+		}
+		c.Response().Header.VisitAll(func(name, value []byte) {
+			h := string(name)
+			if h == fiber.HeaderContentLength {
+				return
+			}
+			if keepHeaders != nil {
+				if _, ok := keepHeaders[strings.ToLower(h)]; !ok {
+					return
+				}
+			}
+			res.headers[h] = value
+		})
+		data, err := res.MarshalMsg(nil)
+		if err != nil {
+			return err
+		}
+		return cfg.Storage.Set(key, data, cfg.Lifetime)
+	}
+}
+
+// load returns the stored response of the key, or nil if there is none
+func load(storage fiber.Storage, key string) (*response, error) {
+	data, err := storage.Get(key)
+	if err != nil && err.Error() != errNotExist {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	res := &response{}
+	if _, err = res.UnmarshalMsg(data); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// replay writes the stored response
+func replay(c *fiber.Ctx, res *response) error {
+	c.Status(res.status)
+	for name, value := range res.headers {
+		c.Response().Header.SetBytesV(name, value)
+	}
+	c.Set(HeaderReplayed, "true")
+	c.Response().SetBodyRaw(res.body)
+	return nil
+}
+
+// isSafeMethod reports whether the method doesn't change the state of the
+// server, see RFC 7231 section 4.2.1
+func isSafeMethod(method string) bool {
+	switch method {
+	case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions, fiber.MethodTrace:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,259 @@
+package idempotency
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+func newRequest(method, key string) *http.Request {
+	req := httptest.NewRequest(method, "/", nil)
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	return req
+}
+
+// go test -run Test_Idempotency
+func Test_Idempotency(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	var hits int32
+	app.All("/", func(c *fiber.Ctx) error {
+		n := atomic.AddInt32(&hits, 1)
+		c.Set("X-Hit", strconv.Itoa(int(n)))
+		return c.Status(fiber.StatusCreated).SendString("charged " + strconv.Itoa(int(n)))
+	})
+
+	resp, err := app.Test(newRequest("POST", "key-1"))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusCreated, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderReplayed))
+
+	resp, err = app.Test(newRequest("POST", "key-1"))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusCreated, resp.StatusCode)
+	utils.AssertEqual(t, "true", resp.Header.Get(HeaderReplayed))
+	utils.AssertEqual(t, "1", resp.Header.Get("X-Hit"))
+	utils.AssertEqual(t, fiber.MIMETextPlainCharsetUTF8, resp.Header.Get(fiber.HeaderContentType))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "charged 1", string(body))
+
+	// Another key executes the handler
+	resp, err = app.Test(newRequest("POST", "key-2"))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderReplayed))
+	utils.AssertEqual(t, "2", resp.Header.Get("X-Hit"))
+
+	// Safe methods, missing and too long keys bypass the middleware
+	for _, req := range []*http.Request{
+		newRequest("GET", "key-1"),
+		newRequest("POST", ""),
+		newRequest("POST", strings.Repeat("k", 65)),
+	} {
+		resp, err = app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, "", resp.Header.Get(HeaderReplayed))
+	}
+	utils.AssertEqual(t, int32(5), atomic.LoadInt32(&hits))
+}
+
+// go test -run Test_Idempotency_Next
+func Test_Idempotency_Next(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Next: func(_ *fiber.Ctx) bool {
+			return true
+		},
+	}))
+
+	var hits int32
+	app.Post("/", func(c *fiber.Ctx) error {
+		atomic.AddInt32(&hits, 1)
+		return nil
+	})
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(newRequest("POST", "key"))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, "", resp.Header.Get(HeaderReplayed))
+	}
+	utils.AssertEqual(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+// go test -run Test_Idempotency_Error
+func Test_Idempotency_Error(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	var hits int32
+	app.Post("/", func(c *fiber.Ctx) error {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			return errors.New("timeout")
+		}
+		return c.SendString("done")
+	})
+
+	// Failed requests aren't stored, the retry executes again
+	resp, err := app.Test(newRequest("POST", "key"))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	resp, err = app.Test(newRequest("POST", "key"))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderReplayed))
+	utils.AssertEqual(t, int32(2), atomic.LoadInt32(&hits))
+}
+
+// go test -run Test_Idempotency_KeepResponseHeaders
+func Test_Idempotency_KeepResponseHeaders(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		KeepResponseHeaders: []string{"x-keep"},
+	}))
+
+	app.Post("/", func(c *fiber.Ctx) error {
+		c.Set("X-Keep", "1")
+		c.Set("X-Drop", "1")
+		return nil
+	})
+
+	_, err := app.Test(newRequest("POST", "key"))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+
+	resp, err := app.Test(newRequest("POST", "key"))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "true", resp.Header.Get(HeaderReplayed))
+	utils.AssertEqual(t, "1", resp.Header.Get("X-Keep"))
+	utils.AssertEqual(t, "", resp.Header.Get("X-Drop"))
+}
+
+// go test -run Test_Idempotency_Concurrent -race
+func Test_Idempotency_Concurrent(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Lifetime: time.Minute,
+	}))
+
+	var hits int32
+	app.Post("/", func(c *fiber.Ctx) error {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(50 * time.Millisecond)
+		return c.SendString("done")
+	})
+
+	var (
+		wg       sync.WaitGroup
+		replayed int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := app.Test(newRequest("POST", "key"))
+			utils.AssertEqual(t, nil, err, "app.Test(req)")
+			utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+			if resp.Header.Get(HeaderReplayed) == "true" {
+				atomic.AddInt32(&replayed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	utils.AssertEqual(t, int32(1), atomic.LoadInt32(&hits))
+	utils.AssertEqual(t, int32(9), atomic.LoadInt32(&replayed))
+}
+
+// go test -run Test_Idempotency_Unrelated_Keys
+func Test_Idempotency_Unrelated_Keys(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New())
+
+	release := make(chan struct{})
+	app.Post("/", func(c *fiber.Ctx) error {
+		if c.Get("Idempotency-Key") == "slow" {
+			<-release
+		}
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		_, err := app.Test(newRequest("POST", "slow"), -1)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		close(done)
+	}()
+
+	// Another key doesn't wait on the running request
+	resp, err := app.Test(newRequest("POST", "fast"))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	close(release)
+	<-done
+}
+
+// go test -run Test_MemoryLock
+func Test_MemoryLock(t *testing.T) {
+	lock := NewMemoryLock()
+
+	utils.AssertEqual(t, nil, lock.Lock("a"))
+	utils.AssertEqual(t, nil, lock.Lock("b"))
+
+	locked := make(chan struct{})
+	go func() {
+		_ = lock.Lock("a")
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("the key must stay locked")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	utils.AssertEqual(t, nil, lock.Unlock("a"))
+	<-locked
+	utils.AssertEqual(t, nil, lock.Unlock("a"))
+	utils.AssertEqual(t, nil, lock.Unlock("b"))
+
+	// Released keys are removed
+	utils.AssertEqual(t, 0, len(lock.locks))
+	utils.AssertEqual(t, nil, lock.Unlock("c"))
+}
+
+// go test -run Test_Response_Msgp
+func Test_Response_Msgp(t *testing.T) {
+	res := response{
+		status:  fiber.StatusCreated,
+		headers: map[string][]byte{"X-Keep": []byte("1")},
+		body:    []byte("done"),
+	}
+	data, err := res.MarshalMsg(nil)
+	utils.AssertEqual(t, nil, err)
+
+	var decoded response
+	left, err := decoded.UnmarshalMsg(data)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 0, len(left))
+	utils.AssertEqual(t, res, decoded)
+}
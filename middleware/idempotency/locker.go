@@ -0,0 +1,65 @@
+package idempotency
+
+import (
+	"sync"
+)
+
+// Locker serializes the requests of an idempotency key, i.e. across
+// multiple instances of an app
+type Locker interface {
+	Lock(key string) error
+	Unlock(key string) error
+}
+
+// MemoryLock is a Locker with a mutex per key, mutexes are removed once no
+// request holds or waits on them
+type MemoryLock struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// NewMemoryLock creates a Locker for this process only
+func NewMemoryLock() *MemoryLock {
+	return &MemoryLock{
+		locks: make(map[string]*keyLock),
+	}
+}
+
+// Lock blocks until the key is unlocked by the other requests
+func (l *MemoryLock) Lock(key string) error {
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &keyLock{}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	// Only this key waits, the map is released before
+	lock.mu.Lock()
+	return nil
+}
+
+// Unlock releases the key
+func (l *MemoryLock) Unlock(key string) error {
+	l.mu.Lock()
+	lock, ok := l.locks[key]
+	if !ok {
+		l.mu.Unlock()
+		return nil
+	}
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, key)
+	}
+	l.mu.Unlock()
+
+	lock.mu.Unlock()
+	return nil
+}
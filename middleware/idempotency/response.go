@@ -0,0 +1,11 @@
+package idempotency
+
+// go:generate msgp
+// msgp -file="response.go" -o="response_msgp.go" -tests=false -unexported
+// don't forget to replace the msgp import path to:
+// "github.com/gofiber/fiber/v2/internal/msgp"
+type response struct {
+	status  int               `msg:"status"`
+	headers map[string][]byte `msg:"headers"`
+	body    []byte            `msg:"body"`
+}
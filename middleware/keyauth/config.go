@@ -0,0 +1,125 @@
+package keyauth
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Config defines the config for middleware.
+type Config struct {
+	// Next defines a function to skip this middleware when returned true,
+	// i.e. for public routes.
+	//
+	// Optional. Default: nil
+	Next func(c *fiber.Ctx) bool
+
+	// KeyLookup is a string in the form of "<source>:<key>" that is used
+	// to extract the key from the request. Multiple sources are separated
+	// by commas and tried in order, i.e. "header:Authorization,query:api_key".
+	// Possible values:
+	// - "header:<name>"
+	// - "query:<name>"
+	// - "form:<name>"
+	// - "param:<name>"
+	// - "cookie:<name>"
+	//
+	// Optional. Default: "header:Authorization"
+	KeyLookup string
+
+	// AuthScheme is the scheme which prefixes the key in the Authorization
+	// header, keys without it are malformed
+	//
+	// Optional. Default: "Bearer"
+	AuthScheme string
+
+	// Validator reports whether the key is valid, i.e. by looking it up in
+	// a database with c.UserContext(). Returned errors are passed to the
+	// ErrorHandler, false without an error passes ErrInvalidAPIKey.
+	//
+	// Required.
+	Validator func(c *fiber.Ctx, key string) (bool, error)
+
+	// SuccessHandler is called for valid keys
+	//
+	// Optional. Default: func(c *fiber.Ctx) error { return c.Next() }
+	SuccessHandler fiber.Handler
+
+	// ErrorHandler is called for missing and invalid keys. The error wraps
+	// ErrMissingOrMalformedAPIKey, is ErrInvalidAPIKey or was returned by
+	// the Validator.
+	//
+	// Optional. Default: responds with 401 and a challenge for missing keys,
+	// with InvalidKeyStatus for invalid keys and returns other errors
+	ErrorHandler fiber.ErrorHandler
+
+	// InvalidKeyStatus is the status code of the default ErrorHandler for
+	// invalid keys
+	//
+	// Optional. Default: 401
+	InvalidKeyStatus int
+
+	// ContextKey is the key to store the valid key in Locals
+	//
+	// Optional. Default: "token"
+	ContextKey string
+}
+
+// ConfigDefault is the default config
+var ConfigDefault = Config{
+	Next:       nil,
+	KeyLookup:  "header:" + fiber.HeaderAuthorization,
+	AuthScheme: "Bearer",
+	Validator:  nil,
+	SuccessHandler: func(c *fiber.Ctx) error {
+		return c.Next()
+	},
+	ErrorHandler:     nil,
+	InvalidKeyStatus: fiber.StatusUnauthorized,
+	ContextKey:       "token",
+}
+
+// Helper function to set default values
+func configDefault(config ...Config) Config {
+	// Return default config if nothing provided
+	if len(config) < 1 {
+		panic("[keyauth] Validator is required")
+	}
+
+	// Override default config
+	cfg := config[0]
+
+	// Set default values
+	if cfg.Validator == nil {
+		panic("[keyauth] Validator is required")
+	}
+	if cfg.KeyLookup == "" {
+		cfg.KeyLookup = ConfigDefault.KeyLookup
+	}
+	if cfg.AuthScheme == "" {
+		cfg.AuthScheme = ConfigDefault.AuthScheme
+	}
+	if cfg.SuccessHandler == nil {
+		cfg.SuccessHandler = ConfigDefault.SuccessHandler
+	}
+	if cfg.InvalidKeyStatus == 0 {
+		cfg.InvalidKeyStatus = ConfigDefault.InvalidKeyStatus
+	}
+	if cfg.ErrorHandler == nil {
+		challenge := cfg.AuthScheme + ` realm="Restricted"`
+		cfg.ErrorHandler = func(c *fiber.Ctx, err error) error {
+			switch {
+			case errors.Is(err, ErrMissingOrMalformedAPIKey):
+				c.Set(fiber.HeaderWWWAuthenticate, challenge)
+				return c.Status(fiber.StatusUnauthorized).SendString(ErrMissingOrMalformedAPIKey.Error())
+			case errors.Is(err, ErrInvalidAPIKey):
+				return c.Status(cfg.InvalidKeyStatus).SendString(ErrInvalidAPIKey.Error())
+			}
+			return err
+		}
+	}
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigDefault.ContextKey
+	}
+	return cfg
+}
@@ -0,0 +1,86 @@
+package keyauth
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/internal/extractor"
+)
+
+var (
+	// ErrMissingOrMalformedAPIKey is wrapped by the errors passed to the
+	// ErrorHandler if the request has no key, or the Authorization header
+	// lacks the AuthScheme
+	ErrMissingOrMalformedAPIKey = errors.New("missing or malformed API key")
+	// ErrInvalidAPIKey is passed to the ErrorHandler if the Validator
+	// rejected the key
+	ErrInvalidAPIKey = errors.New("invalid or expired API key")
+)
+
+// New creates a new middleware handler
+func New(config ...Config) fiber.Handler {
+	// Set default config
+	cfg := configDefault(config...)
+
+	// Generate the extractor of the key sources
+	extractor := newExtractor(cfg.KeyLookup, cfg.AuthScheme)
+
+	// Return new handler
+	return func(c *fiber.Ctx) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Extract the key
+		key, err := extractor(c)
+		if err != nil {
+			return cfg.ErrorHandler(c, err)
+		}
+
+		// Validate the key
+		valid, err := cfg.Validator(c, key)
+		if err == nil && !valid {
+			err = ErrInvalidAPIKey
+		}
+		if err != nil {
+			return cfg.ErrorHandler(c, err)
+		}
+
+		c.Locals(cfg.ContextKey, key)
+		return cfg.SuccessHandler(c)
+	}
+}
+
+// newExtractor returns a function which tries the sources of the keyLookup
+// in order, the key of the first one which has it is returned
+func newExtractor(keyLookup, authScheme string) func(c *fiber.Ctx) (string, error) {
+	sources, err := extractor.Parse(keyLookup)
+	if err != nil {
+		panic("[keyauth] " + err.Error())
+	}
+	return extractor.New(sources, ErrMissingOrMalformedAPIKey, func(header string, errMissing error) extractor.Func {
+		if header == fiber.HeaderAuthorization {
+			return keyFromAuthorization(authScheme, errMissing)
+		}
+		return nil
+	})
+}
+
+// keyFromAuthorization returns a function that extracts the key following
+// the auth scheme from the Authorization header.
+func keyFromAuthorization(authScheme string, errMissing error) func(c *fiber.Ctx) (string, error) {
+	prefix := authScheme + " "
+	return func(c *fiber.Ctx) (string, error) {
+		auth := c.Get(fiber.HeaderAuthorization)
+		if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+			return "", errMissing
+		}
+		key := strings.TrimSpace(auth[len(prefix):])
+		if key == "" {
+			return "", errMissing
+		}
+		return key, nil
+	}
+}
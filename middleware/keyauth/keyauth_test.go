@@ -0,0 +1,220 @@
+package keyauth
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+const testKey = "valid-key"
+
+var errDatabase = errors.New("database is down")
+
+func validator(_ *fiber.Ctx, key string) (bool, error) {
+	switch key {
+	case testKey:
+		return true, nil
+	case "crash":
+		return false, errDatabase
+	}
+	return false, nil
+}
+
+// go test -run Test_KeyAuth
+func Test_KeyAuth(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Validator: validator,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("key=" + c.Locals("token").(string))
+	})
+
+	for _, tt := range []struct {
+		auth      string
+		status    int
+		body      string
+		challenge string
+	}{
+		{"Bearer " + testKey, fiber.StatusOK, "key=" + testKey, ""},
+		{"bearer " + testKey, fiber.StatusOK, "key=" + testKey, ""},
+		{"", fiber.StatusUnauthorized, "missing or malformed API key", `Bearer realm="Restricted"`},
+		{testKey, fiber.StatusUnauthorized, "missing or malformed API key", `Bearer realm="Restricted"`},
+		{"Bearer ", fiber.StatusUnauthorized, "missing or malformed API key", `Bearer realm="Restricted"`},
+		{"Bearer invalid", fiber.StatusUnauthorized, "invalid or expired API key", ""},
+		{"Bearer crash", fiber.StatusInternalServerError, "database is down", ""},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if tt.auth != "" {
+			req.Header.Set(fiber.HeaderAuthorization, tt.auth)
+		}
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, tt.status, resp.StatusCode, tt.auth)
+		utils.AssertEqual(t, tt.challenge, resp.Header.Get(fiber.HeaderWWWAuthenticate))
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, tt.body, string(body))
+	}
+}
+
+// go test -run Test_KeyAuth_Next
+func Test_KeyAuth_Next(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Next: func(c *fiber.Ctx) bool {
+			return c.Path() == "/public"
+		},
+		Validator: validator,
+	}))
+
+	app.Get("/public", func(c *fiber.Ctx) error {
+		return nil
+	})
+	app.Get("/private", func(c *fiber.Ctx) error {
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/public", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/private", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusUnauthorized, resp.StatusCode)
+}
+
+// go test -run Test_KeyAuth_KeyLookup
+func Test_KeyAuth_KeyLookup(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		KeyLookup: "header:Authorization,header:X-API-Key,query:api_key,cookie:api_key",
+		Validator: validator,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Locals("token").(string))
+	})
+
+	test := func(req *http.Request, status int, key string) {
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, status, resp.StatusCode)
+		if status == fiber.StatusOK {
+			body, err := ioutil.ReadAll(resp.Body)
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, key, string(body))
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-API-Key", testKey)
+	test(req, fiber.StatusOK, testKey)
+
+	test(httptest.NewRequest("GET", "/?api_key="+testKey, nil), fiber.StatusOK, testKey)
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderCookie, "api_key="+testKey)
+	test(req, fiber.StatusOK, testKey)
+
+	// The sources are tried in priority order
+	req = httptest.NewRequest("GET", "/?api_key="+testKey, nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Bearer invalid")
+	test(req, fiber.StatusUnauthorized, "")
+
+	req = httptest.NewRequest("GET", "/?api_key=invalid", nil)
+	req.Header.Set("X-API-Key", testKey)
+	test(req, fiber.StatusOK, testKey)
+
+	test(httptest.NewRequest("GET", "/", nil), fiber.StatusUnauthorized, "")
+}
+
+// go test -run Test_KeyAuth_Handlers
+func Test_KeyAuth_Handlers(t *testing.T) {
+	app := fiber.New()
+
+	var handled error
+	app.Use(New(Config{
+		KeyLookup:        "header:Authorization,form:api_key",
+		AuthScheme:       "Token",
+		InvalidKeyStatus: fiber.StatusForbidden,
+		Validator:        validator,
+		SuccessHandler: func(c *fiber.Ctx) error {
+			c.Set("X-Authenticated", "true")
+			return c.Next()
+		},
+	}))
+
+	app.Use(func(c *fiber.Ctx) error {
+		return c.SendString("ok")
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Token "+testKey)
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "true", resp.Header.Get("X-Authenticated"))
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderAuthorization, "Token invalid")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusForbidden, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusUnauthorized, resp.StatusCode)
+	utils.AssertEqual(t, `Token realm="Restricted"`, resp.Header.Get(fiber.HeaderWWWAuthenticate))
+
+	// Custom ErrorHandler
+	app = fiber.New()
+	app.Use(New(Config{
+		KeyLookup: "query:api_key",
+		Validator: validator,
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			handled = err
+			return c.SendStatus(fiber.StatusTeapot)
+		},
+	}))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusTeapot, resp.StatusCode)
+	utils.AssertEqual(t, true, errors.Is(handled, ErrMissingOrMalformedAPIKey))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/?api_key=invalid", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, ErrInvalidAPIKey, handled)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/?api_key=crash", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, errDatabase, handled)
+}
+
+// go test -run Test_KeyAuth_Panics
+func Test_KeyAuth_Panics(t *testing.T) {
+	func() {
+		defer func() {
+			utils.AssertEqual(t, "[keyauth] Validator is required", recover())
+		}()
+		New()
+	}()
+
+	defer func() {
+		utils.AssertEqual(t, "[keyauth] KeyLookup must in the form of <source>:<key>", recover())
+	}()
+	New(Config{
+		KeyLookup: "header",
+		Validator: validator,
+	})
+}
@@ -31,7 +31,8 @@ type Config struct {
 	// Default: 1 * time.Minute
 	Expiration time.Duration
 
-	// LimitReached is called when a request hits the limit
+	// LimitReached is called when a request hits the limit, instead of the
+	// default response. The Retry-After header and the ResetKey local are set.
 	//
 	// Default: func(c *fiber.Ctx) error {
 	//   return c.SendStatus(fiber.StatusTooManyRequests)
@@ -43,6 +44,42 @@ type Config struct {
 	// Default: an in memory store for this process only
 	Storage fiber.Storage
 
+	// SkipFailedRequests doesn't count requests with a status code of 400
+	// or higher, or which returned an error
+	//
+	// Default: false
+	SkipFailedRequests bool
+
+	// SkipSuccessfulRequests doesn't count requests with a status code
+	// below 400
+	//
+	// Default: false
+	SkipSuccessfulRequests bool
+
+	// Weight returns how many hits a request counts as
+	//
+	// Default: func(c *fiber.Ctx) int {
+	//   return 1
+	// }
+	Weight func(*fiber.Ctx) int
+
+	// DisableHeaders doesn't set the RateLimit headers, Retry-After is
+	// still set when the limit is reached
+	//
+	// Default: false
+	DisableHeaders bool
+
+	// HeaderPrefix is the prefix of the Limit, Remaining and Reset headers,
+	// e.g. "RateLimit-" per the IETF draft
+	//
+	// Default: "X-RateLimit-"
+	HeaderPrefix string
+
+	// LimiterMiddleware is the strategy used to limit the requests
+	//
+	// Default: LimiterFixedWindow
+	LimiterMiddleware LimiterHandler
+
 	// DEPRECATED: Use Expiration instead
 	Duration time.Duration
 
@@ -63,6 +100,11 @@ var ConfigDefault = Config{
 	LimitReached: func(c *fiber.Ctx) error {
 		return c.SendStatus(fiber.StatusTooManyRequests)
 	},
+	Weight: func(c *fiber.Ctx) int {
+		return 1
+	},
+	HeaderPrefix:      xRateLimitPrefix,
+	LimiterMiddleware: LimiterFixedWindow,
 }
 
 // Helper function to set default values
@@ -103,5 +145,29 @@ func configDefault(config ...Config) Config {
 	if cfg.LimitReached == nil {
 		cfg.LimitReached = ConfigDefault.LimitReached
 	}
+	if cfg.Weight == nil {
+		cfg.Weight = ConfigDefault.Weight
+	}
+	if cfg.HeaderPrefix == "" {
+		cfg.HeaderPrefix = ConfigDefault.HeaderPrefix
+	}
+	if cfg.LimiterMiddleware == nil {
+		cfg.LimiterMiddleware = ConfigDefault.LimiterMiddleware
+	}
 	return cfg
 }
+
+// weight returns the hits of the request, at least 0
+func (cfg *Config) weight(c *fiber.Ctx) int {
+	if weight := cfg.Weight(c); weight > 0 {
+		return weight
+	}
+	return 0
+}
+
+// skip reports whether the hits of the request are given back after the
+// handler ran, a returned error counts as a failed request
+func (cfg *Config) skip(c *fiber.Ctx, err error) bool {
+	failed := err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest
+	return (cfg.SkipSuccessfulRequests && !failed) || (cfg.SkipFailedRequests && failed)
+}
@@ -3,7 +3,6 @@ package limiter
 import (
 	"fmt"
 	"strconv"
-	"sync"
 	"sync/atomic"
 	"time"
 
@@ -14,10 +13,28 @@ const (
 	// Storage ErrNotExist
 	errNotExist = "key does not exist"
 
-	// X-RateLimit-* headers
-	xRateLimitLimit     = "X-RateLimit-Limit"
-	xRateLimitRemaining = "X-RateLimit-Remaining"
-	xRateLimitReset     = "X-RateLimit-Reset"
+	// Default prefix of the X-RateLimit-* headers
+	xRateLimitPrefix = "X-RateLimit-"
+)
+
+// ResetKey is the Locals key of the seconds until the next request is allowed,
+// an int set before LimitReached is called
+const ResetKey = "limiter_reset"
+
+// LimiterHandler is a rate limiting strategy, it creates the handler of the middleware
+type LimiterHandler interface {
+	New(config Config) fiber.Handler
+}
+
+var (
+	// LimiterFixedWindow counts the hits per window of Expiration. Around the
+	// end of a window up to twice Max requests may pass in a short time.
+	LimiterFixedWindow LimiterHandler = fixedWindow{}
+
+	// LimiterSlidingWindow adds the hits of the previous window, weighted by
+	// the part of it that's still within the Expiration, to the hits of the
+	// current window.
+	LimiterSlidingWindow LimiterHandler = slidingWindow{}
 )
 
 // New creates a new middleware handler
@@ -25,111 +42,115 @@ func New(config ...Config) fiber.Handler {
 	// Set default config
 	cfg := configDefault(config...)
 
-	var (
-		// Limiter settings
-		max        = strconv.Itoa(cfg.Max)
-		timestamp  = uint64(time.Now().Unix())
-		expiration = uint64(cfg.Expiration.Seconds())
-		mux        = &sync.RWMutex{}
+	// Return the handler of the strategy
+	return cfg.LimiterMiddleware.New(cfg)
+}
 
-		// Default store logic (if no Store is provided)
-		entries = make(map[string]entry)
-	)
+// manager loads and stores the entries in the Storage, or in memory if no
+// Storage is provided. The caller must hold the lock.
+type manager struct {
+	storage fiber.Storage
 
-	// Update timestamp every second
-	go func() {
-		for {
-			atomic.StoreUint64(&timestamp, uint64(time.Now().Unix()))
-			time.Sleep(1 * time.Second)
-		}
-	}()
+	// Default store logic (if no Store is provided)
+	entries map[string]entry
+}
 
-	// Return new handler
-	return func(c *fiber.Ctx) error {
-		// Don't execute middleware if Next returns true
-		if cfg.Next != nil && cfg.Next(c) {
-			return c.Next()
-		}
+func newManager(storage fiber.Storage) *manager {
+	return &manager{
+		storage: storage,
+		entries: make(map[string]entry),
+	}
+}
 
-		// Get key from request
-		key := cfg.KeyGenerator(c)
-
-		// Create new entry
-		entry := entry{}
-
-		// Lock entry
-		mux.Lock()
-		defer mux.Unlock()
-
-		// Use Storage if provided
-		if cfg.Storage != nil {
-			val, err := cfg.Storage.Get(key)
-			if val != nil && len(val) > 0 {
-				if _, err := entry.UnmarshalMsg(val); err != nil {
-					return err
-				}
-			}
-			if err != nil && err.Error() != errNotExist {
-				fmt.Println("[LIMITER]", err.Error())
-			}
-		} else {
-			entry = entries[key]
+// get returns the entry of the key, or an empty entry
+func (m *manager) get(key string) (entry, error) {
+	e := entry{}
+	if m.storage == nil {
+		return m.entries[key], nil
+	}
+	val, err := m.storage.Get(key)
+	if val != nil && len(val) > 0 {
+		if _, err := e.UnmarshalMsg(val); err != nil {
+			return e, err
 		}
+	}
+	if err != nil && err.Error() != errNotExist {
+		fmt.Println("[LIMITER]", err.Error())
+	}
+	return e, nil
+}
 
-		// Get timestamp
-		ts := atomic.LoadUint64(&timestamp)
+// set stores the entry of the key for the given time
+func (m *manager) set(key string, e entry, exp time.Duration) error {
+	if m.storage == nil {
+		m.entries[key] = e
+		return nil
+	}
+	// Marshal entry to bytes
+	val, err := e.MarshalMsg(nil)
+	if err != nil {
+		return err
+	}
+	// Pass value to Storage
+	return m.storage.Set(key, val, exp)
+}
 
-		// Set expiration if entry does not exist
-		if entry.exp == 0 {
-			entry.exp = ts + expiration
+// rateLimitHeaders sets the RateLimit headers of the responses
+type rateLimitHeaders struct {
+	disabled  bool
+	max       string
+	limit     string
+	remaining string
+	reset     string
+}
 
-		} else if ts >= entry.exp {
-			// Check if entry is expired
-			entry.hits = 0
-			entry.exp = ts + expiration
-		}
+func newHeaders(cfg Config) rateLimitHeaders {
+	return rateLimitHeaders{
+		disabled:  cfg.DisableHeaders,
+		max:       strconv.Itoa(cfg.Max),
+		limit:     cfg.HeaderPrefix + "Limit",
+		remaining: cfg.HeaderPrefix + "Remaining",
+		reset:     cfg.HeaderPrefix + "Reset",
+	}
+}
 
-		// Increment hits
-		entry.hits++
-
-		// Use Storage if provided
-		if cfg.Storage != nil {
-			// Marshal entry to bytes
-			val, err := entry.MarshalMsg(nil)
-			if err != nil {
-				return err
-			}
-
-			// Pass value to Storage
-			if err = cfg.Storage.Set(key, val, cfg.Expiration); err != nil {
-				return err
-			}
-		} else {
-			entries[key] = entry
-		}
+func (h rateLimitHeaders) set(c *fiber.Ctx, remaining int, reset uint64) {
+	if h.disabled {
+		return
+	}
+	c.Set(h.limit, h.max)
+	c.Set(h.remaining, strconv.Itoa(remaining))
+	c.Set(h.reset, strconv.FormatUint(reset, 10))
+}
 
-		// Calculate when it resets in seconds
-		expire := entry.exp - ts
+// limitReached sets the Retry-After header and the ResetKey local,
+// then calls the LimitReached handler
+func limitReached(c *fiber.Ctx, cfg Config, retryAfter uint64) error {
+	// Return response with Retry-After header
+	// https://tools.ietf.org/html/rfc6584
+	c.Set(fiber.HeaderRetryAfter, strconv.FormatUint(retryAfter, 10))
+	c.Locals(ResetKey, int(retryAfter))
 
-		// Set how many hits we have left
-		remaining := cfg.Max - entry.hits
+	// Call LimitReached handler
+	return cfg.LimitReached(c)
+}
 
-		// Check if hits exceed the cfg.Max
-		if remaining < 0 {
-			// Return response with Retry-After header
-			// https://tools.ietf.org/html/rfc6584
-			c.Set(fiber.HeaderRetryAfter, strconv.FormatUint(expire, 10))
+// subtractHits returns hits minus weight, at least 0
+func subtractHits(hits, weight int) int {
+	if hits -= weight; hits < 0 {
+		return 0
+	}
+	return hits
+}
 
-			// Call LimitReached handler
-			return cfg.LimitReached(c)
+// timestamp returns the address of a unix timestamp that's updated every second
+func timestamp() *uint64 {
+	ts := uint64(time.Now().Unix())
+	go func() {
+		for {
+			atomic.StoreUint64(&ts, uint64(time.Now().Unix()))
+			time.Sleep(1 * time.Second)
 		}
-
-		// We can continue, update RateLimit headers
-		c.Set(xRateLimitLimit, max)
-		c.Set(xRateLimitRemaining, strconv.Itoa(remaining))
-		c.Set(xRateLimitReset, strconv.FormatUint(expire, 10))
-
-		// Continue stack
-		return c.Next()
-	}
+	}()
+	return &ts
 }
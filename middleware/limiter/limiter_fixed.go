@@ -0,0 +1,101 @@
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type fixedWindow struct{}
+
+// New creates a new fixed window middleware handler
+func (fixedWindow) New(cfg Config) fiber.Handler {
+	var (
+		// Limiter settings
+		headers    = newHeaders(cfg)
+		timestamp  = timestamp()
+		expiration = uint64(cfg.Expiration.Seconds())
+		mux        = &sync.RWMutex{}
+		manager    = newManager(cfg.Storage)
+	)
+
+	// Return new handler
+	return func(c *fiber.Ctx) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Get key and weight from request
+		key := cfg.KeyGenerator(c)
+		weight := cfg.weight(c)
+
+		// Lock entry
+		mux.Lock()
+
+		// Get entry from pool
+		entry, err := manager.get(key)
+		if err != nil {
+			mux.Unlock()
+			return err
+		}
+
+		// Get timestamp
+		ts := atomic.LoadUint64(timestamp)
+
+		// Set expiration if entry does not exist
+		if entry.exp == 0 {
+			entry.exp = ts + expiration
+
+		} else if ts >= entry.exp {
+			// Check if entry is expired
+			entry.hits = 0
+			entry.exp = ts + expiration
+		}
+
+		// Increment hits
+		entry.hits += weight
+
+		// Store entry
+		if err := manager.set(key, entry, cfg.Expiration); err != nil {
+			mux.Unlock()
+			return err
+		}
+
+		// Unlock entry
+		mux.Unlock()
+
+		// Calculate when it resets in seconds
+		expire := entry.exp - ts
+
+		// Set how many hits we have left
+		remaining := cfg.Max - entry.hits
+
+		// Check if hits exceed the cfg.Max
+		if remaining < 0 {
+			return limitReached(c, cfg, expire)
+		}
+
+		// Continue stack
+		err = c.Next()
+
+		// Give the hits back if the request is skipped
+		if cfg.skip(c, err) {
+			mux.Lock()
+			skipped, getErr := manager.get(key)
+			if getErr == nil && skipped.exp == entry.exp {
+				skipped.hits = subtractHits(skipped.hits, weight)
+				if setErr := manager.set(key, skipped, cfg.Expiration); setErr == nil {
+					remaining = cfg.Max - skipped.hits
+				}
+			}
+			mux.Unlock()
+		}
+
+		// Update RateLimit headers
+		headers.set(c, remaining, expire)
+
+		return err
+	}
+}
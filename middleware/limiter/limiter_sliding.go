@@ -0,0 +1,147 @@
+package limiter
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type slidingWindow struct{}
+
+// New creates a new sliding window middleware handler
+func (slidingWindow) New(cfg Config) fiber.Handler {
+	var (
+		// Limiter settings
+		headers    = newHeaders(cfg)
+		timestamp  = timestamp()
+		expiration = uint64(cfg.Expiration.Seconds())
+		mux        = &sync.RWMutex{}
+		manager    = newManager(cfg.Storage)
+	)
+
+	// Return new handler
+	return func(c *fiber.Ctx) error {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Get key and weight from request
+		key := cfg.KeyGenerator(c)
+		weight := cfg.weight(c)
+
+		// Lock entry
+		mux.Lock()
+
+		// Get entry from pool
+		entry, err := manager.get(key)
+		if err != nil {
+			mux.Unlock()
+			return err
+		}
+
+		// Get timestamp
+		ts := atomic.LoadUint64(timestamp)
+
+		// Set expiration if entry does not exist
+		if entry.exp == 0 {
+			entry.exp = ts + expiration
+
+		} else if ts >= entry.exp {
+			// The current window becomes the previous one, unless
+			// more than a window passed since it ended
+			if ts < entry.exp+expiration {
+				entry.prevHits = entry.hits
+				entry.exp += expiration
+			} else {
+				entry.prevHits = 0
+				entry.exp = ts + expiration
+			}
+			entry.hits = 0
+		}
+
+		// Increment hits
+		entry.hits += weight
+
+		// Calculate when the current window ends in seconds
+		resetIn := entry.exp - ts
+
+		// Keep the entry until the hits of the current window no longer count
+		if err := manager.set(key, entry, time.Duration(resetIn+expiration)*time.Second); err != nil {
+			mux.Unlock()
+			return err
+		}
+
+		// Unlock entry
+		mux.Unlock()
+
+		// rate returns the weighted hits in s seconds
+		rate := func(s uint64) int {
+			return slidingRate(entry.prevHits, entry.hits, resetIn, expiration, s)
+		}
+
+		// Check if hits exceed the cfg.Max
+		if cfg.Max-rate(0) < 0 {
+			// Retry after the next request is allowed
+			return limitReached(c, cfg, secondsUntil(rate, cfg.Max-1, resetIn+expiration))
+		}
+
+		// Continue stack
+		err = c.Next()
+
+		// Give the hits back if the request is skipped, they may
+		// have become the hits of the previous window meanwhile
+		if cfg.skip(c, err) {
+			mux.Lock()
+			skipped, getErr := manager.get(key)
+			if getErr == nil && (skipped.exp == entry.exp || skipped.exp == entry.exp+expiration) {
+				if skipped.exp == entry.exp {
+					skipped.hits = subtractHits(skipped.hits, weight)
+				} else {
+					skipped.prevHits = subtractHits(skipped.prevHits, weight)
+				}
+				ts = atomic.LoadUint64(timestamp)
+				ttl := expiration
+				if ts < skipped.exp {
+					ttl += skipped.exp - ts
+				}
+				if setErr := manager.set(key, skipped, time.Duration(ttl)*time.Second); setErr == nil && ts < skipped.exp {
+					entry, resetIn = skipped, skipped.exp-ts
+				}
+			}
+			mux.Unlock()
+		}
+
+		// Update RateLimit headers. The reset is in how many seconds
+		// no hits are counted anymore.
+		headers.set(c, cfg.Max-rate(0), secondsUntil(rate, 0, resetIn+expiration))
+
+		return err
+	}
+}
+
+// slidingRate returns the weighted hits in s seconds, when the current
+// window ends in resetIn seconds
+func slidingRate(prevHits, hits int, resetIn, window, s uint64) int {
+	switch {
+	case s < resetIn:
+		// The sliding window overlaps the previous window by resetIn-s seconds
+		return int(uint64(prevHits)*(resetIn-s)/window) + hits
+	case s < resetIn+window:
+		// The current window becomes the previous one
+		return int(uint64(hits) * (resetIn + window - s) / window)
+	default:
+		return 0
+	}
+}
+
+// secondsUntil returns the seconds until the decreasing rate is at most
+// limit, which it is after max seconds at the latest
+func secondsUntil(rate func(s uint64) int, limit int, max uint64) uint64 {
+	return uint64(sort.Search(int(max), func(s int) bool {
+		return rate(uint64(s)) <= limit
+	}))
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
@@ -177,6 +178,328 @@ func Test_Limiter_Headers(t *testing.T) {
 	}
 }
 
+// go test -run Test_Limiter_Sliding_Window
+func Test_Limiter_Sliding_Window(t *testing.T) {
+	for _, storage := range []fiber.Storage{nil, memory.New()} {
+		app := fiber.New()
+		app.Use(New(Config{
+			Max:               2,
+			Expiration:        60 * time.Second,
+			Storage:           storage,
+			LimiterMiddleware: LimiterSlidingWindow,
+		}))
+
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.SendString("Hello tester!")
+		})
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+		utils.AssertEqual(t, "1", resp.Header.Get("X-RateLimit-Remaining"))
+		// the hit is no longer counted once the next window started
+		if v := resp.Header.Get("X-RateLimit-Reset"); !(v == "60" || v == "61") {
+			t.Errorf("The X-RateLimit-Reset header is out of bounds: %s", v)
+		}
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+		utils.AssertEqual(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusTooManyRequests, resp.StatusCode)
+		// the three hits weigh less than two 21 seconds into the next window
+		if v := resp.Header.Get(fiber.HeaderRetryAfter); !(v == "80" || v == "81") {
+			t.Errorf("The Retry-After header is out of bounds: %s", v)
+		}
+	}
+}
+
+// go test -run Test_Limiter_Sliding_Window_Previous
+func Test_Limiter_Sliding_Window_Previous(t *testing.T) {
+	// the previous window ended just now and was used up
+	storage := memory.New()
+	val, err := entry{hits: 6, exp: uint64(time.Now().Unix())}.MarshalMsg(nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, storage.Set("0.0.0.0", val, time.Minute))
+
+	app := fiber.New()
+	app.Use(New(Config{
+		Max:               5,
+		Expiration:        60 * time.Second,
+		Storage:           storage,
+		LimiterMiddleware: LimiterSlidingWindow,
+	}))
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString("Hello tester!")
+	})
+
+	// a fixed window would allow a burst of 5 requests
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	if v := resp.Header.Get(fiber.HeaderRetryAfter); !(v == "20" || v == "21") {
+		t.Errorf("The Retry-After header is out of bounds: %s", v)
+	}
+
+	// the hits are stored with the previous ones
+	val, err = storage.Get("0.0.0.0")
+	utils.AssertEqual(t, nil, err)
+	var e entry
+	_, err = e.UnmarshalMsg(val)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 6, e.prevHits)
+	utils.AssertEqual(t, 1, e.hits)
+}
+
+// go test -run Test_Limiter_Sliding_Rate
+func Test_Limiter_Sliding_Rate(t *testing.T) {
+	t.Parallel()
+	// 10 hits in the previous window, 2 in the current one which ends in 30 seconds
+	rate := func(s uint64) int {
+		return slidingRate(10, 2, 30, 60, s)
+	}
+	utils.AssertEqual(t, 7, rate(0))
+	utils.AssertEqual(t, 4, rate(15))
+	utils.AssertEqual(t, 2, rate(29))
+	utils.AssertEqual(t, 2, rate(30))
+	utils.AssertEqual(t, 1, rate(60))
+	utils.AssertEqual(t, 0, rate(61))
+	utils.AssertEqual(t, 0, rate(90))
+
+	utils.AssertEqual(t, uint64(0), secondsUntil(rate, 7, 90))
+	utils.AssertEqual(t, uint64(13), secondsUntil(rate, 4, 90))
+	utils.AssertEqual(t, uint64(25), secondsUntil(rate, 2, 90))
+	utils.AssertEqual(t, uint64(61), secondsUntil(rate, 0, 90))
+}
+
+// go test -run Test_Limiter_Skip_Requests
+func Test_Limiter_Skip_Requests(t *testing.T) {
+	for _, strategy := range []LimiterHandler{LimiterFixedWindow, LimiterSlidingWindow} {
+		app := fiber.New()
+		app.Use(New(Config{
+			Max:                    2,
+			Expiration:             60 * time.Second,
+			SkipSuccessfulRequests: true,
+			LimiterMiddleware:      strategy,
+		}))
+
+		app.Post("/login", func(c *fiber.Ctx) error {
+			if c.Query("password") != "secret" {
+				return fiber.ErrUnauthorized
+			}
+			return c.SendString("Welcome!")
+		})
+
+		login := func(password string) *http.Response {
+			resp, err := app.Test(httptest.NewRequest("POST", "/login?password="+password, nil))
+			utils.AssertEqual(t, nil, err)
+			return resp
+		}
+
+		// only the failed attempts count
+		for i := 0; i < 5; i++ {
+			resp := login("secret")
+			utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+			utils.AssertEqual(t, "2", resp.Header.Get("X-RateLimit-Remaining"))
+		}
+		resp := login("wrong")
+		utils.AssertEqual(t, fiber.StatusUnauthorized, resp.StatusCode)
+		utils.AssertEqual(t, "1", resp.Header.Get("X-RateLimit-Remaining"))
+		resp = login("wrong")
+		utils.AssertEqual(t, fiber.StatusUnauthorized, resp.StatusCode)
+		utils.AssertEqual(t, "0", resp.Header.Get("X-RateLimit-Remaining"))
+		utils.AssertEqual(t, fiber.StatusTooManyRequests, login("secret").StatusCode)
+	}
+}
+
+// go test -run Test_Limiter_Skip_Failed_Requests
+func Test_Limiter_Skip_Failed_Requests(t *testing.T) {
+	for _, strategy := range []LimiterHandler{LimiterFixedWindow, LimiterSlidingWindow} {
+		app := fiber.New()
+		app.Use(New(Config{
+			Max:                2,
+			Expiration:         60 * time.Second,
+			SkipFailedRequests: true,
+			LimiterMiddleware:  strategy,
+		}))
+
+		app.Get("/:status", func(c *fiber.Ctx) error {
+			status, _ := strconv.Atoi(c.Params("status"))
+			return c.SendStatus(status)
+		})
+
+		for _, tc := range []struct {
+			path   string
+			status int
+		}{
+			{"/500", fiber.StatusInternalServerError},
+			{"/404", fiber.StatusNotFound},
+			{"/200", fiber.StatusOK},
+			{"/400", fiber.StatusBadRequest},
+			{"/204", fiber.StatusNoContent},
+			{"/200", fiber.StatusTooManyRequests},
+		} {
+			resp, err := app.Test(httptest.NewRequest("GET", tc.path, nil))
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, tc.status, resp.StatusCode, tc.path)
+		}
+	}
+}
+
+// go test -run Test_Limiter_Weight
+func Test_Limiter_Weight(t *testing.T) {
+	for _, strategy := range []LimiterHandler{LimiterFixedWindow, LimiterSlidingWindow} {
+		app := fiber.New()
+		app.Use(New(Config{
+			Max:        10,
+			Expiration: 60 * time.Second,
+			Storage:    memory.New(),
+			Weight: func(c *fiber.Ctx) int {
+				if c.Path() == "/expensive" {
+					return 5
+				}
+				return 1
+			},
+			LimiterMiddleware: strategy,
+		}))
+
+		app.Get("/*", func(c *fiber.Ctx) error {
+			return c.SendString("Hello tester!")
+		})
+
+		for _, tc := range []struct {
+			path      string
+			status    int
+			remaining string
+		}{
+			{"/expensive", fiber.StatusOK, "5"},
+			{"/cheap", fiber.StatusOK, "4"},
+			{"/cheap", fiber.StatusOK, "3"},
+			{"/expensive", fiber.StatusTooManyRequests, ""},
+		} {
+			resp, err := app.Test(httptest.NewRequest("GET", tc.path, nil))
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, tc.status, resp.StatusCode, tc.path)
+			utils.AssertEqual(t, tc.remaining, resp.Header.Get("X-RateLimit-Remaining"), tc.path)
+		}
+	}
+}
+
+// go test -run Test_Limiter_Skip_Concurrency -race
+func Test_Limiter_Skip_Concurrency(t *testing.T) {
+	for _, strategy := range []LimiterHandler{LimiterFixedWindow, LimiterSlidingWindow} {
+		app := fiber.New()
+		app.Use(New(Config{
+			Max:                    100,
+			Expiration:             60 * time.Second,
+			SkipSuccessfulRequests: true,
+			LimiterMiddleware:      strategy,
+		}))
+
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.SendString("Hello tester!")
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+				utils.AssertEqual(t, nil, err)
+				utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+			}()
+		}
+		wg.Wait()
+
+		// every hit has been given back
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "100", resp.Header.Get("X-RateLimit-Remaining"))
+	}
+}
+
+// go test -run Test_Limiter_LimitReached
+func Test_Limiter_LimitReached(t *testing.T) {
+	for _, strategy := range []LimiterHandler{LimiterFixedWindow, LimiterSlidingWindow} {
+		app := fiber.New()
+
+		var reset interface{}
+		app.Use(func(c *fiber.Ctx) error {
+			c.Locals("correlation_id", "abc-123")
+			return c.Next()
+		})
+		app.Use(New(Config{
+			Max:        1,
+			Expiration: 60 * time.Second,
+			LimitReached: func(c *fiber.Ctx) error {
+				reset = c.Locals(ResetKey)
+				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+					"title":          "Too Many Requests",
+					"retry_after":    c.Locals(ResetKey),
+					"correlation_id": c.Locals("correlation_id"),
+				})
+			},
+			LimiterMiddleware: strategy,
+		}))
+
+		app.Get("/", func(c *fiber.Ctx) error {
+			return c.SendString("Hello tester!")
+		})
+
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+		resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusTooManyRequests, resp.StatusCode)
+		utils.AssertEqual(t, fiber.MIMEApplicationJSON, resp.Header.Get(fiber.HeaderContentType))
+		retryAfter := resp.Header.Get(fiber.HeaderRetryAfter)
+		utils.AssertEqual(t, true, retryAfter != "")
+
+		// the handler gets the Ctx of the request, with its locals and the reset time
+		utils.AssertEqual(t, retryAfter, strconv.Itoa(reset.(int)))
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, `{"correlation_id":"abc-123","retry_after":`+retryAfter+`,"title":"Too Many Requests"}`, string(body))
+	}
+}
+
+// go test -run Test_Limiter_Headers_Control
+func Test_Limiter_Headers_Control(t *testing.T) {
+	app := fiber.New()
+	app.Use("/disabled", New(Config{Max: 1, DisableHeaders: true}))
+	app.Use("/prefix", New(Config{Max: 1, HeaderPrefix: "RateLimit-"}))
+
+	app.Get("/*", func(c *fiber.Ctx) error {
+		return c.SendString("Hello tester!")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/disabled", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	for _, name := range []string{"X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"} {
+		utils.AssertEqual(t, "", resp.Header.Get(name), name)
+	}
+
+	// Retry-After is still set
+	resp, err = app.Test(httptest.NewRequest("GET", "/disabled", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusTooManyRequests, resp.StatusCode)
+	utils.AssertEqual(t, true, resp.Header.Get(fiber.HeaderRetryAfter) != "")
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/prefix", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "1", resp.Header.Get("RateLimit-Limit"))
+	utils.AssertEqual(t, "0", resp.Header.Get("RateLimit-Remaining"))
+	utils.AssertEqual(t, true, resp.Header.Get("RateLimit-Reset") != "")
+	utils.AssertEqual(t, "", resp.Header.Get("X-RateLimit-Limit"))
+}
+
 // go test -v -run=^$ -bench=Benchmark_Limiter -benchmem -count=4
 func Benchmark_Limiter(b *testing.B) {
 	app := fiber.New()
@@ -7,4 +7,6 @@ package limiter
 type entry struct {
 	hits int    `msg:"hits"`
 	exp  uint64 `msg:"exp"`
+	// prevHits are the hits of the previous window of LimiterSlidingWindow
+	prevHits int `msg:"prevHits"`
 }
@@ -36,6 +36,12 @@ func (z *entry) DecodeMsg(dc *msgp.Reader) (err error) {
 				err = msgp.WrapError(err, "exp")
 				return
 			}
+		case "prevHits":
+			z.prevHits, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "prevHits")
+				return
+			}
 		default:
 			err = dc.Skip()
 			if err != nil {
@@ -49,9 +55,9 @@ func (z *entry) DecodeMsg(dc *msgp.Reader) (err error) {
 
 // EncodeMsg implements msgp.Encodable
 func (z entry) EncodeMsg(en *msgp.Writer) (err error) {
-	// map header, size 2
+	// map header, size 3
 	// write "hits"
-	err = en.Append(0x82, 0xa4, 0x68, 0x69, 0x74, 0x73)
+	err = en.Append(0x83, 0xa4, 0x68, 0x69, 0x74, 0x73)
 	if err != nil {
 		return
 	}
@@ -70,19 +76,32 @@ func (z entry) EncodeMsg(en *msgp.Writer) (err error) {
 		err = msgp.WrapError(err, "exp")
 		return
 	}
+	// write "prevHits"
+	err = en.Append(0xa8, 0x70, 0x72, 0x65, 0x76, 0x48, 0x69, 0x74, 0x73)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.prevHits)
+	if err != nil {
+		err = msgp.WrapError(err, "prevHits")
+		return
+	}
 	return
 }
 
 // MarshalMsg implements msgp.Marshaler
 func (z entry) MarshalMsg(b []byte) (o []byte, err error) {
 	o = msgp.Require(b, z.Msgsize())
-	// map header, size 2
+	// map header, size 3
 	// string "hits"
-	o = append(o, 0x82, 0xa4, 0x68, 0x69, 0x74, 0x73)
+	o = append(o, 0x83, 0xa4, 0x68, 0x69, 0x74, 0x73)
 	o = msgp.AppendInt(o, z.hits)
 	// string "exp"
 	o = append(o, 0xa3, 0x65, 0x78, 0x70)
 	o = msgp.AppendUint64(o, z.exp)
+	// string "prevHits"
+	o = append(o, 0xa8, 0x70, 0x72, 0x65, 0x76, 0x48, 0x69, 0x74, 0x73)
+	o = msgp.AppendInt(o, z.prevHits)
 	return
 }
 
@@ -116,6 +135,12 @@ func (z *entry) UnmarshalMsg(bts []byte) (o []byte, err error) {
 				err = msgp.WrapError(err, "exp")
 				return
 			}
+		case "prevHits":
+			z.prevHits, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "prevHits")
+				return
+			}
 		default:
 			bts, err = msgp.Skip(bts)
 			if err != nil {
@@ -130,6 +155,6 @@ func (z *entry) UnmarshalMsg(bts []byte) (o []byte, err error) {
 
 // Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
 func (z entry) Msgsize() (s int) {
-	s = 1 + 5 + msgp.IntSize + 4 + msgp.Uint64Size
+	s = 1 + 5 + msgp.IntSize + 4 + msgp.Uint64Size + 9 + msgp.IntSize
 	return
 }
@@ -15,22 +15,33 @@ type Config struct {
 	// Optional. Default: nil
 	Next func(c *fiber.Ctx) bool
 
-	// Format defines the logging tags
+	// Format defines the logging tags, use FormatJSON to log one JSON
+	// document per request instead
 	//
 	// Optional. Default: [${time}] ${status} - ${latency} ${method} ${path}\n
 	Format string
 
+	// CustomTags defines functions for tags which are not built-in, the
+	// tag ${userID} is resolved by CustomTags["userID"]. Custom tags take
+	// precedence over the built-in tags and are added as string fields
+	// to the FormatJSON output.
+	//
+	// Optional. Default: nil
+	CustomTags map[string]LogFunc
+
 	// TimeFormat https://programming.guide/go/format-parse-string-time-date-example.html
 	//
-	// Optional. Default: 15:04:05
+	// Optional. Default: 15:04:05, time.RFC3339 for FormatJSON
 	TimeFormat string
 
-	// TimeZone can be specified, such as "UTC" and "America/New_York" and "Asia/Chongqing", etc
+	// TimeZone can be specified, such as "UTC" and "America/New_York" and "Asia/Chongqing", etc.
+	// It is loaded with time.LoadLocation, New panics on invalid names.
 	//
 	// Optional. Default: "Local"
 	TimeZone string
 
-	// TimeInterval is the delay before the timestamp is updated
+	// TimeInterval is the delay before the cached timestamp is updated,
+	// the ticker stops when the app shuts down
 	//
 	// Optional. Default: 500 * time.Millisecond
 	TimeInterval time.Duration
@@ -40,11 +51,44 @@ type Config struct {
 	// Default: os.Stderr
 	Output io.Writer
 
+	// Done is called with every log line after it is built, the line is a
+	// copy and safe to retain
+	//
+	// Optional. Default: nil
+	Done func(c *fiber.Ctx, logString []byte)
+
+	// DisableDefaultOutput disables writing to Output, which makes Done
+	// the only sink of the log lines
+	//
+	// Optional. Default: false
+	DisableDefaultOutput bool
+
 	enableColors     bool
+	enableJSON       bool
 	enableLatency    bool
 	timeZoneLocation *time.Location
 }
 
+// FormatJSON logs every request as a JSON document with the fields time,
+// status, latency_ms, method, path, ip, bytes_in, bytes_out and error
+const FormatJSON = "${json}"
+
+// LogFunc writes the value of a custom tag to output
+type LogFunc func(output io.Writer, c *fiber.Ctx, data *LoggerData) (int, error)
+
+// LoggerData holds the request data available to custom tags
+type LoggerData struct {
+	// Pid is the process id
+	Pid string
+	// Timestamp is the current time formatted with TimeFormat
+	Timestamp string
+	// Start and Stop are the times before and after the handler chain
+	Start time.Time
+	Stop  time.Time
+	// ChainErr is the error returned by the handler chain
+	ChainErr error
+}
+
 // ConfigDefault is the default config
 var ConfigDefault = Config{
 	Next:         nil,
@@ -81,6 +125,9 @@ func configDefault(config ...Config) Config {
 	if cfg.TimeZone == "" {
 		cfg.TimeZone = ConfigDefault.TimeZone
 	}
+	if cfg.TimeFormat == "" && cfg.Format == FormatJSON {
+		cfg.TimeFormat = time.RFC3339
+	}
 	if cfg.TimeFormat == "" {
 		cfg.TimeFormat = ConfigDefault.TimeFormat
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -74,41 +75,44 @@ func New(config ...Config) fiber.Handler {
 
 	// Get timezone location
 	tz, err := time.LoadLocation(cfg.TimeZone)
-	if err != nil || tz == nil {
-		cfg.timeZoneLocation = time.Local
-	} else {
-		cfg.timeZoneLocation = tz
+	if err != nil {
+		panic(fmt.Sprintf("[logger] invalid TimeZone %q: %v", cfg.TimeZone, err))
 	}
+	cfg.timeZoneLocation = tz
 
-	// Check if format contains latency
-	cfg.enableLatency = strings.Contains(cfg.Format, "${latency}")
+	// JSON output replaces the template
+	cfg.enableJSON = cfg.Format == FormatJSON
+
+	// Check if format contains latency, custom tags may read it as well
+	cfg.enableLatency = cfg.enableJSON || len(cfg.CustomTags) > 0 || strings.Contains(cfg.Format, "${latency}")
 
 	// Create template parser
 	tmpl := fasttemplate.New(cfg.Format, "${", "}")
 
 	// Create correct timeformat
 	var timestamp atomic.Value
-	timestamp.Store(time.Now().In(cfg.timeZoneLocation).Format(cfg.TimeFormat))
-
-	// Update date/time every 750 milliseconds in a separate go routine
-	if strings.Contains(cfg.Format, "${time}") {
-		go func() {
-			for {
-				time.Sleep(cfg.TimeInterval)
-				timestamp.Store(time.Now().In(cfg.timeZoneLocation).Format(cfg.TimeFormat))
-			}
-		}()
+	updateTimestamp := func() {
+		timestamp.Store(time.Now().In(cfg.timeZoneLocation).Format(cfg.TimeFormat))
 	}
+	updateTimestamp()
+
+	// The timestamp is only refreshed if it is logged
+	enableTime := cfg.enableJSON || strings.Contains(cfg.Format, "${time}")
 
 	// Set PID once
 	pid := strconv.Itoa(os.Getpid())
 
+	// Sort custom tag names for a stable JSON output
+	customTags := make([]string, 0, len(cfg.CustomTags))
+	for tag := range cfg.CustomTags {
+		customTags = append(customTags, tag)
+	}
+	sort.Strings(customTags)
+
 	// Set variables
 	var (
-		start, stop time.Time
-		once        sync.Once
-		mu          sync.Mutex
-		errHandler  fiber.ErrorHandler
+		once sync.Once
+		mu   sync.Mutex
 	)
 
 	// If colors are enabled, check terminal compatibility
@@ -118,6 +122,26 @@ func New(config ...Config) fiber.Handler {
 			cfg.Output = colorable.NewNonColorable(os.Stderr)
 		}
 	}
+	// Pass a copy of the log line to Done and write it to the output,
+	// the buffer is put back to the pool afterwards
+	output := func(c *fiber.Ctx, buf *bytebufferpool.ByteBuffer) {
+		if cfg.Done != nil {
+			cfg.Done(c, append([]byte(nil), buf.Bytes()...))
+		}
+		if !cfg.DisableDefaultOutput {
+			mu.Lock()
+			if _, err := cfg.Output.Write(buf.Bytes()); err != nil {
+				// Write error to output
+				if _, err := cfg.Output.Write([]byte(err.Error())); err != nil {
+					// There is something wrong with the given io.Writer
+					// TODO: What should we do here?
+				}
+			}
+			mu.Unlock()
+		}
+		bytebufferpool.Put(buf)
+	}
+
 	var errPadding = 15
 	var errPaddingStr = strconv.Itoa(errPadding)
 	// Return new handler
@@ -129,7 +153,6 @@ func New(config ...Config) fiber.Handler {
 
 		// Set error handler once
 		once.Do(func() {
-			errHandler = c.App().Config().ErrorHandler
 			stack := c.App().Stack()
 			for m := range stack {
 				for r := range stack[m] {
@@ -140,9 +163,22 @@ func New(config ...Config) fiber.Handler {
 				}
 			}
 
+			// Update date/time every TimeInterval in a separate go routine
+			// until the app shuts down
+			if enableTime {
+				updateTimestamp()
+				done := make(chan struct{})
+				var stop sync.Once
+				c.App().Hooks().OnShutdown(func() error {
+					stop.Do(func() { close(done) })
+					return nil
+				})
+				go tickTimestamp(cfg.TimeInterval, done, updateTimestamp)
+			}
 		})
 
 		// Set latency start time
+		var start, stop time.Time
 		if cfg.enableLatency {
 			start = time.Now()
 		}
@@ -152,11 +188,16 @@ func New(config ...Config) fiber.Handler {
 
 		// Manually call error handler
 		if chainErr != nil {
-			if err := errHandler(c, chainErr); err != nil {
+			if err := c.ErrorHandler()(c, chainErr); err != nil {
 				_ = c.SendStatus(fiber.StatusInternalServerError)
 			}
 		}
 
+		// Don't log requests which asked to be skipped
+		if skip, ok := c.Locals(fiber.SkipLogKey).(bool); ok && skip {
+			return nil
+		}
+
 		// Set latency stop time
 		if cfg.enableLatency {
 			stop = time.Now()
@@ -185,117 +226,120 @@ func New(config ...Config) fiber.Handler {
 			))
 
 			// Write buffer to output
-			_, _ = cfg.Output.Write(buf.Bytes())
-
-			// Put buffer back to pool
-			bytebufferpool.Put(buf)
+			output(c, buf)
 
 			// End chain
 			return nil
 		}
 
-		// Loop over template tags to replace it with the correct value
-		_, err = tmpl.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
-			switch tag {
-			case TagTime:
-				return buf.WriteString(timestamp.Load().(string))
-			case TagReferer:
-				return buf.WriteString(c.Get(fiber.HeaderReferer))
-			case TagProtocol:
-				return buf.WriteString(c.Protocol())
-			case TagPid:
-				return buf.WriteString(pid)
-			case TagIP:
-				return buf.WriteString(c.IP())
-			case TagIPs:
-				return buf.WriteString(c.Get(fiber.HeaderXForwardedFor))
-			case TagHost:
-				return buf.WriteString(c.Hostname())
-			case TagPath:
-				return buf.WriteString(c.Path())
-			case TagURL:
-				return buf.WriteString(c.OriginalURL())
-			case TagUA:
-				return buf.WriteString(c.Get(fiber.HeaderUserAgent))
-			case TagLatency:
-				return buf.WriteString(stop.Sub(start).String())
-			case TagBody:
-				return buf.Write(c.Body())
-			case TagBytesReceived:
-				return appendInt(buf, len(c.Request().Body()))
-			case TagBytesSent:
-				return appendInt(buf, len(c.Response().Body()))
-			case TagRoute:
-				return buf.WriteString(c.Route().Path)
-			case TagStatus:
-				return appendInt(buf, c.Response().StatusCode())
-			case TagMethod:
-				return buf.WriteString(c.Method())
-			case TagBlack:
-				return buf.WriteString(cBlack)
-			case TagRed:
-				return buf.WriteString(cRed)
-			case TagGreen:
-				return buf.WriteString(cGreen)
-			case TagYellow:
-				return buf.WriteString(cYellow)
-			case TagBlue:
-				return buf.WriteString(cBlue)
-			case TagMagenta:
-				return buf.WriteString(cMagenta)
-			case TagCyan:
-				return buf.WriteString(cCyan)
-			case TagWhite:
-				return buf.WriteString(cWhite)
-			case TagReset:
-				return buf.WriteString(cReset)
-			case TagError:
-				if chainErr != nil {
-					return buf.WriteString(chainErr.Error())
+		// Request data passed to custom tags
+		data := &LoggerData{
+			Pid:       pid,
+			Timestamp: timestamp.Load().(string),
+			Start:     start,
+			Stop:      stop,
+			ChainErr:  chainErr,
+		}
+
+		if cfg.enableJSON {
+			err = appendJSON(buf, c, data, cfg.CustomTags, customTags)
+		} else {
+			// Loop over template tags to replace it with the correct value
+			_, err = tmpl.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
+				if fn, ok := cfg.CustomTags[tag]; ok {
+					return fn(buf, c, data)
 				}
-				return buf.WriteString("-")
-			default:
-				// Check if we have a value tag i.e.: "header:x-key"
-				switch {
-				case strings.HasPrefix(tag, TagHeader):
-					return buf.WriteString(c.Get(tag[7:]))
-				case strings.HasPrefix(tag, TagQuery):
-					return buf.WriteString(c.Query(tag[6:]))
-				case strings.HasPrefix(tag, TagForm):
-					return buf.WriteString(c.FormValue(tag[5:]))
-				case strings.HasPrefix(tag, TagCookie):
-					return buf.WriteString(c.Cookies(tag[7:]))
-				case strings.HasPrefix(tag, TagLocals):
-					switch v := c.Locals(tag[7:]).(type) {
-					case []byte:
-						return buf.Write(v)
-					case string:
-						return buf.WriteString(v)
-					case nil:
-						return 0, nil
-					default:
-						return buf.WriteString(fmt.Sprintf("%v", v))
+				switch tag {
+				case TagTime:
+					return buf.WriteString(timestamp.Load().(string))
+				case TagReferer:
+					return buf.WriteString(c.Get(fiber.HeaderReferer))
+				case TagProtocol:
+					return buf.WriteString(c.Protocol())
+				case TagPid:
+					return buf.WriteString(pid)
+				case TagIP:
+					return buf.WriteString(c.IP())
+				case TagIPs:
+					return buf.WriteString(c.Get(fiber.HeaderXForwardedFor))
+				case TagHost:
+					return buf.WriteString(c.Hostname())
+				case TagPath:
+					return buf.WriteString(c.Path())
+				case TagURL:
+					return buf.WriteString(c.OriginalURL())
+				case TagUA:
+					return buf.WriteString(c.Get(fiber.HeaderUserAgent))
+				case TagLatency:
+					return buf.WriteString(stop.Sub(start).String())
+				case TagBody:
+					return buf.Write(c.Body())
+				case TagBytesReceived:
+					return appendInt(buf, len(c.Request().Body()))
+				case TagBytesSent:
+					return appendInt(buf, len(c.Response().Body()))
+				case TagRoute:
+					return buf.WriteString(c.Route().Path)
+				case TagStatus:
+					return appendInt(buf, c.Response().StatusCode())
+				case TagMethod:
+					return buf.WriteString(c.Method())
+				case TagBlack:
+					return buf.WriteString(cBlack)
+				case TagRed:
+					return buf.WriteString(cRed)
+				case TagGreen:
+					return buf.WriteString(cGreen)
+				case TagYellow:
+					return buf.WriteString(cYellow)
+				case TagBlue:
+					return buf.WriteString(cBlue)
+				case TagMagenta:
+					return buf.WriteString(cMagenta)
+				case TagCyan:
+					return buf.WriteString(cCyan)
+				case TagWhite:
+					return buf.WriteString(cWhite)
+				case TagReset:
+					return buf.WriteString(cReset)
+				case TagError:
+					if chainErr != nil {
+						return buf.WriteString(chainErr.Error())
+					}
+					return buf.WriteString("-")
+				default:
+					// Check if we have a value tag i.e.: "header:x-key"
+					switch {
+					case strings.HasPrefix(tag, TagHeader):
+						return buf.WriteString(c.Get(tag[7:]))
+					case strings.HasPrefix(tag, TagQuery):
+						return buf.WriteString(c.Query(tag[6:]))
+					case strings.HasPrefix(tag, TagForm):
+						return buf.WriteString(c.FormValue(tag[5:]))
+					case strings.HasPrefix(tag, TagCookie):
+						return buf.WriteString(c.Cookies(tag[7:]))
+					case strings.HasPrefix(tag, TagLocals):
+						switch v := c.Locals(tag[7:]).(type) {
+						case []byte:
+							return buf.Write(v)
+						case string:
+							return buf.WriteString(v)
+						case nil:
+							return 0, nil
+						default:
+							return buf.WriteString(fmt.Sprintf("%v", v))
+						}
 					}
 				}
-			}
-			return 0, nil
-		})
+				return 0, nil
+			})
+		}
 		// Also write errors to the buffer
 		if err != nil {
 			_, _ = buf.WriteString(err.Error())
 		}
-		mu.Lock()
 		// Write buffer to output
-		if _, err := cfg.Output.Write(buf.Bytes()); err != nil {
-			// Write error to output
-			if _, err := cfg.Output.Write([]byte(err.Error())); err != nil {
-				// There is something wrong with the given io.Writer
-				// TODO: What should we do here?
-			}
-		}
-		mu.Unlock()
-		// Put buffer back to pool
-		bytebufferpool.Put(buf)
+		output(c, buf)
 
 		return nil
 	}
@@ -1,12 +1,17 @@
 package logger
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http/httptest"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
@@ -36,6 +41,32 @@ func Test_Logger(t *testing.T) {
 	utils.AssertEqual(t, "some random error", buf.String())
 }
 
+// go test -run Test_Logger_Group_ErrorHandler
+func Test_Logger_Group_ErrorHandler(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	app.Use(New(Config{
+		Format: "${status} ${error}",
+		Output: buf,
+	}))
+
+	api := app.Group("/api")
+	api.UseErrorHandler(func(c *fiber.Ctx, err error) error {
+		return c.Status(fiber.StatusTeapot).JSON(fiber.Map{"error": err.Error()})
+	})
+	api.Get("/", func(c *fiber.Ctx) error {
+		return errors.New("some random error")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusTeapot, resp.StatusCode)
+	utils.AssertEqual(t, "418 some random error", buf.String())
+}
+
 // go test -run Test_Logger_locals
 func Test_Logger_locals(t *testing.T) {
 	app := fiber.New()
@@ -96,16 +127,114 @@ func Test_Logger_Next(t *testing.T) {
 	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
 }
 
+// go test -run Test_Logger_SkipLogKey
+func Test_Logger_SkipLogKey(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	app.Use(New(Config{
+		Format: "${path}",
+		Output: buf,
+	}))
+
+	app.Get("/skip", func(c *fiber.Ctx) error {
+		c.Locals(fiber.SkipLogKey, true)
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Get("/log", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/skip", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNoContent, resp.StatusCode)
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/log", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNoContent, resp.StatusCode)
+	utils.AssertEqual(t, "/log", buf.String())
+}
+
 // go test -run Test_Logger_ErrorTimeZone
 func Test_Logger_ErrorTimeZone(t *testing.T) {
+	defer func() {
+		utils.AssertEqual(t, `[logger] invalid TimeZone "invalid": unknown time zone invalid`, recover())
+	}()
+	New(Config{
+		TimeZone: "invalid",
+	})
+}
+
+// go test -run Test_Logger_TimeZone
+func Test_Logger_TimeZone(t *testing.T) {
 	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
 	app.Use(New(Config{
-		TimeZone: "invalid",
+		Format:     "${time}",
+		TimeFormat: time.RFC3339,
+		TimeZone:   "UTC",
+		Output:     buf,
 	}))
 
 	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
+
+	ts, err := time.Parse(time.RFC3339, buf.String())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, strings.HasSuffix(buf.String(), "Z"))
+	utils.AssertEqual(t, true, time.Since(ts) < time.Minute)
+}
+
+// go test -run Test_Logger_TimeInterval
+func Test_Logger_TimeInterval(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	app.Use(New(Config{
+		Format:       "${time}\n",
+		TimeFormat:   time.RFC3339Nano,
+		TimeInterval: 10 * time.Millisecond,
+		Output:       buf,
+	}))
+
+	for i := 0; i < 2; i++ {
+		resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	utils.AssertEqual(t, 2, len(lines))
+	utils.AssertEqual(t, true, lines[0] != lines[1])
+}
+
+// go test -run Test_Logger_tickTimestamp
+func Test_Logger_tickTimestamp(t *testing.T) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	var calls int32
+	go func() {
+		tickTimestamp(time.Millisecond, done, func() { atomic.AddInt32(&calls, 1) })
+		close(stopped)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	close(done)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("ticker did not stop")
+	}
+	utils.AssertEqual(t, true, atomic.LoadInt32(&calls) > 0)
 }
 
 type fakeOutput int
@@ -145,7 +274,8 @@ func Test_Logger_All(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
 
-	expected := fmt.Sprintf("%dhttp0.0.0.0example.com//%s%s%s%s%s%s%s%s%s-", os.Getpid(), cBlack, cRed, cGreen, cYellow, cBlue, cMagenta, cCyan, cWhite, cReset)
+	// The route of unmatched requests is empty
+	expected := fmt.Sprintf("%dhttp0.0.0.0example.com/%s%s%s%s%s%s%s%s%s-", os.Getpid(), cBlack, cRed, cGreen, cYellow, cBlue, cMagenta, cCyan, cWhite, cReset)
 	utils.AssertEqual(t, expected, buf.String())
 }
 
@@ -171,6 +301,190 @@ func Test_Logger_AppendUint(t *testing.T) {
 	utils.AssertEqual(t, "0 5 200", buf.String())
 }
 
+// go test -run Test_Logger_CustomTags
+func Test_Logger_CustomTags(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	app.Use(New(Config{
+		Format: "${userID} ${status} ${method}",
+		CustomTags: map[string]LogFunc{
+			"userID": func(output io.Writer, c *fiber.Ctx, data *LoggerData) (int, error) {
+				return output.Write([]byte(c.Locals("userID").(string)))
+			},
+			TagMethod: func(output io.Writer, c *fiber.Ctx, data *LoggerData) (int, error) {
+				return io.WriteString(output, "method")
+			},
+		},
+		Output: buf,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Locals("userID", "john")
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, "john 200 method", buf.String())
+}
+
+// go test -run Test_Logger_JSON
+func Test_Logger_JSON(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	app.Use(New(Config{
+		Format: FormatJSON,
+		CustomTags: map[string]LogFunc{
+			"user": func(output io.Writer, c *fiber.Ctx, data *LoggerData) (int, error) {
+				return io.WriteString(output, "\"john\"\n")
+			},
+		},
+		Output: buf,
+	}))
+
+	app.Post("/", func(c *fiber.Ctx) error {
+		_ = c.SendString("hello")
+		return errors.New("some random error")
+	})
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/", strings.NewReader("body")))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+
+	raw := buf.String()
+	utils.AssertEqual(t, true, strings.HasPrefix(raw, `{"time":"`))
+	utils.AssertEqual(t, true, strings.HasSuffix(raw, "}\n"))
+
+	var log map[string]interface{}
+	utils.AssertEqual(t, nil, json.Unmarshal(buf.Bytes(), &log))
+	_, err = time.Parse(time.RFC3339, log["time"].(string))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, float64(fiber.StatusInternalServerError), log["status"])
+	_, ok := log["latency_ms"].(float64)
+	utils.AssertEqual(t, true, ok)
+	utils.AssertEqual(t, "POST", log["method"])
+	utils.AssertEqual(t, "/", log["path"])
+	utils.AssertEqual(t, "0.0.0.0", log["ip"])
+	utils.AssertEqual(t, float64(4), log["bytes_in"])
+	utils.AssertEqual(t, float64(len("some random error")), log["bytes_out"])
+	utils.AssertEqual(t, "some random error", log["error"])
+	utils.AssertEqual(t, "\"john\"\n", log["user"])
+
+	// Successful requests log a null error
+	buf.Reset()
+	resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	log = nil
+	utils.AssertEqual(t, nil, json.Unmarshal(buf.Bytes(), &log))
+	value, ok := log["error"]
+	utils.AssertEqual(t, true, ok)
+	utils.AssertEqual(t, nil, value)
+}
+
+// go test -run Test_Logger_Done
+func Test_Logger_Done(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	var lines [][]byte
+	app.Use(New(Config{
+		Format: "${status} ${path}\n",
+		Output: buf,
+		Done: func(c *fiber.Ctx, logString []byte) {
+			lines = append(lines, logString)
+		},
+	}))
+
+	app.Get("/:name", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for _, path := range []string{"/john", "/doe"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	}
+
+	// The retained lines are not overwritten by pooled buffers
+	utils.AssertEqual(t, 2, len(lines))
+	utils.AssertEqual(t, "200 /john\n", string(lines[0]))
+	utils.AssertEqual(t, "200 /doe\n", string(lines[1]))
+	utils.AssertEqual(t, "200 /john\n200 /doe\n", buf.String())
+}
+
+// go test -run Test_Logger_Done_Sampling
+func Test_Logger_Done_Sampling(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	threshold := 20 * time.Millisecond
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("start", time.Now())
+		return c.Next()
+	})
+	app.Use(New(Config{
+		Format:               "${path}\n",
+		Output:               ioutil.Discard,
+		DisableDefaultOutput: true,
+		Done: func(c *fiber.Ctx, logString []byte) {
+			// Only log slow requests
+			if time.Since(c.Locals("start").(time.Time)) > threshold {
+				_, _ = buf.Write(logString)
+			}
+		},
+	}))
+
+	app.Get("/fast", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/slow", func(c *fiber.Ctx) error {
+		time.Sleep(2 * threshold)
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for _, path := range []string{"/fast", "/slow", "/fast"} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	}
+
+	utils.AssertEqual(t, "/slow\n", buf.String())
+}
+
+// go test -run Test_Logger_DisableDefaultOutput
+func Test_Logger_DisableDefaultOutput(t *testing.T) {
+	app := fiber.New()
+
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	app.Use(New(Config{
+		Format:               "${status}",
+		Output:               buf,
+		DisableDefaultOutput: true,
+	}))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
+	utils.AssertEqual(t, "", buf.String())
+}
+
 // go test -v -run=^$ -bench=Benchmark_Logger -benchmem -count=4
 func Benchmark_Logger(b *testing.B) {
 	app := fiber.New()
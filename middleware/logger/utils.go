@@ -1,7 +1,11 @@
 package logger
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
 )
 
 func methodColor(method string) string {
@@ -37,3 +41,87 @@ func statusColor(code int) string {
 		return cRed
 	}
 }
+
+// tickTimestamp calls update every interval until done is closed
+func tickTimestamp(interval time.Duration, done <-chan struct{}, update func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			update()
+		case <-done:
+			return
+		}
+	}
+}
+
+// appendJSON writes the FormatJSON document of the request to buf,
+// followed by the custom tags in the given order
+func appendJSON(buf *bytebufferpool.ByteBuffer, c *fiber.Ctx, data *LoggerData, tags map[string]LogFunc, names []string) error {
+	b := append(buf.B, `{"time":`...)
+	b = appendJSONString(b, data.Timestamp)
+	b = append(b, `,"status":`...)
+	b = strconv.AppendInt(b, int64(c.Response().StatusCode()), 10)
+	b = append(b, `,"latency_ms":`...)
+	b = strconv.AppendFloat(b, float64(data.Stop.Sub(data.Start))/float64(time.Millisecond), 'f', -1, 64)
+	b = append(b, `,"method":`...)
+	b = appendJSONString(b, c.Method())
+	b = append(b, `,"path":`...)
+	b = appendJSONString(b, c.Path())
+	b = append(b, `,"ip":`...)
+	b = appendJSONString(b, c.IP())
+	b = append(b, `,"bytes_in":`...)
+	b = strconv.AppendInt(b, int64(len(c.Request().Body())), 10)
+	b = append(b, `,"bytes_out":`...)
+	b = strconv.AppendInt(b, int64(len(c.Response().Body())), 10)
+	b = append(b, `,"error":`...)
+	if data.ChainErr != nil {
+		b = appendJSONString(b, data.ChainErr.Error())
+	} else {
+		b = append(b, "null"...)
+	}
+
+	// Custom tags are rendered into a separate buffer to be escaped
+	if len(names) > 0 {
+		value := bytebufferpool.Get()
+		defer bytebufferpool.Put(value)
+		for _, name := range names {
+			value.Reset()
+			if _, err := tags[name](value, c, data); err != nil {
+				buf.B = b
+				return err
+			}
+			b = append(b, ',')
+			b = appendJSONString(b, name)
+			b = append(b, ':')
+			b = appendJSONString(b, value.String())
+		}
+	}
+	buf.B = append(b, "}\n"...)
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a quoted JSON string
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; {
+		case ch == '"' || ch == '\\':
+			b = append(b, '\\', ch)
+		case ch == '\n':
+			b = append(b, '\\', 'n')
+		case ch == '\r':
+			b = append(b, '\\', 'r')
+		case ch == '\t':
+			b = append(b, '\\', 't')
+		case ch < 0x20:
+			b = append(b, '\\', 'u', '0', '0', hexDigits[ch>>4], hexDigits[ch&0xf])
+		default:
+			b = append(b, ch)
+		}
+	}
+	return append(b, '"')
+}
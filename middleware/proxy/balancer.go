@@ -0,0 +1,258 @@
+package proxy
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+)
+
+// LoadBalancer distributes requests in a round-robin manner among the
+// healthy upstream servers
+type LoadBalancer struct {
+	cfg       Config
+	client    fasthttp.Client
+	upstreams []*upstream
+	counter   uint32
+	webSocket webSocketProxy
+	done      chan struct{}
+	stopOnce  sync.Once
+}
+
+// upstream holds the health state of a server
+type upstream struct {
+	addr    string
+	healthy int32
+	// Consecutive check results, only used by the health checker
+	successes int
+	failures  int
+}
+
+// NewBalancer creates a load balancer among multiple upstream servers and
+// starts the health checks if a HealthCheckPath is configured
+func NewBalancer(config Config) *LoadBalancer {
+	// Set default config
+	cfg := configDefault(config)
+
+	lb := &LoadBalancer{
+		cfg: cfg,
+		client: fasthttp.Client{
+			NoDefaultUserAgentHeader: true,
+			DisablePathNormalizing:   true,
+			TLSConfig:                cfg.TlsConfig,
+		},
+		webSocket: webSocketProxy{
+			tlsConfig:   cfg.TlsConfig,
+			timeout:     cfg.Timeout,
+			idleTimeout: cfg.WebSocketIdleTimeout,
+			maxLifetime: cfg.WebSocketMaxLifetime,
+		},
+		done: make(chan struct{}),
+	}
+
+	// Scheme must be provided, falls back to http
+	for _, addr := range cfg.Servers {
+		if !strings.HasPrefix(addr, "http") {
+			addr = "http://" + addr
+		}
+		lb.upstreams = append(lb.upstreams, &upstream{addr: addr, healthy: 1})
+	}
+
+	if cfg.HealthCheckPath != "" {
+		go lb.healthCheck()
+	}
+	return lb
+}
+
+// Healthy returns the servers which are currently in the rotation
+func (lb *LoadBalancer) Healthy() []string {
+	servers := make([]string, 0, len(lb.upstreams))
+	for _, u := range lb.upstreams {
+		if atomic.LoadInt32(&u.healthy) == 1 {
+			servers = append(servers, u.addr)
+		}
+	}
+	return servers
+}
+
+// Stop stops the health checks
+func (lb *LoadBalancer) Stop() {
+	lb.stopOnce.Do(func() { close(lb.done) })
+}
+
+// Handler returns the handler which proxies the requests, the health checks
+// are stopped when the app shuts down
+func (lb *LoadBalancer) Handler() fiber.Handler {
+	cfg := lb.cfg
+	var once sync.Once
+
+	// Return new handler
+	return func(c *fiber.Ctx) (err error) {
+		// Don't execute middleware if Next returns true
+		if cfg.Next != nil && cfg.Next(c) {
+			return c.Next()
+		}
+
+		// Stop the health checks with the app
+		once.Do(func() {
+			c.App().Hooks().OnShutdown(func() error {
+				lb.Stop()
+				return nil
+			})
+		})
+
+		// Set request and response
+		req := c.Request()
+		res := c.Response()
+
+		// Don't proxy "Connection" header, unless it asks for an upgrade
+		upgrade := isWebSocketUpgrade(c)
+		if !upgrade {
+			req.Header.Del(fiber.HeaderConnection)
+		}
+
+		// Modify request
+		if cfg.ModifyRequest != nil {
+			if err = cfg.ModifyRequest(c); err != nil {
+				return err
+			}
+		}
+
+		// Read a streamed body once, so it can be sent again on retries
+		if cfg.MaxRetries > 0 {
+			_ = req.Body()
+		}
+
+		uri := utils.CopyString(utils.UnsafeString(req.RequestURI()))
+
+		// WebSocket connections are piped to a single upstream
+		if upgrade {
+			u := lb.next()
+			if u == nil {
+				return fiber.ErrServiceUnavailable
+			}
+			req.SetRequestURI(u.addr + uri)
+			return lb.webSocket.forward(c)
+		}
+		for attempt := 0; ; attempt++ {
+			u := lb.next()
+			if u == nil {
+				return fiber.ErrServiceUnavailable
+			}
+			req.SetRequestURI(u.addr + uri)
+
+			// Forward request
+			err = lb.do(req, res)
+			retry := attempt < cfg.MaxRetries
+			if err != nil {
+				if retry && isConnError(err) {
+					continue
+				}
+				return upstreamError(err)
+			}
+			if retry && cfg.RetryOn5xx && res.StatusCode() >= fiber.StatusInternalServerError {
+				continue
+			}
+			break
+		}
+
+		// Don't proxy "Connection" header
+		res.Header.Del(fiber.HeaderConnection)
+
+		// Modify response
+		if cfg.ModifyResponse != nil {
+			if err = cfg.ModifyResponse(c); err != nil {
+				return err
+			}
+		}
+
+		// Return nil to end proxying if no error
+		return nil
+	}
+}
+
+// do forwards the request within the Timeout
+func (lb *LoadBalancer) do(req *fasthttp.Request, res *fasthttp.Response) error {
+	if lb.cfg.Timeout > 0 {
+		return lb.client.DoTimeout(req, res, lb.cfg.Timeout)
+	}
+	return lb.client.Do(req, res)
+}
+
+// next returns the next healthy upstream in the rotation, nil is returned
+// if all upstreams are unhealthy
+func (lb *LoadBalancer) next() *upstream {
+	n := uint32(len(lb.upstreams))
+	for i := uint32(0); i < n; i++ {
+		u := lb.upstreams[(atomic.AddUint32(&lb.counter, 1)-1)%n]
+		if atomic.LoadInt32(&u.healthy) == 1 {
+			return u
+		}
+	}
+	return nil
+}
+
+// healthCheck checks all upstreams every HealthCheckInterval until the
+// balancer is stopped
+func (lb *LoadBalancer) healthCheck() {
+	ticker := time.NewTicker(lb.cfg.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			var wg sync.WaitGroup
+			for _, u := range lb.upstreams {
+				wg.Add(1)
+				go func(u *upstream) {
+					defer wg.Done()
+					lb.check(u)
+				}(u)
+			}
+			wg.Wait()
+		case <-lb.done:
+			return
+		}
+	}
+}
+
+// check requests the HealthCheckPath of the upstream and updates its state
+// once a threshold is reached
+func (lb *LoadBalancer) check(u *upstream) {
+	req := fasthttp.AcquireRequest()
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI(u.addr + lb.cfg.HealthCheckPath)
+	err := lb.client.DoTimeout(req, res, lb.cfg.HealthCheckTimeout)
+
+	if err == nil && res.StatusCode() < fiber.StatusBadRequest {
+		u.successes++
+		u.failures = 0
+		if u.successes >= lb.cfg.HealthyThreshold {
+			atomic.StoreInt32(&u.healthy, 1)
+		}
+		return
+	}
+	u.failures++
+	u.successes = 0
+	if u.failures >= lb.cfg.UnhealthyThreshold {
+		atomic.StoreInt32(&u.healthy, 0)
+	}
+}
+
+// isConnError reports whether the request failed because the upstream
+// could not be reached or closed the connection
+func isConnError(err error) bool {
+	var netErr *net.OpError
+	return errors.As(err, &netErr) ||
+		errors.Is(err, fasthttp.ErrConnectionClosed) ||
+		errors.Is(err, fasthttp.ErrDialTimeout) ||
+		errors.Is(err, fasthttp.ErrNoFreeConns)
+}
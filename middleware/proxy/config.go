@@ -1,6 +1,9 @@
 package proxy
 
 import (
+	"crypto/tls"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -28,13 +31,85 @@ type Config struct {
 	//
 	// Optional. Default: nil
 	ModifyResponse fiber.Handler
+
+	// Timeout is the maximum duration of an upstream request, independent
+	// of the client connection. Timeouts are answered with 504 Gateway Timeout.
+	//
+	// Optional. Default: 0, no timeout
+	Timeout time.Duration
+
+	// TlsConfig is the TLS config of the upstream client, i.e. to accept
+	// self-signed certificates
+	//
+	// Optional. Default: nil
+	TlsConfig *tls.Config
+
+	// HealthCheckPath is requested on every server each HealthCheckInterval,
+	// servers are removed from the rotation after UnhealthyThreshold failed
+	// checks and restored after HealthyThreshold successful ones. A check
+	// succeeds on a status code below 400. Health checks are disabled if
+	// the path is empty.
+	//
+	// Optional. Default: ""
+	HealthCheckPath string
+
+	// HealthCheckInterval is the delay between two health checks
+	//
+	// Optional. Default: 10 * time.Second
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout is the timeout of a single health check
+	//
+	// Optional. Default: 2 * time.Second
+	HealthCheckTimeout time.Duration
+
+	// HealthyThreshold is the number of successful checks in a row to
+	// restore a server
+	//
+	// Optional. Default: 2
+	HealthyThreshold int
+
+	// UnhealthyThreshold is the number of failed checks in a row to remove
+	// a server from the rotation
+	//
+	// Optional. Default: 3
+	UnhealthyThreshold int
+
+	// MaxRetries is the number of times a request is retried against the
+	// next healthy server if the connection to the upstream fails
+	//
+	// Optional. Default: 0
+	MaxRetries int
+
+	// RetryOn5xx also retries requests which were answered with a 5xx
+	// status code
+	//
+	// Optional. Default: false
+	RetryOn5xx bool
+	// WebSocketIdleTimeout closes proxied WebSocket connections if one
+	// direction didn't receive data for this duration, a negative value
+	// disables it
+	//
+	// Optional. Default: 60 * time.Second
+	WebSocketIdleTimeout time.Duration
+
+	// WebSocketMaxLifetime closes proxied WebSocket connections once they
+	// are open for this duration, 0 disables it
+	//
+	// Optional. Default: 0
+	WebSocketMaxLifetime time.Duration
 }
 
 // ConfigDefault is the default config
 var ConfigDefault = Config{
-	Next:           nil,
-	ModifyRequest:  nil,
-	ModifyResponse: nil,
+	Next:                 nil,
+	ModifyRequest:        nil,
+	ModifyResponse:       nil,
+	HealthCheckInterval:  10 * time.Second,
+	HealthCheckTimeout:   2 * time.Second,
+	HealthyThreshold:     2,
+	UnhealthyThreshold:   3,
+	WebSocketIdleTimeout: 60 * time.Second,
 }
 
 // Helper function to set default values
@@ -51,5 +126,23 @@ func configDefault(config ...Config) Config {
 	if len(cfg.Servers) == 0 {
 		panic("Servers cannot be empty")
 	}
+	if cfg.HealthCheckInterval <= 0 {
+		cfg.HealthCheckInterval = ConfigDefault.HealthCheckInterval
+	}
+	if cfg.HealthCheckTimeout <= 0 {
+		cfg.HealthCheckTimeout = ConfigDefault.HealthCheckTimeout
+	}
+	if cfg.HealthyThreshold <= 0 {
+		cfg.HealthyThreshold = ConfigDefault.HealthyThreshold
+	}
+	if cfg.UnhealthyThreshold <= 0 {
+		cfg.UnhealthyThreshold = ConfigDefault.UnhealthyThreshold
+	}
+	if cfg.WebSocketIdleTimeout == 0 {
+		cfg.WebSocketIdleTimeout = ConfigDefault.WebSocketIdleTimeout
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	}
 	return cfg
 }
@@ -1,11 +1,11 @@
 package proxy
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
 )
 
@@ -15,67 +15,10 @@ func New(config Config) fiber.Handler {
 	return Balancer(config)
 }
 
-// Balancer creates a load balancer among multiple upstream servers
+// Balancer creates a load balancer among multiple upstream servers,
+// use NewBalancer to access the healthy servers
 func Balancer(config Config) fiber.Handler {
-	// Set default config
-	cfg := configDefault(config)
-
-	client := fasthttp.Client{
-		NoDefaultUserAgentHeader: true,
-		DisablePathNormalizing:   true,
-	}
-
-	// Scheme must be provided, falls back to http
-	for i := 0; i < len(cfg.Servers); i++ {
-		if !strings.HasPrefix(cfg.Servers[i], "http") {
-			cfg.Servers[i] = "http://" + cfg.Servers[i]
-		}
-	}
-
-	var counter = 0
-
-	// Return new handler
-	return func(c *fiber.Ctx) (err error) {
-		// Don't execute middleware if Next returns true
-		if cfg.Next != nil && cfg.Next(c) {
-			return c.Next()
-		}
-
-		// Set request and response
-		req := c.Request()
-		res := c.Response()
-
-		// Don't proxy "Connection" header
-		req.Header.Del(fiber.HeaderConnection)
-
-		// Modify request
-		if cfg.ModifyRequest != nil {
-			if err = cfg.ModifyRequest(c); err != nil {
-				return err
-			}
-		}
-
-		req.SetRequestURI(cfg.Servers[counter] + utils.UnsafeString(req.RequestURI()))
-		counter = (counter + 1) % len(cfg.Servers)
-
-		// Forward request
-		if err = client.Do(req, res); err != nil {
-			return err
-		}
-
-		// Don't proxy "Connection" header
-		res.Header.Del(fiber.HeaderConnection)
-
-		// Modify response
-		if cfg.ModifyResponse != nil {
-			if err = cfg.ModifyResponse(c); err != nil {
-				return err
-			}
-		}
-
-		// Return nil to end proxying if no error
-		return nil
-	}
+	return NewBalancer(config).Handler()
 }
 
 var client = fasthttp.Client{
@@ -83,6 +26,19 @@ var client = fasthttp.Client{
 	DisablePathNormalizing:   true,
 }
 
+// webSocket proxies the WebSocket connections of Forward, Do and DoRedirects
+var webSocket = webSocketProxy{
+	idleTimeout: ConfigDefault.WebSocketIdleTimeout,
+	maxLifetime: ConfigDefault.WebSocketMaxLifetime,
+}
+
+// WithTlsConfig sets the TLS config of the client used by Forward, Do and
+// DoRedirects. It must be called before the first request.
+func WithTlsConfig(tlsConfig *tls.Config) {
+	client.TLSConfig = tlsConfig
+	webSocket.tlsConfig = tlsConfig
+}
+
 // Forward performs the given http request and fills the given http response.
 // This method will return an fiber.Handler
 func Forward(addr string) fiber.Handler {
@@ -94,13 +50,37 @@ func Forward(addr string) fiber.Handler {
 // Do performs the given http request and fills the given http response.
 // This method can be used within a fiber.Handler
 func Do(c *fiber.Ctx, addr string) error {
+	return doAction(c, addr, client.Do)
+}
+
+// DoRedirects performs the given http request and fills the given http
+// response, following up to maxRedirects redirects.
+// This method can be used within a fiber.Handler
+func DoRedirects(c *fiber.Ctx, addr string, maxRedirects int) error {
+	return doAction(c, addr, func(req *fasthttp.Request, res *fasthttp.Response) error {
+		return client.DoRedirects(req, res, maxRedirects)
+	})
+}
+
+func doAction(c *fiber.Ctx, addr string, action func(req *fasthttp.Request, res *fasthttp.Response) error) error {
 	req := c.Request()
 	res := c.Response()
 	req.SetRequestURI(addr)
+	if isWebSocketUpgrade(c) {
+		return webSocket.forward(c)
+	}
 	req.Header.Del(fiber.HeaderConnection)
-	if err := client.Do(req, res); err != nil {
-		return err
+	if err := action(req, res); err != nil {
+		return upstreamError(err)
 	}
 	res.Header.Del(fiber.HeaderConnection)
 	return nil
 }
+
+// upstreamError turns upstream timeouts into a 504 Gateway Timeout
+func upstreamError(err error) error {
+	if errors.Is(err, fasthttp.ErrTimeout) {
+		return fiber.ErrGatewayTimeout
+	}
+	return err
+}
@@ -1,8 +1,14 @@
 package proxy
 
 import (
+	"bufio"
+	"crypto/tls"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -142,3 +148,311 @@ func Test_Proxy_Modify_Request(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, "modified request", string(b))
 }
+
+// createProxyTestServer starts the app on a random port and returns its address
+func createProxyTestServer(t *testing.T, handler fiber.Handler) (*fiber.App, string) {
+	target := fiber.New(fiber.Config{DisableStartupMessage: true})
+	target.All("/*", handler)
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	go func() {
+		utils.AssertEqual(t, nil, target.Listener(ln))
+	}()
+	return target, ln.Addr().String()
+}
+
+// unusedAddr returns an address without a listening server
+func unusedAddr(t *testing.T) string {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, ln.Close())
+	return ln.Addr().String()
+}
+
+// go test -run Test_Proxy_Balancer_HealthCheck
+func Test_Proxy_Balancer_HealthCheck(t *testing.T) {
+	var healthy int32 = 1
+	target, addr := createProxyTestServer(t, func(c *fiber.Ctx) error {
+		if c.Path() == "/health" && atomic.LoadInt32(&healthy) == 0 {
+			return c.SendStatus(fiber.StatusServiceUnavailable)
+		}
+		return c.SendString("upstream")
+	})
+	defer func() { _ = target.Shutdown() }()
+
+	lb := NewBalancer(Config{
+		Servers:             []string{addr},
+		HealthCheckPath:     "/health",
+		HealthCheckInterval: 10 * time.Millisecond,
+		HealthyThreshold:    2,
+		UnhealthyThreshold:  2,
+	})
+	defer lb.Stop()
+
+	app := fiber.New()
+	app.Use(lb.Handler())
+
+	utils.AssertEqual(t, []string{"http://" + addr}, lb.Healthy())
+
+	// The server is removed from the rotation
+	atomic.StoreInt32(&healthy, 0)
+	time.Sleep(100 * time.Millisecond)
+	utils.AssertEqual(t, []string{}, lb.Healthy())
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	// The server is restored
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(100 * time.Millisecond)
+	utils.AssertEqual(t, []string{"http://" + addr}, lb.Healthy())
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+}
+
+// go test -run Test_Proxy_Balancer_Retry
+func Test_Proxy_Balancer_Retry(t *testing.T) {
+	target, addr := createProxyTestServer(t, func(c *fiber.Ctx) error {
+		return c.Send(c.Body())
+	})
+	defer func() { _ = target.Shutdown() }()
+
+	app := fiber.New()
+	app.Use(Balancer(Config{
+		Servers:    []string{unusedAddr(t), addr},
+		MaxRetries: 1,
+	}))
+
+	// Every second request is retried against the running server
+	for i := 0; i < 4; i++ {
+		resp, err := app.Test(httptest.NewRequest("POST", "/", strings.NewReader("body")))
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, "body", string(body))
+	}
+}
+
+// go test -run Test_Proxy_Balancer_RetryOn5xx
+func Test_Proxy_Balancer_RetryOn5xx(t *testing.T) {
+	failing, failingAddr := createProxyTestServer(t, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusBadGateway)
+	})
+	defer func() { _ = failing.Shutdown() }()
+	target, addr := createProxyTestServer(t, func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	defer func() { _ = target.Shutdown() }()
+
+	for retryOn5xx, statuses := range map[bool][]int{
+		false: {fiber.StatusBadGateway, fiber.StatusOK},
+		true:  {fiber.StatusOK, fiber.StatusOK},
+	} {
+		app := fiber.New()
+		app.Use(Balancer(Config{
+			Servers:    []string{failingAddr, addr},
+			MaxRetries: 1,
+			RetryOn5xx: retryOn5xx,
+		}))
+
+		for _, status := range statuses {
+			resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+			utils.AssertEqual(t, nil, err)
+			utils.AssertEqual(t, status, resp.StatusCode)
+		}
+	}
+}
+
+// go test -run Test_Proxy_Timeout
+func Test_Proxy_Timeout(t *testing.T) {
+	target, addr := createProxyTestServer(t, func(c *fiber.Ctx) error {
+		time.Sleep(200 * time.Millisecond)
+		return c.SendStatus(fiber.StatusOK)
+	})
+	defer func() { _ = target.Shutdown() }()
+
+	app := fiber.New()
+	app.Use(Balancer(Config{
+		Servers: []string{addr},
+		Timeout: 50 * time.Millisecond,
+	}))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusGatewayTimeout, resp.StatusCode)
+}
+
+// go test -run Test_Proxy_TlsConfig
+func Test_Proxy_TlsConfig(t *testing.T) {
+	cer, err := tls.LoadX509KeyPair("../../.github/testdata/ssl.pem", "../../.github/testdata/ssl.key")
+	utils.AssertEqual(t, nil, err)
+
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	ln = tls.NewListener(ln, &tls.Config{Certificates: []tls.Certificate{cer}})
+
+	target := fiber.New(fiber.Config{DisableStartupMessage: true})
+	target.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(c.Protocol())
+	})
+	go func() {
+		utils.AssertEqual(t, nil, target.Listener(ln))
+	}()
+	defer func() { _ = target.Shutdown() }()
+
+	app := fiber.New()
+	app.Use(Balancer(Config{
+		Servers:   []string{"https://" + ln.Addr().String()},
+		TlsConfig: &tls.Config{InsecureSkipVerify: true},
+	}))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "https", string(body))
+}
+
+// go test -run Test_Proxy_DoRedirects
+func Test_Proxy_DoRedirects(t *testing.T) {
+	target, addr := createProxyTestServer(t, func(c *fiber.Ctx) error {
+		if c.Path() == "/old" {
+			return c.Redirect("/new")
+		}
+		return c.SendString(c.Path())
+	})
+	defer func() { _ = target.Shutdown() }()
+
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return DoRedirects(c, "http://"+addr+"/old", 1)
+	})
+	app.Get("/none", func(c *fiber.Ctx) error {
+		return Do(c, "http://"+addr+"/old")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "/new", string(body))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/none", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusFound, resp.StatusCode)
+}
+
+// createWebSocketTestServer starts an upstream which accepts every upgrade
+// and echoes the data it receives
+func createWebSocketTestServer(t *testing.T) (net.Listener, string) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	utils.AssertEqual(t, nil, err)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				br := bufio.NewReader(conn)
+				for {
+					line, err := br.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == "\r\n" {
+						break
+					}
+				}
+				_, _ = conn.Write([]byte("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+				_, _ = io.Copy(conn, br)
+			}(conn)
+		}
+	}()
+	return ln, ln.Addr().String()
+}
+
+// dialWebSocket performs the upgrade through the proxy at addr
+func dialWebSocket(t *testing.T, addr string) (net.Conn, *bufio.Reader) {
+	conn, err := net.Dial("tcp4", addr)
+	utils.AssertEqual(t, nil, err)
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: " + addr + "\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n"))
+	utils.AssertEqual(t, nil, err)
+
+	br := bufio.NewReader(conn)
+	status, err := br.ReadString('\n')
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "HTTP/1.1 101 Switching Protocols\r\n", status)
+	for {
+		line, err := br.ReadString('\n')
+		utils.AssertEqual(t, nil, err)
+		if line == "\r\n" {
+			break
+		}
+	}
+	return conn, br
+}
+
+// go test -run Test_Proxy_WebSocket
+func Test_Proxy_WebSocket(t *testing.T) {
+	upstream, upstreamAddr := createWebSocketTestServer(t)
+	defer upstream.Close()
+
+	for name, handler := range map[string]fiber.Handler{
+		"balancer": Balancer(Config{Servers: []string{upstreamAddr}}),
+		"forward":  Forward("http://" + upstreamAddr + "/ws"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			app, addr := createProxyTestServer(t, handler)
+			defer func() { _ = app.Shutdown() }()
+
+			conn, br := dialWebSocket(t, addr)
+			defer conn.Close()
+
+			for _, msg := range []string{"hello", "world"} {
+				_, err := conn.Write([]byte(msg))
+				utils.AssertEqual(t, nil, err)
+				buf := make([]byte, len(msg))
+				_, err = io.ReadFull(br, buf)
+				utils.AssertEqual(t, nil, err)
+				utils.AssertEqual(t, msg, string(buf))
+			}
+		})
+	}
+}
+
+// go test -run Test_Proxy_WebSocket_Timeouts
+func Test_Proxy_WebSocket_Timeouts(t *testing.T) {
+	upstream, upstreamAddr := createWebSocketTestServer(t)
+	defer upstream.Close()
+
+	for name, cfg := range map[string]Config{
+		"idle":     {WebSocketIdleTimeout: 50 * time.Millisecond},
+		"lifetime": {WebSocketIdleTimeout: -1, WebSocketMaxLifetime: 50 * time.Millisecond},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg.Servers = []string{upstreamAddr}
+			app, addr := createProxyTestServer(t, Balancer(cfg))
+			defer func() { _ = app.Shutdown() }()
+
+			conn, br := dialWebSocket(t, addr)
+			defer conn.Close()
+
+			// The proxy closes the connection
+			_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+			_, err := br.ReadByte()
+			utils.AssertEqual(t, io.EOF, err)
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// maxWebSocketHandshake limits the size of the upstream handshake response
+const maxWebSocketHandshake = 64 * 1024
+
+var errWebSocketHandshake = errors.New("proxy: invalid websocket handshake response")
+
+// webSocketProxy holds the settings of proxied WebSocket connections
+type webSocketProxy struct {
+	tlsConfig   *tls.Config
+	timeout     time.Duration
+	idleTimeout time.Duration
+	maxLifetime time.Duration
+}
+
+// isWebSocketUpgrade reports whether the request asks for a WebSocket upgrade
+func isWebSocketUpgrade(c *fiber.Ctx) bool {
+	return strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") &&
+		strings.Contains(strings.ToLower(c.Get(fiber.HeaderConnection)), "upgrade")
+}
+
+// forward sends the upgrade request to the upstream in the request URI and
+// relays the handshake. Once the upstream switched protocols, the client
+// connection is hijacked and both connections are piped until one closes.
+func (wp *webSocketProxy) forward(c *fiber.Ctx) error {
+	req := c.Request()
+	conn, err := wp.dial(req.URI())
+	if err != nil {
+		return upstreamError(err)
+	}
+
+	// The handshake is limited by the Timeout
+	if wp.timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(wp.timeout))
+	}
+	bw := bufio.NewWriter(conn)
+	if err = req.Write(bw); err == nil {
+		err = bw.Flush()
+	}
+	br := bufio.NewReader(conn)
+	var head []byte
+	var status int
+	if err == nil {
+		head, status, err = readHandshake(br)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return upstreamError(err)
+	}
+
+	// The upstream refused the upgrade, answer with its response
+	if status != fiber.StatusSwitchingProtocols {
+		defer conn.Close()
+		res := c.Response()
+		if err = res.Read(bufio.NewReader(io.MultiReader(bytes.NewReader(head), br))); err != nil {
+			return upstreamError(err)
+		}
+		res.Header.Del(fiber.HeaderConnection)
+		return nil
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	c.Context().HijackSetNoResponse(true)
+	c.Context().Hijack(func(client net.Conn) {
+		if _, err := client.Write(head); err != nil {
+			_ = conn.Close()
+			return
+		}
+		wp.pipe(client, conn, br)
+	})
+	return nil
+}
+
+// dial connects to the host of the uri, https and wss use the TLS config
+func (wp *webSocketProxy) dial(uri *fasthttp.URI) (net.Conn, error) {
+	scheme := string(uri.Scheme())
+	secure := scheme == "https" || scheme == "wss"
+	addr := string(uri.Host())
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		if secure {
+			addr += ":443"
+		} else {
+			addr += ":80"
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: wp.timeout}
+	if secure {
+		return tls.DialWithDialer(dialer, "tcp", addr, wp.tlsConfig)
+	}
+	return dialer.Dial("tcp", addr)
+}
+
+// pipe copies both directions until either side closes or is idle for too
+// long, close frames are relayed like any other data
+func (wp *webSocketProxy) pipe(client, upstream net.Conn, upstreamReader io.Reader) {
+	// The hijacked client connection is closed by fasthttp once the
+	// handler returns, the deadline interrupts its pending reads
+	closeBoth := func() {
+		_ = client.SetDeadline(time.Now())
+		_ = client.Close()
+		_ = upstream.Close()
+	}
+	if wp.maxLifetime > 0 {
+		timer := time.AfterFunc(wp.maxLifetime, closeBoth)
+		defer timer.Stop()
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		wp.copy(upstream, client, client)
+		done <- struct{}{}
+	}()
+	go func() {
+		wp.copy(client, upstreamReader, upstream)
+		done <- struct{}{}
+	}()
+
+	// One direction ended, stop the other one as well
+	<-done
+	closeBoth()
+	<-done
+}
+
+// copy reads from src until it fails, the read deadline of conn is extended
+// by the idle timeout before every read
+func (wp *webSocketProxy) copy(dst io.Writer, src io.Reader, conn net.Conn) {
+	buf := make([]byte, 32*1024)
+	for {
+		if wp.idleTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(wp.idleTimeout))
+		}
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readHandshake reads the raw response header and returns it with its
+// status code
+func readHandshake(br *bufio.Reader) ([]byte, int, error) {
+	var head []byte
+	for {
+		line, err := br.ReadSlice('\n')
+		if err != nil {
+			return nil, 0, err
+		}
+		head = append(head, line...)
+		if len(head) > maxWebSocketHandshake {
+			return nil, 0, errWebSocketHandshake
+		}
+		if len(bytes.TrimRight(line, "\r\n")) == 0 {
+			break
+		}
+	}
+
+	// i.e. HTTP/1.1 101 Switching Protocols
+	fields := bytes.Fields(head[:bytes.IndexByte(head, '\n')])
+	if len(fields) < 2 || !bytes.HasPrefix(fields[0], []byte("HTTP/")) {
+		return nil, 0, errWebSocketHandshake
+	}
+	status, err := strconv.Atoi(string(fields[1]))
+	if err != nil {
+		return nil, 0, errWebSocketHandshake
+	}
+	return head, status, nil
+}
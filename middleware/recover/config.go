@@ -10,11 +10,24 @@ type Config struct {
 	//
 	// Optional. Default: nil
 	Next func(c *fiber.Ctx) bool
+
+	// EnableStackTrace calls the StackTraceHandler for every panic
+	//
+	// Optional. Default: false
+	EnableStackTrace bool
+
+	// StackTraceHandler is called with the recovered value, within the
+	// deferred function so debug.Stack() includes the panicking frames
+	//
+	// Optional. Default: defaultStackTraceHandler
+	StackTraceHandler func(c *fiber.Ctx, e interface{})
 }
 
 // ConfigDefault is the default config
 var ConfigDefault = Config{
-	Next: nil,
+	Next:              nil,
+	EnableStackTrace:  false,
+	StackTraceHandler: defaultStackTraceHandler,
 }
 
 // Helper function to set default values
@@ -27,5 +40,9 @@ func configDefault(config ...Config) Config {
 	// Override default config
 	cfg := config[0]
 
+	// Set default values
+	if cfg.EnableStackTrace && cfg.StackTraceHandler == nil {
+		cfg.StackTraceHandler = ConfigDefault.StackTraceHandler
+	}
 	return cfg
 }
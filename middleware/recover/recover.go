@@ -1,11 +1,22 @@
 package recover
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"runtime/debug"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// ErrNilPanic is passed to the ErrorHandler if a handler called panic(nil)
+var ErrNilPanic = errors.New("panic with nil value")
+
+// defaultStackTraceHandler prints the recovered value and the stack to stderr
+func defaultStackTraceHandler(_ *fiber.Ctx, e interface{}) {
+	_, _ = os.Stderr.WriteString(fmt.Sprintf("panic: %v\n\n%s\n", e, debug.Stack()))
+}
+
 // New creates a new middleware handler
 func New(config ...Config) fiber.Handler {
 	// Set default config
@@ -18,18 +29,41 @@ func New(config ...Config) fiber.Handler {
 			return c.Next()
 		}
 
-		// Catch panics
+		// Catch panics, panicking is tracked since recover returns nil
+		// for panic(nil)
+		panicking := true
 		defer func() {
-			if r := recover(); r != nil {
-				var ok bool
-				if err, ok = r.(error); !ok {
-					// Set error that will call the global error handler
-					err = fmt.Errorf("%v", r)
-				}
+			r := recover()
+			if !panicking {
+				return
+			}
+			if cfg.EnableStackTrace {
+				stackTrace(cfg.StackTraceHandler, c, r)
+			}
+			// Set error that will call the global error handler, errors keep
+			// their identity for errors.Is and errors.As
+			switch e := r.(type) {
+			case nil:
+				err = ErrNilPanic
+			case error:
+				err = e
+			default:
+				err = fmt.Errorf("%v", r)
 			}
 		}()
 
 		// Return err if exist, else move to next handler
-		return c.Next()
+		err = c.Next()
+		panicking = false
+		return err
 	}
 }
+
+// stackTrace calls the handler, a panic of the handler can't escape the
+// recovery of the original one
+func stackTrace(handler func(c *fiber.Ctx, e interface{}), c *fiber.Ctx, r interface{}) {
+	defer func() {
+		_ = recover()
+	}()
+	handler(c, r)
+}
@@ -1,6 +1,9 @@
 package recover
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http/httptest"
 	"testing"
 
@@ -8,6 +11,8 @@ import (
 	"github.com/gofiber/fiber/v2/utils"
 )
 
+var errPayment = errors.New("payment failed")
+
 // go test -run Test_Recover
 func Test_Recover(t *testing.T) {
 	app := fiber.New(fiber.Config{
@@ -28,6 +33,39 @@ func Test_Recover(t *testing.T) {
 	utils.AssertEqual(t, fiber.StatusTeapot, resp.StatusCode)
 }
 
+// go test -run Test_Recover_Group_ErrorHandler
+func Test_Recover_Group_ErrorHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return c.SendStatus(fiber.StatusTeapot)
+		},
+	})
+
+	app.Use(New())
+
+	api := app.Group("/api")
+	api.UseErrorHandler(func(c *fiber.Ctx, err error) error {
+		return c.Status(fiber.StatusBadGateway).SendString("api: " + err.Error())
+	})
+	api.Get("/panic", func(c *fiber.Ctx) error {
+		panic("Hi, I'm an error!")
+	})
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		panic("Hi, I'm an error!")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/api/panic", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusBadGateway, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "api: Hi, I'm an error!", string(body))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/panic", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusTeapot, resp.StatusCode)
+}
+
 // go test -run Test_Recover_Next
 func Test_Recover_Next(t *testing.T) {
 	app := fiber.New()
@@ -41,3 +79,142 @@ func Test_Recover_Next(t *testing.T) {
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, fiber.StatusNotFound, resp.StatusCode)
 }
+
+// go test -run Test_Recover_Error_Identity
+func Test_Recover_Error_Identity(t *testing.T) {
+	var handled error
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			handled = err
+			return fiber.DefaultErrorHandler(c, err)
+		},
+	})
+
+	app.Use(New())
+
+	app.Get("/sentinel", func(c *fiber.Ctx) error {
+		panic(fmt.Errorf("charge: %w", errPayment))
+	})
+	app.Get("/fiber", func(c *fiber.Ctx) error {
+		panic(fiber.NewError(fiber.StatusTeapot, "teapot"))
+	})
+	app.Get("/nil", func(c *fiber.Ctx) error {
+		panic(nil)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/sentinel", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+	utils.AssertEqual(t, true, errors.Is(handled, errPayment))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/fiber", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusTeapot, resp.StatusCode)
+	var fiberErr *fiber.Error
+	utils.AssertEqual(t, true, errors.As(handled, &fiberErr))
+
+	// A nil panic isn't swallowed
+	resp, err = app.Test(httptest.NewRequest("GET", "/nil", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+	utils.AssertEqual(t, ErrNilPanic, handled)
+}
+
+// go test -run Test_Recover_RePanic
+func Test_Recover_RePanic(t *testing.T) {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return c.Status(fiber.StatusTeapot).SendString(err.Error())
+		},
+	})
+
+	app.Use(New())
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		defer func() {
+			panic(fmt.Sprintf("again: %v", recover()))
+		}()
+		panic("first")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusTeapot, resp.StatusCode)
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "again: first", string(body))
+}
+
+// go test -run Test_Recover_StackTrace
+func Test_Recover_StackTrace(t *testing.T) {
+	app := fiber.New()
+
+	var (
+		recovered interface{}
+		path      string
+	)
+	app.Use(New(Config{
+		EnableStackTrace: true,
+		StackTraceHandler: func(c *fiber.Ctx, e interface{}) {
+			recovered = e
+			path = c.Path()
+		},
+	}))
+
+	app.Get("/panic", func(c *fiber.Ctx) error {
+		panic("Hi, I'm an error!")
+	})
+	app.Get("/ok", func(c *fiber.Ctx) error {
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/panic", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+	utils.AssertEqual(t, "Hi, I'm an error!", recovered)
+	utils.AssertEqual(t, "/panic", path)
+
+	recovered = nil
+	resp, err = app.Test(httptest.NewRequest("GET", "/ok", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+	utils.AssertEqual(t, nil, recovered)
+}
+
+// go test -run Test_Recover_StackTrace_Panic
+func Test_Recover_StackTrace_Panic(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		EnableStackTrace: true,
+		StackTraceHandler: func(_ *fiber.Ctx, e interface{}) {
+			panic(e)
+		},
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		panic("Hi, I'm an error!")
+	})
+
+	// The panic of the handler doesn't escape the middleware
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+}
+
+// go test -run Test_Recover_Default_StackTraceHandler
+func Test_Recover_Default_StackTraceHandler(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		EnableStackTrace: true,
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		panic("Hi, I'm an error!")
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+}
@@ -22,19 +22,39 @@ type Config struct {
 	// Optional. Default: utils.UUID
 	Generator func() string
 
+	// IgnoreHeader always generates a new ID, instead of using the ID of the
+	// request header, i.e. if the clients aren't trusted
+	//
+	// Optional. Default: false
+	IgnoreHeader bool
+
+	// Validator reports whether the ID of the request header is used,
+	// otherwise a new one is generated
+	//
+	// Optional. Default: 1 to 128 visible ASCII characters
+	Validator func(id string) bool
+
 	// ContextKey defines the key used when storing the request ID in
 	// the locals for a specific request.
 	//
-	// Optional. Default: requestid
+	// Optional. Default: ContextKey
 	ContextKey string
 }
 
+// ContextKey is the default key of the request ID in the locals
+const ContextKey = "requestid"
+
+// maxIDLength is the longest request ID accepted by the default Validator
+const maxIDLength = 128
+
 // ConfigDefault is the default config
 var ConfigDefault = Config{
-	Next:       nil,
-	Header:     fiber.HeaderXRequestID,
-	Generator:  utils.UUID,
-	ContextKey: "requestid",
+	Next:         nil,
+	Header:       fiber.HeaderXRequestID,
+	Generator:    utils.UUID,
+	IgnoreHeader: false,
+	Validator:    validID,
+	ContextKey:   ContextKey,
 }
 
 // Helper function to set default values
@@ -54,8 +74,25 @@ func configDefault(config ...Config) Config {
 	if cfg.Generator == nil {
 		cfg.Generator = ConfigDefault.Generator
 	}
+	if cfg.Validator == nil {
+		cfg.Validator = ConfigDefault.Validator
+	}
 	if cfg.ContextKey == "" {
 		cfg.ContextKey = ConfigDefault.ContextKey
 	}
 	return cfg
 }
+
+// validID accepts 1 to 128 visible ASCII characters, so the ID can't break
+// the log lines or the headers of other services
+func validID(id string) bool {
+	if len(id) == 0 || len(id) > maxIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -15,10 +15,17 @@ func New(config ...Config) fiber.Handler {
 		if cfg.Next != nil && cfg.Next(c) {
 			return c.Next()
 		}
-		// Get id from request, else we generate one
-		rid := c.Get(cfg.Header, cfg.Generator())
+		// Get a valid id from request, else we generate one
+		var rid string
+		if !cfg.IgnoreHeader {
+			rid = c.Get(cfg.Header)
+		}
+		if rid == "" || !cfg.Validator(rid) {
+			rid = cfg.Generator()
+		}
 
-		// Set new id to response header
+		// Set new id to response header, before the handlers run so error
+		// responses include it
 		c.Set(cfg.Header, rid)
 
 		// Add the request ID to locals
@@ -28,3 +35,16 @@ func New(config ...Config) fiber.Handler {
 		return c.Next()
 	}
 }
+
+// FromContext returns the request ID stored by the middleware, or an empty
+// string if there is none. Pass the ContextKey if a custom one is configured.
+func FromContext(c *fiber.Ctx, contextKey ...string) string {
+	key := ContextKey
+	if len(contextKey) > 0 {
+		key = contextKey[0]
+	}
+	if rid, ok := c.Locals(key).(string); ok {
+		return rid
+	}
+	return ""
+}
@@ -1,7 +1,9 @@
 package requestid
 
 import (
+	"errors"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gofiber/fiber/v2"
@@ -34,6 +36,88 @@ func Test_RequestID(t *testing.T) {
 	utils.AssertEqual(t, reqid, resp.Header.Get(fiber.HeaderXRequestID))
 }
 
+// go test -run Test_RequestID_Validator
+func Test_RequestID_Validator(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		Generator: func() string {
+			return "generated"
+		},
+	}))
+
+	app.Get("/", func(c *fiber.Ctx) error {
+		return errors.New("failed")
+	})
+
+	for id, expected := range map[string]string{
+		"trace-1234":             "trace-1234",
+		strings.Repeat("a", 128): strings.Repeat("a", 128),
+		strings.Repeat("a", 129): "generated",
+		"with space":             "generated",
+		"tab\tid":                "generated",
+		"ünicode":                "generated",
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(fiber.HeaderXRequestID, id)
+
+		// Error responses include the id as well
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, fiber.StatusInternalServerError, resp.StatusCode)
+		utils.AssertEqual(t, expected, resp.Header.Get(fiber.HeaderXRequestID))
+	}
+}
+
+// go test -run Test_RequestID_IgnoreHeader
+func Test_RequestID_IgnoreHeader(t *testing.T) {
+	app := fiber.New()
+
+	app.Use(New(Config{
+		IgnoreHeader: true,
+		Generator: func() string {
+			return "generated"
+		},
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "incoming")
+
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "generated", resp.Header.Get(fiber.HeaderXRequestID))
+}
+
+// go test -run Test_RequestID_Custom_Validator
+func Test_RequestID_Custom_Validator(t *testing.T) {
+	app := fiber.New()
+
+	var generated int
+	app.Use(New(Config{
+		Generator: func() string {
+			generated++
+			return "generated"
+		},
+		Validator: func(id string) bool {
+			return strings.HasPrefix(id, "ingress-")
+		},
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "ingress-1")
+	resp, err := app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "ingress-1", resp.Header.Get(fiber.HeaderXRequestID))
+	utils.AssertEqual(t, 0, generated)
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "client-1")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "generated", resp.Header.Get(fiber.HeaderXRequestID))
+	utils.AssertEqual(t, 1, generated)
+}
+
 // go test -run Test_RequestID_Next
 func Test_RequestID_Next(t *testing.T) {
 	app := fiber.New()
@@ -62,14 +146,36 @@ func Test_RequestID_Locals(t *testing.T) {
 		ContextKey: ctxKey,
 	}))
 
-	var ctxVal string
+	var ctxVal, fromCtx, defaultKey string
 
 	app.Use(func(c *fiber.Ctx) error {
 		ctxVal = c.Locals(ctxKey).(string)
+		fromCtx = FromContext(c, ctxKey)
+		defaultKey = FromContext(c)
 		return c.Next()
 	})
 
 	_, err := app.Test(httptest.NewRequest("GET", "/", nil))
 	utils.AssertEqual(t, nil, err)
 	utils.AssertEqual(t, reqId, ctxVal)
+	utils.AssertEqual(t, reqId, fromCtx)
+	utils.AssertEqual(t, "", defaultKey)
+}
+
+// go test -run Test_RequestID_FromContext
+func Test_RequestID_FromContext(t *testing.T) {
+	app := fiber.New()
+	app.Use(New())
+
+	var rid string
+	app.Get("/", func(c *fiber.Ctx) error {
+		rid = FromContext(c)
+		utils.AssertEqual(t, rid, c.Locals(ContextKey))
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, resp.Header.Get(fiber.HeaderXRequestID), rid)
+	utils.AssertEqual(t, 36, len(rid))
 }
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	"github.com/gofiber/fiber/v2/internal/encoding/json"
+)
+
+// Codec serializes the session data before it is passed to the Storage
+type Codec interface {
+	Marshal(data map[string]interface{}) ([]byte, error)
+	Unmarshal(raw []byte) (map[string]interface{}, error)
+}
+
+// MsgpackCodec serializes session data with msgpack, time.Time and []byte
+// values keep their exact value
+type MsgpackCodec struct{}
+
+// Marshal implements Codec
+func (MsgpackCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	book := &db{d: make([]kv, 0, len(data))}
+	for k, v := range data {
+		book.append(k, wrapValue(v))
+	}
+	return book.MarshalMsg(nil)
+}
+
+// Unmarshal implements Codec
+func (MsgpackCodec) Unmarshal(raw []byte) (map[string]interface{}, error) {
+	book := new(db)
+	if _, err := book.UnmarshalMsg(raw); err != nil {
+		return nil, err
+	}
+	data := make(map[string]interface{}, book.Len())
+	for _, kv := range book.d {
+		data[kv.k] = unwrapValue(kv.v)
+	}
+	return data, nil
+}
+
+// JSONCodec serializes session data with JSON, numbers are decoded as float64
+type JSONCodec struct{}
+
+// Marshal implements Codec
+func (JSONCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	return json.Marshal(data)
+}
+
+// Unmarshal implements Codec
+func (JSONCodec) Unmarshal(raw []byte) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GobCodec serializes session data with encoding/gob, custom types must be
+// registered with gob.Register
+type GobCodec struct{}
+
+func init() {
+	gob.Register(time.Time{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// Marshal implements Codec
+func (GobCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements Codec
+func (GobCodec) Unmarshal(raw []byte) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	if err := gob.NewDecoder(bytes.NewReader(raw)).Decode(&data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -9,15 +9,62 @@ import (
 
 // Config defines the config for middleware.
 type Config struct {
+	// Next defines a function to skip the Store.Handler middleware when
+	// returned true.
+	// Optional. Default value nil
+	Next func(c *fiber.Ctx) bool
+
+	// ContextKey is the Locals key the Store.Handler middleware stores the
+	// session under.
+	// Optional. Default value "session"
+	ContextKey string
+
 	// Allowed session duration
 	// Optional. Default value 24 * time.Hour
 	Expiration time.Duration
 
+	// Sliding refreshes the session expiration on every request that loads
+	// the session, so active sessions only expire after being idle for
+	// Expiration.
+	// Optional. Default value false
+	Sliding bool
+
+	// AbsoluteTimeout is the maximum lifetime of a session since it was
+	// created, regardless of activity. Zero means no limit.
+	// Optional. Default value 0
+	AbsoluteTimeout time.Duration
+
 	// Storage interface to store the session data
 	// Optional. Default value memory.New()
 	Storage fiber.Storage
 
+	// KeyPrefix is prepended to the session id to create the storage key,
+	// so multiple apps can share the same storage.
+	// Optional. Default value "".
+	KeyPrefix string
+
+	// Codec serializes the session data, MsgpackCodec, JSONCodec and
+	// GobCodec are provided.
+	// Optional. Default value MsgpackCodec{}
+	Codec Codec
+
+	// Metrics observes the storage lookups, writes and deletes, see
+	// CounterMetrics for a simple implementation.
+	// Optional. Default value NopMetrics{}
+	Metrics Metrics
+
+	// KeyLookup is a string in the form of "<source>:<name>" that is used
+	// to extract the session id from the request.
+	// Possible values:
+	// - "cookie:<name>"
+	// - "header:<name>"
+	// - "query:<name>"
+	//
+	// Optional. Default value "cookie:session_id".
+	KeyLookup string
+
 	// Name of the session cookie. This cookie will store session key.
+	// Only used when KeyLookup is empty.
 	// Optional. Default value "session_id".
 	CookieName string
 
@@ -37,20 +84,92 @@ type Config struct {
 	// Optional. Default value false.
 	CookieHTTPOnly bool
 
-	// Indicates if CSRF cookie is HTTP only.
-	// Optional. Default value false.
+	// Value of SameSite cookie: "Lax", "Strict", "None" or "Disabled" to
+	// omit the attribute. "None" always sets CookieSecure, as browsers drop
+	// SameSite=None cookies without it.
+	// Optional. Default value "Lax".
 	CookieSameSite string
 
+	// SigningKey is used to sign the session id with HMAC-SHA256 before it
+	// is passed to the client. Ids with an invalid signature are rejected
+	// without a storage lookup.
+	// Optional. Default value nil
+	SigningKey []byte
+
+	// PreviousSigningKeys are only used to verify session ids, allowing
+	// the SigningKey to be rotated without ending existing sessions.
+	// Optional. Default value nil
+	PreviousSigningKeys [][]byte
+
+	// EncryptionKey is a 32 byte key used to encrypt the session data with
+	// AES-GCM before it is passed to the Storage.
+	// Optional. Default value nil
+	EncryptionKey []byte
+
+	// PreviousEncryptionKeys are only used to decrypt session data,
+	// allowing the EncryptionKey to be rotated.
+	// Optional. Default value nil
+	PreviousEncryptionKeys [][]byte
+
+	// ErrorHandler is called when session data can't be read. Storage
+	// errors are also returned by Store.Get, data that fails to decrypt
+	// results in a fresh session.
+	// Optional. Default value nil
+	ErrorHandler func(*fiber.Ctx, error)
+
+	// Indicates if the session cookie is partitioned (CHIPS), requires
+	// CookieSecure and CookieSameSite "None".
+	// Optional. Default value false.
+	CookiePartitioned bool
+
+	// CookieSessionOnly writes the session cookie without Max-Age and
+	// Expires, so it is removed when the browser closes. The storage still
+	// uses Expiration.
+	// Optional. Default value false.
+	CookieSessionOnly bool
+
+	// OnCreate is called when a new session is created by Store.Get.
+	// Returning an error aborts Store.Get.
+	// Optional. Default value nil
+	OnCreate func(c *fiber.Ctx, s *Session) error
+
+	// OnSave is called before the session is saved.
+	// Returning an error aborts Session.Save.
+	// Optional. Default value nil
+	OnSave func(c *fiber.Ctx, s *Session) error
+
+	// OnDestroy is called before the session is destroyed.
+	// Returning an error aborts Session.Destroy.
+	// Optional. Default value nil
+	OnDestroy func(c *fiber.Ctx, s *Session) error
+
 	// KeyGenerator generates the session key.
 	// Optional. Default value utils.UUID
 	KeyGenerator func() string
+
+	// MaxIDLength is the maximum length of a session id sent by the client,
+	// longer ids are ignored and a fresh session is created.
+	// Optional. Default value 64
+	MaxIDLength int
+
+	// IDValidator reports whether a session id sent by the client is valid,
+	// it must accept every id created by KeyGenerator. Ids containing
+	// control characters are always rejected.
+	// Optional. Default value only allows letters, digits, '-' and '_'
+	IDValidator func(id string) bool
 }
 
 // ConfigDefault is the default config
 var ConfigDefault = Config{
+	ContextKey:   "session",
 	Expiration:   24 * time.Hour,
+	Codec:        MsgpackCodec{},
+	Metrics:      NopMetrics{},
+	KeyLookup:    "cookie:session_id",
 	CookieName:   "session_id",
 	KeyGenerator: utils.UUID,
+	MaxIDLength:  64,
+	IDValidator:  validID,
 }
 
 // Helper function to set default values
@@ -64,11 +183,44 @@ func configDefault(config ...Config) Config {
 	cfg := config[0]
 
 	// Set default values
+	if cfg.ContextKey == "" {
+		cfg.ContextKey = ConfigDefault.ContextKey
+	}
 	if int(cfg.Expiration.Seconds()) <= 0 {
 		cfg.Expiration = ConfigDefault.Expiration
 	}
+	if cfg.KeyLookup == "" {
+		if cfg.CookieName != "" {
+			cfg.KeyLookup = "cookie:" + cfg.CookieName
+		} else {
+			cfg.KeyLookup = ConfigDefault.KeyLookup
+		}
+	}
+	if cfg.Codec == nil {
+		cfg.Codec = ConfigDefault.Codec
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = ConfigDefault.Metrics
+	}
 	if cfg.KeyGenerator == nil {
 		cfg.KeyGenerator = ConfigDefault.KeyGenerator
 	}
+	if cfg.MaxIDLength <= 0 {
+		cfg.MaxIDLength = ConfigDefault.MaxIDLength
+	}
+	if cfg.IDValidator == nil {
+		cfg.IDValidator = ConfigDefault.IDValidator
+	}
 	return cfg
 }
+
+// validID only allows ids made of letters, digits, '-' and '_'
+func validID(id string) bool {
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -6,6 +6,9 @@ package session
 // "github.com/gofiber/fiber/v2/internal/msgp"
 type db struct {
 	d []kv
+
+	// dirty is set when the data changed, it is not serialized
+	dirty bool
 }
 
 // go:generate msgp
@@ -16,6 +19,7 @@ type kv struct {
 
 func (d *db) Reset() {
 	d.d = d.d[:0]
+	d.dirty = true
 }
 
 func (d *db) Get(key string) interface{} {
@@ -27,6 +31,7 @@ func (d *db) Get(key string) interface{} {
 }
 
 func (d *db) Set(key string, value interface{}) {
+	d.dirty = true
 	idx := d.indexOf(key)
 	if idx > -1 {
 		kv := &d.d[idx]
@@ -42,7 +47,24 @@ func (d *db) Delete(key string) {
 		n := len(d.d) - 1
 		d.swap(idx, n)
 		d.d = d.d[:n]
+		d.dirty = true
+	}
+}
+
+func (d *db) Keys() []string {
+	keys := make([]string, len(d.d))
+	for i := range d.d {
+		keys[i] = d.d[i].k
+	}
+	return keys
+}
+
+func (d *db) All() map[string]interface{} {
+	all := make(map[string]interface{}, len(d.d))
+	for i := range d.d {
+		all[d.d[i].k] = d.d[i].v
 	}
+	return all
 }
 
 func (d *db) Len() int {
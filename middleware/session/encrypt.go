@@ -0,0 +1,57 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// errDecrypt is returned when a payload can't be decrypted by any key
+var errDecrypt = errors.New("session: failed to decrypt session data")
+
+// newAEAD creates an AES-GCM cipher for a 32 byte key
+func newAEAD(key []byte) cipher.AEAD {
+	if len(key) != 32 {
+		panic("[session] EncryptionKey must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(err)
+	}
+	return gcm
+}
+
+// seal encrypts the payload with the newest key, the nonce is prepended
+func (s *Store) seal(data []byte) ([]byte, error) {
+	if len(s.aeads) == 0 {
+		return data, nil
+	}
+	gcm := s.aeads[0]
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(data)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// open decrypts the payload, trying the previous keys if the newest fails
+func (s *Store) open(raw []byte) ([]byte, error) {
+	if len(s.aeads) == 0 {
+		return raw, nil
+	}
+	for _, gcm := range s.aeads {
+		if len(raw) < gcm.NonceSize() {
+			continue
+		}
+		nonce, data := raw[:gcm.NonceSize()], raw[gcm.NonceSize():]
+		if plain, err := gcm.Open(nil, nonce, data, nil); err == nil {
+			return plain, nil
+		}
+	}
+	return nil, errDecrypt
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2/internal/msgp"
+)
+
+// msgp extension types used to keep session values intact
+const (
+	timeExtension  = 10
+	bytesExtension = 11
+)
+
+func init() {
+	msgp.RegisterExtension(timeExtension, func() msgp.Extension { return new(timeExt) })
+	msgp.RegisterExtension(bytesExtension, func() msgp.Extension { return new(bytesExt) })
+}
+
+// timeExt keeps the zone offset of a time.Time, the builtin msgp
+// time extension always decodes into the local time zone
+type timeExt struct {
+	t time.Time
+}
+
+func (e *timeExt) ExtensionType() int8 { return timeExtension }
+
+func (e *timeExt) Len() int {
+	b, _ := e.t.MarshalBinary()
+	return len(b)
+}
+
+func (e *timeExt) MarshalBinaryTo(d []byte) error {
+	b, err := e.t.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	copy(d, b)
+	return nil
+}
+
+func (e *timeExt) UnmarshalBinary(b []byte) error {
+	return e.t.UnmarshalBinary(b)
+}
+
+// bytesExt keeps empty byte slices from decoding into nil
+type bytesExt struct {
+	b []byte
+}
+
+func (e *bytesExt) ExtensionType() int8 { return bytesExtension }
+
+func (e *bytesExt) Len() int { return len(e.b) }
+
+func (e *bytesExt) MarshalBinaryTo(d []byte) error {
+	copy(d, e.b)
+	return nil
+}
+
+func (e *bytesExt) UnmarshalBinary(b []byte) error {
+	e.b = make([]byte, len(b))
+	copy(e.b, b)
+	return nil
+}
+
+// wrapValue converts a session value to its msgp extension if needed
+func wrapValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case time.Time:
+		return &timeExt{t: v}
+	case []byte:
+		return &bytesExt{b: v}
+	}
+	return v
+}
+
+// unwrapValue converts a msgp extension back to the session value
+func unwrapValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *timeExt:
+		return v.t
+	case *bytesExt:
+		return v.b
+	}
+	return v
+}
@@ -0,0 +1,99 @@
+package session
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics observes the storage operations of a Store. The durations
+// only cover the storage call, not encoding or encryption.
+type Metrics interface {
+	// ObserveGet is called after a session was looked up in the storage,
+	// hit is false if it does not exist or the lookup failed
+	ObserveGet(hit bool, d time.Duration)
+	// ObserveSave is called after session data was written to the storage
+	ObserveSave(d time.Duration, err error)
+	// ObserveDestroy is called after a session was deleted from the storage
+	ObserveDestroy(d time.Duration, err error)
+}
+
+// NopMetrics discards all observations
+type NopMetrics struct{}
+
+// ObserveGet implements Metrics
+func (NopMetrics) ObserveGet(bool, time.Duration) {}
+
+// ObserveSave implements Metrics
+func (NopMetrics) ObserveSave(time.Duration, error) {}
+
+// ObserveDestroy implements Metrics
+func (NopMetrics) ObserveDestroy(time.Duration, error) {}
+
+// CounterMetrics counts the storage operations, it is safe for concurrent use
+type CounterMetrics struct {
+	hits     int64
+	misses   int64
+	saves    int64
+	destroys int64
+	errors   int64
+	duration int64
+}
+
+// ObserveGet implements Metrics
+func (m *CounterMetrics) ObserveGet(hit bool, d time.Duration) {
+	if hit {
+		atomic.AddInt64(&m.hits, 1)
+	} else {
+		atomic.AddInt64(&m.misses, 1)
+	}
+	atomic.AddInt64(&m.duration, int64(d))
+}
+
+// ObserveSave implements Metrics
+func (m *CounterMetrics) ObserveSave(d time.Duration, err error) {
+	atomic.AddInt64(&m.saves, 1)
+	m.observe(d, err)
+}
+
+// ObserveDestroy implements Metrics
+func (m *CounterMetrics) ObserveDestroy(d time.Duration, err error) {
+	atomic.AddInt64(&m.destroys, 1)
+	m.observe(d, err)
+}
+
+func (m *CounterMetrics) observe(d time.Duration, err error) {
+	if err != nil {
+		atomic.AddInt64(&m.errors, 1)
+	}
+	atomic.AddInt64(&m.duration, int64(d))
+}
+
+// Hits returns the number of sessions found in the storage
+func (m *CounterMetrics) Hits() int64 {
+	return atomic.LoadInt64(&m.hits)
+}
+
+// Misses returns the number of sessions not found in the storage
+func (m *CounterMetrics) Misses() int64 {
+	return atomic.LoadInt64(&m.misses)
+}
+
+// Saves returns the number of writes to the storage
+func (m *CounterMetrics) Saves() int64 {
+	return atomic.LoadInt64(&m.saves)
+}
+
+// Destroys returns the number of deletes from the storage
+func (m *CounterMetrics) Destroys() int64 {
+	return atomic.LoadInt64(&m.destroys)
+}
+
+// Errors returns the number of failed writes and deletes
+func (m *CounterMetrics) Errors() int64 {
+	return atomic.LoadInt64(&m.errors)
+}
+
+// Duration returns the total time spent in the storage
+func (m *CounterMetrics) Duration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&m.duration))
+}
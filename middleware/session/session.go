@@ -1,147 +1,561 @@
 package session
 
 import (
+	"math"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
 )
 
 type Session struct {
-	ctx    *fiber.Ctx
-	config *Store
-	db     *db
-	id     string
-	fresh  bool
+	mu        sync.RWMutex
+	ctx       *fiber.Ctx
+	config    *Store
+	db        *db
+	id        string
+	fresh     bool
+	stale     bool
+	readOnly  bool
+	exp       time.Duration
+	deadline  time.Time
+	expiresAt time.Time
 }
 
-var sessionPool = sync.Pool{
-	New: func() interface{} {
-		return new(Session)
-	},
-}
-
-func acquireSession() *Session {
-	s := sessionPool.Get().(*Session)
-	s.db = new(db)
-	s.fresh = true
-	return s
-}
+// Reserved keys used to store session metadata next to the session data
+const (
+	keyReserved   = "\x00fiber."
+	keyExpiration = keyReserved + "expiration"
+	keyDeadline   = keyReserved + "deadline"
+	keyExpiresAt  = keyReserved + "expires_at"
+	keyFlash      = keyReserved + "flash."
+)
 
-func releaseSession(s *Session) {
-	s.ctx = nil
-	s.config = nil
-	if s.db != nil {
-		s.db.Reset()
+func newSession(c *fiber.Ctx, store *Store, id string) *Session {
+	s := &Session{
+		ctx:    c,
+		config: store,
+		db:     new(db),
+		id:     id,
+		fresh:  true,
 	}
-	s.id = ""
-	s.fresh = true
-	sessionPool.Put(s)
+	if store.AbsoluteTimeout > 0 {
+		s.deadline = time.Now().Add(store.AbsoluteTimeout)
+	}
+	return s
 }
 
 // Fresh is true if the current session is new
 func (s *Session) Fresh() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.fresh
 }
 
 // ID returns the session id
 func (s *Session) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.id
 }
 
+// ReadOnly is true if the session was loaded with Store.GetReadOnly
+func (s *Session) ReadOnly() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.readOnly
+}
+
+// Dirty is true if the session data changed since it was loaded or saved
+func (s *Session) Dirty() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.db.dirty
+}
+
 // Get will return the value
 func (s *Session) Get(key string) interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.db.Get(key)
 }
 
-// Set will update or create a new key value
+// GetString returns the value as a string
+func (s *Session) GetString(key string) (string, bool) {
+	switch v := s.Get(key).(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	}
+	return "", false
+}
+
+// GetInt returns the value as an int
+func (s *Session) GetInt(key string) (int, bool) {
+	v, ok := s.GetInt64(key)
+	return int(v), ok
+}
+
+// GetInt64 returns the value as an int64
+func (s *Session) GetInt64(key string) (int64, bool) {
+	return toInt64(s.Get(key))
+}
+
+// GetBool returns the value as a bool
+func (s *Session) GetBool(key string) (bool, bool) {
+	v, ok := s.Get(key).(bool)
+	return v, ok
+}
+
+// GetFloat64 returns the value as a float64
+func (s *Session) GetFloat64(key string) (float64, bool) {
+	val := s.Get(key)
+	switch v := val.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	if v, ok := toInt64(val); ok {
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// GetTime returns the value as a time.Time
+func (s *Session) GetTime(key string) (time.Time, bool) {
+	v, ok := s.Get(key).(time.Time)
+	return v, ok
+}
+
+// GetBytes returns the value as a byte slice
+func (s *Session) GetBytes(key string) ([]byte, bool) {
+	switch v := s.Get(key).(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	}
+	return nil, false
+}
+
+// Set will update or create a new key value, read-only sessions ignore it
 func (s *Session) Set(key string, val interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return
+	}
 	s.db.Set(key, val)
 }
 
-// Delete will delete the value
+// Delete will delete the value, read-only sessions ignore it
 func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return
+	}
 	s.db.Delete(key)
 }
 
+// Keys returns all keys stored in the session
+func (s *Session) Keys() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := s.db.Keys()
+	n := 0
+	for _, key := range keys {
+		if !strings.HasPrefix(key, keyReserved) {
+			keys[n] = key
+			n++
+		}
+	}
+	return keys[:n]
+}
+
+// GetAll returns a copy of all key value pairs stored in the session
+func (s *Session) GetAll() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := s.db.All()
+	for key := range all {
+		if strings.HasPrefix(key, keyReserved) {
+			delete(all, key)
+		}
+	}
+	return all
+}
+
+// SetFlash stores a value that is removed once it is read
+func (s *Session) SetFlash(key string, val interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return
+	}
+	s.db.Set(keyFlash+key, val)
+}
+
+// GetFlash returns the flash value and removes it from the session,
+// the removal is persisted on the next Save. Read-only sessions keep it.
+func (s *Session) GetFlash(key string) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	val := s.db.Get(keyFlash + key)
+	if !s.readOnly {
+		s.db.Delete(keyFlash + key)
+	}
+	return val
+}
+
+// Flashes returns all flash values and removes them from the session
+func (s *Session) Flashes() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flashes := make(map[string]interface{})
+	for _, key := range s.db.Keys() {
+		if strings.HasPrefix(key, keyFlash) {
+			flashes[strings.TrimPrefix(key, keyFlash)] = s.db.Get(key)
+			if !s.readOnly {
+				s.db.Delete(key)
+			}
+		}
+	}
+	return flashes
+}
+
+// Reset will clear the session data, keeping the session id and cookie.
+// The cleared state is persisted on the next Save.
+func (s *Session) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return
+	}
+	s.db.Reset()
+}
+
 // Destroy will delete the session from Storage and expire session cookie
 func (s *Session) Destroy() error {
+	if s.ReadOnly() {
+		return ErrReadOnly
+	}
+
+	if s.config.OnDestroy != nil {
+		if err := s.config.OnDestroy(s.ctx, s); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Reset local data
 	s.db.Reset()
 
-	// Delete data from storage
-	if err := s.config.Storage.Delete(s.id); err != nil {
-		return err
+	// Delete data from storage, fresh sessions were never persisted
+	if !s.fresh {
+		start := time.Now()
+		err := s.config.deleteStorage(s.ctx, s.id)
+		if notExist(err) {
+			err = nil
+		}
+		s.config.Metrics.ObserveDestroy(time.Since(start), err)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Expire cookie
-	s.delCookie()
+	// The session is gone from storage, only an empty session remains
+	s.fresh = true
+	s.db.dirty = false
+
+	// Expire session id on the client
+	s.delSession()
 	return nil
 }
 
-// Regenerate generates a new session id and delete the old one from Storage
+// Regenerate generates a new session id, moves the data from the old id to
+// the new one and passes the new id to the client
 func (s *Session) Regenerate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Delete old id from storage
-	if err := s.config.Storage.Delete(s.id); err != nil {
-		return err
+	if s.readOnly {
+		return ErrReadOnly
+	}
+
+	// Fresh sessions have nothing stored yet, only rotate the id
+	if s.fresh {
+		s.id = s.config.KeyGenerator()
+		s.setSession()
+		return nil
 	}
-	// Create new ID
+
+	oldID := s.id
 	s.id = s.config.KeyGenerator()
 
+	// Store the data under the new id. It was decoded when the session was
+	// loaded and has the changes of the handler, if every key was deleted
+	// nothing is stored.
+	if s.db.Len() > 0 {
+		data, err := s.encode()
+		if err != nil {
+			return err
+		}
+		if data, err = s.config.seal(data); err != nil {
+			return err
+		}
+		if err = s.config.setStorage(s.ctx, s.id, data, s.ttl()); err != nil {
+			return err
+		}
+		s.db.dirty = false
+	}
+
+	// Delete old id from storage, it may have expired already
+	if err := s.config.deleteStorage(s.ctx, oldID); err != nil && !notExist(err) {
+		return err
+	}
+
+	// Pass the new session ID to the client, even if Save is not called
+	s.setSession()
+
 	return nil
 }
 
 // Save will update the storage and client cookie
 func (s *Session) Save() error {
+	if s.ReadOnly() {
+		return ErrReadOnly
+	}
+
+	if s.config.OnSave != nil {
+		if err := s.config.OnSave(s.ctx, s); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// Don't save to Storage if no data is available
 	if s.db.Len() <= 0 {
+		// Remove the previously stored data, nothing is left to restore
+		if !s.fresh {
+			if err := s.config.deleteStorage(s.ctx, s.id); err != nil && !notExist(err) {
+				return err
+			}
+			s.fresh = true
+			s.db.dirty = false
+			s.delSession()
+		}
+		return nil
+	}
+
+	// Nothing changed since the session was loaded, only re-issue the
+	// session id if it expires on the client or is signed with an old key
+	if !s.fresh && !s.db.dirty {
+		if s.config.Sliding || s.stale {
+			s.setSession()
+			s.stale = false
+		}
 		return nil
 	}
 
+	// pass raw bytes with session id to provider
+	if err := s.store(); err != nil {
+		return err
+	}
+
+	// The session is persisted and no longer new
+	s.fresh = false
+
+	// Pass the session ID to the client
+	s.setSession()
+
+	return nil
+}
+
+// SetExpiry overrides the store expiration for this session only,
+// a zero value falls back to the store expiration
+func (s *Session) SetExpiry(exp time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.readOnly {
+		return
+	}
+	s.exp = exp
+	s.db.dirty = true
+}
+
+// expiration returns the session expiration, preferring the session override
+// and never exceeding the absolute deadline
+func (s *Session) expiration() time.Duration {
+	exp := s.config.Expiration
+	if s.exp > 0 {
+		exp = s.exp
+	}
+	if !s.deadline.IsZero() {
+		if left := time.Until(s.deadline); left < exp {
+			exp = left
+		}
+		if exp < time.Second {
+			exp = time.Second
+		}
+	}
+	return exp
+}
+
+// expired is true if the absolute deadline of the session has passed
+func (s *Session) expired() bool {
+	return !s.deadline.IsZero() && time.Now().After(s.deadline)
+}
+
+// ExpiresAt returns the time the session expires
+func (s *Session) ExpiresAt() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.expiresAt.IsZero() {
+		return time.Now().Add(s.expiration())
+	}
+	return s.expiresAt
+}
+
+// TTL returns the time left before the session expires
+func (s *Session) TTL() time.Duration {
+	return time.Until(s.ExpiresAt())
+}
+
+// ttl returns the time left of the stored session, or the full expiration
+// if it is unknown
+func (s *Session) ttl() time.Duration {
+	if s.expiresAt.IsZero() {
+		return s.expiration()
+	}
+	if left := time.Until(s.expiresAt); left > time.Second {
+		return left
+	}
+	return time.Second
+}
+
+// store encodes the session data and passes it to the storage
+func (s *Session) store() error {
+	exp := s.expiration()
+	s.expiresAt = time.Now().Add(exp)
+
 	// Convert book to bytes
-	data, err := s.db.MarshalMsg(nil)
+	data, err := s.encode()
 	if err != nil {
 		return err
 	}
 
-	// pass raw bytes with session id to provider
-	if err := s.config.Storage.Set(s.id, data, s.config.Expiration); err != nil {
+	// Encrypt the data if an EncryptionKey is set
+	if data, err = s.config.seal(data); err != nil {
 		return err
 	}
 
-	// Create cookie with the session ID
-	s.setCookie()
+	start := time.Now()
+	err = s.config.setStorage(s.ctx, s.id, data, exp)
+	s.config.Metrics.ObserveSave(time.Since(start), err)
+	if err != nil {
+		return err
+	}
+	s.db.dirty = false
+	return nil
+}
+
+// refresh re-arms the storage expiration and re-issues the session id
+func (s *Session) refresh() error {
+	if err := s.store(); err != nil {
+		return err
+	}
+	s.setSession()
+	return nil
+}
+
+// encode marshals the session data together with its metadata
+func (s *Session) encode() ([]byte, error) {
+	data := s.db.All()
+	if s.exp > 0 {
+		data[keyExpiration] = int64(s.exp)
+	}
+	if !s.deadline.IsZero() {
+		data[keyDeadline] = s.deadline.UnixNano()
+	}
+	if !s.expiresAt.IsZero() {
+		data[keyExpiresAt] = s.expiresAt.UnixNano()
+	}
+	return s.config.Codec.Marshal(data)
+}
 
-	// release session to pool to be re-used on next request
-	releaseSession(s)
+// decode unmarshals the session data and extracts its metadata
+func (s *Session) decode(raw []byte) error {
+	data, err := s.config.Codec.Unmarshal(raw)
+	if err != nil {
+		return err
+	}
+	if exp, ok := toInt64(data[keyExpiration]); ok {
+		s.exp = time.Duration(exp)
+	}
+	if deadline, ok := toInt64(data[keyDeadline]); ok {
+		s.deadline = time.Unix(0, deadline)
+	}
+	if expiresAt, ok := toInt64(data[keyExpiresAt]); ok {
+		s.expiresAt = time.Unix(0, expiresAt)
+	}
+	delete(data, keyExpiration)
+	delete(data, keyDeadline)
+	delete(data, keyExpiresAt)
 
+	s.db.Reset()
+	for k, v := range data {
+		s.db.append(k, v)
+	}
+	s.db.dirty = false
 	return nil
 }
 
+// setSession passes the session id to the client through the configured source
+func (s *Session) setSession() {
+	switch s.config.source {
+	case sourceHeader:
+		s.ctx.Response().Header.Set(s.config.sessionKey, s.config.sign(s.id))
+	case sourceCookie:
+		s.setCookie()
+	}
+}
+
+// delSession removes the session id from the configured source
+func (s *Session) delSession() {
+	switch s.config.source {
+	case sourceHeader:
+		s.ctx.Request().Header.Del(s.config.sessionKey)
+		s.ctx.Response().Header.Del(s.config.sessionKey)
+	case sourceCookie:
+		s.delCookie()
+	}
+}
+
 func (s *Session) setCookie() {
 	fcookie := fasthttp.AcquireCookie()
 	fcookie.SetKey(s.config.CookieName)
-	fcookie.SetValue(s.id)
+	fcookie.SetValue(s.config.sign(s.id))
 	fcookie.SetPath(s.config.CookiePath)
 	fcookie.SetDomain(s.config.CookieDomain)
-	fcookie.SetMaxAge(int(s.config.Expiration.Seconds()))
-	fcookie.SetExpire(time.Now().Add(s.config.Expiration))
+	if !s.config.CookieSessionOnly {
+		fcookie.SetMaxAge(int(s.expiration().Seconds()))
+		fcookie.SetExpire(time.Now().Add(s.expiration()))
+	}
 	fcookie.SetSecure(s.config.CookieSecure)
 	fcookie.SetHTTPOnly(s.config.CookieHTTPOnly)
 
-	switch utils.ToLower(s.config.CookieSameSite) {
-	case "strict":
-		fcookie.SetSameSite(fasthttp.CookieSameSiteStrictMode)
-	case "none":
-		fcookie.SetSameSite(fasthttp.CookieSameSiteNoneMode)
-	default:
-		fcookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
-	}
+	fcookie.SetSameSite(s.config.sameSite)
 
-	s.ctx.Response().Header.SetCookie(fcookie)
+	s.writeCookie(fcookie)
 	fasthttp.ReleaseCookie(fcookie)
 }
 
@@ -158,15 +572,50 @@ func (s *Session) delCookie() {
 	fcookie.SetSecure(s.config.CookieSecure)
 	fcookie.SetHTTPOnly(s.config.CookieHTTPOnly)
 
-	switch utils.ToLower(s.config.CookieSameSite) {
-	case "strict":
-		fcookie.SetSameSite(fasthttp.CookieSameSiteStrictMode)
-	case "none":
-		fcookie.SetSameSite(fasthttp.CookieSameSiteNoneMode)
-	default:
-		fcookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
-	}
+	fcookie.SetSameSite(s.config.sameSite)
 
-	s.ctx.Response().Header.SetCookie(fcookie)
+	s.writeCookie(fcookie)
 	fasthttp.ReleaseCookie(fcookie)
 }
+
+// writeCookie adds the cookie to the response, fasthttp does not support
+// the Partitioned attribute so it is appended manually
+func (s *Session) writeCookie(fcookie *fasthttp.Cookie) {
+	if !s.config.CookiePartitioned {
+		s.ctx.Response().Header.SetCookie(fcookie)
+		return
+	}
+	s.ctx.Response().Header.DelCookieBytes(fcookie.Key())
+	s.ctx.Response().Header.Set(fiber.HeaderSetCookie, string(fcookie.Cookie())+"; Partitioned")
+}
+
+// toInt64 converts integers, and floats without a fraction, to an int64
+func toInt64(val interface{}) (int64, bool) {
+	switch v := val.(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), true
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), true
+	case float64:
+		if v == math.Trunc(v) {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
@@ -1,10 +1,22 @@
 package session
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/internal/storage/memory"
 	"github.com/gofiber/fiber/v2/utils"
 	"github.com/valyala/fasthttp"
 )
@@ -170,3 +182,1775 @@ func Test_Session_Cookie(t *testing.T) {
 	// cookie should not be set if empty data
 	utils.AssertEqual(t, 0, len(ctx.Response().Header.PeekCookie(store.CookieName)))
 }
+
+// go test -run Test_Session_Regenerate
+func Test_Session_Regenerate(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// get session & save data
+	sess, _ := store.Get(ctx)
+	sess.Set("cart", "apple")
+	oldID := sess.ID()
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// load the saved session
+	ctx.Request().Header.SetCookie(store.CookieName, oldID)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+
+	// regenerate & save
+	utils.AssertEqual(t, nil, sess.Regenerate())
+	newID := sess.ID()
+	utils.AssertEqual(t, true, oldID != newID)
+	utils.AssertEqual(t, "apple", sess.Get("cart"))
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// old id must be gone
+	ctx.Request().Header.SetCookie(store.CookieName, oldID)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, nil, sess.Get("cart"))
+
+	// new id keeps the data
+	ctx.Request().Header.SetCookie(store.CookieName, newID)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "apple", sess.Get("cart"))
+}
+
+// go test -run Test_Session_Regenerate_NotSaved
+func Test_Session_Regenerate_NotSaved(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// get session
+	sess, _ := store.Get(ctx)
+	sess.Set("locale", "nl")
+	oldID := sess.ID()
+
+	// regenerate without saving first
+	utils.AssertEqual(t, nil, sess.Regenerate())
+	newID := sess.ID()
+	utils.AssertEqual(t, true, oldID != newID)
+	utils.AssertEqual(t, "nl", sess.Get("locale"))
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// data is stored under the new id
+	ctx.Request().Header.SetCookie(store.CookieName, newID)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "nl", sess.Get("locale"))
+}
+
+// go test -run Test_Session_Regenerate_Deleted
+func Test_Session_Regenerate_Deleted(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// get session & save data
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	sess.Set("cart", "apple")
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// delete every key of the loaded session, then regenerate
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	sess.Delete("name")
+	sess.Delete("cart")
+	utils.AssertEqual(t, nil, sess.Regenerate())
+	utils.AssertEqual(t, 0, len(sess.Keys()))
+	newID := sess.ID()
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// the deleted keys don't come back under the new id
+	ctx.Request().Header.SetCookie(store.CookieName, newID)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, nil, sess.Get("name"))
+	utils.AssertEqual(t, nil, sess.Get("cart"))
+
+	// changes before the regeneration are stored under the new id
+	sess.Set("name", "john")
+	sess.Set("cart", "apple")
+	utils.AssertEqual(t, nil, sess.Save())
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	sess.Delete("cart")
+	utils.AssertEqual(t, nil, sess.Regenerate())
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, "john", sess.Get("name"))
+	utils.AssertEqual(t, nil, sess.Get("cart"))
+}
+
+// go test -run Test_Session_Regenerate_Expired
+func Test_Session_Regenerate_Expired(t *testing.T) {
+	t.Parallel()
+	storage := &strictStorage{Storage: memory.New()}
+	// session store
+	store := New(Config{Storage: storage})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+
+	// the entry expired from storage while the request was handled
+	utils.AssertEqual(t, nil, storage.Storage.Delete(sess.ID()))
+	utils.AssertEqual(t, nil, sess.Regenerate())
+	utils.AssertEqual(t, 1, storage.deletes)
+
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, "john", sess.Get("name"))
+}
+
+// go test -run Test_Session_KeyLookup_Header
+func Test_Session_KeyLookup_Header(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New(Config{KeyLookup: "header:x-session-id"})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// get session & save data
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	id := sess.ID()
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// session id is passed through the response header, not a cookie
+	utils.AssertEqual(t, id, string(ctx.Response().Header.Peek("X-Session-Id")))
+	utils.AssertEqual(t, 0, len(ctx.Response().Header.Peek(fiber.HeaderSetCookie)))
+
+	// load session from the request header
+	ctx.Request().Header.Set("X-Session-Id", id)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "john", sess.Get("name"))
+
+	// destroy must not emit a cookie
+	utils.AssertEqual(t, nil, sess.Destroy())
+	utils.AssertEqual(t, 0, len(ctx.Response().Header.Peek(fiber.HeaderSetCookie)))
+	utils.AssertEqual(t, 0, len(ctx.Response().Header.Peek("X-Session-Id")))
+	utils.AssertEqual(t, 0, len(ctx.Request().Header.Peek("X-Session-Id")))
+}
+
+// go test -run Test_Session_KeyLookup_Query
+func Test_Session_KeyLookup_Query(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New(Config{KeyLookup: "query:sid"})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// get session & save data
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	id := sess.ID()
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, 0, len(ctx.Response().Header.Peek(fiber.HeaderSetCookie)))
+
+	// load session from the query string
+	ctx.Request().URI().SetQueryString("sid=" + id)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "john", sess.Get("name"))
+}
+
+// go test -run Test_Session_KeyLookup_Invalid
+func Test_Session_KeyLookup_Invalid(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		utils.AssertEqual(t, "[session] KeyLookup must in the form of <source>:<name>", recover())
+	}()
+	New(Config{KeyLookup: "session_id"})
+}
+
+// go test -run Test_Session_SetExpiry
+func Test_Session_SetExpiry(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// get session & extend the expiration
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	sess.SetExpiry(30 * 24 * time.Hour)
+	id := sess.ID()
+	utils.AssertEqual(t, nil, sess.Save())
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(store.CookieName)
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	utils.AssertEqual(t, int((30 * 24 * time.Hour).Seconds()), cookie.MaxAge())
+
+	// the override survives a round trip
+	ctx.Response().Reset()
+	ctx.Request().Header.SetCookie(store.CookieName, id)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "john", sess.Get("name"))
+	utils.AssertEqual(t, nil, sess.Get(keyExpiration))
+	sess.Set("name", "doe")
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	utils.AssertEqual(t, int((30 * 24 * time.Hour).Seconds()), cookie.MaxAge())
+
+	// zero value falls back to the store expiration
+	ctx.Response().Reset()
+	sess, _ = store.Get(ctx)
+	sess.SetExpiry(0)
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	utils.AssertEqual(t, int(store.Expiration.Seconds()), cookie.MaxAge())
+}
+
+// go test -race -run Test_Session_Save_Twice
+func Test_Session_Save_Twice(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		sess.Set("name", "john")
+		if err = sess.Save(); err != nil {
+			return err
+		}
+		// the session must still be usable after saving
+		sess.Set("age", 21)
+		if err = sess.Save(); err != nil {
+			return err
+		}
+		return c.SendString(fmt.Sprintf("%s:%v:%s", sess.Get("name"), sess.Get("age"), sess.ID()))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+			utils.AssertEqual(t, nil, err)
+			body, err := ioutil.ReadAll(resp.Body)
+			utils.AssertEqual(t, nil, err)
+			parts := strings.Split(string(body), ":")
+			utils.AssertEqual(t, 3, len(parts))
+			utils.AssertEqual(t, "john", parts[0])
+			utils.AssertEqual(t, "21", parts[1])
+			utils.AssertEqual(t, true, strings.Contains(resp.Header.Get(fiber.HeaderSetCookie), parts[2]))
+		}()
+	}
+	wg.Wait()
+}
+
+// strictStorage is a storage stub that returns an error for missing keys
+type strictStorage struct {
+	*memory.Storage
+	deletes int
+}
+
+func (s *strictStorage) Delete(key string) error {
+	s.deletes++
+	if _, err := s.Storage.Get(key); err != nil {
+		return err
+	}
+	return s.Storage.Delete(key)
+}
+
+// go test -run Test_Session_Destroy_Fresh
+func Test_Session_Destroy_Fresh(t *testing.T) {
+	t.Parallel()
+	storage := &strictStorage{Storage: memory.New()}
+	// session store
+	store := New(Config{Storage: storage})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// destroying a fresh session does not touch the storage
+	sess, _ := store.Get(ctx)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, nil, sess.Destroy())
+	utils.AssertEqual(t, 0, storage.deletes)
+
+	// a session that expired from storage is destroyed without error
+	sess, _ = store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, nil, storage.Storage.Delete(sess.ID()))
+	utils.AssertEqual(t, nil, sess.Destroy())
+	utils.AssertEqual(t, 1, storage.deletes)
+
+	// a session saved in this request is deleted from storage
+	sess, _ = store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, nil, sess.Destroy())
+	utils.AssertEqual(t, 2, storage.deletes)
+	_, err := storage.Get(sess.ID())
+	utils.AssertEqual(t, memory.ErrNotExist, err)
+}
+
+// go test -run Test_Session_Keys_GetAll
+func Test_Session_Keys_GetAll(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// fresh session is empty
+	sess, _ := store.Get(ctx)
+	utils.AssertEqual(t, []string{}, sess.Keys())
+	utils.AssertEqual(t, map[string]interface{}{}, sess.GetAll())
+
+	// save some data
+	sess.Set("name", "john")
+	sess.Set("locale", "nl")
+	sess.SetExpiry(time.Hour)
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// load the session from storage
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	keys := sess.Keys()
+	sort.Strings(keys)
+	utils.AssertEqual(t, []string{"locale", "name"}, keys)
+	all := sess.GetAll()
+	utils.AssertEqual(t, map[string]interface{}{"name": "john", "locale": "nl"}, all)
+
+	// mutating the copy does not change the session
+	all["name"] = "doe"
+	delete(all, "locale")
+	utils.AssertEqual(t, "john", sess.Get("name"))
+	utils.AssertEqual(t, "nl", sess.Get("locale"))
+}
+
+// go test -run Test_Session_Sliding
+func Test_Session_Sliding(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New(Config{Sliding: true})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	id := sess.ID()
+
+	// loading the session re-issues the cookie without saving
+	ctx.Response().Reset()
+	ctx.Request().Header.SetCookie(store.CookieName, id)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "john", sess.Get("name"))
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(store.CookieName)
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	utils.AssertEqual(t, id, string(cookie.Value()))
+	utils.AssertEqual(t, int(store.Expiration.Seconds()), cookie.MaxAge())
+
+	// without sliding no cookie is issued by loading
+	store = New(Config{Storage: store.Storage})
+	ctx.Response().Reset()
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, false, ctx.Response().Header.Cookie(cookie))
+}
+
+// go test -run Test_Session_AbsoluteTimeout
+func Test_Session_AbsoluteTimeout(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New(Config{Sliding: true, AbsoluteTimeout: time.Hour})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// the storage ttl never exceeds the absolute timeout
+	sess, _ := store.Get(ctx)
+	utils.AssertEqual(t, true, sess.expiration() <= time.Hour)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// the deadline survives a round trip
+	deadline := sess.deadline
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, deadline.UnixNano(), sess.deadline.UnixNano())
+
+	// store a session whose deadline has passed
+	sess.deadline = time.Now().Add(-time.Minute)
+	raw, err := sess.encode()
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, store.Storage.Set(sess.ID(), raw, time.Hour))
+
+	// expired sessions are replaced by a fresh one
+	id := sess.ID()
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, true, id != sess.ID())
+	utils.AssertEqual(t, nil, sess.Get("name"))
+	_, err = store.Storage.Get(id)
+	utils.AssertEqual(t, memory.ErrNotExist, err)
+}
+
+// go test -run Test_Session_Save_Empty
+func Test_Session_Save_Empty(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session
+	sess, _ := store.Get(ctx)
+	sess.Set("user_id", 1)
+	utils.AssertEqual(t, nil, sess.Save())
+	id := sess.ID()
+
+	// load, delete the last key & save
+	ctx.Request().Header.SetCookie(store.CookieName, id)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	sess.Delete("user_id")
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// stored data is removed & cookie is expired
+	_, err := store.Storage.Get(id)
+	utils.AssertEqual(t, memory.ErrNotExist, err)
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(store.CookieName)
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	utils.AssertEqual(t, "", string(cookie.Value()))
+	utils.AssertEqual(t, true, cookie.Expire().Before(time.Now()))
+
+	// reloading does not restore the user
+	ctx.Request().Header.SetCookie(store.CookieName, id)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, nil, sess.Get("user_id"))
+}
+
+// go test -run Test_Session_Typed_Values
+func Test_Session_Typed_Values(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	now := time.Date(2020, 11, 5, 10, 30, 0, 123, time.FixedZone("", 3600))
+
+	// save all supported types
+	sess, _ := store.Get(ctx)
+	sess.Set("string", "john")
+	sess.Set("int", 42)
+	sess.Set("int64", int64(-42))
+	sess.Set("uint", uint(42))
+	sess.Set("bool", true)
+	sess.Set("float64", 4.2)
+	sess.Set("time", now)
+	sess.Set("bytes", []byte("john"))
+	sess.Set("empty", []byte{})
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// load the session from storage
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+
+	tests := []struct {
+		key    string
+		expect interface{}
+		get    func(key string) (interface{}, bool)
+	}{
+		{"string", "john", func(k string) (interface{}, bool) { return sess.GetString(k) }},
+		{"int", 42, func(k string) (interface{}, bool) { return sess.GetInt(k) }},
+		{"int64", int64(-42), func(k string) (interface{}, bool) { return sess.GetInt64(k) }},
+		{"uint", 42, func(k string) (interface{}, bool) { return sess.GetInt(k) }},
+		{"bool", true, func(k string) (interface{}, bool) { return sess.GetBool(k) }},
+		{"float64", 4.2, func(k string) (interface{}, bool) { return sess.GetFloat64(k) }},
+		{"int", float64(42), func(k string) (interface{}, bool) { return sess.GetFloat64(k) }},
+		{"time", now, func(k string) (interface{}, bool) { return sess.GetTime(k) }},
+		{"bytes", []byte("john"), func(k string) (interface{}, bool) { return sess.GetBytes(k) }},
+		{"empty", []byte{}, func(k string) (interface{}, bool) { return sess.GetBytes(k) }},
+	}
+	for _, tt := range tests {
+		v, ok := tt.get(tt.key)
+		utils.AssertEqual(t, true, ok, tt.key)
+		utils.AssertEqual(t, tt.expect, v, tt.key)
+	}
+
+	// time.Time and []byte keep their exact value
+	utils.AssertEqual(t, now, sess.Get("time"))
+	utils.AssertEqual(t, []byte{}, sess.Get("empty"))
+
+	// mismatching and missing keys return the zero value
+	str, ok := sess.GetString("int")
+	utils.AssertEqual(t, false, ok)
+	utils.AssertEqual(t, "", str)
+	i, ok := sess.GetInt("missing")
+	utils.AssertEqual(t, false, ok)
+	utils.AssertEqual(t, 0, i)
+	tm, ok := sess.GetTime("string")
+	utils.AssertEqual(t, false, ok)
+	utils.AssertEqual(t, true, tm.IsZero())
+}
+
+// go test -race -run Test_Session_Concurrency
+func Test_Session_Concurrency(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// get session
+	sess, _ := store.Get(ctx)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key%d", i%5)
+			for j := 0; j < 100; j++ {
+				sess.Set(key, j)
+				_, _ = sess.GetInt(key)
+				_ = sess.Keys()
+				_ = sess.GetAll()
+				sess.Delete(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	utils.AssertEqual(t, 0, len(sess.Keys()))
+}
+
+// countStorage is a storage stub that counts the lookups
+type countStorage struct {
+	*memory.Storage
+	gets int
+}
+
+func (s *countStorage) Get(key string) ([]byte, error) {
+	s.gets++
+	return s.Storage.Get(key)
+}
+
+// go test -run Test_Session_SigningKey
+func Test_Session_SigningKey(t *testing.T) {
+	t.Parallel()
+	storage := &countStorage{Storage: memory.New()}
+	// session store
+	store := New(Config{Storage: storage, SigningKey: []byte("secret")})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	id := sess.ID()
+
+	// cookie carries the signed id
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(store.CookieName)
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	signed := string(cookie.Value())
+	utils.AssertEqual(t, true, strings.HasPrefix(signed, id+"."))
+
+	// signed id loads the session
+	ctx.Request().Header.SetCookie(store.CookieName, signed)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, id, sess.ID())
+	utils.AssertEqual(t, "john", sess.Get("name"))
+	utils.AssertEqual(t, 1, storage.gets)
+
+	// forged ids never reach the storage
+	for _, forged := range []string{id, id + ".forged", "random.value", "."} {
+		ctx.Request().Header.SetCookie(store.CookieName, forged)
+		sess, _ = store.Get(ctx)
+		utils.AssertEqual(t, true, sess.Fresh(), forged)
+		utils.AssertEqual(t, true, sess.ID() != id, forged)
+	}
+	utils.AssertEqual(t, 1, storage.gets)
+
+	// previous keys are accepted for verification
+	rotated := New(Config{
+		Storage:             storage,
+		SigningKey:          []byte("new secret"),
+		PreviousSigningKeys: [][]byte{[]byte("secret")},
+	})
+	ctx.Request().Header.SetCookie(store.CookieName, signed)
+	sess, _ = rotated.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "john", sess.Get("name"))
+
+	// and the id is re-signed with the newest key
+	ctx.Response().Reset()
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	utils.AssertEqual(t, rotated.sign(id), string(cookie.Value()))
+	utils.AssertEqual(t, true, signed != string(cookie.Value()))
+}
+
+// go test -run Test_Session_EncryptionKey
+func Test_Session_EncryptionKey(t *testing.T) {
+	t.Parallel()
+	key := []byte("0123456789abcdef0123456789abcdef")
+	var handled error
+	// session store
+	store := New(Config{
+		EncryptionKey: key,
+		ErrorHandler: func(c *fiber.Ctx, err error) {
+			handled = err
+		},
+	})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session
+	sess, _ := store.Get(ctx)
+	sess.Set("email", "john@example.com")
+	utils.AssertEqual(t, nil, sess.Save())
+	id := sess.ID()
+
+	// stored data is encrypted
+	raw, err := store.Storage.Get(id)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, false, strings.Contains(string(raw), "john@example.com"))
+
+	// encrypted data is loaded
+	ctx.Request().Header.SetCookie(store.CookieName, id)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "john@example.com", sess.Get("email"))
+
+	// rotated keys can still decrypt with the previous key
+	rotated := New(Config{
+		Storage:                store.Storage,
+		EncryptionKey:          []byte("fedcba9876543210fedcba9876543210"),
+		PreviousEncryptionKeys: [][]byte{key},
+	})
+	sess, _ = rotated.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "john@example.com", sess.Get("email"))
+
+	// data that fails to decrypt results in a fresh session
+	utils.AssertEqual(t, nil, store.Storage.Set(id, []byte("tampered data"), time.Hour))
+	sess, err = store.Get(ctx)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, nil, sess.Get("email"))
+	utils.AssertEqual(t, errDecrypt, handled)
+}
+
+// go test -run Test_Session_EncryptionKey_Invalid
+func Test_Session_EncryptionKey_Invalid(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		utils.AssertEqual(t, "[session] EncryptionKey must be 32 bytes", recover())
+	}()
+	New(Config{EncryptionKey: []byte("short")})
+}
+
+// go test -run Test_Session_CookieSessionOnly
+func Test_Session_CookieSessionOnly(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New(Config{CookieSessionOnly: true})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// cookie has no lifetime
+	header := utils.ToLower(string(ctx.Response().Header.Peek(fiber.HeaderSetCookie)))
+	utils.AssertEqual(t, true, strings.Contains(header, store.CookieName+"="+sess.ID()))
+	utils.AssertEqual(t, false, strings.Contains(header, "max-age"))
+	utils.AssertEqual(t, false, strings.Contains(header, "expires"))
+
+	// storage still expires the data
+	utils.AssertEqual(t, ConfigDefault.Expiration, sess.expiration())
+	_, err := store.Storage.Get(sess.ID())
+	utils.AssertEqual(t, nil, err)
+}
+
+// go test -run Test_Session_Store_Delete
+func Test_Session_Store_Delete(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save two sessions
+	sess, _ := store.Get(ctx)
+	sess.Set("device", "phone")
+	utils.AssertEqual(t, nil, sess.Save())
+	phone := sess.ID()
+	sess, _ = store.Get(ctx)
+	sess.Set("device", "laptop")
+	utils.AssertEqual(t, nil, sess.Save())
+	laptop := sess.ID()
+
+	// delete one session without a request
+	ctx.Response().Reset()
+	utils.AssertEqual(t, nil, store.Delete(phone))
+	utils.AssertEqual(t, 0, len(ctx.Response().Header.Peek(fiber.HeaderSetCookie)))
+
+	ctx.Request().Header.SetCookie(store.CookieName, phone)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, nil, sess.Get("device"))
+
+	ctx.Request().Header.SetCookie(store.CookieName, laptop)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "laptop", sess.Get("device"))
+
+	// deleting missing or empty ids is not an error
+	utils.AssertEqual(t, nil, store.Delete(phone))
+	utils.AssertEqual(t, nil, store.Delete(""))
+
+	// reset removes all sessions
+	utils.AssertEqual(t, nil, store.Reset())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, nil, sess.Get("device"))
+}
+
+// go test -run Test_Session_Flash
+func Test_Session_Flash(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// set flashes next to a user key named flash
+	sess, _ := store.Get(ctx)
+	sess.Set("flash", "user value")
+	sess.SetFlash("success", "form submitted")
+	sess.SetFlash("info", "welcome back")
+	utils.AssertEqual(t, []string{"flash"}, sess.Keys())
+	utils.AssertEqual(t, map[string]interface{}{"flash": "user value"}, sess.GetAll())
+	utils.AssertEqual(t, nil, sess.Save())
+	id := sess.ID()
+
+	// flash survives the round trip and is removed once read
+	ctx.Request().Header.SetCookie(store.CookieName, id)
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, "form submitted", sess.GetFlash("success"))
+	utils.AssertEqual(t, nil, sess.GetFlash("success"))
+	utils.AssertEqual(t, "user value", sess.Get("flash"))
+	utils.AssertEqual(t, nil, sess.GetFlash("flash"))
+	utils.AssertEqual(t, nil, sess.Save())
+
+	// removal is persisted, remaining flashes are drained at once
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, nil, sess.GetFlash("success"))
+	utils.AssertEqual(t, map[string]interface{}{"info": "welcome back"}, sess.Flashes())
+	utils.AssertEqual(t, map[string]interface{}{}, sess.Flashes())
+	utils.AssertEqual(t, nil, sess.Save())
+
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, map[string]interface{}{}, sess.Flashes())
+	utils.AssertEqual(t, "user value", sess.Get("flash"))
+}
+
+// go test -run Test_Session_Hooks
+func Test_Session_Hooks(t *testing.T) {
+	t.Parallel()
+	var events []string
+	errAbort := errors.New("abort")
+	abort := false
+	hook := func(name string) func(c *fiber.Ctx, s *Session) error {
+		return func(c *fiber.Ctx, s *Session) error {
+			events = append(events, name+":"+c.IP()+":"+s.ID())
+			if abort {
+				return errAbort
+			}
+			return nil
+		}
+	}
+	// session store
+	store := New(Config{
+		KeyGenerator: func() string { return "id" },
+		OnCreate:     hook("create"),
+		OnSave:       hook("save"),
+		OnDestroy:    hook("destroy"),
+	})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// create, save, load & destroy a session
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, nil, sess.Destroy())
+	utils.AssertEqual(t, []string{"create:0.0.0.0:id", "save:0.0.0.0:id", "destroy:0.0.0.0:id"}, events)
+
+	// hook errors abort the operation
+	abort = true
+	ctx.Request().Header.Del(fiber.HeaderCookie)
+	_, err := store.Get(ctx)
+	utils.AssertEqual(t, errAbort, err)
+
+	sess = newSession(ctx, store, "id")
+	sess.Set("name", "john")
+	utils.AssertEqual(t, errAbort, sess.Save())
+	_, err = store.Storage.Get("id")
+	utils.AssertEqual(t, memory.ErrNotExist, err)
+
+	sess.fresh = false
+	utils.AssertEqual(t, errAbort, sess.Destroy())
+	utils.AssertEqual(t, "john", sess.Get("name"))
+}
+
+// go test -run Test_Session_ExpiresAt
+func Test_Session_ExpiresAt(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New(Config{Expiration: time.Hour})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// fresh sessions report the configured expiration
+	sess, _ := store.Get(ctx)
+	ttl := sess.TTL()
+	utils.AssertEqual(t, true, ttl > 59*time.Minute && ttl <= time.Hour)
+
+	// save with a custom expiration
+	sess.Set("name", "john")
+	sess.SetExpiry(10 * time.Minute)
+	utils.AssertEqual(t, nil, sess.Save())
+	expiresAt := sess.ExpiresAt()
+
+	// the expiry survives a round trip
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, expiresAt.UnixNano(), sess.ExpiresAt().UnixNano())
+	ttl = sess.TTL()
+	utils.AssertEqual(t, true, ttl > 9*time.Minute && ttl <= 10*time.Minute)
+
+	// payloads without an expiry still load
+	old := &db{}
+	old.Set("name", "doe")
+	raw, err := old.MarshalMsg(nil)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, store.Storage.Set("old", raw, time.Hour))
+	ctx.Request().Header.SetCookie(store.CookieName, "old")
+	sess, err = store.Get(ctx)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "doe", sess.Get("name"))
+	ttl = sess.TTL()
+	utils.AssertEqual(t, true, ttl > 59*time.Minute && ttl <= time.Hour)
+}
+
+// writeStorage is a storage stub that counts the writes
+type writeStorage struct {
+	*memory.Storage
+	sets int
+}
+
+func (s *writeStorage) Set(key string, val []byte, exp time.Duration) error {
+	s.sets++
+	return s.Storage.Set(key, val, exp)
+}
+
+// go test -run Test_Session_Dirty
+func Test_Session_Dirty(t *testing.T) {
+	t.Parallel()
+	storage := &writeStorage{Storage: memory.New()}
+	// session store
+	store := New(Config{Storage: storage})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// fresh sessions are always written
+	sess, _ := store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Dirty())
+	sess.Set("name", "john")
+	utils.AssertEqual(t, true, sess.Dirty())
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, false, sess.Dirty())
+	utils.AssertEqual(t, 1, storage.sets)
+
+	// saving a clean session skips the storage
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, false, sess.Dirty())
+	_ = sess.Get("name")
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, 1, storage.sets)
+
+	// every change marks the session dirty
+	for _, change := range []func(){
+		func() { sess.Set("name", "doe") },
+		func() { sess.SetExpiry(time.Hour) },
+		func() { sess.SetFlash("info", "hello") },
+		func() { sess.GetFlash("info") },
+	} {
+		sess, _ = store.Get(ctx)
+		change()
+		utils.AssertEqual(t, true, sess.Dirty())
+		utils.AssertEqual(t, nil, sess.Save())
+		utils.AssertEqual(t, false, sess.Dirty())
+	}
+	utils.AssertEqual(t, 5, storage.sets)
+
+	// sliding sessions still re-issue the cookie without writing
+	store = New(Config{Storage: storage, Sliding: true})
+	sess, _ = store.Get(ctx)
+	sets := storage.sets
+	ctx.Response().Reset()
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, sets, storage.sets)
+	utils.AssertEqual(t, true, strings.Contains(string(ctx.Response().Header.Peek(fiber.HeaderSetCookie)), sess.ID()))
+}
+
+// go test -v -run=^$ -bench=Benchmark_Session_Save -benchmem -count=4
+func Benchmark_Session_Save(b *testing.B) {
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(b, nil, sess.Save())
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+
+	b.Run("clean", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			_ = sess.Save()
+		}
+	})
+
+	b.Run("dirty", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			sess.Set("name", "john")
+			_ = sess.Save()
+		}
+	})
+}
+
+// contextStorage is a storage stub that honors context cancellation
+type contextStorage struct {
+	*memory.Storage
+	delay time.Duration
+	calls int32
+}
+
+func (s *contextStorage) wait(ctx context.Context) error {
+	atomic.AddInt32(&s.calls, 1)
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *contextStorage) GetWithContext(ctx context.Context, key string) ([]byte, error) {
+	if err := s.wait(ctx); err != nil {
+		return nil, err
+	}
+	return s.Storage.Get(key)
+}
+
+func (s *contextStorage) SetWithContext(ctx context.Context, key string, val []byte, exp time.Duration) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	return s.Storage.Set(key, val, exp)
+}
+
+func (s *contextStorage) DeleteWithContext(ctx context.Context, key string) error {
+	if err := s.wait(ctx); err != nil {
+		return err
+	}
+	return s.Storage.Delete(key)
+}
+
+// go test -run Test_Session_Storage_Context
+func Test_Session_Storage_Context(t *testing.T) {
+	t.Parallel()
+	storage := &contextStorage{Storage: memory.New(), delay: time.Minute}
+	// session store
+	store := New(Config{Storage: storage})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session without delay
+	storage.delay = 0
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, err := store.Get(ctx)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "john", sess.Get("name"))
+	utils.AssertEqual(t, int32(2), atomic.LoadInt32(&storage.calls))
+
+	// slow storage calls are cancelled by the request context
+	storage.delay = time.Minute
+	deadline, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	ctx.SetUserContext(deadline)
+
+	_, err = store.Get(ctx)
+	utils.AssertEqual(t, context.DeadlineExceeded, err)
+
+	sess.Set("name", "doe")
+	utils.AssertEqual(t, context.DeadlineExceeded, sess.Save())
+	utils.AssertEqual(t, context.DeadlineExceeded, sess.Destroy())
+	utils.AssertEqual(t, context.DeadlineExceeded, sess.Regenerate())
+}
+
+// go test -run Test_Session_ID_Validation
+func Test_Session_ID_Validation(t *testing.T) {
+	t.Parallel()
+	storage := &countStorage{Storage: memory.New()}
+	// session store
+	store := New(Config{Storage: storage})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// invalid ids never reach the storage
+	for _, id := range []string{
+		strings.Repeat("a", 65),
+		"../../etc/passwd",
+		"id with spaces",
+		"id\x00null",
+		"id\ttab",
+	} {
+		ctx.Request().Header.SetCookie(store.CookieName, id)
+		sess, err := store.Get(ctx)
+		utils.AssertEqual(t, nil, err, id)
+		utils.AssertEqual(t, true, sess.Fresh(), id)
+		utils.AssertEqual(t, true, sess.ID() != id, id)
+	}
+	utils.AssertEqual(t, 0, storage.gets)
+
+	// valid ids are looked up
+	ctx.Request().Header.SetCookie(store.CookieName, strings.Repeat("a", 64))
+	sess, _ := store.Get(ctx)
+	utils.AssertEqual(t, strings.Repeat("a", 64), sess.ID())
+	utils.AssertEqual(t, 1, storage.gets)
+
+	// custom validators still reject control characters
+	store = New(Config{
+		Storage:     storage,
+		MaxIDLength: 8,
+		IDValidator: func(id string) bool { return true },
+	})
+	ctx.Request().Header.SetCookie(store.CookieName, "a.b")
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, "a.b", sess.ID())
+	ctx.Request().Header.SetCookie(store.CookieName, "a\x01b")
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, true, sess.ID() != "a\x01b")
+	ctx.Request().Header.SetCookie(store.CookieName, "123456789")
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, true, sess.ID() != "123456789")
+	utils.AssertEqual(t, 2, storage.gets)
+}
+
+// go test -run Test_Session_CookiePartitioned
+func Test_Session_CookiePartitioned(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New(Config{CookiePartitioned: true, CookieSecure: true, CookieSameSite: "None"})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// saved cookie is partitioned
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, 1, bytes.Count(ctx.Response().Header.Header(), []byte(fiber.HeaderSetCookie)))
+	header := string(ctx.Response().Header.Peek(fiber.HeaderSetCookie))
+	utils.AssertEqual(t, true, strings.HasPrefix(header, store.CookieName+"="+sess.ID()))
+	utils.AssertEqual(t, true, strings.Contains(header, "secure; SameSite=None; Partitioned"))
+
+	// expired cookie is partitioned as well
+	utils.AssertEqual(t, nil, sess.Destroy())
+	utils.AssertEqual(t, 1, bytes.Count(ctx.Response().Header.Header(), []byte(fiber.HeaderSetCookie)))
+	header = string(ctx.Response().Header.Peek(fiber.HeaderSetCookie))
+	utils.AssertEqual(t, true, strings.HasPrefix(header, store.CookieName+"=;"))
+	utils.AssertEqual(t, true, strings.HasSuffix(header, "; Partitioned"))
+}
+
+// go test -run Test_Session_CookiePartitioned_Invalid
+func Test_Session_CookiePartitioned_Invalid(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		utils.AssertEqual(t, "[session] CookiePartitioned requires CookieSecure and CookieSameSite None", recover())
+	}()
+	New(Config{CookiePartitioned: true, CookieSecure: true, CookieSameSite: "Lax"})
+}
+
+// go test -run Test_Session_CookieSameSite
+func Test_Session_CookieSameSite(t *testing.T) {
+	t.Parallel()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	cookie := func(store *Store) string {
+		ctx.Response().Reset()
+		sess, _ := store.Get(ctx)
+		sess.Set("name", "john")
+		utils.AssertEqual(t, nil, sess.Save())
+		return string(ctx.Response().Header.Peek(fiber.HeaderSetCookie))
+	}
+
+	// default is Lax
+	header := cookie(New())
+	utils.AssertEqual(t, true, strings.HasSuffix(header, "; SameSite=Lax"))
+	utils.AssertEqual(t, false, strings.Contains(header, "secure"))
+
+	// disabled omits the attribute
+	header = cookie(New(Config{CookieSameSite: "Disabled"}))
+	utils.AssertEqual(t, false, strings.Contains(header, "SameSite"))
+
+	// none forces the secure flag
+	store := New(Config{CookieSameSite: "None"})
+	utils.AssertEqual(t, true, store.CookieSecure)
+	header = cookie(store)
+	utils.AssertEqual(t, true, strings.HasSuffix(header, "; secure; SameSite=None"))
+}
+
+// errorStorage is a storage stub that fails every lookup once err is set
+type errorStorage struct {
+	*memory.Storage
+	err error
+}
+
+func (s *errorStorage) Get(key string) ([]byte, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.Storage.Get(key)
+}
+
+// go test -run Test_Session_Storage_Errors
+func Test_Session_Storage_Errors(t *testing.T) {
+	t.Parallel()
+	errDown := errors.New("connection refused")
+	var handled error
+	storage := &errorStorage{Storage: memory.New(), err: errDown}
+	// session store
+	store := New(Config{
+		Storage: storage,
+		ErrorHandler: func(c *fiber.Ctx, err error) {
+			handled = err
+		},
+	})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+	ctx.Request().Header.SetCookie(store.CookieName, "123")
+
+	// storage errors are returned to the handler
+	sess, err := store.Get(ctx)
+	utils.AssertEqual(t, errDown, err)
+	utils.AssertEqual(t, errDown, handled)
+	utils.AssertEqual(t, true, sess == nil)
+
+	// missing keys result in a fresh session
+	handled = nil
+	for _, err := range []error{ErrSessionNotFound, memory.ErrNotExist, nil} {
+		storage.err = err
+		sess, err = store.Get(ctx)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, true, sess.Fresh())
+		utils.AssertEqual(t, "123", sess.ID())
+	}
+	utils.AssertEqual(t, nil, handled)
+}
+
+// listStorage is a storage stub that can list its keys
+type listStorage struct {
+	*memory.Storage
+	keys map[string]bool
+}
+
+func (s *listStorage) Set(key string, val []byte, exp time.Duration) error {
+	s.keys[key] = true
+	return s.Storage.Set(key, val, exp)
+}
+
+func (s *listStorage) Delete(key string) error {
+	delete(s.keys, key)
+	return s.Storage.Delete(key)
+}
+
+func (s *listStorage) Keys() ([]string, error) {
+	keys := make([]string, 0, len(s.keys))
+	for key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// go test -run Test_Session_KeyPrefix
+func Test_Session_KeyPrefix(t *testing.T) {
+	t.Parallel()
+	storage := &listStorage{Storage: memory.New(), keys: make(map[string]bool)}
+	// session stores sharing one storage
+	storeA := New(Config{Storage: storage, KeyPrefix: "a:"})
+	storeB := New(Config{Storage: storage, KeyPrefix: "b:"})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session in both stores
+	sess, _ := storeA.Get(ctx)
+	sess.Set("app", "a")
+	utils.AssertEqual(t, nil, sess.Save())
+	idA := sess.ID()
+	sess, _ = storeB.Get(ctx)
+	sess.Set("app", "b")
+	utils.AssertEqual(t, nil, sess.Save())
+	idB := sess.ID()
+
+	// storage keys are prefixed, cookies carry the bare id
+	_, err := storage.Get("a:" + idA)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, strings.HasPrefix(string(ctx.Response().Header.PeekCookie(storeB.CookieName)), storeB.CookieName+"="+idB+";"))
+
+	// sessions can't be replayed across stores
+	ctx.Request().Header.SetCookie(storeA.CookieName, idA)
+	sess, _ = storeB.Get(ctx)
+	utils.AssertEqual(t, true, sess.Fresh())
+	sess, _ = storeA.Get(ctx)
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, "a", sess.Get("app"))
+
+	// regenerate & delete work with the prefix
+	utils.AssertEqual(t, nil, sess.Regenerate())
+	_, err = storage.Get("a:" + sess.ID())
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, nil, storeA.Delete(sess.ID()))
+	_, err = storage.Get("a:" + sess.ID())
+	utils.AssertEqual(t, memory.ErrNotExist, err)
+
+	// reset only removes keys under the prefix
+	sess, _ = storeA.Get(ctx)
+	sess.Set("app", "a")
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, nil, storeA.Reset())
+	keys, _ := storage.Keys()
+	utils.AssertEqual(t, []string{"b:" + idB}, keys)
+
+	// reset is refused if the storage can't list keys
+	store := New(Config{KeyPrefix: "c:"})
+	utils.AssertEqual(t, ErrResetUnsupported, store.Reset())
+}
+
+// go test -run Test_Session_Reset_Data
+func Test_Session_Reset_Data(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// save a session
+	sess, _ := store.Get(ctx)
+	sess.Set("step", 2)
+	sess.Set("items", 3)
+	utils.AssertEqual(t, nil, sess.Save())
+	id := sess.ID()
+
+	// reset keeps the id without touching the storage or cookie
+	ctx.Response().Reset()
+	ctx.Request().Header.SetCookie(store.CookieName, id)
+	sess, _ = store.Get(ctx)
+	sess.Reset()
+	utils.AssertEqual(t, id, sess.ID())
+	utils.AssertEqual(t, true, sess.Dirty())
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, []string{}, sess.Keys())
+	utils.AssertEqual(t, 0, len(ctx.Response().Header.Peek(fiber.HeaderSetCookie)))
+	_, err := store.Storage.Get(id)
+	utils.AssertEqual(t, nil, err)
+
+	// new data is saved under the same id
+	sess.Set("step", 1)
+	utils.AssertEqual(t, nil, sess.Save())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, id, sess.ID())
+	utils.AssertEqual(t, []string{"step"}, sess.Keys())
+
+	// saving the cleared state removes the stored data
+	sess.Reset()
+	utils.AssertEqual(t, nil, sess.Save())
+	_, err = store.Storage.Get(id)
+	utils.AssertEqual(t, memory.ErrNotExist, err)
+}
+
+type gobUser struct {
+	Name  string
+	Roles []string
+}
+
+func init() {
+	gob.Register(&gobUser{})
+}
+
+// go test -run Test_Session_Codec
+func Test_Session_Codec(t *testing.T) {
+	t.Parallel()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	t.Run("gob", func(t *testing.T) {
+		store := New(Config{Codec: GobCodec{}})
+		user := &gobUser{Name: "john", Roles: []string{"admin"}}
+
+		sess, _ := store.Get(ctx)
+		sess.Set("user", user)
+		sess.SetExpiry(time.Hour)
+		utils.AssertEqual(t, nil, sess.Save())
+
+		ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+		sess, err := store.Get(ctx)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, false, sess.Fresh())
+		utils.AssertEqual(t, user, sess.Get("user"))
+		utils.AssertEqual(t, time.Hour, sess.exp)
+		utils.AssertEqual(t, []string{"user"}, sess.Keys())
+	})
+
+	t.Run("json", func(t *testing.T) {
+		store := New(Config{Codec: JSONCodec{}})
+
+		sess, _ := store.Get(ctx)
+		sess.Set("name", "john")
+		sess.Set("age", 21)
+		sess.SetExpiry(time.Hour)
+		utils.AssertEqual(t, nil, sess.Save())
+
+		raw, err := store.Storage.Get(sess.ID())
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, true, bytes.Contains(raw, []byte(`"name":"john"`)))
+
+		ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+		sess, err = store.Get(ctx)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, false, sess.Fresh())
+		utils.AssertEqual(t, "john", sess.Get("name"))
+		age, ok := sess.GetInt("age")
+		utils.AssertEqual(t, true, ok)
+		utils.AssertEqual(t, 21, age)
+		utils.AssertEqual(t, time.Hour, sess.exp)
+	})
+}
+
+// go test -run Test_Session_Handler
+func Test_Session_Handler(t *testing.T) {
+	t.Parallel()
+	storage := &writeStorage{Storage: memory.New()}
+	// session store
+	store := New(Config{Storage: storage})
+	// fiber instance
+	app := fiber.New()
+	app.Use(store.Handler())
+
+	app.Get("/set", func(c *fiber.Ctx) error {
+		sess := FromContext(c)
+		sess.Set("name", "john")
+		return nil
+	})
+	app.Get("/get", func(c *fiber.Ctx) error {
+		// manual usage returns the loaded session
+		sess, err := store.Get(c)
+		if err != nil {
+			return err
+		}
+		utils.AssertEqual(t, c.Locals("session"), sess)
+		return c.SendString(fmt.Sprint(sess.Get("name")))
+	})
+	app.Get("/save", func(c *fiber.Ctx) error {
+		sess := FromContext(c)
+		sess.Set("name", "doe")
+		return sess.Save()
+	})
+
+	// changed sessions are saved after the handler
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/set", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 1, storage.sets)
+	cookie := resp.Header.Get(fiber.HeaderSetCookie)
+	utils.AssertEqual(t, true, strings.HasPrefix(cookie, store.CookieName+"="))
+	cookie = strings.Split(cookie, ";")[0]
+
+	// unchanged sessions are not saved
+	req := httptest.NewRequest(fiber.MethodGet, "/get", nil)
+	req.Header.Set(fiber.HeaderCookie, cookie)
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	body, _ := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, "john", string(body))
+	utils.AssertEqual(t, 1, storage.sets)
+
+	// sessions saved by the handler are not saved twice
+	req = httptest.NewRequest(fiber.MethodGet, "/save", nil)
+	req.Header.Set(fiber.HeaderCookie, cookie)
+	_, err = app.Test(req)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, 2, storage.sets)
+}
+
+// go test -run Test_Session_Handler_Error
+func Test_Session_Handler_Error(t *testing.T) {
+	t.Parallel()
+	errDown := errors.New("storage down")
+	// session store
+	store := New(Config{
+		ContextKey: "sess",
+		OnSave: func(c *fiber.Ctx, s *Session) error {
+			return errDown
+		},
+		Next: func(c *fiber.Ctx) bool {
+			return c.Path() == "/skip"
+		},
+	})
+	// fiber instance
+	app := fiber.New(fiber.Config{
+		ErrorHandler: func(c *fiber.Ctx, err error) error {
+			return c.Status(fiber.StatusServiceUnavailable).SendString(err.Error())
+		},
+	})
+	app.Use(store.Handler())
+	app.Get("/", func(c *fiber.Ctx) error {
+		c.Locals("sess").(*Session).Set("name", "john")
+		return nil
+	})
+	app.Get("/skip", func(c *fiber.Ctx) error {
+		utils.AssertEqual(t, nil, c.Locals("sess"))
+		return nil
+	})
+
+	// save errors are passed to the error handler
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusServiceUnavailable, resp.StatusCode)
+
+	// skipped requests have no session
+	resp, err = app.Test(httptest.NewRequest(fiber.MethodGet, "/skip", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+}
+
+// go test -run Test_Session_Regenerate_Cookie
+func Test_Session_Regenerate_Cookie(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New()
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+	cookie.SetKey(store.CookieName)
+
+	// regenerating an empty session issues the new id
+	sess, _ := store.Get(ctx)
+	oldID := sess.ID()
+	utils.AssertEqual(t, nil, sess.Regenerate())
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	utils.AssertEqual(t, sess.ID(), string(cookie.Value()))
+	utils.AssertEqual(t, true, oldID != sess.ID())
+
+	// regenerating a stored session issues the new id without Save
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	ctx.Response().Reset()
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	oldID = sess.ID()
+	utils.AssertEqual(t, nil, sess.Regenerate())
+	utils.AssertEqual(t, true, ctx.Response().Header.Cookie(cookie))
+	utils.AssertEqual(t, sess.ID(), string(cookie.Value()))
+	utils.AssertEqual(t, true, oldID != sess.ID())
+}
+
+// go test -run Test_Session_ReadOnly
+func Test_Session_ReadOnly(t *testing.T) {
+	t.Parallel()
+	// session store
+	store := New(Config{Sliding: true})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// store a session to read
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	sess.SetFlash("notice", "saved")
+	utils.AssertEqual(t, nil, sess.Save())
+	id := sess.ID()
+
+	ctx.Response().Reset()
+	ctx.Request().Header.SetCookie(store.CookieName, id)
+	sess, err := store.GetReadOnly(ctx)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, sess.ReadOnly())
+	utils.AssertEqual(t, false, sess.Fresh())
+	utils.AssertEqual(t, id, sess.ID())
+	utils.AssertEqual(t, "john", sess.Get("name"))
+
+	// changes are ignored
+	sess.Set("name", "doe")
+	sess.Delete("name")
+	sess.Reset()
+	utils.AssertEqual(t, "saved", sess.GetFlash("notice"))
+	utils.AssertEqual(t, "saved", sess.GetFlash("notice"))
+	utils.AssertEqual(t, "john", sess.Get("name"))
+	utils.AssertEqual(t, false, sess.Dirty())
+
+	// persisting is rejected
+	utils.AssertEqual(t, ErrReadOnly, sess.Save())
+	utils.AssertEqual(t, ErrReadOnly, sess.Destroy())
+	utils.AssertEqual(t, ErrReadOnly, sess.Regenerate())
+	utils.AssertEqual(t, id, sess.ID())
+
+	// no cookie is issued, not even for sliding sessions
+	utils.AssertEqual(t, "", string(ctx.Response().Header.Peek(fiber.HeaderSetCookie)))
+
+	// fresh read-only sessions stay fresh
+	ctx.Request().Header.DelAllCookies()
+	sess, err = store.GetReadOnly(ctx)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, sess.Fresh())
+	utils.AssertEqual(t, ErrReadOnly, sess.Save())
+	utils.AssertEqual(t, "", string(ctx.Response().Header.Peek(fiber.HeaderSetCookie)))
+}
+
+// slowCodec is a codec stub that takes its time to marshal
+type slowCodec struct {
+	MsgpackCodec
+	delay time.Duration
+}
+
+func (c slowCodec) Marshal(data map[string]interface{}) ([]byte, error) {
+	time.Sleep(c.delay)
+	return c.MsgpackCodec.Marshal(data)
+}
+
+// go test -run Test_Session_Metrics
+func Test_Session_Metrics(t *testing.T) {
+	t.Parallel()
+	metrics := &CounterMetrics{}
+	storage := &errorStorage{Storage: memory.New()}
+	// session store
+	store := New(Config{
+		Storage: storage,
+		Metrics: metrics,
+		Codec:   slowCodec{delay: 100 * time.Millisecond},
+	})
+	// fiber instance
+	app := fiber.New()
+	// fiber context
+	ctx := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(ctx)
+
+	// fresh sessions don't touch the storage
+	sess, _ := store.Get(ctx)
+	sess.Set("name", "john")
+	utils.AssertEqual(t, nil, sess.Save())
+	utils.AssertEqual(t, int64(0), metrics.Hits()+metrics.Misses())
+	utils.AssertEqual(t, int64(1), metrics.Saves())
+	// marshaling is not part of the storage time
+	utils.AssertEqual(t, true, metrics.Duration() < 100*time.Millisecond)
+
+	// found and missing sessions
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	sess, _ = store.Get(ctx)
+	utils.AssertEqual(t, int64(1), metrics.Hits())
+	ctx.Request().Header.SetCookie(store.CookieName, "missing")
+	_, _ = store.Get(ctx)
+	utils.AssertEqual(t, int64(1), metrics.Misses())
+
+	// failed lookups are misses
+	storage.err = errors.New("connection refused")
+	ctx.Request().Header.SetCookie(store.CookieName, sess.ID())
+	_, err := store.Get(ctx)
+	utils.AssertEqual(t, storage.err, err)
+	utils.AssertEqual(t, int64(2), metrics.Misses())
+
+	// destroyed sessions
+	utils.AssertEqual(t, nil, sess.Destroy())
+	utils.AssertEqual(t, int64(1), metrics.Destroys())
+	utils.AssertEqual(t, int64(0), metrics.Errors())
+}
+
+// go test -run Test_Session_FromContext
+func Test_Session_FromContext(t *testing.T) {
+	t.Parallel()
+	store := New()
+	custom := New(Config{ContextKey: "sess"})
+	app := fiber.New()
+
+	app.Get("/", store.Handler(), custom.Handler(), func(c *fiber.Ctx) error {
+		utils.AssertEqual(t, c.Locals("session"), FromContext(c))
+		utils.AssertEqual(t, c.Locals("sess"), FromContext(c, "sess"))
+		utils.AssertEqual(t, true, FromContext(c) != FromContext(c, "sess"))
+		utils.AssertEqual(t, (*Session)(nil), FromContext(c, "missing"))
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode)
+}
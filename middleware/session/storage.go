@@ -0,0 +1,52 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Optional storage methods that accept a context, they are used instead of
+// the fiber.Storage methods so slow storage calls can be cancelled
+type (
+	getterWithContext interface {
+		GetWithContext(ctx context.Context, key string) ([]byte, error)
+	}
+	setterWithContext interface {
+		SetWithContext(ctx context.Context, key string, val []byte, exp time.Duration) error
+	}
+	deleterWithContext interface {
+		DeleteWithContext(ctx context.Context, key string) error
+	}
+)
+
+// keyLister is implemented by storages that can list their keys, it is
+// needed to reset only the sessions under the KeyPrefix
+type keyLister interface {
+	Keys() ([]string, error)
+}
+
+// getStorage reads the key from the storage using the request context if possible
+func (s *Store) getStorage(c *fiber.Ctx, key string) ([]byte, error) {
+	if storage, ok := s.Storage.(getterWithContext); ok && c != nil {
+		return storage.GetWithContext(c.UserContext(), s.KeyPrefix+key)
+	}
+	return s.Storage.Get(s.KeyPrefix + key)
+}
+
+// setStorage writes the key to the storage using the request context if possible
+func (s *Store) setStorage(c *fiber.Ctx, key string, val []byte, exp time.Duration) error {
+	if storage, ok := s.Storage.(setterWithContext); ok && c != nil {
+		return storage.SetWithContext(c.UserContext(), s.KeyPrefix+key, val, exp)
+	}
+	return s.Storage.Set(s.KeyPrefix+key, val, exp)
+}
+
+// deleteStorage deletes the key from the storage using the request context if possible
+func (s *Store) deleteStorage(c *fiber.Ctx, key string) error {
+	if storage, ok := s.Storage.(deleterWithContext); ok && c != nil {
+		return storage.DeleteWithContext(c.UserContext(), s.KeyPrefix+key)
+	}
+	return s.Storage.Delete(s.KeyPrefix + key)
+}
@@ -1,17 +1,63 @@
 package session
 
 import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/internal/storage/memory"
+	"github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
 )
 
 type Store struct {
 	Config
+
+	// source and name of the session id, parsed from KeyLookup
+	source     string
+	sessionKey string
+
+	// ciphers for the encryption keys, the newest key comes first
+	aeads []cipher.AEAD
+
+	// SameSite mode of the session cookie, parsed from CookieSameSite
+	sameSite fasthttp.CookieSameSite
 }
 
+// ErrSessionNotFound can be returned by storages to signal that a session
+// does not exist, Store.Get then hands out a fresh session
+var ErrSessionNotFound = errors.New("session: session not found")
+
+// ErrResetUnsupported is returned by Store.Reset if a KeyPrefix is set and
+// the storage can't list its keys
+var ErrResetUnsupported = errors.New("session: storage can't reset keys with a prefix")
+
+// ErrReadOnly is returned when a session loaded with Store.GetReadOnly
+// is saved, destroyed or regenerated
+var ErrReadOnly = errors.New("session: session is read-only")
+
 // Storage ErrNotExist
 var errNotExist = "key does not exist"
 
+// notExist reports whether the storage error means the key does not exist
+func notExist(err error) bool {
+	return err != nil && (errors.Is(err, ErrSessionNotFound) || err.Error() == errNotExist)
+}
+
+// Session id sources
+const (
+	sourceCookie = "cookie"
+	sourceHeader = "header"
+	sourceQuery  = "query"
+)
+
 func New(config ...Config) *Store {
 	// Set default config
 	cfg := configDefault(config...)
@@ -20,16 +66,100 @@ func New(config ...Config) *Store {
 		cfg.Storage = memory.New()
 	}
 
-	return &Store{
-		cfg,
+	// Parse the session id source from KeyLookup
+	selectors := strings.Split(cfg.KeyLookup, ":")
+	if len(selectors) != 2 || selectors[1] == "" {
+		panic("[session] KeyLookup must in the form of <source>:<name>")
+	}
+	switch selectors[0] {
+	case sourceCookie:
+		cfg.CookieName = selectors[1]
+	case sourceHeader:
+		selectors[1] = textproto.CanonicalMIMEHeaderKey(selectors[1])
+	case sourceQuery:
+	default:
+		panic(fmt.Sprintf("[session] KeyLookup source %q is not supported", selectors[0]))
+	}
+
+	// SameSite=None cookies are only accepted by browsers if they are secure
+	if utils.ToLower(cfg.CookieSameSite) == "none" {
+		cfg.CookieSecure = true
+	}
+
+	if cfg.CookiePartitioned && (!cfg.CookieSecure || utils.ToLower(cfg.CookieSameSite) != "none") {
+		panic("[session] CookiePartitioned requires CookieSecure and CookieSameSite None")
+	}
+
+	store := &Store{
+		Config:     cfg,
+		source:     selectors[0],
+		sessionKey: selectors[1],
+		sameSite:   parseSameSite(cfg.CookieSameSite),
+	}
+
+	// Prepare the ciphers used to encrypt the session data
+	if len(cfg.EncryptionKey) > 0 {
+		store.aeads = append(store.aeads, newAEAD(cfg.EncryptionKey))
+		for _, key := range cfg.PreviousEncryptionKeys {
+			store.aeads = append(store.aeads, newAEAD(key))
+		}
+	}
+
+	return store
+}
+
+// parseSameSite maps the CookieSameSite value to its fasthttp mode,
+// unknown values fall back to Lax
+func parseSameSite(value string) fasthttp.CookieSameSite {
+	switch utils.ToLower(value) {
+	case "strict":
+		return fasthttp.CookieSameSiteStrictMode
+	case "none":
+		return fasthttp.CookieSameSiteNoneMode
+	case "disabled":
+		return fasthttp.CookieSameSiteDisabled
+	default:
+		return fasthttp.CookieSameSiteLaxMode
 	}
 }
 
 func (s *Store) Get(c *fiber.Ctx) (*Session, error) {
+	// Reuse the session loaded by the Store.Handler middleware
+	if sess, ok := c.Locals(s.ContextKey).(*Session); ok && sess.config == s {
+		return sess, nil
+	}
+
+	sess, err := s.load(c, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify about newly created sessions
+	if sess.fresh && s.OnCreate != nil {
+		if err = s.OnCreate(c, sess); err != nil {
+			return nil, err
+		}
+	}
+
+	return sess, nil
+}
+
+// GetReadOnly loads the session without the means to change it. Set and
+// Delete are ignored, Save, Destroy and Regenerate return ErrReadOnly and
+// no session cookie is issued. Sliding sessions are not refreshed.
+func (s *Store) GetReadOnly(c *fiber.Ctx) (*Session, error) {
+	return s.load(c, true)
+}
+
+// load returns the session of the request, or a fresh one
+func (s *Store) load(c *fiber.Ctx, readOnly bool) (*Session, error) {
 	var fresh bool
 
-	// Get key from cookie
-	id := c.Cookies(s.CookieName)
+	// Get key from request
+	id, stale, ok := s.verify(s.getSessionID(c))
+	if !ok || !s.validID(id) {
+		id = ""
+	}
 
 	// If no key exist, create new one
 	if len(id) == 0 {
@@ -38,30 +168,201 @@ func (s *Store) Get(c *fiber.Ctx) (*Session, error) {
 	}
 
 	// Create session object
-	sess := acquireSession()
-	sess.ctx = c
-	sess.config = s
-	sess.id = id
+	sess := newSession(c, s, id)
+	sess.readOnly = readOnly
 
 	// Fetch existing data
 	if !fresh {
-		raw, err := s.Storage.Get(id)
+		start := time.Now()
+		raw, err := s.getStorage(c, id)
+		// A missing key only means the session does not exist
+		if notExist(err) {
+			raw, err = nil, nil
+		}
+		s.Metrics.ObserveGet(err == nil && len(raw) > 0, time.Since(start))
+		if err != nil {
+			// The storage failed, don't hide it behind a fresh session
+			if s.ErrorHandler != nil {
+				s.ErrorHandler(c, err)
+			}
+			return nil, err
+		}
 		// Unmashal if we found data
-		if err == nil {
-			if _, err = sess.db.UnmarshalMsg(raw); err != nil {
+		if len(raw) > 0 {
+			var data []byte
+			if data, err = s.open(raw); err != nil {
+				// Data that can't be decrypted is handled like a missing session
+				if s.ErrorHandler != nil {
+					s.ErrorHandler(c, err)
+				}
+				return sess, nil
+			}
+			if err = sess.decode(data); err != nil {
 				return nil, err
 			}
+			if sess.expired() {
+				// Absolute timeout passed, hand out a fresh session
+				if readOnly {
+					sess = newSession(c, s, id)
+					sess.readOnly = true
+					return sess, nil
+				}
+				if err = s.deleteStorage(c, id); err != nil {
+					return nil, err
+				}
+				return newSession(c, s, s.KeyGenerator()), nil
+			}
 			sess.fresh = false
-		} else if err.Error() != errNotExist {
-			// Only return error if it's not ErrNotExist
-			return nil, err
+			sess.stale = stale
+			// Keep the session alive as long as it is used
+			if s.Sliding && !readOnly {
+				if err = sess.refresh(); err != nil {
+					return nil, err
+				}
+			}
 		}
 	}
 
 	return sess, nil
 }
 
-// Reset will delete all session from the storage
+// Handler returns a middleware that loads the session before the next
+// handlers and saves it afterwards if it changed. The session is stored in
+// c.Locals under the ContextKey.
+func (s *Store) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Don't execute middleware if Next returns true
+		if s.Next != nil && s.Next(c) {
+			return c.Next()
+		}
+
+		sess, err := s.Get(c)
+		if err != nil {
+			return err
+		}
+		c.Locals(s.ContextKey, sess)
+
+		// Continue stack
+		err = c.Next()
+
+		// Save the session if it was changed and not saved by the handlers
+		if sess.Dirty() {
+			if saveErr := sess.Save(); err == nil {
+				err = saveErr
+			}
+		}
+		return err
+	}
+}
+
+// FromContext returns the session stored by the Store.Handler middleware, or
+// nil if there is none. Pass the ContextKey if a custom one is configured.
+func FromContext(c *fiber.Ctx, contextKey ...string) *Session {
+	key := ConfigDefault.ContextKey
+	if len(contextKey) > 0 {
+		key = contextKey[0]
+	}
+	if sess, ok := c.Locals(key).(*Session); ok {
+		return sess
+	}
+	return nil
+}
+
+// getSessionID extracts the session id from the configured source
+func (s *Store) getSessionID(c *fiber.Ctx) string {
+	switch s.source {
+	case sourceHeader:
+		return c.Get(s.sessionKey)
+	case sourceQuery:
+		return c.Query(s.sessionKey)
+	default:
+		return c.Cookies(s.sessionKey)
+	}
+}
+
+// validID reports whether the session id of the client can be passed to the storage
+func (s *Store) validID(id string) bool {
+	if len(id) > s.MaxIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x20 || id[i] == 0x7f {
+			return false
+		}
+	}
+	return s.IDValidator(id)
+}
+
+// sign appends the HMAC signature to the session id if a SigningKey is set
+func (s *Store) sign(id string) string {
+	if len(s.SigningKey) == 0 {
+		return id
+	}
+	return id + "." + signature(s.SigningKey, id)
+}
+
+// verify returns the session id if the signature matches one of the signing keys,
+// stale is true if the id was signed with one of the previous keys
+func (s *Store) verify(value string) (id string, stale, ok bool) {
+	if len(s.SigningKey) == 0 || len(value) == 0 {
+		return value, false, true
+	}
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return "", false, false
+	}
+	id, sig := value[:i], value[i+1:]
+	if hmac.Equal([]byte(sig), []byte(signature(s.SigningKey, id))) {
+		return id, false, true
+	}
+	for _, key := range s.PreviousSigningKeys {
+		if hmac.Equal([]byte(sig), []byte(signature(key, id))) {
+			return id, true, true
+		}
+	}
+	return "", false, false
+}
+
+// signature returns the base64 encoded HMAC-SHA256 of the session id
+func signature(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	_, _ = mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Delete will delete the session with the given id from the storage,
+// without touching the client cookie
+func (s *Store) Delete(id string) error {
+	if len(id) == 0 {
+		return nil
+	}
+	if err := s.deleteStorage(nil, id); err != nil && !notExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Reset will delete all session from the storage. If a KeyPrefix is set,
+// only the keys under the prefix are deleted, which requires the storage to
+// list its keys with a Keys() ([]string, error) method.
 func (s *Store) Reset() error {
-	return s.Storage.Reset()
+	if s.KeyPrefix == "" {
+		return s.Storage.Reset()
+	}
+	lister, ok := s.Storage.(keyLister)
+	if !ok {
+		return ErrResetUnsupported
+	}
+	keys, err := lister.Keys()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if strings.HasPrefix(key, s.KeyPrefix) {
+			if err = s.Storage.Delete(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
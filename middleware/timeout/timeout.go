@@ -1,6 +1,8 @@
 package timeout
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -41,3 +43,45 @@ func New(handler fiber.Handler, timeout time.Duration) fiber.Handler {
 		return nil
 	}
 }
+
+// NewWithContext runs the handler with a context which is cancelled once the
+// timeout is reached, the handler gets it from c.UserContext(). The handler
+// runs in the calling goroutine, so it must return on its own. Returned
+// context.DeadlineExceeded errors, and the errors matching one of errs, are
+// replaced by fiber.ErrRequestTimeout, other results are passed on.
+func NewWithContext(handler fiber.Handler, timeout time.Duration, errs ...error) fiber.Handler {
+	if timeout <= 0 {
+		return handler
+	}
+
+	return func(ctx *fiber.Ctx) error {
+		parent := ctx.UserContext()
+		timeoutContext, cancel := context.WithTimeout(parent, timeout)
+		ctx.SetUserContext(timeoutContext)
+		defer func() {
+			cancel()
+			ctx.SetUserContext(parent)
+		}()
+
+		// A response written after the deadline is kept, only errors are
+		// replaced
+		err := handler(ctx)
+		if err != nil && isTimeout(err, errs) {
+			return fiber.ErrRequestTimeout
+		}
+		return err
+	}
+}
+
+// isTimeout reports whether the error is a deadline or one of errs
+func isTimeout(err error, errs []error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	for _, e := range errs {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,5 +1,133 @@
 package timeout
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+var errDriverTimeout = errors.New("driver: i/o timeout")
+
+// sleepWithContext sleeps for d or until the context is done, then the
+// timeoutErr or the error of the context is returned
+func sleepWithContext(ctx context.Context, d time.Duration, timeoutErr error) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		if timeoutErr != nil {
+			return timeoutErr
+		}
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// go test -run Test_Timeout_WithContext
+func Test_Timeout_WithContext(t *testing.T) {
+	app := fiber.New()
+
+	app.Get("/test/:sleepTime", NewWithContext(func(c *fiber.Ctx) error {
+		sleepTime, _ := time.ParseDuration(c.Params("sleepTime") + "ms")
+		if err := sleepWithContext(c.UserContext(), sleepTime, nil); err != nil {
+			return fmt.Errorf("query: %w", err)
+		}
+		return c.SendString("After " + c.Params("sleepTime") + "ms sleeping")
+	}, 20*time.Millisecond))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/test/100", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusRequestTimeout, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "Request Timeout", string(body))
+
+	resp, err = app.Test(httptest.NewRequest("GET", "/test/1", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "After 1ms sleeping", string(body))
+}
+
+// go test -run Test_Timeout_WithContext_Errors
+func Test_Timeout_WithContext_Errors(t *testing.T) {
+	app := fiber.New()
+
+	app.Get("/driver", NewWithContext(func(c *fiber.Ctx) error {
+		return sleepWithContext(c.UserContext(), time.Second, errDriverTimeout)
+	}, 20*time.Millisecond, errDriverTimeout))
+	app.Get("/other", NewWithContext(func(c *fiber.Ctx) error {
+		return fiber.ErrBadGateway
+	}, 20*time.Millisecond, errDriverTimeout))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/driver", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusRequestTimeout, resp.StatusCode, "Status code")
+
+	// Other errors are passed on
+	resp, err = app.Test(httptest.NewRequest("GET", "/other", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusBadGateway, resp.StatusCode, "Status code")
+}
+
+// go test -run Test_Timeout_WithContext_Late_Response
+func Test_Timeout_WithContext_Late_Response(t *testing.T) {
+	app := fiber.New()
+
+	// The handler ignores the context and answers after the deadline
+	app.Get("/", NewWithContext(func(c *fiber.Ctx) error {
+		time.Sleep(30 * time.Millisecond)
+		utils.AssertEqual(t, context.DeadlineExceeded, c.UserContext().Err())
+		return c.SendString("late")
+	}, 10*time.Millisecond))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, fiber.StatusOK, resp.StatusCode, "Status code")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "late", string(body))
+}
+
+// go test -run Test_Timeout_WithContext_Parent
+func Test_Timeout_WithContext_Parent(t *testing.T) {
+	app := fiber.New()
+
+	type key struct{}
+	var after context.Context
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(context.WithValue(c.UserContext(), key{}, "parent"))
+		err := c.Next()
+		after = c.UserContext()
+		return err
+	})
+	app.Get("/", NewWithContext(func(c *fiber.Ctx) error {
+		_, ok := c.UserContext().Deadline()
+		utils.AssertEqual(t, true, ok)
+		return c.SendString(c.UserContext().Value(key{}).(string))
+	}, time.Second))
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "parent", string(body))
+
+	// The parent context is restored
+	_, ok := after.Deadline()
+	utils.AssertEqual(t, false, ok)
+	utils.AssertEqual(t, nil, after.Err())
+}
+
 // // go test -run Test_Middleware_Timeout
 // func Test_Middleware_Timeout(t *testing.T) {
 // 	app := fiber.New(fiber.Config{DisableStartupMessage: true})
@@ -7,8 +7,11 @@
 package fiber
 
 import (
+	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2/utils"
 )
@@ -32,6 +35,8 @@ type routeSegment struct {
 	PartCount   int    // how often is the search part contained in the non-param segments? -> necessary for greedy search
 	IsGreedy    bool   // indicates whether the parameter is greedy or not, is used with wildcard and plus
 	IsOptional  bool   // indicates whether the parameter is optional or not
+	// constraints the parameter value must satisfy, parsed from "/:param<int;max(10)>"
+	Constraints []*routeConstraint
 	// common information
 	IsLast           bool // shows if the segment is the last one for the route
 	HasOptionalSlash bool // segment has the possibility of an optional slash
@@ -46,6 +51,11 @@ const (
 	optionalParam    byte = '?' // concludes a parameter by name and makes it optional
 	paramStarterChar byte = ':' // start character for a parameter with name
 	slashDelimiter   byte = '/' // separator for the route, unlike the other delimiters this character at the end can be optional
+	// constraint signs
+	paramConstraintStart     byte = '<'  // start of the constraints of a parameter
+	paramConstraintEnd       byte = '>'  // end of the constraints of a parameter
+	paramConstraintSeparator byte = ';'  // separator between multiple constraints
+	escapeChar               byte = '\\' // escapes the next character inside of the constraints
 )
 
 // list of possible parameter and segment delimiter
@@ -175,6 +185,13 @@ func (routeParser *routeParser) analyseParameterPart(pattern string) (string, *r
 	isPlusParam := pattern[0] == plusParam
 	parameterEndPosition := findNextCharsetPosition(pattern[1:], parameterEndChars)
 
+	// constraints are only possible for named parameters
+	if !isWildCard && !isPlusParam {
+		if start := strings.IndexByte(pattern, paramConstraintStart); start != -1 && (parameterEndPosition == -1 || start <= parameterEndPosition) {
+			return routeParser.analyseConstrainedParameterPart(pattern, start)
+		}
+	}
+
 	// handle wildcard end
 	if isWildCard || isPlusParam {
 		parameterEndPosition = 0
@@ -204,6 +221,52 @@ func (routeParser *routeParser) analyseParameterPart(pattern string) (string, *r
 	}
 }
 
+// analyseConstrainedParameterPart find the end of the constraints and create the route segment,
+// the constraints are parsed once here and not for every request
+func (routeParser *routeParser) analyseConstrainedParameterPart(pattern string, start int) (string, *routeSegment) {
+	end := findConstraintEnd(pattern, start)
+	if end == -1 {
+		panic(fmt.Sprintf("route: missing '%c' for the constraints in %s", paramConstraintEnd, pattern))
+	}
+	// cut params part, including the optional sign
+	processedPart := pattern[:end+1]
+	isOptional := len(pattern) > end+1 && pattern[end+1] == optionalParam
+	if isOptional {
+		processedPart = pattern[:end+2]
+	}
+
+	return processedPart, &routeSegment{
+		ParamName:   pattern[1:start],
+		IsParam:     true,
+		IsOptional:  isOptional,
+		Constraints: parseConstraints(pattern[start+1 : end]),
+	}
+}
+
+// findConstraintEnd search the end of the constraints which start at the given position,
+// signs inside of parentheses and escaped signs are skipped
+func findConstraintEnd(pattern string, start int) int {
+	depth := 0
+	for i := start + 1; i < len(pattern); i++ {
+		switch pattern[i] {
+		case escapeChar:
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case paramConstraintEnd:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
 // isInCharset check is the given character in the charset list
 func isInCharset(searchChar byte, charset []byte) bool {
 	for _, char := range charset {
@@ -247,6 +310,10 @@ func (routeParser *routeParser) getMatch(s, original string, params *[maxParams]
 			if !segment.IsOptional && i == 0 {
 				return false
 			}
+			// check the constraints against the original value of the parameter
+			if i > 0 && !segment.matchConstraints(original[:i]) {
+				return false
+			}
 			// take over the params positions
 			params[paramsIterator] = original[:i]
 			paramsIterator++
@@ -339,3 +406,195 @@ func GetTrimmedParam(param string) string {
 
 	return param[start:end]
 }
+
+// toLowerRoute converts the route to lowercase, apart from the parameter constraints
+// which are case sensitive
+func toLowerRoute(pattern string) string {
+	start := strings.IndexByte(pattern, paramConstraintStart)
+	if start == -1 {
+		return utils.ToLower(pattern)
+	}
+	end := findConstraintEnd(pattern, start)
+	if end == -1 {
+		return utils.ToLower(pattern)
+	}
+
+	return utils.ToLower(pattern[:start]) + pattern[start:end+1] + toLowerRoute(pattern[end+1:])
+}
+
+// constraintType identifies the check of a route constraint
+type constraintType int
+
+// supported route constraints
+const (
+	intConstraint constraintType = iota
+	boolConstraint
+	floatConstraint
+	guidConstraint
+	minLenConstraint
+	maxLenConstraint
+	lenConstraint
+	minConstraint
+	maxConstraint
+	regexConstraint
+)
+
+// routeConstraint holds a parsed parameter constraint
+type routeConstraint struct {
+	ID    constraintType // type of the check
+	Value int            // argument of the length and range checks
+	Regex *regexp.Regexp // compiled expression of the regex check
+}
+
+// parseConstraints parses the constraints separated by ';', for example "int;min(1)"
+func parseConstraints(constraints string) []*routeConstraint {
+	var parsed []*routeConstraint
+	for _, part := range splitConstraints(constraints) {
+		parsed = append(parsed, parseConstraint(part))
+	}
+
+	return parsed
+}
+
+// splitConstraints splits the constraints at the separators outside of parentheses
+func splitConstraints(constraints string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(constraints); i++ {
+		switch constraints[i] {
+		case escapeChar:
+			i++
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case paramConstraintSeparator:
+			if depth == 0 {
+				parts = append(parts, constraints[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, constraints[start:])
+}
+
+// parseConstraint parses a single constraint like "int", "minLen(5)" or "regex(^[a-z]+$)"
+func parseConstraint(constraint string) *routeConstraint {
+	name, arg := utils.Trim(constraint, ' '), ""
+	if start := strings.IndexByte(name, '('); start != -1 {
+		if name[len(name)-1] != ')' {
+			panic(fmt.Sprintf("route: missing ')' in constraint %s", constraint))
+		}
+		name, arg = name[:start], name[start+1:len(name)-1]
+	}
+
+	var c routeConstraint
+	switch utils.ToLower(name) {
+	case "int":
+		c.ID = intConstraint
+	case "bool":
+		c.ID = boolConstraint
+	case "float":
+		c.ID = floatConstraint
+	case "guid":
+		c.ID = guidConstraint
+	case "minlen":
+		c.ID = minLenConstraint
+	case "maxlen":
+		c.ID = maxLenConstraint
+	case "len":
+		c.ID = lenConstraint
+	case "min":
+		c.ID = minConstraint
+	case "max":
+		c.ID = maxConstraint
+	case "regex":
+		regex, err := regexp.Compile(arg)
+		if err != nil {
+			panic(fmt.Sprintf("route: invalid regex in constraint %s: %v", constraint, err))
+		}
+		return &routeConstraint{ID: regexConstraint, Regex: regex}
+	default:
+		panic(fmt.Sprintf("route: unknown constraint %s", constraint))
+	}
+
+	// the length and range checks require a number, the type checks none
+	if c.ID >= minLenConstraint {
+		value, err := strconv.Atoi(arg)
+		if err != nil {
+			panic(fmt.Sprintf("route: constraint %s requires a number", constraint))
+		}
+		c.Value = value
+	} else if arg != "" {
+		panic(fmt.Sprintf("route: constraint %s has no arguments", constraint))
+	}
+
+	return &c
+}
+
+// matchConstraints checks if the parameter value satisfies all constraints of the segment
+func (segment *routeSegment) matchConstraints(value string) bool {
+	for _, c := range segment.Constraints {
+		if !c.match(value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// match checks if the parameter value satisfies the constraint
+func (c *routeConstraint) match(value string) bool {
+	var err error
+	switch c.ID {
+	case intConstraint:
+		_, err = strconv.Atoi(value)
+	case boolConstraint:
+		_, err = strconv.ParseBool(value)
+	case floatConstraint:
+		_, err = strconv.ParseFloat(value, 64)
+	case guidConstraint:
+		return isGUID(value)
+	case minLenConstraint:
+		return utf8.RuneCountInString(value) >= c.Value
+	case maxLenConstraint:
+		return utf8.RuneCountInString(value) <= c.Value
+	case lenConstraint:
+		return utf8.RuneCountInString(value) == c.Value
+	case minConstraint:
+		var num int
+		num, err = strconv.Atoi(value)
+		return err == nil && num >= c.Value
+	case maxConstraint:
+		var num int
+		num, err = strconv.Atoi(value)
+		return err == nil && num <= c.Value
+	case regexConstraint:
+		return c.Regex.MatchString(value)
+	}
+
+	return err == nil
+}
+
+// isGUID checks if the value is a GUID in the form of "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx"
+func isGUID(value string) bool {
+	if len(value) != 36 {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		switch {
+		case i == 8 || i == 13 || i == 18 || i == 23:
+			if value[i] != '-' {
+				return false
+			}
+		case '0' <= value[i] && value[i] <= '9', 'a' <= value[i] && value[i] <= 'f', 'A' <= value[i] && value[i] <= 'F':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -390,6 +390,108 @@ func Test_Path_matchParams(t *testing.T) {
 	})
 }
 
+// go test -race -run Test_Path_parseRoute_Constraints
+func Test_Path_parseRoute_Constraints(t *testing.T) {
+	t.Parallel()
+	rp := parseRoute("/api/:id<int;max(10)>/:name<regex(^[a-z]+(-[a-z]+)?$)>?")
+	utils.AssertEqual(t, []string{"id", "name"}, rp.params)
+	utils.AssertEqual(t, 4, len(rp.segs))
+	utils.AssertEqual(t, []*routeConstraint{{ID: intConstraint}, {ID: maxConstraint, Value: 10}}, rp.segs[1].Constraints)
+	utils.AssertEqual(t, true, rp.segs[3].IsOptional)
+	utils.AssertEqual(t, regexConstraint, rp.segs[3].Constraints[0].ID)
+	utils.AssertEqual(t, "^[a-z]+(-[a-z]+)?$", rp.segs[3].Constraints[0].Regex.String())
+
+	// invalid constraints panic when the route is registered
+	for _, route := range []string{
+		"/api/:id<int",
+		"/api/:id<unknown>",
+		"/api/:id<min(a)>",
+		"/api/:id<int(1)>",
+		"/api/:id<regex([a-z)>",
+		"/api/:id<len(5>",
+	} {
+		func() {
+			defer func() {
+				utils.AssertEqual(t, true, recover() != nil, route)
+			}()
+			parseRoute(route)
+		}()
+	}
+}
+
+// go test -race -run Test_Path_matchParams_Constraints
+func Test_Path_matchParams_Constraints(t *testing.T) {
+	t.Parallel()
+	type testparams struct {
+		url    string
+		params []string
+		match  bool
+	}
+	var ctxParams [maxParams]string
+	testCase := func(r string, cases []testparams) {
+		parser := parseRoute(r)
+		for _, c := range cases {
+			match := parser.getMatch(c.url, c.url, &ctxParams, false)
+			utils.AssertEqual(t, c.match, match, fmt.Sprintf("route: '%s', url: '%s'", r, c.url))
+			if match && len(c.params) > 0 {
+				utils.AssertEqual(t, c.params, ctxParams[0:len(c.params)], fmt.Sprintf("route: '%s', url: '%s'", r, c.url))
+			}
+		}
+	}
+	testCase("/api/:id<int>", []testparams{
+		{url: "/api/123", params: []string{"123"}, match: true},
+		{url: "/api/-1", params: []string{"-1"}, match: true},
+		{url: "/api/abc", match: false},
+		{url: "/api/1.5", match: false},
+	})
+	testCase("/api/:flag<bool>", []testparams{
+		{url: "/api/true", params: []string{"true"}, match: true},
+		{url: "/api/yes", match: false},
+	})
+	testCase("/api/:price<float>", []testparams{
+		{url: "/api/1.5", params: []string{"1.5"}, match: true},
+		{url: "/api/abc", match: false},
+	})
+	testCase("/api/:id<guid>", []testparams{
+		{url: "/api/b1f9a2c4-6e1d-4c2a-9f3e-0a1b2c3d4e5f", params: []string{"b1f9a2c4-6e1d-4c2a-9f3e-0a1b2c3d4e5f"}, match: true},
+		{url: "/api/B1F9A2C4-6E1D-4C2A-9F3E-0A1B2C3D4E5F", params: []string{"B1F9A2C4-6E1D-4C2A-9F3E-0A1B2C3D4E5F"}, match: true},
+		{url: "/api/b1f9a2c4-6e1d-4c2a-9f3e-0a1b2c3d4e5", match: false},
+		{url: "/api/b1f9a2c4x6e1d-4c2a-9f3e-0a1b2c3d4e5f", match: false},
+		{url: "/api/g1f9a2c4-6e1d-4c2a-9f3e-0a1b2c3d4e5f", match: false},
+	})
+	testCase("/api/:name<minLen(2);maxLen(4)>", []testparams{
+		{url: "/api/ab", params: []string{"ab"}, match: true},
+		{url: "/api/abcd", params: []string{"abcd"}, match: true},
+		{url: "/api/a", match: false},
+		{url: "/api/abcde", match: false},
+	})
+	testCase("/api/:code<len(3)>", []testparams{
+		{url: "/api/abc", params: []string{"abc"}, match: true},
+		{url: "/api/ab", match: false},
+	})
+	testCase("/api/:age<min(18);max(99)>", []testparams{
+		{url: "/api/18", params: []string{"18"}, match: true},
+		{url: "/api/99", params: []string{"99"}, match: true},
+		{url: "/api/17", match: false},
+		{url: "/api/100", match: false},
+		{url: "/api/abc", match: false},
+	})
+	testCase("/api/:date<regex(^\\d{4}-\\d{2}-\\d{2}$)>/:title", []testparams{
+		{url: "/api/2020-12-31/news", params: []string{"2020-12-31", "news"}, match: true},
+		{url: "/api/2020-1-31/news", match: false},
+	})
+	testCase("/api/:id<int>?", []testparams{
+		{url: "/api/123", params: []string{"123"}, match: true},
+		{url: "/api/", params: []string{""}, match: true},
+		{url: "/api", params: []string{""}, match: true},
+		{url: "/api/abc", match: false},
+	})
+	testCase("/api/:id<int>-:name", []testparams{
+		{url: "/api/1-john", params: []string{"1", "john"}, match: true},
+		{url: "/api/a-john", match: false},
+	})
+}
+
 func Test_Utils_GetTrimmedParam(t *testing.T) {
 	t.Parallel()
 	res := GetTrimmedParam("*")
@@ -17,6 +17,7 @@ import (
 const (
 	envPreforkChildKey = "FIBER_PREFORK_CHILD"
 	envPreforkChildVal = "1"
+	envPreforkAddrKey  = "FIBER_PREFORK_ADDR"
 )
 
 var (
@@ -34,6 +35,10 @@ func (app *App) prefork(addr string, tlsConfig *tls.Config) (err error) {
 	if IsChild() {
 		// use 1 cpu core per child process
 		runtime.GOMAXPROCS(1)
+		// listen on the address resolved by the master
+		if resolved := os.Getenv(envPreforkAddrKey); resolved != "" {
+			addr = resolved
+		}
 		var ln net.Listener
 		// Linux will use SO_REUSEPORT and Windows falls back to SO_REUSEADDR
 		// Only tcp4 or tcp6 is supported when preforking, both are not supported
@@ -52,7 +57,7 @@ func (app *App) prefork(addr string, tlsConfig *tls.Config) (err error) {
 		go watchMaster()
 
 		// listen for incoming connections
-		return app.server.Serve(ln)
+		return app.serve(ln)
 	}
 
 	// 👮 master process 👮
@@ -60,6 +65,10 @@ func (app *App) prefork(addr string, tlsConfig *tls.Config) (err error) {
 		pid int
 		err error
 	}
+	// resolve the port, so every child listens on the same one for ":0"
+	if addr, err = resolveAddr(addr); err != nil {
+		return fmt.Errorf("prefork: %v", err)
+	}
 	// create variables
 	var max = runtime.GOMAXPROCS(0)
 	var childs = make(map[int]*exec.Cmd)
@@ -91,6 +100,7 @@ func (app *App) prefork(addr string, tlsConfig *tls.Config) (err error) {
 		// add fiber prefork child flag into child proc env
 		cmd.Env = append(os.Environ(),
 			fmt.Sprintf("%s=%s", envPreforkChildKey, envPreforkChildVal),
+			fmt.Sprintf("%s=%s", envPreforkAddrKey, addr),
 		)
 		if err = cmd.Start(); err != nil {
 			return fmt.Errorf("failed to start a child prefork process, error: %v", err)
@@ -101,12 +111,22 @@ func (app *App) prefork(addr string, tlsConfig *tls.Config) (err error) {
 		childs[pid] = cmd
 		pids = append(pids, strconv.Itoa(pid))
 
+		// run fork hooks
+		if err = app.hooks.executeOnForkHooks(pid); err != nil {
+			return err
+		}
+
 		// notify master if child crashes
 		go func() {
 			channel <- child{pid, cmd.Wait()}
 		}()
 	}
 
+	// run listen hooks
+	if err = app.hooks.executeOnListenHooks(addr, tlsConfig != nil); err != nil {
+		return err
+	}
+
 	// Print startup message
 	if !app.config.DisableStartupMessage {
 		app.startupMessage(addr, tlsConfig != nil, ","+strings.Join(pids, ","))
@@ -116,6 +136,20 @@ func (app *App) prefork(addr string, tlsConfig *tls.Config) (err error) {
 	return (<-channel).err
 }
 
+// resolveAddr binds addresses without a port to pick one. The listener is
+// closed before the children are started, so it doesn't take their connections.
+func resolveAddr(addr string) (string, error) {
+	if _, port, err := net.SplitHostPort(addr); err != nil || (port != "" && port != "0") {
+		return addr, nil
+	}
+	ln, err := reuseport.Listen("tcp4", addr)
+	if err != nil {
+		return "", err
+	}
+	addr = ln.Addr().String()
+	return addr, ln.Close()
+}
+
 // watchMaster watches child procs
 func watchMaster() {
 	if runtime.GOOS == "windows" {
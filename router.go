@@ -6,6 +6,8 @@ package fiber
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -36,25 +38,50 @@ type Router interface {
 	Group(prefix string, handlers ...Handler) Router
 
 	Mount(prefix string, fiber *App) Router
+
+	Name(name string) Router
+
+	UseErrorHandler(handler ErrorHandler) Router
 }
 
 // Route is a struct that holds all metadata for each registered handler
 type Route struct {
 	// Data for routing
-	pos         int         // Position in stack -> important for the sort of the matched routes
-	use         bool        // USE matches path prefixes
-	star        bool        // Path equals '*'
-	root        bool        // Path equals '/'
-	path        string      // Prettified path
-	routeParser routeParser // Parameter parser
+	pos          int          // Position in stack -> important for the sort of the matched routes
+	use          bool         // USE matches path prefixes
+	static       bool         // Static matches every path starting with its prefix
+	autoHead     bool         // HEAD route added for a GET route
+	scoped       bool         // USE prefix ends at a segment boundary, for mounted apps and error handlers
+	notFound     bool         // Route of the Ctx if no route matched, see NotFound
+	star         bool         // Path equals '*'
+	root         bool         // Path equals '/'
+	path         string       // Prettified path
+	routeParser  routeParser  // Parameter parser
+	errorHandler ErrorHandler // Error handler of the mounted app or group owning the route
 
 	// Public fields
 	Method   string    `json:"method"` // HTTP method
+	Name     string    `json:"name"`   // Route's name
 	Path     string    `json:"path"`   // Original registered route path
 	Params   []string  `json:"params"` // Case sensitive param keys
 	Handlers []Handler `json:"-"`      // Ctx handlers
 }
 
+// notFoundRoute is copied to the Ctx once the stack is exhausted without a
+// non-middleware route matching the request.
+var notFoundRoute = Route{
+	notFound: true,
+	Name:     "NotFound",
+	Params:   make([]string, 0),
+	Handlers: make([]Handler, 0),
+}
+
+// NotFound reports whether the route is the one of a Ctx whose request didn't
+// match any non-middleware route. Its Path is empty.
+func (r *Route) NotFound() bool {
+	return r.notFound
+}
+
 func (r *Route) match(path, original string, params *[maxParams]string) (match bool) {
 	// root path check
 	if r.root && path == "/" {
@@ -78,8 +105,10 @@ func (r *Route) match(path, original string, params *[maxParams]string) (match b
 	}
 	// Is this route a Middleware?
 	if r.use {
-		// Single slash will match or path prefix
-		if r.root || strings.HasPrefix(path, r.path) {
+		// Single slash will match or path prefix, the scoped middleware of
+		// mounted apps doesn't match i.e. "/apiv2" for "/api"
+		if r.root || (strings.HasPrefix(path, r.path) &&
+			(!r.scoped || r.static || len(path) == len(r.path) || path[len(r.path)] == '/')) {
 			return true
 		}
 		// Check for a simple path match
@@ -127,13 +156,19 @@ func (app *App) next(c *Ctx) (match bool, err error) {
 		return match, err // Stop scanning the stack
 	}
 
+	// Only middleware matched the request
+	if !c.matched {
+		c.notFound = notFoundRoute
+		c.route = &c.notFound
+	}
+
 	// If c.Next() does not match, return 404
 	_ = c.SendStatus(StatusNotFound)
 	_ = c.SendString("Cannot " + c.method + " " + c.pathOriginal)
 
 	// If no match, scan stack again if other methods match the request
 	// Moved from app.handler because middleware may break the route chain
-	if !c.matched && methodExist(c) {
+	if !c.matched && !app.config.DisableMethodNotAllowed && methodExist(c) {
 		err = ErrMethodNotAllowed
 	}
 	return
@@ -153,7 +188,7 @@ func (app *App) handler(rctx *fasthttp.RequestCtx) {
 	// Find match in stack
 	match, err := app.next(c)
 	if err != nil {
-		if catch := c.app.config.ErrorHandler(c, err); catch != nil {
+		if catch := c.ErrorHandler()(c, err); catch != nil {
 			_ = c.SendStatus(StatusInternalServerError)
 		}
 	}
@@ -170,7 +205,7 @@ func (app *App) addPrefixToRoute(prefix string, route *Route) *Route {
 	prettyPath := prefixedPath
 	// Case sensitive routing, all to lowercase
 	if !app.config.CaseSensitive {
-		prettyPath = utils.ToLower(prettyPath)
+		prettyPath = toLowerRoute(prettyPath)
 	}
 	// Strict routing, remove trailing slashes
 	if !app.config.StrictRouting && len(prettyPath) > 1 {
@@ -189,18 +224,23 @@ func (app *App) addPrefixToRoute(prefix string, route *Route) *Route {
 func (app *App) copyRoute(route *Route) *Route {
 	return &Route{
 		// Router booleans
-		use:  route.use,
-		star: route.star,
-		root: route.root,
+		use:      route.use,
+		static:   route.static,
+		autoHead: route.autoHead,
+		scoped:   route.scoped,
+		star:     route.star,
+		root:     route.root,
 
 		// Path data
-		path:        route.path,
-		routeParser: route.routeParser,
-		Params:      route.Params,
+		path:         route.path,
+		routeParser:  route.routeParser,
+		errorHandler: route.errorHandler,
+		Params:       route.Params,
 
 		// Public data
-		Path:     route.path,
+		Path:     route.Path,
 		Method:   route.Method,
+		Name:     route.Name,
 		Handlers: route.Handlers,
 	}
 }
@@ -228,7 +268,7 @@ func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
 	pathPretty := pathRaw
 	// Case sensitive routing, all to lowercase
 	if !app.config.CaseSensitive {
-		pathPretty = utils.ToLower(pathPretty)
+		pathPretty = toLowerRoute(pathPretty)
 	}
 	// Strict routing, remove trailing slashes
 	if !app.config.StrictRouting && len(pathPretty) > 1 {
@@ -269,51 +309,102 @@ func (app *App) register(method, pathRaw string, handlers ...Handler) Router {
 	// Middleware route matches all HTTP methods
 	if isUse {
 		// Add route to all HTTP methods stack
+		routes := make([]*Route, 0, len(intMethod))
 		for _, m := range intMethod {
 			// Create a route copy to avoid duplicates during compression
 			r := route
-			app.addRoute(m, &r)
+			routes = append(routes, app.addRoute(m, &r))
 		}
+		app.setLatestRoutes(pathRaw, handlers, routes...)
+		route.Method = methodUse
 	} else {
+		switch {
+		case method == MethodHead:
+			// Explicit HEAD routes take precedence over the ones of GET routes
+			app.removeAutoHeadRoutes(pathPretty)
+		case method == MethodGet && !app.config.DisableHeadAutoRegister && !app.hasHeadRoute(pathPretty):
+			// GET routes answer HEAD requests too, fasthttp drops the body
+			head := route
+			head.Method = MethodHead
+			head.autoHead = true
+			app.mutex.Lock()
+			app.handlerCount += len(handlers)
+			app.mutex.Unlock()
+			app.setLatestRoutes(pathRaw, handlers, app.addRoute(MethodHead, &head))
+			app.hooks.executeOnRouteHooks(head)
+		}
 		// Add route to stack
-		app.addRoute(method, &route)
+		app.setLatestRoutes(pathRaw, handlers, app.addRoute(method, &route))
 	}
+	// Run route hooks
+	app.hooks.executeOnRouteHooks(route)
 	return app
 }
 
+// setLatestRoutes remembers the routes registered last, which are named by
+// App.Name. Get and All register the same handlers for several methods,
+// these routes are kept together.
+func (app *App) setLatestRoutes(path string, handlers []Handler, routes ...*Route) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	if app.latestPath != path || len(app.latestHandlers) != len(handlers) ||
+		len(handlers) == 0 || &app.latestHandlers[0] != &handlers[0] {
+		app.latestRoutes = app.latestRoutes[:0]
+	}
+	app.latestPath, app.latestHandlers = path, handlers
+	app.latestRoutes = append(app.latestRoutes, routes...)
+}
+
+// hasHeadRoute reports whether a HEAD route was registered for the path
+func (app *App) hasHeadRoute(path string) bool {
+	for _, route := range app.stack[methodInt(MethodHead)] {
+		if !route.use && !route.autoHead && route.path == path {
+			return true
+		}
+	}
+	return false
+}
+
+// removeAutoHeadRoutes removes the HEAD routes added for GET routes of the path
+func (app *App) removeAutoHeadRoutes(path string) {
+	app.mutex.Lock()
+	defer app.mutex.Unlock()
+	m := methodInt(MethodHead)
+	routes := app.stack[m][:0]
+	for _, route := range app.stack[m] {
+		if !route.autoHead || route.path != path {
+			routes = append(routes, route)
+			continue
+		}
+		// Decrement global handler count
+		app.handlerCount -= len(route.Handlers)
+	}
+	app.stack[m] = routes
+}
+
+// containsRoute reports whether the route is one of the routes
+func containsRoute(routes []*Route, route *Route) bool {
+	for _, r := range routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
 func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 	// For security we want to restrict to the current work directory.
 	if len(root) == 0 {
 		root = "."
 	}
-	// Cannot have an empty prefix
-	if prefix == "" {
-		prefix = "/"
-	}
-	// Prefix always start with a '/' or '*'
-	if prefix[0] != '/' {
-		prefix = "/" + prefix
-	}
-	// in case sensitive routing, all to lowercase
-	if !app.config.CaseSensitive {
-		prefix = utils.ToLower(prefix)
-	}
 	// Strip trailing slashes from the root path
 	if len(root) > 0 && root[len(root)-1] == '/' {
 		root = root[:len(root)-1]
 	}
-	// Is prefix a direct wildcard?
-	var isStar = prefix == "/*"
-	// Is prefix a root slash?
-	var isRoot = prefix == "/"
-	// Is prefix a partial wildcard?
-	if strings.Contains(prefix, "*") {
-		// /john* -> /john
-		isStar = true
-		prefix = strings.Split(prefix, "*")[0]
-		// Fix this later
-	}
+	prefix, isStar, isRoot := app.staticPrefix(prefix)
 	prefixLen := len(prefix)
+	// Download and ModifyResponse need the resolved file
+	modifyResponse := len(config) > 0 && (config[0].Download || config[0].ModifyResponse != nil)
 	// Fileserver settings
 	fs := &fasthttp.FS{
 		Root:                 root,
@@ -335,6 +426,10 @@ func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 			if len(path) > 0 && path[0] != '/' {
 				path = append([]byte("/"), path...)
 			}
+			// Remember the file for the Download and ModifyResponse settings
+			if modifyResponse {
+				fctx.SetUserValue(StaticFileKey, root+utils.TrimRight(string(path), '/'))
+			}
 			return path
 		},
 		PathNotFound: func(fctx *fasthttp.RequestCtx) {
@@ -343,8 +438,11 @@ func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 	}
 
 	// Set config if provided
+	var cfg Static
 	var cacheControlValue string
+	var byteRange bool
 	if len(config) > 0 {
+		cfg = config[0]
 		maxAge := config[0].MaxAge
 		if maxAge > 0 {
 			cacheControlValue = "public, max-age=" + strconv.Itoa(maxAge)
@@ -354,6 +452,7 @@ func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 		}
 		fs.Compress = config[0].Compress
 		fs.AcceptByteRange = config[0].ByteRange
+		byteRange = config[0].ByteRange
 		fs.GenerateIndexPages = config[0].Browse
 		if config[0].Index != "" {
 			fs.IndexNames = []string{config[0].Index}
@@ -362,13 +461,25 @@ func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 	fileHandler := fs.NewRequestHandler()
 	handler := func(c *Ctx) error {
 		// Serve file
-		fileHandler(c.fasthttp)
+		if byteRange {
+			c.serveFile(fileHandler)
+		} else {
+			fileHandler(c.fasthttp)
+		}
 		// Return request if found and not forbidden
 		status := c.fasthttp.Response.StatusCode()
 		if status != StatusNotFound && status != StatusForbidden {
 			if len(cacheControlValue) > 0 {
 				c.fasthttp.Response.Header.Set(HeaderCacheControl, cacheControlValue)
 			}
+			if modifyResponse {
+				file, _ := c.fasthttp.UserValue(StaticFileKey).(string)
+				// Directories are served by their index file
+				if stat, err := os.Stat(file); err == nil && stat.IsDir() {
+					file = filepath.Join(file, fs.IndexNames[0])
+				}
+				return cfg.modifyResponse(c, file)
+			}
 			return nil
 		}
 		// Reset response to default
@@ -379,12 +490,118 @@ func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 		return c.Next()
 	}
 
+	app.addStaticRoute(prefix, isRoot, handler)
+	return app
+}
+
+// modifyResponse applies the Download and ModifyResponse settings
+// to the response of the resolved file.
+func (cfg *Static) modifyResponse(c *Ctx, file string) error {
+	if cfg.Download {
+		c.setCanonical(HeaderContentDisposition, contentDisposition(filepath.Base(file)))
+	}
+	if cfg.ModifyResponse == nil {
+		return nil
+	}
+	// The file name may reference the request path
+	c.Locals(StaticFileKey, utils.CopyString(file))
+	return cfg.ModifyResponse(c)
+}
+
+// addErrorHandler registers an error handler for the routes below the prefix
+func (app *App) addErrorHandler(prefix string, handler ErrorHandler) {
+	if handler == nil {
+		panic("error handler cannot be nil\n")
+	}
+	// Cannot have an empty prefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	// Prefix always start with a '/'
+	if prefix[0] != '/' {
+		prefix = "/" + prefix
+	}
+	prefixPretty := prefix
+	// Case sensitive routing, all to lowercase
+	if !app.config.CaseSensitive {
+		prefixPretty = toLowerRoute(prefixPretty)
+	}
+	// Strict routing, remove trailing slashes
+	if !app.config.StrictRouting && len(prefixPretty) > 1 {
+		prefixPretty = utils.TrimRight(prefixPretty, '/')
+	}
+	// The prefix is matched like the middleware of a mounted app
+	route := &Route{
+		use:          true,
+		scoped:       true,
+		star:         prefixPretty == "/*",
+		root:         prefixPretty == "/",
+		path:         prefixPretty,
+		routeParser:  parseRoute(prefixPretty),
+		Params:       parseRoute(prefix).params,
+		Path:         prefix,
+		errorHandler: handler,
+	}
+	app.mutex.Lock()
+	app.errorHandlers = append(app.errorHandlers, route)
+	app.mutex.Unlock()
+}
+
+// nearestErrorHandler returns the error handler of the longest prefix matching
+// the path, or nil if there is none
+func (app *App) nearestErrorHandler(path, original string) ErrorHandler {
+	var nearest *Route
+	var params [maxParams]string
+	for _, route := range app.errorHandlers {
+		// Later registrations win for prefixes of the same length
+		if (nearest == nil || len(route.path) >= len(nearest.path)) && route.match(path, original, &params) {
+			nearest = route
+		}
+	}
+	if nearest == nil {
+		return nil
+	}
+	return nearest.errorHandler
+}
+
+// staticPrefix normalizes the prefix of a static route and reports
+// whether it is a wildcard or the root path.
+func (app *App) staticPrefix(prefix string) (path string, isStar, isRoot bool) {
+	// Cannot have an empty prefix
+	if prefix == "" {
+		prefix = "/"
+	}
+	// Prefix always start with a '/' or '*'
+	if prefix[0] != '/' {
+		prefix = "/" + prefix
+	}
+	// in case sensitive routing, all to lowercase
+	if !app.config.CaseSensitive {
+		prefix = utils.ToLower(prefix)
+	}
+	// Is prefix a direct wildcard?
+	isStar = prefix == "/*"
+	// Is prefix a root slash?
+	isRoot = prefix == "/"
+	// Is prefix a partial wildcard?
+	if strings.Contains(prefix, "*") {
+		// /john* -> /john
+		isStar = true
+		prefix = strings.Split(prefix, "*")[0]
+		// Fix this later
+	}
+	return prefix, isStar, isRoot
+}
+
+// addStaticRoute registers a static file handler for GET and HEAD requests
+func (app *App) addStaticRoute(prefix string, isRoot bool, handler Handler) {
 	// Create route metadata without pointer
 	route := Route{
 		// Router booleans
-		use:  true,
-		root: isRoot,
-		path: prefix,
+		use:    true,
+		static: true,
+		root:   isRoot,
+		path:   prefix,
 		// Public data
 		Method:   MethodGet,
 		Path:     prefix,
@@ -395,21 +612,30 @@ func (app *App) registerStatic(prefix, root string, config ...Static) Router {
 	app.handlerCount++
 	app.mutex.Unlock()
 	// Add route to stack
-	app.addRoute(MethodGet, &route)
+	getRoute := app.addRoute(MethodGet, &route)
 	// Add HEAD route
-	app.addRoute(MethodHead, &route)
-	return app
+	headRoute := app.addRoute(MethodHead, &route)
+	app.setLatestRoutes(prefix, route.Handlers, getRoute, headRoute)
+	// Run route hooks for both methods
+	hookRoute := route
+	for _, method := range []string{MethodGet, MethodHead} {
+		hookRoute.Method = method
+		app.hooks.executeOnRouteHooks(hookRoute)
+	}
 }
 
-func (app *App) addRoute(method string, route *Route) {
+func (app *App) addRoute(method string, route *Route) *Route {
 	// Get unique HTTP method indentifier
 	m := methodInt(method)
 
 	// prevent identically route registration
 	l := len(app.stack[m])
-	if l > 0 && app.stack[m][l-1].Path == route.Path && route.use == app.stack[m][l-1].use {
+	if l > 0 && app.stack[m][l-1].Path == route.Path && route.use == app.stack[m][l-1].use &&
+		route.autoHead == app.stack[m][l-1].autoHead && route.scoped == app.stack[m][l-1].scoped &&
+		route.errorHandler == nil && app.stack[m][l-1].errorHandler == nil {
 		preRoute := app.stack[m][l-1]
 		preRoute.Handlers = append(preRoute.Handlers, route.Handlers...)
+		route = preRoute
 	} else {
 		// Increment global route position
 		app.mutex.Lock()
@@ -422,6 +648,7 @@ func (app *App) addRoute(method string, route *Route) {
 	}
 	// Build router tree
 	app.buildTree()
+	return route
 }
 
 // buildTree build the prefix tree from the previously registered routes
@@ -144,6 +144,35 @@ func Test_Route_Match_Parser(t *testing.T) {
 	utils.AssertEqual(t, "test", getString(body))
 }
 
+func Test_Route_Match_Constraints(t *testing.T) {
+	app := New()
+
+	app.Get("/users/:id<int>", func(c *Ctx) error {
+		return c.SendString("id " + c.Params("id"))
+	})
+	app.Get("/users/:name<regex(^[A-Z][a-z]+$)>", func(c *Ctx) error {
+		return c.SendString("name " + c.Params("name"))
+	})
+
+	for path, expected := range map[string]string{
+		"/users/123":  "id 123",
+		"/users/John": "name John",
+	} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+
+		body, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, expected, getString(body))
+	}
+
+	// no constraint matches
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/users/john", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+}
+
 func Test_Route_Match_Middleware(t *testing.T) {
 	app := New()
 
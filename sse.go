@@ -0,0 +1,95 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"bufio"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errSSELineBreak is returned if an event id or name contains a line break
+var errSSELineBreak = errors.New("sse: id and event must not contain line breaks")
+
+// SSEWriter writes server-sent events to the client, see Ctx.SSE
+type SSEWriter struct {
+	w *bufio.Writer
+}
+
+// SSE streams server-sent events to the client. The producer is called after
+// the handler returned, so it must not use the Ctx. It runs until it returns,
+// which should happen once a write fails because the client went away.
+// The response is neither buffered by proxies nor compressed.
+func (c *Ctx) SSE(producer func(w *SSEWriter)) error {
+	c.fasthttp.Response.Header.SetContentType(MIMETextEventStream)
+	c.setCanonical(HeaderCacheControl, "no-cache")
+	c.setCanonical(HeaderXAccelBuffering, "no")
+	c.fasthttp.SetBodyStreamWriter(func(w *bufio.Writer) {
+		producer(&SSEWriter{w: w})
+	})
+	return nil
+}
+
+// WriteEvent writes an event and flushes it to the client. The id and event
+// are optional, every line of the data is sent as its own data field.
+// An error is returned if the client went away.
+func (w *SSEWriter) WriteEvent(id, event string, data []byte) error {
+	if strings.ContainsAny(id, "\r\n") || strings.ContainsAny(event, "\r\n") {
+		return errSSELineBreak
+	}
+	if id != "" {
+		w.writeField("id", id)
+	}
+	if event != "" {
+		w.writeField("event", event)
+	}
+	// Lines end with CRLF, LF or CR
+	start := 0
+	for i := 0; i < len(data); i++ {
+		if data[i] != '\r' && data[i] != '\n' {
+			continue
+		}
+		w.writeField("data", getString(data[start:i]))
+		if data[i] == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			i++
+		}
+		start = i + 1
+	}
+	w.writeField("data", getString(data[start:]))
+	return w.flush()
+}
+
+// Retry tells the client how long to wait before it reconnects
+func (w *SSEWriter) Retry(retry time.Duration) error {
+	w.writeField("retry", strconv.FormatInt(retry.Milliseconds(), 10))
+	return w.flush()
+}
+
+// Comment writes a comment, which is ignored by the client
+// and can be used to keep the connection alive
+func (w *SSEWriter) Comment(comment string) error {
+	for _, line := range strings.Split(strings.ReplaceAll(comment, "\r", "\n"), "\n") {
+		_, _ = w.w.WriteString(": ")
+		_, _ = w.w.WriteString(line)
+		_ = w.w.WriteByte('\n')
+	}
+	return w.flush()
+}
+
+func (w *SSEWriter) writeField(name, value string) {
+	// bufio.Writer keeps the first error, which is returned by flush
+	_, _ = w.w.WriteString(name)
+	_, _ = w.w.WriteString(": ")
+	_, _ = w.w.WriteString(value)
+	_ = w.w.WriteByte('\n')
+}
+
+// flush ends the event and sends it to the client
+func (w *SSEWriter) flush() error {
+	_ = w.w.WriteByte('\n')
+	return w.w.Flush()
+}
@@ -0,0 +1,62 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 📝 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// go test -run Test_Ctx_SSE
+func Test_Ctx_SSE(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Get("/", func(c *Ctx) error {
+		return c.SSE(func(w *SSEWriter) {
+			utils.AssertEqual(t, nil, w.Retry(3*time.Second))
+			utils.AssertEqual(t, nil, w.Comment("keep-alive"))
+			utils.AssertEqual(t, nil, w.WriteEvent("1", "message", []byte("line 1\nline 2\r\nline 3\rline 4")))
+			utils.AssertEqual(t, nil, w.WriteEvent("", "", []byte("done")))
+			utils.AssertEqual(t, errSSELineBreak, w.WriteEvent("2\n", "", nil))
+			utils.AssertEqual(t, errSSELineBreak, w.WriteEvent("", "event\r", nil))
+		})
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, StatusOK, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, MIMETextEventStream, resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, "no-cache", resp.Header.Get(HeaderCacheControl))
+	utils.AssertEqual(t, "no", resp.Header.Get(HeaderXAccelBuffering))
+
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, "retry: 3000\n\n"+
+		": keep-alive\n\n"+
+		"id: 1\nevent: message\ndata: line 1\ndata: line 2\ndata: line 3\ndata: line 4\n\n"+
+		"data: done\n\n", getString(body))
+}
+
+// sseClosedWriter fails like a connection of a client that went away
+type sseClosedWriter struct{}
+
+func (sseClosedWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("broken pipe")
+}
+
+// go test -run Test_SSEWriter_ClientGone
+func Test_SSEWriter_ClientGone(t *testing.T) {
+	t.Parallel()
+	w := &SSEWriter{w: bufio.NewWriter(sseClosedWriter{})}
+	utils.AssertEqual(t, "broken pipe", w.WriteEvent("1", "message", []byte("data")).Error())
+	utils.AssertEqual(t, "broken pipe", w.Retry(time.Second).Error())
+	utils.AssertEqual(t, "broken pipe", w.Comment("ping").Error())
+}
@@ -0,0 +1,241 @@
+//go:build go1.16
+// +build go1.16
+
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"errors"
+	"html"
+	"io/fs"
+	"net/http"
+	"net/url"
+	pathpkg "path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2/internal/bytebufferpool"
+	"github.com/valyala/fasthttp"
+)
+
+// Files bigger than this are served without compression
+const staticFSMaxCompressSize = 8 * 1024 * 1024
+
+// Compressed files must be smaller than this ratio of the original file
+const staticFSMinCompressRatio = 0.8
+
+// StaticFS will create a file server serving the files of fsys, such as an embed.FS.
+// Use fs.Sub to serve a directory of an embed.FS, and Static.NotFoundFile to
+// serve the index of a SPA for unknown paths. The ByteRange and CacheDuration
+// settings of the Static config are not used.
+func (app *App) StaticFS(prefix string, fsys fs.FS, config ...Static) Router {
+	return app.registerStaticFS(prefix, fsys, config...)
+}
+
+// StaticFS will create a file server serving the files of fsys, such as an embed.FS.
+// It is not part of the Router interface, which has to build with Go 1.14,
+// so the Router returned by App.Group has to be asserted to *Group.
+func (grp *Group) StaticFS(prefix string, fsys fs.FS, config ...Static) Router {
+	return grp.app.registerStaticFS(getGroupPath(grp.prefix, prefix), fsys, config...)
+}
+
+// staticFSFile is a compressed file served by StaticFS
+type staticFSFile struct {
+	body    []byte
+	size    int64
+	modTime time.Time
+}
+
+func (app *App) registerStaticFS(prefix string, fsys fs.FS, config ...Static) Router {
+	if fsys == nil {
+		panic("static: fsys cannot be nil\n")
+	}
+	prefix, _, isRoot := app.staticPrefix(prefix)
+	prefixLen := len(prefix)
+
+	cfg := Static{Index: "index.html"}
+	if len(config) > 0 {
+		cfg = config[0]
+		if cfg.Index == "" {
+			cfg.Index = "index.html"
+		}
+	}
+	var cacheControlValue string
+	if cfg.MaxAge > 0 {
+		cacheControlValue = "public, max-age=" + strconv.Itoa(cfg.MaxAge)
+	}
+	notFoundFile := strings.Trim(cfg.NotFoundFile, "/")
+
+	// Compressed files by encoding and name
+	var compressed sync.Map
+
+	handler := func(c *Ctx) error {
+		// Reject path traversal attempts before the path is normalized
+		if hasDotDotSegment(c.fasthttp.Request.URI().PathOriginal()) {
+			return ErrBadRequest
+		}
+		path := c.Path()
+		if len(path) >= prefixLen {
+			path = path[prefixLen:]
+		}
+		name := strings.Trim(path, "/")
+		if name == "" {
+			name = "."
+		}
+		if !fs.ValidPath(name) {
+			return ErrBadRequest
+		}
+
+		stat, err := fs.Stat(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) && notFoundFile != "" {
+			name = notFoundFile
+			stat, err = fs.Stat(fsys, name)
+		}
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return c.Next()
+			}
+			return err
+		}
+
+		// Serve index if path is a directory
+		if stat.IsDir() {
+			index := pathpkg.Join(name, cfg.Index)
+			if indexStat, err := fs.Stat(fsys, index); err == nil && !indexStat.IsDir() {
+				name, stat = index, indexStat
+			} else if cfg.Browse {
+				return staticFSDirList(c, fsys, name)
+			} else {
+				return c.Next()
+			}
+		}
+
+		c.Type(pathpkg.Ext(name))
+		if modTime := stat.ModTime(); !modTime.IsZero() {
+			c.fasthttp.Response.Header.Set(HeaderLastModified, modTime.UTC().Format(http.TimeFormat))
+		}
+		if len(cacheControlValue) > 0 {
+			c.fasthttp.Response.Header.Set(HeaderCacheControl, cacheControlValue)
+		}
+
+		if cfg.Compress && stat.Size() <= staticFSMaxCompressSize && len(c.Get(HeaderAcceptEncoding)) > 0 {
+			if encoding := c.AcceptsEncodings("br", "gzip"); encoding != "" {
+				file, err := staticFSCompress(&compressed, fsys, name, stat, encoding)
+				if err != nil {
+					return err
+				}
+				c.Vary(HeaderAcceptEncoding)
+				if file.body != nil {
+					c.fasthttp.Response.Header.Set(HeaderContentEncoding, encoding)
+					c.fasthttp.Response.SetBodyRaw(file.body)
+					return cfg.modifyResponse(c, name)
+				}
+			}
+		}
+
+		file, err := fsys.Open(name)
+		if err != nil {
+			return err
+		}
+		if c.Method() == MethodHead {
+			c.fasthttp.Response.Header.SetContentLength(int(stat.Size()))
+			if err := file.Close(); err != nil {
+				return err
+			}
+		} else {
+			// The body stream closes the file once it is sent
+			c.fasthttp.Response.SetBodyStream(file, int(stat.Size()))
+		}
+		return cfg.modifyResponse(c, name)
+	}
+
+	app.addStaticRoute(prefix, isRoot, handler)
+	return app
+}
+
+// hasDotDotSegment reports whether the decoded path contains a ".." segment
+func hasDotDotSegment(path []byte) bool {
+	decoded, err := url.PathUnescape(getString(path))
+	if err != nil {
+		return true
+	}
+	for _, segment := range strings.FieldsFunc(decoded, func(r rune) bool {
+		return r == '/' || r == '\\'
+	}) {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// staticFSCompress returns the compressed file for the encoding, the body is
+// nil if the file doesn't compress well enough.
+func staticFSCompress(cache *sync.Map, fsys fs.FS, name string, stat fs.FileInfo, encoding string) (*staticFSFile, error) {
+	key := encoding + ":" + name
+	if cached, ok := cache.Load(key); ok {
+		file := cached.(*staticFSFile)
+		if file.size == stat.Size() && file.modTime.Equal(stat.ModTime()) {
+			return file, nil
+		}
+	}
+	body, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var zbody []byte
+	if encoding == "br" {
+		zbody = fasthttp.AppendBrotliBytesLevel(nil, body, fasthttp.CompressBrotliDefaultCompression)
+	} else {
+		zbody = fasthttp.AppendGzipBytesLevel(nil, body, fasthttp.CompressDefaultCompression)
+	}
+	file := &staticFSFile{size: stat.Size(), modTime: stat.ModTime()}
+	if float64(len(zbody)) < float64(len(body))*staticFSMinCompressRatio {
+		file.body = zbody
+	}
+	cache.Store(key, file)
+	return file, nil
+}
+
+// staticFSDirList writes a HTML listing of the directory
+func staticFSDirList(c *Ctx, fsys fs.FS, name string) error {
+	entries, err := fs.ReadDir(fsys, name)
+	if err != nil {
+		return err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	base := c.Path()
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+	title := html.EscapeString(base)
+
+	bb := bytebufferpool.Get()
+	defer bytebufferpool.Put(bb)
+	_, _ = bb.WriteString("<html><head><title>" + title + "</title></head><body><h1>" + title + "</h1><ul>")
+	if base != "/" {
+		_, _ = bb.WriteString(`<li><a href="` + html.EscapeString(pathpkg.Dir(strings.TrimSuffix(base, "/"))) + `" class="dir">..</a></li>`)
+	}
+	for _, entry := range entries {
+		entryName, class := entry.Name(), "file"
+		if entry.IsDir() {
+			entryName, class = entryName+"/", "dir"
+		}
+		href := base + (&url.URL{Path: entryName}).EscapedPath()
+		_, _ = bb.WriteString(`<li><a href="` + html.EscapeString(href) + `" class="` + class + `">` + html.EscapeString(entryName) + "</a></li>")
+	}
+	_, _ = bb.WriteString("</ul></body></html>")
+
+	c.fasthttp.Response.Header.SetContentType(MIMETextHTMLCharsetUTF8)
+	c.fasthttp.Response.SetBody(bb.Bytes())
+	return nil
+}
@@ -0,0 +1,218 @@
+//go:build go1.16
+// +build go1.16
+
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package fiber
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2/utils"
+)
+
+// go:embed needs go1.16 in go.mod, os.DirFS serves the same files
+var testStaticFS = os.DirFS("./.github/testdata")
+
+func testStaticSubFS(t *testing.T) fs.FS {
+	sub, err := fs.Sub(testStaticFS, "fs")
+	utils.AssertEqual(t, nil, err)
+	return sub
+}
+
+// go test -run Test_App_StaticFS
+func Test_App_StaticFS(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.StaticFS("/", testStaticSubFS(t))
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, MIMETextHTML, resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderCacheControl))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, strings.Contains(string(body), "Hello, World!"))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/css/style.css", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, utils.GetMIME("css"), resp.Header.Get(HeaderContentType))
+	body, err = ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, strings.Contains(string(body), "color: red;"))
+
+	resp, err = app.Test(httptest.NewRequest(MethodHead, "/css/style.css", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, "46", resp.Header.Get(HeaderContentLength))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/not-found", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+
+	// Directories without index are not listed by default
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/css", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+}
+
+// go test -run Test_App_StaticFS_Config
+func Test_App_StaticFS_Config(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.StaticFS("/static", testStaticSubFS(t), Static{
+		Index:  "style.css",
+		Browse: true,
+		MaxAge: 100,
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/static/css/", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, utils.GetMIME("css"), resp.Header.Get(HeaderContentType))
+	utils.AssertEqual(t, "public, max-age=100", resp.Header.Get(HeaderCacheControl))
+
+	// Directories without index are listed
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/static/img", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, MIMETextHTMLCharsetUTF8, resp.Header.Get(HeaderContentType))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, strings.Contains(string(body), `<a href="/static/img/fiber.png" class="file">fiber.png</a>`))
+	utils.AssertEqual(t, true, strings.Contains(string(body), `<a href="/static" class="dir">..</a>`))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/static/index.html", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, MIMETextHTML, resp.Header.Get(HeaderContentType))
+}
+
+// go test -run Test_App_StaticFS_NotFoundFile
+func Test_App_StaticFS_NotFoundFile(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.Group("/app").(*Group).StaticFS("/", testStaticSubFS(t), Static{NotFoundFile: "index.html"})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/app/users/12", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, MIMETextHTML, resp.Header.Get(HeaderContentType))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, true, strings.Contains(string(body), "Hello, World!"))
+
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/app/css/style.css", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, utils.GetMIME("css"), resp.Header.Get(HeaderContentType))
+}
+
+// go test -run Test_App_StaticFS_Compress
+func Test_App_StaticFS_Compress(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.StaticFS("/", testStaticSubFS(t), Static{Compress: true})
+
+	expected, err := fs.ReadFile(testStaticFS, "fs/index.html")
+	utils.AssertEqual(t, nil, err)
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(MethodGet, "/index.html", nil)
+		req.Header.Set(HeaderAcceptEncoding, "gzip")
+		resp, err := app.Test(req)
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+		utils.AssertEqual(t, "gzip", resp.Header.Get(HeaderContentEncoding))
+		utils.AssertEqual(t, HeaderAcceptEncoding, resp.Header.Get(HeaderVary))
+
+		zbody, err := ioutil.ReadAll(resp.Body)
+		utils.AssertEqual(t, nil, err)
+		zr, err := gzip.NewReader(bytes.NewReader(zbody))
+		utils.AssertEqual(t, nil, err)
+		body, err := ioutil.ReadAll(zr)
+		utils.AssertEqual(t, nil, err)
+		utils.AssertEqual(t, expected, body)
+	}
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/index.html", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "", resp.Header.Get(HeaderContentEncoding))
+	body, err := ioutil.ReadAll(resp.Body)
+	utils.AssertEqual(t, nil, err)
+	utils.AssertEqual(t, expected, body)
+}
+
+// go test -run Test_App_StaticFS_ModifyResponse
+func Test_App_StaticFS_ModifyResponse(t *testing.T) {
+	t.Parallel()
+	app := New()
+
+	var files []string
+	app.StaticFS("/", testStaticSubFS(t), Static{
+		Download: true,
+		Compress: true,
+		ModifyResponse: func(c *Ctx) error {
+			files = append(files, c.Locals(StaticFileKey).(string))
+			return nil
+		},
+	})
+
+	resp, err := app.Test(httptest.NewRequest(MethodGet, "/css/style.css", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 200, resp.StatusCode, "Status code")
+	utils.AssertEqual(t, `attachment; filename="style.css"`, resp.Header.Get(HeaderContentDisposition))
+
+	req := httptest.NewRequest(MethodGet, "/", nil)
+	req.Header.Set(HeaderAcceptEncoding, "gzip")
+	resp, err = app.Test(req)
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, "gzip", resp.Header.Get(HeaderContentEncoding))
+	utils.AssertEqual(t, `attachment; filename="index.html"`, resp.Header.Get(HeaderContentDisposition))
+
+	// Not called for missing files
+	resp, err = app.Test(httptest.NewRequest(MethodGet, "/not-found", nil))
+	utils.AssertEqual(t, nil, err, "app.Test(req)")
+	utils.AssertEqual(t, 404, resp.StatusCode, "Status code")
+
+	utils.AssertEqual(t, []string{"css/style.css", "index.html"}, files)
+}
+
+// go test -run Test_App_StaticFS_PathTraversal
+func Test_App_StaticFS_PathTraversal(t *testing.T) {
+	t.Parallel()
+	app := New()
+	app.StaticFS("/", testStaticSubFS(t))
+
+	for _, path := range []string{
+		"/..%2fapp.go",
+		"/css/..%2f..%2fapp.go",
+		"/css%2f..%2f..%2fapp.go",
+		"/..%5capp.go",
+		"/%2e%2e/app.go",
+	} {
+		resp, err := app.Test(httptest.NewRequest(MethodGet, path, nil))
+		utils.AssertEqual(t, nil, err, "app.Test(req)")
+		utils.AssertEqual(t, StatusBadRequest, resp.StatusCode, path)
+	}
+}
+
+// go test -run Test_App_StaticFS_Nil
+func Test_App_StaticFS_Nil(t *testing.T) {
+	t.Parallel()
+	defer func() {
+		utils.AssertEqual(t, "static: fsys cannot be nil\n", recover())
+	}()
+	New().StaticFS("/", nil)
+}
@@ -33,6 +33,12 @@ func SafeString(s string) string {
 	return string(UnsafeBytes(s))
 }
 
+// CopyString copies a string returned by the zero-copy APIs,
+// so it can be used after the handler returned
+func CopyString(s string) string {
+	return SafeString(s)
+}
+
 // SafeBytes copies a slice to make it immutable
 func SafeBytes(b []byte) []byte {
 	tmp := make([]byte, len(b))
@@ -56,6 +56,14 @@ func Benchmark_GetBytes(b *testing.B) {
 	})
 }
 
+func Test_Utils_CopyString(t *testing.T) {
+	t.Parallel()
+	b := []byte("Hello, World!")
+	res := CopyString(UnsafeString(b))
+	b[0] = 'J'
+	AssertEqual(t, "Hello, World!", res)
+}
+
 func Test_Utils_ImmutableString(t *testing.T) {
 	t.Parallel()
 	res := ImmutableString("Hello, World!")
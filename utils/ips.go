@@ -0,0 +1,128 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package utils
+
+import "net"
+
+// IsIPv4 works the same way as net.ParseIP, but only accepts IPv4 addresses
+// and doesn't return a net.IP slice, so it makes no allocations.
+func IsIPv4(s string) bool {
+	for i := 0; i < net.IPv4len; i++ {
+		if len(s) == 0 {
+			return false
+		}
+		if i > 0 {
+			if s[0] != '.' {
+				return false
+			}
+			s = s[1:]
+		}
+		n, ci := 0, 0
+		for ci = 0; ci < len(s) && '0' <= s[ci] && s[ci] <= '9'; ci++ {
+			n = n*10 + int(s[ci]-'0')
+			if n > 0xFF {
+				return false
+			}
+		}
+		// Leading zeros are rejected like in net.ParseIP
+		if ci == 0 || (ci > 1 && s[0] == '0') {
+			return false
+		}
+		s = s[ci:]
+	}
+	return len(s) == 0
+}
+
+// IsIPv6 works the same way as net.ParseIP, but only accepts IPv6 addresses
+// and doesn't return a net.IP slice, so it makes no allocations.
+func IsIPv6(s string) bool {
+	ellipsis := -1 // position of ellipsis in ip
+
+	// Might have leading ellipsis
+	if len(s) >= 2 && s[0] == ':' && s[1] == ':' {
+		ellipsis = 0
+		s = s[2:]
+		// Might be only ellipsis
+		if len(s) == 0 {
+			return true
+		}
+	}
+
+	// Loop, parsing hex numbers followed by colon.
+	i := 0
+	for i < net.IPv6len {
+		// Hex number.
+		ci := 0
+		for ci < len(s) && isHexDigit(s[ci]) {
+			if ci++; ci > 4 {
+				return false
+			}
+		}
+		if ci == 0 {
+			return false
+		}
+
+		// If followed by dot, might be in trailing IPv4.
+		if ci < len(s) && s[ci] == '.' {
+			if ellipsis < 0 && i != net.IPv6len-net.IPv4len {
+				// Not the right place.
+				return false
+			}
+			if i+net.IPv4len > net.IPv6len {
+				// Not enough room.
+				return false
+			}
+			if !IsIPv4(s) {
+				return false
+			}
+			s = ""
+			i += net.IPv4len
+			break
+		}
+
+		// Save this 16-bit chunk.
+		i += 2
+
+		// Stop at end of string.
+		s = s[ci:]
+		if len(s) == 0 {
+			break
+		}
+
+		// Otherwise must be followed by colon and more.
+		if s[0] != ':' || len(s) == 1 {
+			return false
+		}
+		s = s[1:]
+
+		// Look for ellipsis.
+		if s[0] == ':' {
+			if ellipsis >= 0 { // already have one
+				return false
+			}
+			ellipsis = i
+			s = s[1:]
+			if len(s) == 0 { // can be at end
+				break
+			}
+		}
+	}
+
+	// Must have used entire string.
+	if len(s) != 0 {
+		return false
+	}
+
+	// If didn't parse enough, the ellipsis has to fill the gap,
+	// but it must represent at least one 0 group.
+	if i < net.IPv6len {
+		return ellipsis >= 0
+	}
+	return ellipsis < 0
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
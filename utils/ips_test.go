@@ -0,0 +1,67 @@
+// ⚡️ Fiber is an Express inspired web framework written in Go with ☕️
+// 🤖 Github Repository: https://github.com/gofiber/fiber
+// 📌 API Documentation: https://docs.gofiber.io
+
+package utils
+
+import (
+	"net"
+	"testing"
+)
+
+func Test_Utils_IsIPv4(t *testing.T) {
+	t.Parallel()
+	for _, ip := range []string{"0.0.0.0", "127.0.0.1", "255.255.255.255", "203.0.113.195"} {
+		AssertEqual(t, true, IsIPv4(ip), ip)
+		AssertEqual(t, false, IsIPv6(ip), ip)
+	}
+	for _, ip := range []string{"", "1.2.3", "1.2.3.4.5", "256.0.0.1", "01.2.3.4", "1.2.3.4 ", "1..2.3", "a.b.c.d", "unknown", "::1"} {
+		AssertEqual(t, false, IsIPv4(ip), ip)
+		AssertEqual(t, net.ParseIP(ip) != nil && net.ParseIP(ip).To4() != nil, IsIPv4(ip), ip)
+	}
+}
+
+func Test_Utils_IsIPv6(t *testing.T) {
+	t.Parallel()
+	for _, ip := range []string{"::", "::1", "2001:db8::68", "fe80::1:2:3:4", "::ffff:192.0.2.1", "2001:0db8:85a3:0000:0000:8a2e:0370:7334", "1:2:3:4:5:6:7::"} {
+		AssertEqual(t, true, IsIPv6(ip), ip)
+		AssertEqual(t, false, IsIPv4(ip), ip)
+	}
+	for _, ip := range []string{"", ":", ":::", "1::2::3", "12345::", "1:2:3:4:5:6:7:8:9", "1:2:3:4:5:6:7:8::", "2001:db8::g", "::1 ", "1.2.3.4", "[::1]", "fe80::1%eth0"} {
+		AssertEqual(t, false, IsIPv6(ip), ip)
+	}
+}
+
+// go test -v -run=^$ -bench=Benchmark_IsIPv4 -benchmem -count=4
+func Benchmark_IsIPv4(b *testing.B) {
+	var res bool
+	b.Run("fiber", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			res = IsIPv4("174.23.33.100")
+		}
+		AssertEqual(b, true, res)
+	})
+	b.Run("default", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			res = net.ParseIP("174.23.33.100") != nil
+		}
+		AssertEqual(b, true, res)
+	})
+}
+
+// go test -v -run=^$ -bench=Benchmark_IsIPv6 -benchmem -count=4
+func Benchmark_IsIPv6(b *testing.B) {
+	var res bool
+	b.Run("fiber", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			res = IsIPv6("9396:9549:b4f7:8ed0:4791:1330:8c06:e62d")
+		}
+		AssertEqual(b, true, res)
+	})
+	b.Run("default", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			res = net.ParseIP("9396:9549:b4f7:8ed0:4791:1330:8c06:e62d") != nil
+		}
+		AssertEqual(b, true, res)
+	})
+}